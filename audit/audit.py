@@ -3,5 +3,7 @@ Audit logging for compliance
 """
 import logging
 
-def log_audit(filename, entities, recommendations):
+def log_audit(filename, entities, recommendations, redaction=None):
     logging.info(f"AUDIT: File {filename} processed. Entities: {entities}. Recommendations: {recommendations}")
+    if redaction:
+        logging.info(f"AUDIT: Redaction {redaction.get('id')} applied before analysis: {redaction.get('report') or 'no PHI found'}")
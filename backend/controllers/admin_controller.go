@@ -3,13 +3,16 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AdminController struct{}
@@ -218,6 +221,12 @@ func (ctrl *AdminController) DeletePatient(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
 		return
 	}
+	services.RevokePatientShareLinks(objectID, "patient deleted")
+	services.RevokeAccountDelegations(objectID, "account deleted")
+	services.PublishWebhookEvent(services.WebhookPatientDeleted, map[string]interface{}{
+		"patient_id": objectID.Hex(),
+		"deleted_at": time.Now(),
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -256,3 +265,90 @@ func (ctrl *AdminController) DeleteDoctor(c *gin.Context) {
 		"message": "Doctor deleted successfully",
 	})
 }
+
+// SetDoctorRole assigns a doctor's role, which edit policies match on
+// POST /api/admin/doctor/:id/role
+// Body: { "role": "attending" }
+func (ctrl *AdminController) SetDoctorRole(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("doctors").UpdateOne(ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{"role": role, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update doctor"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"role":    role,
+	})
+}
+
+// GetRedactionAudit lists redaction reports of outbound AI requests, newest first
+// GET /api/admin/redactions?report_id=xxx&patient_id=xxx&destination=azure-openai
+func (ctrl *AdminController) GetRedactionAudit(c *gin.Context) {
+	filter := bson.M{}
+	for _, key := range []string{"report_id", "patient_id"} {
+		if value := c.Query(key); value != "" {
+			objID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + key})
+				return
+			}
+			filter[key] = objID
+		}
+	}
+	if destination := c.Query("destination"); destination != "" {
+		filter["destination"] = destination
+	}
+
+	collection := config.GetCollection("redaction_audit")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch redaction audit"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var reports []models.RedactionReport
+	if err = cursor.All(ctx, &reports); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode redaction audit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"redactions": reports,
+		"count":      len(reports),
+	})
+}
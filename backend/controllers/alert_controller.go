@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AlertController handles critical value alerts and their thresholds
+type AlertController struct{}
+
+func NewAlertController() *AlertController {
+	return &AlertController{}
+}
+
+// findAlerts lists alerts matching the filter, newest first
+func findAlerts(c *gin.Context, filter bson.M, errorMessage string) {
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	collection := config.GetCollection("critical_alerts")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errorMessage})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []models.CriticalAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errorMessage})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(alerts),
+		"alerts":  alerts,
+	})
+}
+
+// GetDoctorAlerts returns the critical alerts sent to a doctor
+// GET /api/doctor/alerts?doctor_id=xxx&status=open
+func (ctrl *AlertController) GetDoctorAlerts(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	findAlerts(c, bson.M{"recipient_ids": doctorObjID}, "failed to fetch alerts")
+}
+
+// AcknowledgeAlert stops re-notification of a critical alert. Any recipient
+// may acknowledge, so a covering colleague on the alert can take over.
+// POST /api/doctor/alerts/:id/acknowledge
+// Body: { "doctor_id": "xxx", "note": "..." }
+func (ctrl *AlertController) AcknowledgeAlert(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert id"})
+		return
+	}
+
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Note     string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	collection := config.GetCollection("critical_alerts")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if n, _ := config.GetCollection("doctors").CountDocuments(ctx, bson.M{"_id": doctorObjID}); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "doctor not found"})
+		return
+	}
+
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objID, "status": services.AlertOpen, "recipient_ids": doctorObjID},
+		bson.M{
+			"$set": bson.M{
+				"status":          services.AlertAcknowledged,
+				"acknowledged_by": doctorObjID,
+				"acknowledged_at": now,
+				"ack_note":        req.Note,
+				"updated_at":      now,
+			},
+			"$unset": bson.M{"next_notify_at": ""},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to acknowledge alert"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		if n, _ := collection.CountDocuments(ctx, bson.M{"_id": objID}); n == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
+			return
+		}
+		if n, _ := collection.CountDocuments(ctx, bson.M{"_id": objID, "recipient_ids": doctorObjID}); n == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the alert's recipients can acknowledge it"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "alert is already acknowledged"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Alert acknowledged",
+	})
+}
+
+// GetAllAlerts returns critical alerts across all doctors
+// GET /api/admin/critical-alerts?status=open
+func (ctrl *AlertController) GetAllAlerts(c *gin.Context) {
+	findAlerts(c, bson.M{}, "Failed to fetch alerts")
+}
+
+// ListThresholds returns the critical value thresholds
+// GET /api/admin/critical-thresholds
+func (ctrl *AlertController) ListThresholds(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	thresholds, err := services.CriticalThresholds(ctx, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thresholds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"count":      len(thresholds),
+		"thresholds": thresholds,
+	})
+}
+
+// validateThreshold checks that a threshold can match results and has a limit
+func validateThreshold(t *models.CriticalThreshold) string {
+	if t.Low == nil && t.High == nil {
+		return "Low or high limit is required"
+	}
+	if t.Low != nil && t.High != nil && *t.Low >= *t.High {
+		return "Low limit must be below the high limit"
+	}
+	return ""
+}
+
+// CreateThreshold adds a critical value threshold
+// POST /api/admin/critical-thresholds
+// Body: { "admin_id": "xxx", "name": "Potassium", "code": "2823-3", "unit": "mmol/L", "low": 2.5, "high": 6.5, "enabled": true }
+func (ctrl *AlertController) CreateThreshold(c *gin.Context) {
+	var req struct {
+		models.CriticalThreshold
+		AdminID string `json:"admin_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	threshold := req.CriticalThreshold
+	if msg := validateThreshold(&threshold); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// Seed the defaults first so adding one threshold doesn't suppress them
+	if _, err := services.CriticalThresholds(ctx, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create threshold"})
+		return
+	}
+
+	threshold.ID = primitive.NewObjectID()
+	threshold.UpdatedBy = adminObjID
+	threshold.CreatedAt = time.Now()
+	threshold.UpdatedAt = time.Now()
+	if _, err := config.GetCollection("critical_thresholds").InsertOne(ctx, threshold); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create threshold"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":   true,
+		"threshold": threshold,
+	})
+}
+
+// UpdateThreshold replaces a critical value threshold
+// PUT /api/admin/critical-thresholds/:id
+// Body: same as CreateThreshold
+func (ctrl *AlertController) UpdateThreshold(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold ID"})
+		return
+	}
+
+	var req struct {
+		models.CriticalThreshold
+		AdminID string `json:"admin_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if msg := validateThreshold(&req.CriticalThreshold); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	collection := config.GetCollection("critical_thresholds")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"name":       req.Name,
+		"aliases":    req.Aliases,
+		"code":       req.Code,
+		"unit":       req.Unit,
+		"low":        req.Low,
+		"high":       req.High,
+		"enabled":    req.Enabled,
+		"updated_by": adminObjID,
+		"updated_at": time.Now(),
+	}}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update threshold"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Threshold not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Threshold updated successfully",
+	})
+}
+
+// DeleteThreshold removes a critical value threshold. Disabling it instead
+// keeps it visible; deleting every threshold restores the defaults.
+// DELETE /api/admin/critical-thresholds/:id
+func (ctrl *AlertController) DeleteThreshold(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold ID"})
+		return
+	}
+
+	collection := config.GetCollection("critical_thresholds")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete threshold"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Threshold not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Threshold deleted successfully",
+	})
+}
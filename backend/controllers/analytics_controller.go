@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsController exposes model accuracy analytics built from doctor feedback
+type AnalyticsController struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsController() *AnalyticsController {
+	return &AnalyticsController{
+		analyticsService: &services.AnalyticsService{},
+	}
+}
+
+// computeFromQuery loads feedback and computes analytics using common query parameters:
+// from, to (YYYY-MM-DD), period (day|week|month), bins, top
+func (ctrl *AnalyticsController) computeFromQuery(c *gin.Context) (*models.FeedbackAnalytics, bool) {
+	records, ok := ctrl.loadFromQuery(c)
+	if !ok {
+		return nil, false
+	}
+
+	period := c.DefaultQuery("period", "month")
+	if period != "day" && period != "week" && period != "month" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week or month"})
+		return nil, false
+	}
+	bins, err := strconv.Atoi(c.DefaultQuery("bins", "10"))
+	if err != nil || bins < 1 || bins > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bins must be between 1 and 100"})
+		return nil, false
+	}
+	top, err := strconv.Atoi(c.DefaultQuery("top", "20"))
+	if err != nil || top < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "top must be a positive number"})
+		return nil, false
+	}
+
+	analytics := ctrl.analyticsService.ComputeAnalytics(records, period, bins, top)
+	return &analytics, true
+}
+
+// loadFromQuery loads feedback records within the optional from/to date range
+func (ctrl *AnalyticsController) loadFromQuery(c *gin.Context) ([]services.FeedbackRecord, bool) {
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+		return nil, false
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+		return nil, false
+	}
+	if !to.IsZero() {
+		// Make the end date inclusive
+		to = to.AddDate(0, 0, 1)
+	}
+
+	records, err := ctrl.analyticsService.LoadFeedback(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feedback"})
+		return nil, false
+	}
+	return records, true
+}
+
+// parseDateQuery parses an optional YYYY-MM-DD query parameter
+func parseDateQuery(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetSummary returns the full analytics report
+// GET /api/admin/analytics/summary?from=2025-01-01&to=2025-12-31&period=month
+func (ctrl *AnalyticsController) GetSummary(c *gin.Context) {
+	analytics, ok := ctrl.computeFromQuery(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"analytics": analytics,
+	})
+}
+
+// GetEntityAccuracy returns per-entity-type precision/recall and top corrections
+// GET /api/admin/analytics/entities
+func (ctrl *AnalyticsController) GetEntityAccuracy(c *gin.Context) {
+	analytics, ok := ctrl.computeFromQuery(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"feedback_count": analytics.FeedbackCount,
+		"entity_types":   analytics.EntityTypes,
+		"most_removed":   analytics.MostRemoved,
+		"most_added":     analytics.MostAdded,
+	})
+}
+
+// GetCalibration returns the confidence calibration curve
+// GET /api/admin/analytics/calibration?bins=10
+func (ctrl *AnalyticsController) GetCalibration(c *gin.Context) {
+	analytics, ok := ctrl.computeFromQuery(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"calibration": analytics.Calibration,
+	})
+}
+
+// GetTrends returns accuracy over time
+// GET /api/admin/analytics/trends?period=week
+func (ctrl *AnalyticsController) GetTrends(c *gin.Context) {
+	analytics, ok := ctrl.computeFromQuery(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"trends":  analytics.Trends,
+	})
+}
+
+// ExportTrainingData exports labelled entity decisions for model retraining
+// GET /api/admin/analytics/export?format=csv
+func (ctrl *AnalyticsController) ExportTrainingData(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	records, ok := ctrl.loadFromQuery(c)
+	if !ok {
+		return
+	}
+	examples := ctrl.analyticsService.TrainingExamples(records)
+
+	filename := fmt.Sprintf("feedback_training_%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"count":    len(examples),
+			"examples": examples,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"feedback_id", "report_id", "doctor_id", "created_at", "entity_type", "entity", "label", "predicted_confidence"})
+	for _, ex := range examples {
+		confidence := ""
+		if ex.PredictedConfidence != nil {
+			confidence = strconv.FormatFloat(*ex.PredictedConfidence, 'f', 1, 64)
+		}
+		writer.Write([]string{
+			ex.FeedbackID.Hex(),
+			ex.ReportID.Hex(),
+			ex.DoctorID.Hex(),
+			ex.CreatedAt.UTC().Format(time.RFC3339),
+			ex.EntityType,
+			ex.Entity,
+			ex.Label,
+			confidence,
+		})
+	}
+	writer.Flush()
+}
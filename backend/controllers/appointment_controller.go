@@ -0,0 +1,457 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AppointmentController handles doctor availability and patient bookings
+type AppointmentController struct{}
+
+func NewAppointmentController() *AppointmentController {
+	return &AppointmentController{}
+}
+
+// appointmentError maps booking errors to HTTP responses
+func appointmentError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrSlotNotFound), errors.Is(err, services.ErrAppointmentNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrAppointmentNotAllowed):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrReportNotReviewed):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrSlotUnavailable), errors.Is(err, services.ErrSlotOverlap),
+		errors.Is(err, services.ErrAppointmentConflict), errors.Is(err, services.ErrAppointmentClosed),
+		errors.Is(err, services.ErrCancelNoticePassed), errors.Is(err, services.ErrRescheduleLimit):
+		status = http.StatusConflict
+	default:
+		c.JSON(status, gin.H{"error": "failed to update appointment"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// findSlots lists slots matching the filter within the optional from/to
+// query range, earliest first
+func findSlots(c *gin.Context, filter bson.M) {
+	startsAt, _ := filter["starts_at"].(bson.M)
+	if startsAt == nil {
+		startsAt = bson.M{}
+	}
+	for param, op := range map[string]string{"from": "$gte", "to": "$lt"} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+				return
+			}
+			startsAt[op] = t
+		}
+	}
+	if len(startsAt) > 0 {
+		filter["starts_at"] = startsAt
+	}
+
+	collection := config.GetCollection("availability_slots")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"starts_at": 1}).SetLimit(500)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch availability"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	slots := []models.AvailabilitySlot{}
+	if err := cursor.All(ctx, &slots); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(slots),
+		"slots":   slots,
+	})
+}
+
+// findAppointments lists appointments matching the filter, earliest first
+func findAppointments(c *gin.Context, filter bson.M) {
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if c.Query("upcoming") == "true" {
+		filter["starts_at"] = bson.M{"$gte": time.Now()}
+	}
+
+	collection := config.GetCollection("appointments")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"starts_at": 1}).SetLimit(200)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch appointments"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	appointments := []models.Appointment{}
+	if err := cursor.All(ctx, &appointments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode appointments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"count":        len(appointments),
+		"appointments": appointments,
+	})
+}
+
+// CreateAvailability offers new slots for booking
+// POST /api/doctor/availability
+// Body: { "doctor_id": "xxx", "slots": [{ "starts_at": "2025-07-01T09:00:00Z", "ends_at": "2025-07-01T09:30:00Z", "mode": "video", "location": "" }] }
+func (ctrl *AppointmentController) CreateAvailability(c *gin.Context) {
+	var req struct {
+		DoctorID string                    `json:"doctor_id" binding:"required"`
+		Slots    []models.AvailabilitySlot `json:"slots" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	if len(req.Slots) == 0 || len(req.Slots) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "between 1 and 200 slots can be added at a time"})
+		return
+	}
+	now := time.Now()
+	for i := range req.Slots {
+		if msg := services.ValidateSlot(&req.Slots[i], now); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg, "slot": i})
+			return
+		}
+	}
+
+	if err := services.CreateSlots(doctorObjID, req.Slots); err != nil {
+		if errors.Is(err, services.ErrSlotOverlap) {
+			appointmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save availability"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"slots":   req.Slots,
+	})
+}
+
+// GetAvailability returns a doctor's slots, booked or not
+// GET /api/doctor/availability?doctor_id=xxx&from=2025-07-01T00:00:00Z&to=2025-07-08T00:00:00Z
+func (ctrl *AppointmentController) GetAvailability(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	findSlots(c, bson.M{"doctor_id": doctorObjID})
+}
+
+// DeleteAvailability withdraws a slot that hasn't been booked
+// DELETE /api/doctor/availability/:id?doctor_id=xxx
+func (ctrl *AppointmentController) DeleteAvailability(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slot id"})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	collection := config.GetCollection("availability_slots")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID, "doctor_id": doctorObjID, "appointment_id": bson.M{"$exists": false}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete slot"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "slot not found or already booked - cancel the appointment first"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Slot deleted",
+	})
+}
+
+// GetDoctorAppointments returns a doctor's appointments
+// GET /api/doctor/appointments?doctor_id=xxx&status=booked&upcoming=true
+func (ctrl *AppointmentController) GetDoctorAppointments(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	findAppointments(c, bson.M{"doctor_id": doctorObjID})
+}
+
+// CancelDoctorAppointment cancels an appointment; doctors must give a reason
+// POST /api/doctor/appointments/:id/cancel
+// Body: { "doctor_id": "xxx", "reason": "..." }
+func (ctrl *AppointmentController) CancelDoctorAppointment(c *gin.Context) {
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Reason   string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctrl.cancel(c, req.DoctorID, "doctor_id", services.RecipientDoctor, req.Reason)
+}
+
+// CreateCalendarFeed returns the doctor's private .ics feed URL. Setting
+// rotate replaces the token so existing subscriptions stop working.
+// POST /api/doctor/calendar-feed
+// Body: { "doctor_id": "xxx", "rotate": false }
+func (ctrl *AppointmentController) CreateCalendarFeed(c *gin.Context) {
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Rotate   bool   `json:"rotate"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	token, err := services.CalendarFeedToken(doctorObjID, req.Rotate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"url":     "/api/calendar/" + token + "/appointments.ics",
+	})
+}
+
+// GetCalendarFeed serves a doctor's appointments as iCalendar. The token in
+// the path is the only credential, so calendar apps can subscribe.
+// GET /api/calendar/:token/appointments.ics
+func (ctrl *AppointmentController) GetCalendarFeed(c *gin.Context) {
+	doctorObjID, err := services.CalendarFeedDoctor(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+	appointments, err := services.CalendarFeedAppointments(doctorObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch appointments"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	name := "Appointments"
+	var doctor models.Doctor
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorObjID}).Decode(&doctor); err == nil {
+		name = doctor.Name + " - Appointments"
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Status(http.StatusOK)
+	services.WriteAppointmentsICS(c.Writer, name, appointments)
+}
+
+// GetOpenSlots returns a doctor's future slots that can still be booked
+// GET /api/patient/doctors/:doctor_id/availability?from=...&to=...
+func (ctrl *AppointmentController) GetOpenSlots(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Param("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	findSlots(c, bson.M{
+		"doctor_id":      doctorObjID,
+		"appointment_id": bson.M{"$exists": false},
+		"starts_at":      bson.M{"$gt": time.Now()},
+	})
+}
+
+// GetPatientAppointments returns a patient's appointments
+// GET /api/patient/appointments?patient_id=xxx&status=booked&upcoming=true
+func (ctrl *AppointmentController) GetPatientAppointments(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	findAppointments(c, bson.M{"patient_id": patientObjID})
+}
+
+// BookAppointment books a slot to discuss reviewed reports
+// POST /api/patient/appointments
+// Body: { "patient_id": "xxx", "slot_id": "yyy", "report_ids": ["zzz"], "reason": "..." }
+func (ctrl *AppointmentController) BookAppointment(c *gin.Context) {
+	var req struct {
+		PatientID string   `json:"patient_id" binding:"required"`
+		SlotID    string   `json:"slot_id" binding:"required"`
+		ReportIDs []string `json:"report_ids" binding:"required"`
+		Reason    string   `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	slotObjID, err := primitive.ObjectIDFromHex(req.SlotID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slot_id"})
+		return
+	}
+	if len(req.ReportIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one report is required"})
+		return
+	}
+	seen := map[primitive.ObjectID]bool{}
+	var reportIDs []primitive.ObjectID
+	for _, id := range req.ReportIDs {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id: " + id})
+			return
+		}
+		if !seen[objID] {
+			seen[objID] = true
+			reportIDs = append(reportIDs, objID)
+		}
+	}
+
+	appointment, err := services.BookAppointment(patientObjID, slotObjID, reportIDs, req.Reason)
+	if err != nil {
+		appointmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":     true,
+		"appointment": appointment,
+	})
+}
+
+// CancelPatientAppointment cancels an appointment before the notice period
+// POST /api/patient/appointments/:id/cancel
+// Body: { "patient_id": "xxx", "reason": "..." }
+func (ctrl *AppointmentController) CancelPatientAppointment(c *gin.Context) {
+	var req struct {
+		PatientID string `json:"patient_id" binding:"required"`
+		Reason    string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctrl.cancel(c, req.PatientID, "patient_id", services.RecipientPatient, req.Reason)
+}
+
+func (ctrl *AppointmentController) cancel(c *gin.Context, actorID, field, actorType, reason string) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+	actorObjID, err := primitive.ObjectIDFromHex(actorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + field})
+		return
+	}
+
+	if err := services.CancelAppointment(objID, actorObjID, actorType, reason); err != nil {
+		appointmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Appointment cancelled",
+	})
+}
+
+// RescheduleAppointment moves an appointment to another slot of the same doctor
+// POST /api/patient/appointments/:id/reschedule
+// Body: { "patient_id": "xxx", "slot_id": "yyy" }
+func (ctrl *AppointmentController) RescheduleAppointment(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment id"})
+		return
+	}
+	var req struct {
+		PatientID string `json:"patient_id" binding:"required"`
+		SlotID    string `json:"slot_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	slotObjID, err := primitive.ObjectIDFromHex(req.SlotID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slot_id"})
+		return
+	}
+
+	appointment, err := services.RescheduleAppointment(objID, patientObjID, slotObjID)
+	if err != nil {
+		appointmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"appointment": appointment,
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -144,7 +145,7 @@ func (ctrl *AuthController) PatientLogin(c *gin.Context) {
 	})
 }
 
-// DoctorSignup creates a new doctor account
+// DoctorSignup creates a new doctor account with the default role
 // POST /api/auth/doctor/signup
 func (ctrl *AuthController) DoctorSignup(c *gin.Context) {
 	var req struct {
@@ -196,6 +197,7 @@ func (ctrl *AuthController) DoctorSignup(c *gin.Context) {
 		Specialization: req.Specialization,
 		LicenseNumber:  req.LicenseNumber,
 		Hospital:       req.Hospital,
+		Role:           services.DefaultDoctorRole,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -0,0 +1,568 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CareRelationshipController manages doctor-patient care relationships and
+// break-glass emergency access
+type CareRelationshipController struct{}
+
+func NewCareRelationshipController() *CareRelationshipController {
+	return &CareRelationshipController{}
+}
+
+// findRelationships lists relationships matching the filter, newest first
+func findRelationships(c *gin.Context, filter bson.M) {
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("care_relationships").Find(ctx, filter,
+		options.Find().SetSort(bson.M{"updated_at": -1}).SetLimit(500))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch care relationships"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	relationships := []models.CareRelationship{}
+	if err := cursor.All(ctx, &relationships); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode care relationships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"count":         len(relationships),
+		"relationships": relationships,
+	})
+}
+
+// endRelationship ends an active relationship; doctorID limits it to the
+// doctor's own relationships
+func endRelationship(ctx context.Context, id primitive.ObjectID, doctorID *primitive.ObjectID, endedBy models.CareRelationshipEndedBy) (bool, error) {
+	filter := bson.M{"_id": id, "status": services.CareRelationshipActive}
+	if doctorID != nil {
+		filter["doctor_id"] = *doctorID
+	}
+	now := time.Now()
+	result, err := config.GetCollection("care_relationships").UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"status":     services.CareRelationshipEnded,
+		"ended_at":   now,
+		"ended_by":   endedBy,
+		"updated_at": now,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// GetDoctorRelationships returns a doctor's care relationships
+// GET /api/doctor/care-relationships?doctor_id=xxx&status=active
+func (ctrl *CareRelationshipController) GetDoctorRelationships(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	findRelationships(c, bson.M{"doctor_id": doctorObjID})
+}
+
+// EndDoctorRelationship lets a doctor discharge a patient from their care
+// POST /api/doctor/care-relationships/:id/end
+// Body: { "doctor_id": "xxx", "reason": "..." }
+func (ctrl *CareRelationshipController) EndDoctorRelationship(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid care relationship id"})
+		return
+	}
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ended, err := endRelationship(ctx, objID, &doctorObjID, models.CareRelationshipEndedBy{
+		ID: doctorObjID, Type: services.RecipientDoctor, Reason: req.Reason,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to end care relationship"})
+		return
+	}
+	if !ended {
+		c.JSON(http.StatusNotFound, gin.H{"error": "active care relationship not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Care relationship ended",
+	})
+}
+
+// ReferPatient refers a patient the doctor cares for to another doctor, who
+// gains a care relationship and is notified
+// POST /api/doctor/patients/:patient_id/referrals
+// Body: { "doctor_id": "xxx", "consultant_id": "yyy", "note": "..." }
+func (ctrl *CareRelationshipController) ReferPatient(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+	var req struct {
+		DoctorID     string `json:"doctor_id" binding:"required"`
+		ConsultantID string `json:"consultant_id" binding:"required"`
+		Note         string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	consultantObjID, err := primitive.ObjectIDFromHex(req.ConsultantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid consultant_id"})
+		return
+	}
+	if consultantObjID == doctorObjID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "consultant must be another doctor"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Break-glass access is for reading in an emergency, not for handing patients on
+	related, err := services.HasCareRelationship(ctx, doctorObjID, patientObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+		return
+	}
+	if !related {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only doctors caring for the patient can refer them"})
+		return
+	}
+	var requester, consultant models.Doctor
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorObjID}).Decode(&requester); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "doctor not found"})
+		return
+	}
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": consultantObjID}).Decode(&consultant); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "consultant not found"})
+		return
+	}
+
+	services.EnsureCareRelationship(consultantObjID, patientObjID, models.CareRelationshipSource{
+		Type: services.CareSourceReferral, ByID: &doctorObjID, ByType: services.RecipientDoctor,
+	}, nil)
+
+	body := fmt.Sprintf("Dr. %s referred patient %s to you.", requester.Name, patientObjID.Hex())
+	if req.Note != "" {
+		body += " Note: " + req.Note
+	}
+	services.Notify(&models.Notification{
+		UserID:   consultantObjID,
+		UserType: services.RecipientDoctor,
+		Event:    services.EventReferral,
+		Title:    "New patient referral",
+		Body:     body,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Patient referred",
+	})
+}
+
+// BreakGlass gives a doctor short-lived emergency access to a patient they
+// have no care relationship with. The patient and admins are notified.
+// POST /api/doctor/patients/:patient_id/break-glass
+// Body: { "doctor_id": "xxx", "reason": "Unconscious in ED, need prior results" }
+func (ctrl *CareRelationshipController) BreakGlass(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Param("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Reason   string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) < services.MinBreakGlassReason {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reason must be at least %d characters", services.MinBreakGlassReason)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var doctor models.Doctor
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorObjID}).Decode(&doctor); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "doctor not found"})
+		return
+	}
+	if n, _ := config.GetCollection("patients").CountDocuments(ctx, bson.M{"_id": patientObjID}); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if related, _ := services.HasCareRelationship(ctx, doctorObjID, patientObjID); related {
+		c.JSON(http.StatusConflict, gin.H{"error": "you already have a care relationship with this patient"})
+		return
+	}
+
+	grant, err := services.StartBreakGlass(&doctor, patientObjID, reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start emergency access"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"access":  grant,
+		"message": "Emergency access granted until " + grant.ExpiresAt.Format(time.RFC3339) + ". The patient and administrators have been notified.",
+	})
+}
+
+// GetCareTeam shows a patient which doctors care for them and any
+// emergency access to their records
+// GET /api/patient/care-team?patient_id=xxx
+func (ctrl *CareRelationshipController) GetCareTeam(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("care_relationships").Find(ctx,
+		bson.M{"patient_id": patientObjID, "status": services.CareRelationshipActive},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch care team"})
+		return
+	}
+	relationships := []models.CareRelationship{}
+	if err := cursor.All(ctx, &relationships); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode care team"})
+		return
+	}
+
+	cursor, err = config.GetCollection("break_glass_access").Find(ctx,
+		bson.M{"patient_id": patientObjID},
+		options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(100))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch emergency access"})
+		return
+	}
+	emergency := []models.BreakGlassAccess{}
+	if err := cursor.All(ctx, &emergency); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode emergency access"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"relationships":    relationships,
+		"emergency_access": emergency,
+	})
+}
+
+// AdminListRelationships returns care relationships by doctor or patient
+// GET /api/admin/care-relationships?doctor_id=xxx&patient_id=yyy&status=active
+func (ctrl *CareRelationshipController) AdminListRelationships(c *gin.Context) {
+	filter := bson.M{}
+	for _, param := range []string{"doctor_id", "patient_id"} {
+		if value := c.Query(param); value != "" {
+			objID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			filter[param] = objID
+		}
+	}
+	findRelationships(c, filter)
+}
+
+// AdminCreateRelationship assigns a doctor to a patient, optionally until a date
+// POST /api/admin/care-relationships
+// Body: { "admin_id": "xxx", "doctor_id": "yyy", "patient_id": "zzz", "ends_at": "2026-01-01T00:00:00Z" }
+func (ctrl *CareRelationshipController) AdminCreateRelationship(c *gin.Context) {
+	var req struct {
+		AdminID   string     `json:"admin_id" binding:"required"`
+		DoctorID  string     `json:"doctor_id" binding:"required"`
+		PatientID string     `json:"patient_id" binding:"required"`
+		EndsAt    *time.Time `json:"ends_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be in the future"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if n, _ := config.GetCollection("doctors").CountDocuments(ctx, bson.M{"_id": doctorObjID}); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return
+	}
+	if n, _ := config.GetCollection("patients").CountDocuments(ctx, bson.M{"_id": patientObjID}); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		return
+	}
+
+	services.EnsureCareRelationship(doctorObjID, patientObjID, models.CareRelationshipSource{
+		Type: services.CareSourceAdmin, ByID: &adminObjID, ByType: services.RecipientAdmin,
+	}, req.EndsAt)
+
+	var relationship models.CareRelationship
+	err = config.GetCollection("care_relationships").FindOne(ctx, bson.M{
+		"doctor_id": doctorObjID, "patient_id": patientObjID, "status": services.CareRelationshipActive,
+	}).Decode(&relationship)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create care relationship"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":      true,
+		"relationship": relationship,
+	})
+}
+
+// AdminEndRelationship ends any care relationship
+// POST /api/admin/care-relationships/:id/end
+// Body: { "admin_id": "xxx", "reason": "..." }
+func (ctrl *CareRelationshipController) AdminEndRelationship(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid care relationship ID"})
+		return
+	}
+	var req struct {
+		AdminID string `json:"admin_id" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ended, err := endRelationship(ctx, objID, nil, models.CareRelationshipEndedBy{
+		ID: adminObjID, Type: services.RecipientAdmin, Reason: req.Reason,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end care relationship"})
+		return
+	}
+	if !ended {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Active care relationship not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Care relationship ended",
+	})
+}
+
+// ListBreakGlass returns break-glass grants, unreviewed first when reviewed=false
+// GET /api/admin/break-glass?reviewed=false&doctor_id=xxx
+func (ctrl *CareRelationshipController) ListBreakGlass(c *gin.Context) {
+	filter := bson.M{}
+	switch c.Query("reviewed") {
+	case "false":
+		filter["reviewed_at"] = bson.M{"$exists": false}
+	case "true":
+		filter["reviewed_at"] = bson.M{"$exists": true}
+	}
+	if doctorID := c.Query("doctor_id"); doctorID != "" {
+		doctorObjID, err := primitive.ObjectIDFromHex(doctorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+			return
+		}
+		filter["doctor_id"] = doctorObjID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("break_glass_access").Find(ctx, filter,
+		options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(200))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch break-glass access"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	grants := []models.BreakGlassAccess{}
+	if err := cursor.All(ctx, &grants); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode break-glass access"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(grants),
+		"access":  grants,
+	})
+}
+
+// ReviewBreakGlass records an admin's review of an emergency access
+// POST /api/admin/break-glass/:id/review
+// Body: { "admin_id": "xxx", "note": "Justified - ED admission" }
+func (ctrl *CareRelationshipController) ReviewBreakGlass(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid break-glass ID"})
+		return
+	}
+	var req struct {
+		AdminID string `json:"admin_id" binding:"required"`
+		Note    string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("break_glass_access").UpdateOne(ctx,
+		bson.M{"_id": objID, "reviewed_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"reviewed_by": adminObjID, "reviewed_at": time.Now(), "review_note": req.Note}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review break-glass access"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Break-glass access not found or already reviewed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Break-glass access reviewed",
+	})
+}
+
+// SetDoctorOnCall puts a doctor on or off call. On-call doctors triage
+// reports of patients who have no care team yet.
+// POST /api/admin/doctor/:id/on-call
+// Body: { "on_call": true }
+func (ctrl *CareRelationshipController) SetDoctorOnCall(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+	var req struct {
+		OnCall bool `json:"on_call"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("doctors").UpdateOne(ctx,
+		bson.M{"_id": doctorObjID},
+		bson.M{"$set": bson.M{"on_call": req.OnCall, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update doctor"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"on_call": req.OnCall,
+	})
+}
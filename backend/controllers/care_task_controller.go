@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CareTaskController handles follow-up care tasks created from recommendations
+type CareTaskController struct{}
+
+func NewCareTaskController() *CareTaskController {
+	return &CareTaskController{}
+}
+
+// careTaskStatusRequest is the body of a care task status change
+type careTaskStatusRequest struct {
+	Status       string     `json:"status" binding:"required"` // "open", "scheduled", "completed", "declined"
+	ScheduledFor *time.Time `json:"scheduled_for"`             // Required when scheduling
+	Note         string     `json:"note"`
+}
+
+// findCareTasks lists tasks matching the filter, soonest due first
+func findCareTasks(c *gin.Context, filter bson.M) {
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	collection := config.GetCollection("care_tasks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}}).SetLimit(200)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch care tasks"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []models.CareTask{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode care tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(tasks),
+		"tasks":   tasks,
+	})
+}
+
+// updateCareTask applies a status change by a patient or doctor. Patients may
+// only change their own tasks.
+func updateCareTask(c *gin.Context, actorID primitive.ObjectID, actorType string, req *careTaskStatusRequest) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid care task id"})
+		return
+	}
+
+	collection := config.GetCollection("care_tasks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var task models.CareTask
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&task); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "care task not found"})
+		return
+	}
+	if actorType == services.RecipientPatient && task.PatientID != actorID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	if !services.CareTaskTransitionAllowed(task.Status, req.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "cannot change a " + task.Status + " task to " + req.Status})
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"status": req.Status, "updated_at": now}
+	unset := bson.M{}
+	previous := task.Status
+	task.Status = req.Status
+	switch req.Status {
+	case services.CareTaskScheduled:
+		if req.ScheduledFor == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scheduled_for is required to schedule a task"})
+			return
+		}
+		// Reminders start over for the appointment
+		task.ScheduledFor = req.ScheduledFor
+		task.ReminderCount, task.LastRemindedAt = 0, nil
+		set["scheduled_for"] = *req.ScheduledFor
+		set["reminder_count"] = 0
+		unset["last_reminded_at"] = ""
+	case services.CareTaskOpen:
+		task.ScheduledFor = nil
+		unset["scheduled_for"] = ""
+	case services.CareTaskCompleted:
+		set["completed_at"] = now
+	case services.CareTaskDeclined:
+		set["declined_reason"] = req.Note
+	}
+	if next := services.NextCareTaskReminder(&task, now); next != nil {
+		set["next_reminder_at"] = *next
+	} else {
+		unset["next_reminder_at"] = ""
+	}
+
+	event := models.CareTaskEvent{At: now, Status: req.Status, ByID: &actorID, ByType: actorType, Note: req.Note}
+	update := bson.M{"$set": set, "$push": bson.M{"history": event}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	// Matching the previous status keeps a concurrent change from being overwritten
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID, "status": previous}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update care task"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "care task was changed by someone else - reload and try again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Care task " + req.Status,
+	})
+}
+
+// GetPatientTasks returns a patient's care tasks
+// GET /api/patient/care-tasks?patient_id=xxx&status=open
+func (ctrl *CareTaskController) GetPatientTasks(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	findCareTasks(c, bson.M{"patient_id": patientObjID})
+}
+
+// UpdatePatientTask lets a patient schedule, complete or decline a task
+// POST /api/patient/care-tasks/:id/status
+// Body: { "patient_id": "xxx", "status": "scheduled", "scheduled_for": "2025-07-01T09:00:00Z", "note": "..." }
+func (ctrl *CareTaskController) UpdatePatientTask(c *gin.Context) {
+	var req struct {
+		careTaskStatusRequest
+		PatientID string `json:"patient_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	updateCareTask(c, patientObjID, services.RecipientPatient, &req.careTaskStatusRequest)
+}
+
+// GetDoctorTasks returns the care tasks assigned to a doctor, or all tasks of
+// one patient they care for when patient_id is given
+// GET /api/doctor/care-tasks?doctor_id=xxx&status=open&patient_id=yyy
+func (ctrl *CareTaskController) GetDoctorTasks(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	filter := bson.M{"assignees": bson.M{"$elemMatch": bson.M{"user_id": doctorObjID, "user_type": services.RecipientDoctor}}}
+	if patientID := c.Query("patient_id"); patientID != "" {
+		patientObjID, err := primitive.ObjectIDFromHex(patientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		grant, err := services.DoctorPatientAccess(ctx, doctorObjID, patientObjID)
+		if errors.Is(err, services.ErrNoCareRelationship) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "no care relationship with this patient"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+			return
+		}
+		services.RecordBreakGlassUse(grant, "care_tasks", nil)
+		filter = bson.M{"patient_id": patientObjID}
+	}
+	findCareTasks(c, filter)
+}
+
+// UpdateDoctorTask lets a doctor schedule, complete or decline a task
+// POST /api/doctor/care-tasks/:id/status
+// Body: { "doctor_id": "xxx", "status": "completed", "note": "..." }
+func (ctrl *CareTaskController) UpdateDoctorTask(c *gin.Context) {
+	var req struct {
+		careTaskStatusRequest
+		DoctorID string `json:"doctor_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	updateCareTask(c, doctorObjID, services.RecipientDoctor, &req.careTaskStatusRequest)
+}
+
+// AssignTask adds a patient or doctor to a task's assignees
+// POST /api/doctor/care-tasks/:id/assignees
+// Body: { "doctor_id": "xxx", "user_id": "yyy", "user_type": "doctor" }
+func (ctrl *CareTaskController) AssignTask(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid care task id"})
+		return
+	}
+
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		UserID   string `json:"user_id" binding:"required"`
+		UserType string `json:"user_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	userObjID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+	if !services.ValidRecipientType(req.UserType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_type must be patient or doctor"})
+		return
+	}
+
+	collection := config.GetCollection("care_tasks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var task models.CareTask
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&task); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "care task not found"})
+		return
+	}
+	// A task belongs to one patient; other patients can't be assigned to it
+	if req.UserType == services.RecipientPatient && userObjID != task.PatientID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only the task's patient can be assigned"})
+		return
+	}
+
+	assignee := models.CareTaskAssignee{UserID: userObjID, UserType: req.UserType}
+	now := time.Now()
+	event := models.CareTaskEvent{At: now, Status: task.Status, ByID: &doctorObjID, ByType: services.RecipientDoctor,
+		Note: "Assigned " + req.UserType + " " + req.UserID}
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{
+		"$addToSet": bson.M{"assignees": assignee},
+		"$push":     bson.M{"history": event},
+		"$set":      bson.M{"updated_at": now},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign care task"})
+		return
+	}
+	if req.UserType == services.RecipientDoctor {
+		services.EnsureCareRelationship(userObjID, task.PatientID, models.CareRelationshipSource{
+			Type: services.CareSourceAssignment, RefID: &task.ReportID, ByID: &doctorObjID, ByType: services.RecipientDoctor,
+		}, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Care task assigned",
+	})
+}
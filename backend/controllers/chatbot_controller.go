@@ -1,13 +1,18 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ChatbotController struct {
@@ -20,6 +25,52 @@ func NewChatbotController() *ChatbotController {
 	}
 }
 
+// chatActorID returns the account making a chat request: the signed-in user
+// when auth middleware is present, otherwise the delegate or patient named
+// in the request
+func chatActorID(c *gin.Context, patientID, delegateID string) (primitive.ObjectID, bool) {
+	if userID, exists := c.Get("user_id"); exists {
+		actorObjID, ok := userID.(primitive.ObjectID)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		}
+		return actorObjID, ok
+	}
+
+	if delegateID != "" {
+		actorObjID, err := primitive.ObjectIDFromHex(delegateID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delegate_id"})
+			return primitive.NilObjectID, false
+		}
+		return actorObjID, true
+	}
+	if patientID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patient_id is required"})
+		return primitive.NilObjectID, false
+	}
+	actorObjID, err := primitive.ObjectIDFromHex(patientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient_id"})
+		return primitive.NilObjectID, false
+	}
+	return actorObjID, true
+}
+
+// authorizeChat checks the actor may chat about the report: the report's
+// patient or one of their delegates with the chat scope
+func authorizeChat(c *gin.Context, actorID primitive.ObjectID, report *models.Report) (*models.Actor, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	actor, err := services.AuthorizeActor(ctx, actorID, report.PatientID, services.DelegateScopeChat)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return actor, true
+}
+
 // SendMessage handles incoming chat messages from patients
 // POST /api/chatbot/message
 func (ctrl *ChatbotController) SendMessage(c *gin.Context) {
@@ -30,31 +81,9 @@ func (ctrl *ChatbotController) SendMessage(c *gin.Context) {
 		return
 	}
 
-	// Get patient ID from context (set by auth middleware) OR from request body
-	var patientObjID primitive.ObjectID
-
-	// Try to get from auth middleware first
-	patientID, exists := c.Get("user_id")
-	if exists {
-		var ok bool
-		patientObjID, ok = patientID.(primitive.ObjectID)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
-			return
-		}
-	} else {
-		// Fall back to patient_id from request body
-		if req.PatientID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "patient_id is required"})
-			return
-		}
-
-		var err error
-		patientObjID, err = primitive.ObjectIDFromHex(req.PatientID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient_id"})
-			return
-		}
+	actorID, ok := chatActorID(c, req.PatientID, req.DelegateID)
+	if !ok {
+		return
 	}
 
 	// Convert report ID
@@ -64,31 +93,31 @@ func (ctrl *ChatbotController) SendMessage(c *gin.Context) {
 		return
 	}
 
-	// Verify report belongs to this patient
+	// Verify the report belongs to this patient or someone they act for
 	report, err := services.GetReportByID(req.ReportID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
 		return
 	}
-
-	if report.PatientID != patientObjID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	actor, ok := authorizeChat(c, actorID, report)
+	if !ok {
 		return
 	}
 
 	// Get or create chat session
-	session, err := ctrl.chatService.GetOrCreateSession(patientObjID, reportObjID)
+	session, err := ctrl.chatService.GetOrCreateSession(report.PatientID, reportObjID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
 
 	// Process the message
-	response, err := ctrl.chatService.ProcessMessage(session.ID.Hex(), req.ReportID, req.Message)
+	response, err := ctrl.chatService.ProcessMessage(session.ID.Hex(), req.ReportID, req.Message, actor)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process message"})
 		return
 	}
+	services.RecordDelegateActivity(actor, report.PatientID, "chat_message", &report.ID)
 
 	c.JSON(http.StatusOK, models.ChatResponse{
 		SessionID: session.ID.Hex(),
@@ -98,35 +127,13 @@ func (ctrl *ChatbotController) SendMessage(c *gin.Context) {
 }
 
 // GetChatHistory retrieves conversation history for a report
-// GET /api/chatbot/history/:report_id?patient_id=xxx
+// GET /api/chatbot/history/:report_id?patient_id=xxx&delegate_id=yyy
 func (ctrl *ChatbotController) GetChatHistory(c *gin.Context) {
 	reportID := c.Param("report_id")
 
-	// Get patient ID from context OR from query parameter
-	var patientObjID primitive.ObjectID
-
-	patientID, exists := c.Get("user_id")
-	if exists {
-		var ok bool
-		patientObjID, ok = patientID.(primitive.ObjectID)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
-			return
-		}
-	} else {
-		// Fall back to query parameter
-		patientIDStr := c.Query("patient_id")
-		if patientIDStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "patient_id is required"})
-			return
-		}
-
-		var err error
-		patientObjID, err = primitive.ObjectIDFromHex(patientIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient_id"})
-			return
-		}
+	actorID, ok := chatActorID(c, c.Query("patient_id"), c.Query("delegate_id"))
+	if !ok {
+		return
 	}
 
 	reportObjID, err := primitive.ObjectIDFromHex(reportID)
@@ -135,15 +142,186 @@ func (ctrl *ChatbotController) GetChatHistory(c *gin.Context) {
 		return
 	}
 
+	report, err := services.GetReportByID(reportID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	actor, ok := authorizeChat(c, actorID, report)
+	if !ok {
+		return
+	}
+
 	// Get session
-	session, err := ctrl.chatService.GetOrCreateSession(patientObjID, reportObjID)
+	session, err := ctrl.chatService.GetOrCreateSession(report.PatientID, reportObjID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve session"})
 		return
 	}
+	services.RecordDelegateActivity(actor, report.PatientID, "chat_history", &report.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"session_id": session.ID.Hex(),
 		"messages":   session.Messages,
 	})
 }
+
+// EscalateChat hands a chatbot conversation over to the patient's care team
+// and notifies them; admins are told when the patient has no care team
+// POST /api/chatbot/escalate
+// Body: { "patient_id": "xxx", "delegate_id": "optional", "report_id": "xxx", "message": "..." }
+func (ctrl *ChatbotController) EscalateChat(c *gin.Context) {
+	var req models.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, ok := chatActorID(c, req.PatientID, req.DelegateID)
+	if !ok {
+		return
+	}
+
+	report, err := services.GetReportByID(req.ReportID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	actor, ok := authorizeChat(c, actorID, report)
+	if !ok {
+		return
+	}
+
+	session, err := ctrl.chatService.GetOrCreateSession(report.PatientID, report.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	careTeam, err := services.CareTeam(ctx, report.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load care team"})
+		return
+	}
+	escalation := models.ChatEscalation{
+		ID:        primitive.NewObjectID(),
+		ReportID:  report.ID,
+		PatientID: report.PatientID,
+		SessionID: session.ID,
+		Message:   req.Message,
+		DoctorIDs: careTeam,
+		RaisedBy:  actor,
+		Status:    "open",
+		CreatedAt: time.Now(),
+	}
+	if _, err := config.GetCollection("chat_escalations").InsertOne(ctx, escalation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to escalate chat"})
+		return
+	}
+	services.RecordDelegateActivity(actor, report.PatientID, "chat_escalation", &report.ID)
+
+	title, who := "Patient asked to speak with a doctor", "A patient"
+	if actor.Type == services.ActorDelegate {
+		title, who = "A patient's caregiver asked to speak with a doctor", "A caregiver acting for the patient"
+	}
+	// Notifications may leave by email or SMS, so they name the escalation
+	// and leave the message to be read in the app
+	body := fmt.Sprintf("%s escalated the chat about report %s. Open escalation %s to read it.", who, report.ID.Hex(), escalation.ID.Hex())
+	for _, doctorID := range escalation.DoctorIDs {
+		services.Notify(&models.Notification{
+			UserID:   doctorID,
+			UserType: services.RecipientDoctor,
+			Event:    services.EventChatEscalation,
+			Title:    title,
+			Body:     body,
+			ReportID: &escalation.ReportID,
+		})
+	}
+	if len(escalation.DoctorIDs) == 0 {
+		services.NotifyAdmins(models.Notification{
+			Event:    services.EventChatEscalation,
+			Title:    "Chat escalation for a patient with no care team",
+			Body:     fmt.Sprintf("Escalation %s about report %s has no care team to route to. Assign a doctor to the patient.", escalation.ID.Hex(), report.ID.Hex()),
+			ReportID: &escalation.ReportID,
+		})
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"escalation": escalation,
+	})
+}
+
+// GetDoctorEscalations lists the chat escalations routed to a doctor, newest first
+// GET /api/doctor/chat-escalations?doctor_id=xxx
+func (ctrl *ChatbotController) GetDoctorEscalations(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("chat_escalations").Find(ctx,
+		bson.M{"doctor_ids": doctorObjID},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	escalations := []models.ChatEscalation{}
+	if err == nil {
+		err = cursor.All(ctx, &escalations)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch escalations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"escalations": escalations,
+	})
+}
+
+// GetDoctorEscalation returns one chat escalation routed to the doctor
+// GET /api/doctor/chat-escalations/:id?doctor_id=xxx
+func (ctrl *ChatbotController) GetDoctorEscalation(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid escalation id"})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var escalation models.ChatEscalation
+	if err := config.GetCollection("chat_escalations").FindOne(ctx, bson.M{"_id": objID}).Decode(&escalation); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "escalation not found"})
+		return
+	}
+	routed := false
+	for _, id := range escalation.DoctorIDs {
+		if id == doctorObjID {
+			routed = true
+			break
+		}
+	}
+	if !routed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "escalation was not routed to this doctor"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"escalation": escalation,
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ClinicalRulesController manages the declarative test recommendation rules
+type ClinicalRulesController struct{}
+
+func NewClinicalRulesController() *ClinicalRulesController {
+	return &ClinicalRulesController{}
+}
+
+// GetRules returns the active rule set and its load status
+// GET /api/admin/clinical-rules
+func (ctrl *ClinicalRulesController) GetRules(c *gin.Context) {
+	engine := services.ClinicalRules()
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"status":   engine.Status(),
+		"rule_set": engine.RuleSet(),
+	})
+}
+
+// ReloadRules re-reads the rules file immediately instead of waiting for the
+// watcher. An invalid file leaves the active rule set unchanged.
+// POST /api/admin/clinical-rules/reload
+func (ctrl *ClinicalRulesController) ReloadRules(c *gin.Context) {
+	engine := services.ClinicalRules()
+	if err := engine.Reload(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Rules not reloaded: " + err.Error(),
+			"status": engine.Status(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Clinical rules reloaded",
+		"status":  engine.Status(),
+	})
+}
+
+// TestRules runs rules against a stored report or a sample report and returns
+// the recommendations with a trace of every rule. A draft rule set can be
+// tested before it is deployed.
+// POST /api/admin/clinical-rules/test
+// Body: { "report_id": "xxx" } or { "sample": { "analysis": {...}, "observations": [...], "patient": { "age": 70, "gender": "female" }, "prior_reports": [...] } }, optionally with "rule_set": "<YAML or JSON>"
+func (ctrl *ClinicalRulesController) TestRules(c *gin.Context) {
+	var req struct {
+		ReportID string `json:"report_id"`
+		Sample   *struct {
+			Analysis     models.AIAnalysis       `json:"analysis"`
+			Observations []models.LabObservation `json:"observations"`
+			Patient      *models.Patient         `json:"patient"`
+			PriorReports []models.Report         `json:"prior_reports"`
+		} `json:"sample"`
+		RuleSet string `json:"rule_set"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := services.ClinicalRules().RuleSet()
+	draft := req.RuleSet != ""
+	if draft {
+		var err error
+		if set, err = services.ParseRuleSet([]byte(req.RuleSet)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var input *services.RuleInput
+	switch {
+	case req.ReportID != "":
+		objID, err := primitive.ObjectIDFromHex(req.ReportID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var report models.Report
+		if err := config.GetCollection("reports").FindOne(ctx, bson.M{"_id": objID}).Decode(&report); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return
+		}
+		input = services.RuleInputForReport(&report)
+	case req.Sample != nil:
+		input = &services.RuleInput{
+			Analysis:     &req.Sample.Analysis,
+			Observations: req.Sample.Observations,
+			Patient:      req.Sample.Patient,
+			Prior:        req.Sample.PriorReports,
+			Now:          time.Now(),
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report_id or sample is required"})
+		return
+	}
+
+	recommendations, traces := services.EvaluateRules(set, input)
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"rule_set":        set.Name,
+		"version":         set.Version,
+		"draft":           draft,
+		"recommendations": recommendations,
+		"traces":          traces,
+	})
+}
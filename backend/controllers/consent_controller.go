@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConsentController manages consent documents and patients' consent to AI
+// analysis, cloud chat and research use
+type ConsentController struct{}
+
+func NewConsentController() *ConsentController {
+	return &ConsentController{}
+}
+
+// consentStatus is a patient's standing for one purpose
+type consentStatus struct {
+	Purpose         string                  `json:"purpose"`
+	Consented       bool                    `json:"consented"`
+	NeedsReconsent  bool                    `json:"needs_reconsent"` // Granted to a version that no longer counts
+	CurrentDocument *models.ConsentDocument `json:"current_document,omitempty"`
+	Record          *models.PatientConsent  `json:"record,omitempty"`
+}
+
+// consentStatusCode maps consent errors to HTTP statuses
+func consentStatusCode(err error) int {
+	switch {
+	case errors.Is(err, services.ErrConsentDocumentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrConsentVersionOutdated), errors.Is(err, services.ErrConsentNotGranted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// patientConsentStatuses returns a patient's standing for every purpose
+func patientConsentStatuses(ctx context.Context, patientID primitive.ObjectID) ([]consentStatus, error) {
+	cursor, err := config.GetCollection("patient_consents").Find(ctx, bson.M{"patient_id": patientID})
+	if err != nil {
+		return nil, err
+	}
+	var records []models.PatientConsent
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	byPurpose := map[string]*models.PatientConsent{}
+	for i := range records {
+		byPurpose[records[i].Purpose] = &records[i]
+	}
+
+	statuses := make([]consentStatus, 0, len(services.ConsentPurposes))
+	for _, purpose := range services.ConsentPurposes {
+		status := consentStatus{Purpose: purpose, Record: byPurpose[purpose]}
+		doc, err := services.CurrentConsentDocument(ctx, purpose)
+		if err != nil && !errors.Is(err, services.ErrConsentDocumentNotFound) {
+			return nil, err
+		}
+		status.CurrentDocument = doc
+		if status.Record != nil {
+			consented, err := services.HasConsent(ctx, patientID, purpose)
+			if err != nil {
+				return nil, err
+			}
+			status.Consented = consented
+			status.NeedsReconsent = status.Record.Status == services.ConsentGranted && !consented
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// GetConsentDocuments returns the current document for each purpose
+// GET /api/patient/consent-documents
+func (ctrl *ConsentController) GetConsentDocuments(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	documents := []models.ConsentDocument{}
+	for _, purpose := range services.ConsentPurposes {
+		doc, err := services.CurrentConsentDocument(ctx, purpose)
+		if errors.Is(err, services.ErrConsentDocumentNotFound) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch consent documents"})
+			return
+		}
+		documents = append(documents, *doc)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"documents": documents,
+	})
+}
+
+// GetPatientConsents returns a patient's consent for each purpose with history
+// GET /api/patient/consents?patient_id=xxx
+func (ctrl *ConsentController) GetPatientConsents(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	statuses, err := patientConsentStatuses(ctx, patientObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch consents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"consents": statuses,
+	})
+}
+
+// GrantConsent records a patient's consent to the current document of a purpose
+// POST /api/patient/consents
+// Body: { "patient_id": "xxx", "purpose": "cloud_chat", "document_version": 2 }
+func (ctrl *ConsentController) GrantConsent(c *gin.Context) {
+	var req struct {
+		PatientID       string `json:"patient_id" binding:"required"`
+		Purpose         string `json:"purpose" binding:"required"`
+		DocumentVersion int    `json:"document_version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	if !services.ValidConsentPurpose(req.Purpose) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "purpose must be one of " + strings.Join(services.ConsentPurposes, ", ")})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if n, _ := config.GetCollection("patients").CountDocuments(ctx, bson.M{"_id": patientObjID}); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	consent, err := services.RecordConsent(ctx, patientObjID, req.Purpose, services.ConsentGranted, req.DocumentVersion,
+		models.ConsentEvent{ByID: patientObjID, ByType: services.ActorPatient})
+	if err != nil {
+		c.JSON(consentStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"consent": consent,
+	})
+}
+
+// WithdrawConsent withdraws a patient's consent to a purpose. It applies to
+// future processing; existing reports and released datasets are unchanged.
+// POST /api/patient/consents/:purpose/withdraw
+// Body: { "patient_id": "xxx" }
+func (ctrl *ConsentController) WithdrawConsent(c *gin.Context) {
+	purpose := c.Param("purpose")
+	if !services.ValidConsentPurpose(purpose) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "purpose must be one of " + strings.Join(services.ConsentPurposes, ", ")})
+		return
+	}
+	var req struct {
+		PatientID string `json:"patient_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	consent, err := services.RecordConsent(ctx, patientObjID, purpose, services.ConsentWithdrawn, 0,
+		models.ConsentEvent{ByID: patientObjID, ByType: services.ActorPatient})
+	if err != nil {
+		c.JSON(consentStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"consent": consent,
+	})
+}
+
+// ListConsentDocuments returns every published version, newest first
+// GET /api/admin/consent-documents?purpose=research
+func (ctrl *ConsentController) ListConsentDocuments(c *gin.Context) {
+	filter := bson.M{}
+	if purpose := c.Query("purpose"); purpose != "" {
+		filter["purpose"] = purpose
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("consent_documents").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "purpose", Value: 1}, {Key: "version", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consent documents"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	documents := []models.ConsentDocument{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode consent documents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"count":     len(documents),
+		"documents": documents,
+	})
+}
+
+// PublishConsentDocument publishes a new version of a purpose's document.
+// With requires_reconsent, consents to earlier versions stop counting until
+// patients agree again.
+// POST /api/admin/consent-documents
+// Body: { "admin_id": "xxx", "purpose": "research", "title": "...", "body": "...", "requires_reconsent": false }
+func (ctrl *ConsentController) PublishConsentDocument(c *gin.Context) {
+	var req struct {
+		AdminID           string `json:"admin_id" binding:"required"`
+		Purpose           string `json:"purpose" binding:"required"`
+		Title             string `json:"title" binding:"required"`
+		Body              string `json:"body" binding:"required"`
+		RequiresReconsent bool   `json:"requires_reconsent"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if !services.ValidConsentPurpose(req.Purpose) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Purpose must be one of " + strings.Join(services.ConsentPurposes, ", ")})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	doc := &models.ConsentDocument{
+		Purpose:           req.Purpose,
+		Title:             req.Title,
+		Body:              req.Body,
+		RequiresReconsent: req.RequiresReconsent,
+		PublishedBy:       adminObjID,
+	}
+	if err := services.PublishConsentDocument(ctx, doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish consent document"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"document": doc,
+	})
+}
+
+// AdminGetPatientConsents returns a patient's consent for each purpose
+// GET /api/admin/patient/:id/consents
+func (ctrl *ConsentController) AdminGetPatientConsents(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	statuses, err := patientConsentStatuses(ctx, patientObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"consents": statuses,
+	})
+}
+
+// AdminRecordConsent records consent given or withdrawn outside the app,
+// e.g. on a signed paper form or by a guardian for a minor
+// POST /api/admin/patient/:id/consents
+// Body: { "admin_id": "xxx", "purpose": "ai_analysis", "action": "granted", "document_version": 1, "note": "Paper form 2024-03-01" }
+func (ctrl *ConsentController) AdminRecordConsent(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+	var req struct {
+		AdminID         string `json:"admin_id" binding:"required"`
+		Purpose         string `json:"purpose" binding:"required"`
+		Action          string `json:"action" binding:"required"` // "granted", "withdrawn"
+		DocumentVersion int    `json:"document_version"`
+		Note            string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if !services.ValidConsentPurpose(req.Purpose) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Purpose must be one of " + strings.Join(services.ConsentPurposes, ", ")})
+		return
+	}
+	if req.Action != services.ConsentGranted && req.Action != services.ConsentWithdrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be granted or withdrawn"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if n, _ := config.GetCollection("patients").CountDocuments(ctx, bson.M{"_id": patientObjID}); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		return
+	}
+
+	consent, err := services.RecordConsent(ctx, patientObjID, req.Purpose, req.Action, req.DocumentVersion,
+		models.ConsentEvent{ByID: adminObjID, ByType: services.ActorAdmin, Note: req.Note})
+	if err != nil {
+		c.JSON(consentStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"consent": consent,
+	})
+}
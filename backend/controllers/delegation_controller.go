@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DelegationController lets patients and admins grant caregivers access
+type DelegationController struct{}
+
+func NewDelegationController() *DelegationController {
+	return &DelegationController{}
+}
+
+// delegationRequest is the body of a new delegation
+type delegationRequest struct {
+	DelegateEmail string     `json:"delegate_email" binding:"required"`
+	Relationship  string     `json:"relationship"`
+	Scopes        []string   `json:"scopes" binding:"required"` // "view_reports", "chat", "upload"
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+// createDelegation validates and stores a delegation, returning the HTTP
+// status and message when it can't be created
+func createDelegation(ctx context.Context, patient *models.Patient, grantedByID primitive.ObjectID, grantedByType string, req *delegationRequest) (*models.Delegation, int, string) {
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, scope := range req.Scopes {
+		if !services.ValidDelegateScope(scope) {
+			return nil, http.StatusBadRequest, "unknown scope: " + scope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, http.StatusBadRequest, "at least one scope is required"
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, http.StatusBadRequest, "expires_at must be in the future"
+	}
+
+	var delegate models.Patient
+	email := strings.TrimSpace(req.DelegateEmail)
+	if err := config.GetCollection("patients").FindOne(ctx, bson.M{"email": email}).Decode(&delegate); err != nil {
+		return nil, http.StatusNotFound, "no account found for delegate_email - the delegate needs to sign up first"
+	}
+	if delegate.ID == patient.ID {
+		return nil, http.StatusBadRequest, "patients can't delegate to themselves"
+	}
+
+	collection := config.GetCollection("delegations")
+	existing := models.Delegation{}
+	err := collection.FindOne(ctx, bson.M{
+		"patient_id":  patient.ID,
+		"delegate_id": delegate.ID,
+		"revoked_at":  bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}).Decode(&existing)
+	if err == nil {
+		return nil, http.StatusConflict, "delegate already has access - revoke it before granting new scopes"
+	}
+
+	now := time.Now()
+	delegation := &models.Delegation{
+		ID:            primitive.NewObjectID(),
+		PatientID:     patient.ID,
+		DelegateID:    delegate.ID,
+		DelegateName:  delegate.Name,
+		DelegateEmail: delegate.Email,
+		Relationship:  req.Relationship,
+		Scopes:        scopes,
+		GrantedByID:   grantedByID,
+		GrantedByType: grantedByType,
+		ExpiresAt:     req.ExpiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if _, err := collection.InsertOne(ctx, delegation); err != nil {
+		return nil, http.StatusInternalServerError, "failed to save delegation"
+	}
+	services.NotifyDelegationGranted(delegation, patient.Name)
+	return delegation, http.StatusCreated, ""
+}
+
+// revokeDelegation marks a delegation revoked; it reports false when it was
+// already revoked
+func revokeDelegation(ctx context.Context, id, revokedByID primitive.ObjectID, revokedByType, reason string) (bool, error) {
+	now := time.Now()
+	result, err := config.GetCollection("delegations").UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{
+			"revoked_at":      now,
+			"revoked_by_id":   revokedByID,
+			"revoked_by_type": revokedByType,
+			"revoke_reason":   reason,
+			"updated_at":      now,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// findDelegations lists delegations matching the filter, newest first.
+// active=true leaves out revoked and expired ones.
+func findDelegations(c *gin.Context, filter bson.M) {
+	if c.Query("active") == "true" {
+		filter["revoked_at"] = bson.M{"$exists": false}
+		filter["$or"] = []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("delegations").Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(200))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delegations"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	delegations := []models.Delegation{}
+	if err := cursor.All(ctx, &delegations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode delegations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"count":       len(delegations),
+		"delegations": delegations,
+	})
+}
+
+// GrantDelegation lets a patient give another account access to their records.
+// Delegations for minors are granted by admins.
+// POST /api/patient/delegations
+// Body: { "patient_id": "xxx", "delegate_email": "carer@example.com", "relationship": "carer", "scopes": ["view_reports", "chat"], "expires_at": "2026-01-01T00:00:00Z" }
+func (ctrl *DelegationController) GrantDelegation(c *gin.Context) {
+	var req struct {
+		delegationRequest
+		PatientID string `json:"patient_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var patient models.Patient
+	if err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": patientObjID}).Decode(&patient); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	if services.IsMinor(&patient) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access for patients under 18 is granted by an administrator"})
+		return
+	}
+
+	delegation, status, msg := createDelegation(ctx, &patient, patientObjID, services.ActorPatient, &req.delegationRequest)
+	if delegation == nil {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"delegation": delegation,
+	})
+}
+
+// GetPatientDelegations returns who can act on a patient's records
+// GET /api/patient/delegations?patient_id=xxx&active=true
+func (ctrl *DelegationController) GetPatientDelegations(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	findDelegations(c, bson.M{"patient_id": patientObjID})
+}
+
+// GetHeldDelegations returns the patients a delegate can act for
+// GET /api/patient/delegations/held?delegate_id=xxx&active=true
+func (ctrl *DelegationController) GetHeldDelegations(c *gin.Context) {
+	delegateObjID, err := primitive.ObjectIDFromHex(c.Query("delegate_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delegate_id"})
+		return
+	}
+	findDelegations(c, bson.M{"delegate_id": delegateObjID})
+}
+
+// RevokeDelegation ends a delegation. The patient (unless a minor) or the
+// delegate may revoke it.
+// POST /api/patient/delegations/:id/revoke
+// Body: { "user_id": "xxx", "reason": "..." }
+func (ctrl *DelegationController) RevokeDelegation(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delegation id"})
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userObjID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var delegation models.Delegation
+	if err := config.GetCollection("delegations").FindOne(ctx, bson.M{"_id": objID}).Decode(&delegation); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "delegation not found"})
+		return
+	}
+	revokedByType := ""
+	switch userObjID {
+	case delegation.DelegateID:
+		revokedByType = services.ActorDelegate
+	case delegation.PatientID:
+		// Guardianship set up by an admin, e.g. for a minor, stays until an admin ends it
+		if delegation.GrantedByType == services.ActorAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this access was granted by an administrator - ask them to revoke it"})
+			return
+		}
+		revokedByType = services.ActorPatient
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	if _, err := revokeDelegation(ctx, objID, userObjID, revokedByType, req.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke delegation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Delegation revoked",
+	})
+}
+
+// GetDelegationActivity returns what a delegate did for the patient, newest first
+// GET /api/patient/delegations/:id/activity?patient_id=xxx
+func (ctrl *DelegationController) GetDelegationActivity(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delegation id"})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("delegate_activity").Find(ctx,
+		bson.M{"delegation_id": objID, "patient_id": patientObjID},
+		options.Find().SetSort(bson.M{"at": -1}).SetLimit(500),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch activity"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	activity := []models.DelegateActivity{}
+	if err := cursor.All(ctx, &activity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"count":    len(activity),
+		"activity": activity,
+	})
+}
+
+// AdminGrantDelegation grants access to a patient's records, typically a
+// parent or guardian of a minor
+// POST /api/admin/delegations
+// Body: { "admin_id": "xxx", "patient_id": "yyy", "delegate_email": "parent@example.com", "relationship": "parent", "scopes": ["view_reports", "chat", "upload"], "expires_at": null }
+func (ctrl *DelegationController) AdminGrantDelegation(c *gin.Context) {
+	var req struct {
+		delegationRequest
+		AdminID   string `json:"admin_id" binding:"required"`
+		PatientID string `json:"patient_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var patient models.Patient
+	if err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": patientObjID}).Decode(&patient); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		return
+	}
+
+	delegation, status, msg := createDelegation(ctx, &patient, adminObjID, services.ActorAdmin, &req.delegationRequest)
+	if delegation == nil {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":    true,
+		"delegation": delegation,
+	})
+}
+
+// AdminListDelegations returns delegations of a patient or a delegate
+// GET /api/admin/delegations?patient_id=xxx&delegate_id=yyy&active=true
+func (ctrl *DelegationController) AdminListDelegations(c *gin.Context) {
+	filter := bson.M{}
+	for param, field := range map[string]string{"patient_id": "patient_id", "delegate_id": "delegate_id"} {
+		if value := c.Query(param); value != "" {
+			objID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			filter[field] = objID
+		}
+	}
+	findDelegations(c, filter)
+}
+
+// AdminRevokeDelegation ends any delegation
+// POST /api/admin/delegations/:id/revoke
+// Body: { "admin_id": "xxx", "reason": "..." }
+func (ctrl *DelegationController) AdminRevokeDelegation(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delegation ID"})
+		return
+	}
+	var req struct {
+		AdminID string `json:"admin_id" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revoked, err := revokeDelegation(ctx, objID, adminObjID, services.ActorAdmin, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke delegation"})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delegation not found or already revoked"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Delegation revoked",
+	})
+}
@@ -2,32 +2,81 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type DoctorController struct{}
+type DoctorController struct {
+	policyService  *services.EditPolicyService
+	signingService *services.SigningService
+}
 
 func NewDoctorController() *DoctorController {
-	return &DoctorController{}
+	return &DoctorController{
+		policyService:  &services.EditPolicyService{},
+		signingService: &services.SigningService{},
+	}
+}
+
+// loadDoctor fetches a doctor, writing the error response when there is none
+func loadDoctor(ctx context.Context, c *gin.Context, doctorID primitive.ObjectID) (*models.Doctor, bool) {
+	var doctor models.Doctor
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorID}).Decode(&doctor); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "doctor not found"})
+		return nil, false
+	}
+	return &doctor, true
 }
 
-// GetAllReports returns all reports (can filter by status)
-// GET /api/doctor/reports?status=pending
+// reviewAccess checks a doctor may review a patient's reports, writing a 403
+// when they may not
+func reviewAccess(ctx context.Context, c *gin.Context, doctor *models.Doctor, patientID primitive.ObjectID) bool {
+	allowed, err := services.DoctorMayReview(ctx, doctor, patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "no care relationship with this patient"})
+		return false
+	}
+	return true
+}
+
+// GetAllReports returns reports of the doctor's patients (can filter by
+// status). On-call doctors also see reports of patients with no care team.
+// GET /api/doctor/reports?doctor_id=xxx&status=pending
 func (ctrl *DoctorController) GetAllReports(c *gin.Context) {
 	status := c.Query("status")
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
 
 	collection := config.GetCollection("reports")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{}
+	doctor, ok := loadDoctor(ctx, c, doctorObjID)
+	if !ok {
+		return
+	}
+	filter, err := services.DoctorReportFilter(ctx, doctor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch care relationships"})
+		return
+	}
 	if status != "" {
 		filter["status"] = status
 	}
@@ -52,8 +101,10 @@ func (ctrl *DoctorController) GetAllReports(c *gin.Context) {
 	})
 }
 
-// GetReportByID returns detailed report view for doctor
-// GET /api/doctor/reports/:id
+// GetReportByID returns detailed report view for doctor. Without a care
+// relationship the doctor needs an active break-glass grant, and the read is
+// logged against it.
+// GET /api/doctor/reports/:id?doctor_id=xxx
 func (ctrl *DoctorController) GetReportByID(c *gin.Context) {
 	reportID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(reportID)
@@ -61,11 +112,20 @@ func (ctrl *DoctorController) GetReportByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
 		return
 	}
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
 
 	collection := config.GetCollection("reports")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	doctor, ok := loadDoctor(ctx, c, doctorObjID)
+	if !ok {
+		return
+	}
 	var report models.Report
 	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&report)
 	if err != nil {
@@ -73,6 +133,24 @@ func (ctrl *DoctorController) GetReportByID(c *gin.Context) {
 		return
 	}
 
+	allowed, err := services.DoctorMayReview(ctx, doctor, report.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+		return
+	}
+	if !allowed {
+		grant, err := services.DoctorPatientAccess(ctx, doctorObjID, report.PatientID)
+		if errors.Is(err, services.ErrNoCareRelationship) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "no care relationship with this patient"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+			return
+		}
+		services.RecordBreakGlassUse(grant, "report", &report.ID)
+	}
+
 	c.JSON(http.StatusOK, report)
 }
 
@@ -112,6 +190,26 @@ func (ctrl *DoctorController) SubmitReview(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	doctor, ok := loadDoctor(ctx, c, doctorObjID)
+	if !ok {
+		return
+	}
+
+	// Signed reports are locked until formally amended
+	var report models.Report
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	if !reviewAccess(ctx, c, doctor, report.PatientID) {
+		return
+	}
+	if report.Signature != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "report is signed and locked - submit an amendment first"})
+		return
+	}
+
 	update := bson.M{
 		"$set": bson.M{
 			"doctor_review": review,
@@ -120,21 +218,135 @@ func (ctrl *DoctorController) SubmitReview(c *gin.Context) {
 		},
 	}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
-	if err != nil || result.MatchedCount == 0 {
+	// The report may have been signed since it was read
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID, "signature": bson.M{"$exists": false}}, update)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update report"})
 		return
 	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "report is signed and locked - submit an amendment first"})
+		return
+	}
+	report.DoctorReview, report.Status = &review, "reviewed"
+	services.AssignCareTaskReviewer(objID, doctorObjID)
+	services.EnsureCareRelationship(doctorObjID, report.PatientID, models.CareRelationshipSource{
+		Type: services.CareSourceAssignment, RefID: &objID,
+	}, nil)
+	services.NotifyReportReviewed(&report, services.EventReportReviewed)
+	services.PublishWebhookEvent(services.WebhookReportReviewed, services.ReportWebhookData(&report))
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Review submitted successfully",
+		"success":           true,
+		"message":           "Review submitted successfully",
+		"open_interactions": services.OpenInteractions(&report.AIAnalysis),
+	})
+}
+
+// ReviewInteraction acknowledges or dismisses a drug interaction finding
+// POST /api/doctor/reports/:id/interactions/:finding_id
+// Body: { "doctor_id": "xxx", "action": "acknowledge" | "dismiss", "comment": "..." }
+func (ctrl *DoctorController) ReviewInteraction(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+	findingID := c.Param("finding_id")
+
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Action   string `json:"action" binding:"required"`
+		Comment  string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	var status string
+	switch req.Action {
+	case "acknowledge":
+		status = services.InteractionAcknowledged
+	case "dismiss":
+		// Overriding a safety finding must be justified
+		if strings.TrimSpace(req.Comment) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "comment is required to dismiss a finding"})
+			return
+		}
+		status = services.InteractionDismissed
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be acknowledge or dismiss"})
+		return
+	}
+
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	doctor, ok := loadDoctor(ctx, c, doctorObjID)
+	if !ok {
+		return
+	}
+	var report models.Report
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	if !reviewAccess(ctx, c, doctor, report.PatientID) {
+		return
+	}
+	if report.Signature != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "report is signed and locked - submit an amendment first"})
+		return
+	}
+
+	index := -1
+	for i, f := range report.AIAnalysis.Interactions {
+		if f.ID == findingID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "finding not found"})
+		return
+	}
+
+	now := time.Now()
+	finding := report.AIAnalysis.Interactions[index]
+	finding.Status = status
+	finding.ReviewedBy = &doctorObjID
+	finding.ReviewedAt = &now
+	finding.Comment = req.Comment
+
+	field := fmt.Sprintf("ai_analysis.interactions.%d", index)
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objID, field + ".id": findingID},
+		bson.M{"$set": bson.M{field: finding, "updated_at": now}},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update finding"})
+		return
+	}
+	report.AIAnalysis.Interactions[index] = finding
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"finding":           finding,
+		"open_interactions": services.OpenInteractions(&report.AIAnalysis),
 	})
 }
 
-// EditAnalysis allows doctor to edit AI analysis when confidence < 90%
+// EditAnalysis allows doctor to edit AI analysis when the edit policy permits it
 // PUT /api/doctor/reports/:id/edit
-// Body: { "doctor_id": "xxx", "edited_fields": {...}, "notes": "..." }
+// Body: { "doctor_id": "xxx", "edited_fields": {...}, "notes": "...", "override": false, "justification": "..." }
+// Care tasks of recommendations left out of edited_fields.recommendations are cancelled
 func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 	reportID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(reportID)
@@ -144,9 +356,11 @@ func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 	}
 
 	var req struct {
-		DoctorID     string                 `json:"doctor_id" binding:"required"`
-		EditedFields map[string]interface{} `json:"edited_fields" binding:"required"`
-		Notes        string                 `json:"notes"`
+		DoctorID      string                 `json:"doctor_id" binding:"required"`
+		EditedFields  map[string]interface{} `json:"edited_fields" binding:"required"`
+		Notes         string                 `json:"notes"`
+		Override      bool                   `json:"override"`
+		Justification string                 `json:"justification"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -159,7 +373,7 @@ func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 		return
 	}
 
-	// First, get the report to check confidence
+	// First, get the report and doctor to evaluate the edit policy
 	collection := config.GetCollection("reports")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -171,13 +385,39 @@ func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 		return
 	}
 
-	// Check if confidence is < 90%
-	if report.AIAnalysis.ConfidenceScore >= 90.0 {
+	// Signed reports are locked until formally amended
+	if report.Signature != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "report is signed and locked - submit an amendment first"})
+		return
+	}
+
+	doctor, ok := loadDoctor(ctx, c, doctorObjID)
+	if !ok {
+		return
+	}
+	if !reviewAccess(ctx, c, doctor, report.PatientID) {
+		return
+	}
+
+	decision, err := ctrl.policyService.Evaluate(doctor, &report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to evaluate edit policy"})
+		return
+	}
+
+	// A denied edit may only proceed through the justified override path
+	overridden, err := services.AuthorizeEdit(decision, req.Override, req.Justification)
+	switch {
+	case errors.Is(err, services.ErrEditDenied):
 		c.JSON(http.StatusForbidden, gin.H{
-			"error":              "Cannot edit - confidence score is >= 90%",
+			"error":              "Cannot edit - " + decision.Reason,
 			"current_confidence": report.AIAnalysis.ConfidenceScore,
+			"override_allowed":   decision.OverrideAllowed,
 		})
 		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Create feedback record
@@ -199,6 +439,16 @@ func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 		return
 	}
 
+	// Flag override edits for audit
+	if overridden {
+		entry := services.NewOverrideAuditEntry(&report, doctorObjID, feedback.ID, decision, req.Justification)
+		_, err = config.GetCollection("edit_audit_log").InsertOne(ctx, entry)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record override audit entry"})
+			return
+		}
+	}
+
 	// Update report with doctor review
 	review := models.DoctorReview{
 		ReviewedBy:   doctorObjID,
@@ -206,6 +456,10 @@ func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 		EditedFields: req.EditedFields,
 		Notes:        req.Notes,
 	}
+	if overridden {
+		review.Override = true
+		review.Justification = req.Justification
+	}
 
 	update := bson.M{
 		"$set": bson.M{
@@ -215,37 +469,401 @@ func (ctrl *DoctorController) EditAnalysis(c *gin.Context) {
 		},
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	// The report may have been signed since it was read
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID, "signature": bson.M{"$exists": false}}, update)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update report"})
 		return
 	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "report is signed and locked - submit an amendment first"})
+		return
+	}
+	report.DoctorReview, report.Status = &review, "edited"
+	services.AssignCareTaskReviewer(objID, doctorObjID)
+	if kept, ok := services.EditedRecommendationTests(req.EditedFields); ok {
+		services.CancelRemovedCareTasks(objID, doctorObjID, kept)
+	}
+	services.EnsureCareRelationship(doctorObjID, report.PatientID, models.CareRelationshipSource{
+		Type: services.CareSourceAssignment, RefID: &objID,
+	}, nil)
+	services.NotifyReportReviewed(&report, services.EventReportEdited)
+	services.PublishWebhookEvent(services.WebhookReportEdited, services.ReportWebhookData(&report))
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
 		"message":     "Analysis edited and feedback saved",
 		"feedback_id": feedback.ID.Hex(),
+		"override":    overridden,
 	})
 }
 
-// GetPatients returns list of all patients (simplified)
-// GET /api/doctor/patients
+// SignReport lets the reviewing doctor digitally sign the final analysis.
+// The doctor must re-enter their password; the signed report is locked.
+// POST /api/doctor/reports/:id/sign
+// Body: { "doctor_id": "xxx", "password": "..." }
+func (ctrl *DoctorController) SignReport(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Re-authenticate the doctor so the signature is attributable
+	var doctor models.Doctor
+	err = config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorObjID}).Decode(&doctor)
+	if err != nil || !checkPasswordHash(req.Password, doctor.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid doctor credentials"})
+		return
+	}
+
+	var report models.Report
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+
+	if report.Signature != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "report is already signed"})
+		return
+	}
+	if report.DoctorReview == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "report must be reviewed before signing"})
+		return
+	}
+	if report.DoctorReview.ReviewedBy != doctorObjID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the reviewing doctor can sign this report"})
+		return
+	}
+
+	signature, err := ctrl.signingService.SignReport(&report, doctorObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign report", "details": err.Error()})
+		return
+	}
+
+	// Only store the signature if the report has not changed since it was read
+	update := bson.M{
+		"$set": bson.M{
+			"signature":  signature,
+			"status":     "signed",
+			"updated_at": time.Now(),
+		},
+	}
+	filter := bson.M{
+		"_id":        objID,
+		"updated_at": report.UpdatedAt,
+		"signature":  bson.M{"$exists": false},
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update report"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "report changed while signing, please retry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Report signed successfully",
+		"signature": signature,
+	})
+}
+
+// VerifySignature verifies a signed report against its current content.
+// Only doctors who may review the patient can verify.
+// GET /api/doctor/reports/:id/verify?doctor_id=xxx
+func (ctrl *DoctorController) VerifySignature(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	doctor, ok := loadDoctor(ctx, c, doctorObjID)
+	if !ok {
+		return
+	}
+	var report models.Report
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	if !reviewAccess(ctx, c, doctor, report.PatientID) {
+		return
+	}
+
+	valid, reason, err := ctrl.signingService.VerifyReport(&report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify signature", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"valid":     valid,
+		"reason":    reason,
+		"signature": report.Signature,
+	})
+}
+
+// AmendReport formally amends a signed report, unlocking it for edits.
+// Only the signing doctor or the patient's care team may amend; the previous
+// signature is preserved in the amendment history.
+// POST /api/doctor/reports/:id/amend
+// Body: { "doctor_id": "xxx", "password": "...", "reason": "..." }
+func (ctrl *DoctorController) AmendReport(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	var req struct {
+		DoctorID string `json:"doctor_id" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		Reason   string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var doctor models.Doctor
+	err = config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorObjID}).Decode(&doctor)
+	if err != nil || !checkPasswordHash(req.Password, doctor.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid doctor credentials"})
+		return
+	}
+
+	var report models.Report
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	if report.Signature == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only signed reports can be amended"})
+		return
+	}
+	if report.Signature.DoctorID != doctorObjID {
+		onTeam, err := services.HasCareRelationship(ctx, doctorObjID, report.PatientID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+			return
+		}
+		if !onTeam {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the signing doctor or the patient's care team can amend this report"})
+			return
+		}
+	}
+
+	amendment := models.ReportAmendment{
+		AmendedBy:         doctorObjID,
+		AmendedAt:         time.Now(),
+		Reason:            req.Reason,
+		PreviousSignature: *report.Signature,
+	}
+
+	update := bson.M{
+		"$push":  bson.M{"amendments": amendment},
+		"$unset": bson.M{"signature": ""},
+		"$set": bson.M{
+			"status":     "amended",
+			"updated_at": time.Now(),
+		},
+	}
+
+	// Only amend the signature that was checked above
+	filter := bson.M{"_id": objID, "signature.signature": report.Signature.Signature}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to amend report"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "report changed while amending, please retry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Report unlocked for amendment - sign again once changes are complete",
+	})
+}
+
+// RequestSecondOpinion asks another doctor to look at a report and notifies them
+// POST /api/doctor/reports/:id/second-opinion
+// Body: { "doctor_id": "xxx", "consultant_id": "yyy", "note": "..." }
+func (ctrl *DoctorController) RequestSecondOpinion(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	var req struct {
+		DoctorID     string `json:"doctor_id" binding:"required"`
+		ConsultantID string `json:"consultant_id" binding:"required"`
+		Note         string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorObjID, err := primitive.ObjectIDFromHex(req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+	consultantObjID, err := primitive.ObjectIDFromHex(req.ConsultantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid consultant_id"})
+		return
+	}
+	if consultantObjID == doctorObjID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "consultant must be another doctor"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var report models.Report
+	if err := config.GetCollection("reports").FindOne(ctx, bson.M{"_id": objID}).Decode(&report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	var requester, consultant models.Doctor
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": doctorObjID}).Decode(&requester); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "doctor not found"})
+		return
+	}
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": consultantObjID}).Decode(&consultant); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "consultant not found"})
+		return
+	}
+	related, err := services.HasCareRelationship(ctx, doctorObjID, report.PatientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+		return
+	}
+	if !related {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only doctors caring for the patient can request a second opinion"})
+		return
+	}
+
+	request := models.SecondOpinionRequest{
+		ID:           primitive.NewObjectID(),
+		ReportID:     objID,
+		RequestedBy:  doctorObjID,
+		ConsultantID: consultantObjID,
+		Note:         req.Note,
+		Status:       "requested",
+		CreatedAt:    time.Now(),
+	}
+	if _, err := config.GetCollection("second_opinion_requests").InsertOne(ctx, request); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save second opinion request"})
+		return
+	}
+	services.EnsureCareRelationship(consultantObjID, report.PatientID, models.CareRelationshipSource{
+		Type: services.CareSourceReferral, RefID: &request.ID, ByID: &doctorObjID, ByType: services.RecipientDoctor,
+	}, nil)
+
+	body := fmt.Sprintf("Dr. %s asked for your opinion on report %s.", requester.Name, objID.Hex())
+	if req.Note != "" {
+		body += " Note: " + req.Note
+	}
+	services.Notify(&models.Notification{
+		UserID:   consultantObjID,
+		UserType: services.RecipientDoctor,
+		Event:    services.EventSecondOpinionRequested,
+		Title:    "Second opinion requested",
+		Body:     body,
+		ReportID: &objID,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"request": request,
+	})
+}
+
+// GetPatients returns the patients the doctor has an active care relationship with
+// GET /api/doctor/patients?doctor_id=xxx
 func (ctrl *DoctorController) GetPatients(c *gin.Context) {
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
+
 	collection := config.GetCollection("patients")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	patientIDs, err := services.RelatedPatientIDs(ctx, doctorObjID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch patients"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch care relationships"})
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var patients []models.Patient
-	if err = cursor.All(ctx, &patients); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode patients"})
-		return
+	patients := []models.Patient{}
+	if len(patientIDs) > 0 {
+		cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": patientIDs}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch patients"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err = cursor.All(ctx, &patients); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode patients"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -255,8 +873,10 @@ func (ctrl *DoctorController) GetPatients(c *gin.Context) {
 	})
 }
 
-// GetPatientReports returns all reports for a specific patient
-// GET /api/doctor/patients/:patient_id/reports
+// GetPatientReports returns all reports for a patient the doctor cares for.
+// Without a care relationship the doctor needs an active break-glass grant,
+// and the read is logged against it.
+// GET /api/doctor/patients/:patient_id/reports?doctor_id=xxx
 func (ctrl *DoctorController) GetPatientReports(c *gin.Context) {
 	patientID := c.Param("patient_id")
 	objID, err := primitive.ObjectIDFromHex(patientID)
@@ -264,11 +884,29 @@ func (ctrl *DoctorController) GetPatientReports(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
 		return
 	}
+	doctorObjID, err := primitive.ObjectIDFromHex(c.Query("doctor_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+		return
+	}
 
 	collection := config.GetCollection("reports")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	grant, err := services.DoctorPatientAccess(ctx, doctorObjID, objID)
+	if errors.Is(err, services.ErrNoCareRelationship) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":       "no care relationship with this patient",
+			"break_glass": "POST /api/doctor/patients/" + patientID + "/break-glass for emergency access",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check care relationship"})
+		return
+	}
+
 	cursor, err := collection.Find(ctx, bson.M{"patient_id": objID})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reports"})
@@ -281,11 +919,36 @@ func (ctrl *DoctorController) GetPatientReports(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode reports"})
 		return
 	}
+	services.RecordBreakGlassUse(grant, "patient_reports", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"patient_id":  patientID,
+		"count":       len(reports),
+		"reports":     reports,
+		"break_glass": grant != nil,
+	})
+}
+
+// LookupTerm codes a term against the bundled terminology tables
+// GET /api/doctor/terminology/lookup?category=diagnosis&q=ckd
+func (ctrl *DoctorController) LookupTerm(c *gin.Context) {
+	category := c.Query("category")
+	switch category {
+	case services.TermCategoryDiagnosis, services.TermCategorySymptom, services.TermCategoryTest, services.TermCategoryMedication:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category must be diagnosis, symptom, test or medication"})
+		return
+	}
+	term := strings.TrimSpace(c.Query("q"))
+	if term == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"patient_id": patientID,
-		"count":      len(reports),
-		"reports":    reports,
+		"success":             true,
+		"coding":              services.CodeEntity(category, term),
+		"terminology_version": services.TerminologyVersion(),
 	})
 }
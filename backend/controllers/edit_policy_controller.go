@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EditPolicyController lets admins manage edit permission policies
+type EditPolicyController struct{}
+
+func NewEditPolicyController() *EditPolicyController {
+	return &EditPolicyController{}
+}
+
+// ListPolicies returns all edit policies
+// GET /api/admin/edit-policies
+func (ctrl *EditPolicyController) ListPolicies(c *gin.Context) {
+	collection := config.GetCollection("edit_policies")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch edit policies"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var policies []models.EditPolicy
+	if err = cursor.All(ctx, &policies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode edit policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"policies": policies,
+		"count":    len(policies),
+	})
+}
+
+// CreatePolicy creates a new edit policy
+// POST /api/admin/edit-policies
+func (ctrl *EditPolicyController) CreatePolicy(c *gin.Context) {
+	var policy models.EditPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if policy.MaxConfidence < 0 || policy.MaxConfidence > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_confidence must be between 0 and 100"})
+		return
+	}
+
+	policy.ID = primitive.NewObjectID()
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = time.Now()
+
+	collection := config.GetCollection("edit_policies")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := collection.InsertOne(ctx, policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create edit policy"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"policy":  policy,
+	})
+}
+
+// UpdatePolicy replaces an existing edit policy
+// PUT /api/admin/edit-policies/:id
+func (ctrl *EditPolicyController) UpdatePolicy(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return
+	}
+
+	var policy models.EditPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if policy.MaxConfidence < 0 || policy.MaxConfidence > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_confidence must be between 0 and 100"})
+		return
+	}
+
+	collection := config.GetCollection("edit_policies")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":             policy.Name,
+			"description":      policy.Description,
+			"roles":            policy.Roles,
+			"specializations":  policy.Specializations,
+			"max_confidence":   policy.MaxConfidence,
+			"allowed_statuses": policy.AllowedStatuses,
+			"allow_override":   policy.AllowOverride,
+			"priority":         policy.Priority,
+			"enabled":          policy.Enabled,
+			"updated_at":       time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update edit policy"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Edit policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Edit policy updated successfully",
+	})
+}
+
+// DeletePolicy deletes an edit policy
+// DELETE /api/admin/edit-policies/:id
+func (ctrl *EditPolicyController) DeletePolicy(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return
+	}
+
+	collection := config.GetCollection("edit_policies")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete edit policy"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Edit policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Edit policy deleted successfully",
+	})
+}
+
+// GetOverrideAudit lists edits made through the override path
+// GET /api/admin/edit-overrides?pending=true
+func (ctrl *EditPolicyController) GetOverrideAudit(c *gin.Context) {
+	collection := config.GetCollection("edit_audit_log")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if c.Query("pending") == "true" {
+		filter["flagged_for_audit"] = true
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch override audit log"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.EditAuditEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode override audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// ResolveOverrideAudit marks a flagged override as audited
+// POST /api/admin/edit-overrides/:id/resolve
+// Body: { "admin_id": "xxx", "notes": "..." }
+func (ctrl *EditPolicyController) ResolveOverrideAudit(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audit entry ID"})
+		return
+	}
+
+	var req struct {
+		AdminID string `json:"admin_id" binding:"required"`
+		Notes   string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	collection := config.GetCollection("edit_audit_log")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"flagged_for_audit": false,
+			"audited_by":        adminObjID,
+			"audited_at":        now,
+			"audit_notes":       req.Notes,
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve audit entry"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audit entry not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Override audit entry resolved",
+	})
+}
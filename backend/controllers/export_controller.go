@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportController provides bulk CSV/XLSX exports of reports, patients and doctors
+type ExportController struct {
+	exportService *services.ExportService
+}
+
+func NewExportController() *ExportController {
+	return &ExportController{
+		exportService: &services.ExportService{},
+	}
+}
+
+// splitList splits a comma-separated query value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// exportFiltersFromQuery reads from, to, status, diagnosis and doctor_id
+func exportFiltersFromQuery(c *gin.Context) (models.ExportFilters, error) {
+	var filters models.ExportFilters
+	from, err := parseDateQuery(c, "from")
+	if err != nil {
+		return filters, fmt.Errorf("from must be YYYY-MM-DD")
+	}
+	to, err := parseDateQuery(c, "to")
+	if err != nil {
+		return filters, fmt.Errorf("to must be YYYY-MM-DD")
+	}
+	if !from.IsZero() {
+		filters.From = &from
+	}
+	if !to.IsZero() {
+		// Include the whole "to" day
+		end := to.AddDate(0, 0, 1)
+		filters.To = &end
+	}
+	filters.Statuses = splitList(c.Query("status"))
+	filters.Diagnosis = strings.TrimSpace(c.Query("diagnosis"))
+	if doctorID := c.Query("doctor_id"); doctorID != "" {
+		objID, err := primitive.ObjectIDFromHex(doctorID)
+		if err != nil {
+			return filters, fmt.Errorf("invalid doctor_id")
+		}
+		filters.DoctorID = &objID
+	}
+	return filters, nil
+}
+
+// GetColumns lists the selectable columns of a dataset
+// GET /api/admin/exports/columns?dataset=reports
+func (ctrl *ExportController) GetColumns(c *gin.Context) {
+	dataset := c.DefaultQuery("dataset", "reports")
+	columns, ok := services.ExportColumns(dataset)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown dataset"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dataset": dataset,
+		"columns": columns,
+	})
+}
+
+// StreamExport streams an export directly in the response. Exports larger than
+// services.ExportSyncRowLimit rows must be requested as background jobs.
+// GET /api/admin/export/:dataset?format=csv&columns=report_id,diagnoses&from=2025-01-01&to=2025-12-31&status=reviewed&diagnosis=diabetes&doctor_id=xxx
+func (ctrl *ExportController) StreamExport(c *gin.Context) {
+	filters, err := exportFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job := models.ExportJob{
+		Dataset:   c.Param("dataset"),
+		Format:    c.DefaultQuery("format", "csv"),
+		Columns:   splitList(c.Query("columns")),
+		Filters:   filters,
+		CreatedAt: time.Now(),
+	}
+	if err := ctrl.exportService.ValidateJob(&job); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, err := ctrl.exportService.CountRows(&job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count rows"})
+		return
+	}
+	if count > services.ExportSyncRowLimit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":     fmt.Sprintf("Export has %d rows; request it as a background job via POST /api/admin/exports", count),
+			"row_count": count,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", services.ExportFileName(&job)))
+	c.Header("Content-Type", services.ExportContentType(job.Format))
+	c.Status(http.StatusOK)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+	if _, err := ctrl.exportService.Write(ctx, &job, c.Writer); err != nil {
+		// Headers are already sent; abort the connection so the client sees a truncated download
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// CreateExportJob starts a background export
+// POST /api/admin/exports
+// Body: { "admin_id": "xxx", "dataset": "reports", "format": "xlsx", "columns": [], "filters": { "from": "2025-01-01T00:00:00Z", "statuses": ["reviewed"], "diagnosis": "diabetes", "doctor_id": "xxx" } }
+func (ctrl *ExportController) CreateExportJob(c *gin.Context) {
+	var req struct {
+		AdminID string               `json:"admin_id" binding:"required"`
+		Dataset string               `json:"dataset" binding:"required"`
+		Format  string               `json:"format"`
+		Columns []string             `json:"columns"`
+		Filters models.ExportFilters `json:"filters"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+
+	job := models.ExportJob{
+		RequestedBy: adminObjID,
+		Dataset:     req.Dataset,
+		Format:      req.Format,
+		Columns:     req.Columns,
+		Filters:     req.Filters,
+	}
+	if err := ctrl.exportService.ValidateJob(&job); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ctrl.exportService.StartJob(&job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Export started",
+		"job":     job,
+	})
+}
+
+// ListExportJobs returns export jobs, newest first
+// GET /api/admin/exports
+func (ctrl *ExportController) ListExportJobs(c *gin.Context) {
+	collection := config.GetCollection("export_jobs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(100)
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exports"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.ExportJob
+	if err = cursor.All(ctx, &jobs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode exports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"jobs":    jobs,
+		"count":   len(jobs),
+	})
+}
+
+// GetExportJob returns the status of an export job
+// GET /api/admin/exports/:id
+func (ctrl *ExportController) GetExportJob(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	job, err := ctrl.exportService.GetJob(objID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"job":     job,
+	})
+}
+
+// DownloadExport downloads the file of a completed export job
+// GET /api/admin/exports/:id/download
+func (ctrl *ExportController) DownloadExport(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	job, err := ctrl.exportService.GetJob(objID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export"})
+		return
+	}
+	if job.Status != "completed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export is " + job.Status})
+		return
+	}
+	if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Export has expired"})
+		return
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Export file is no longer available"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", job.FileName))
+	c.Header("Content-Type", services.ExportContentType(job.Format))
+	c.File(job.FilePath)
+}
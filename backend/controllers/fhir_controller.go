@@ -0,0 +1,574 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	fhirContentType  = "application/fhir+json; charset=utf-8"
+	fhirDefaultCount = 20
+	fhirMaxCount     = 100
+)
+
+// FHIRController serves the HL7 FHIR R4 API
+type FHIRController struct {
+	fhirService   *services.FHIRService
+	ingestService *services.FHIRIngestService
+}
+
+func NewFHIRController() *FHIRController {
+	return &FHIRController{
+		fhirService:   &services.FHIRService{},
+		ingestService: &services.FHIRIngestService{},
+	}
+}
+
+// writeFHIR writes a FHIR resource with the FHIR JSON content type
+func writeFHIR(c *gin.Context, status int, resource interface{}) {
+	body, err := json.Marshal(resource)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode resource"})
+		return
+	}
+	c.Data(status, fhirContentType, body)
+}
+
+// writeOutcome writes an OperationOutcome describing an error
+func writeOutcome(c *gin.Context, status int, code, diagnostics string) {
+	writeFHIR(c, status, models.FHIROperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []models.FHIROperationOutcomeIssue{{
+			Severity:    "error",
+			Code:        code,
+			Diagnostics: diagnostics,
+		}},
+	})
+}
+
+// fhirBaseURL returns the public base URL of the server (without /fhir/R4)
+func fhirBaseURL(c *gin.Context) string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// pagingParams parses _count and _offset
+func pagingParams(c *gin.Context) (int, int, error) {
+	count := fhirDefaultCount
+	if v := c.Query("_count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("_count must be a non-negative integer")
+		}
+		count = n
+	}
+	if count > fhirMaxCount {
+		count = fhirMaxCount
+	}
+
+	offset := 0
+	if v := c.Query("_offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("_offset must be a non-negative integer")
+		}
+		offset = n
+	}
+	return offset, count, nil
+}
+
+// pageURL returns the current request URL with a different _offset
+func pageURL(c *gin.Context, offset, count int) string {
+	query := url.Values{}
+	for key, values := range c.Request.URL.Query() {
+		query[key] = values
+	}
+	query.Set("_offset", strconv.Itoa(offset))
+	query.Set("_count", strconv.Itoa(count))
+	return fhirBaseURL(c) + c.Request.URL.Path + "?" + query.Encode()
+}
+
+// searchBundle builds a searchset Bundle with paging links
+func searchBundle(c *gin.Context, resourceType string, resources []interface{}, ids []string, total, offset, count int) models.FHIRBundle {
+	bundle := models.FHIRBundle{
+		ResourceType: "Bundle",
+		ID:           primitive.NewObjectID().Hex(),
+		Type:         "searchset",
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Total:        &total,
+		Link: []models.FHIRBundleLink{
+			{Relation: "self", URL: pageURL(c, offset, count)},
+		},
+	}
+	if count > 0 && offset+count < total {
+		bundle.Link = append(bundle.Link, models.FHIRBundleLink{Relation: "next", URL: pageURL(c, offset+count, count)})
+	}
+	if offset > 0 {
+		prev := offset - count
+		if prev < 0 {
+			prev = 0
+		}
+		bundle.Link = append(bundle.Link, models.FHIRBundleLink{Relation: "previous", URL: pageURL(c, prev, count)})
+	}
+
+	base := fhirBaseURL(c) + "/fhir/R4/" + resourceType + "/"
+	for i, resource := range resources {
+		bundle.Entry = append(bundle.Entry, models.FHIRBundleEntry{
+			FullURL:  base + ids[i],
+			Resource: resource,
+			Search:   &models.FHIRBundleSearch{Mode: "match"},
+		})
+	}
+	return bundle
+}
+
+// parseDateParam parses a FHIR date search value such as "ge2025-01-01" into a
+// half-open [from, to) range
+func parseDateParam(value string) (time.Time, time.Time, error) {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	var t time.Time
+	var precision time.Duration
+	var err error
+	if len(value) == len("2006-01-02") {
+		t, err = time.Parse("2006-01-02", value)
+		precision = 24 * time.Hour
+	} else {
+		t, err = time.Parse(time.RFC3339, value)
+		precision = time.Second
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	switch prefix {
+	case "eq":
+		return t, t.Add(precision), nil
+	case "ge":
+		return t, time.Time{}, nil
+	case "gt":
+		return t.Add(precision), time.Time{}, nil
+	case "le":
+		return time.Time{}, t.Add(precision), nil
+	case "lt":
+		return time.Time{}, t, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unsupported date prefix %q", prefix)
+}
+
+// reportSearchParams parses the patient, date and status search parameters
+func reportSearchParams(c *gin.Context) (services.ReportSearch, error) {
+	var search services.ReportSearch
+
+	patient := c.Query("patient")
+	if patient == "" {
+		patient = c.Query("subject")
+	}
+	if patient != "" {
+		patient = strings.TrimPrefix(patient, "Patient/")
+		objID, err := primitive.ObjectIDFromHex(patient)
+		if err != nil {
+			return search, fmt.Errorf("invalid patient reference")
+		}
+		search.PatientID = &objID
+	}
+
+	for _, value := range c.QueryArray("date") {
+		from, to, err := parseDateParam(value)
+		if err != nil {
+			return search, err
+		}
+		if !from.IsZero() && (search.From.IsZero() || from.After(search.From)) {
+			search.From = from
+		}
+		if !to.IsZero() && (search.To.IsZero() || to.Before(search.To)) {
+			search.To = to
+		}
+	}
+
+	if status := c.Query("status"); status != "" {
+		search.Statuses = []string{}
+		for _, s := range strings.Split(status, ",") {
+			search.Statuses = append(search.Statuses, services.InternalReportStatuses(strings.TrimSpace(s))...)
+		}
+	}
+	return search, nil
+}
+
+// Metadata returns the CapabilityStatement of this server
+// GET /fhir/R4/metadata
+func (ctrl *FHIRController) Metadata(c *gin.Context) {
+	searchParams := []gin.H{
+		{"name": "patient", "type": "reference"},
+		{"name": "date", "type": "date"},
+		{"name": "status", "type": "token"},
+	}
+	resource := func(resourceType string, params []gin.H) gin.H {
+		return gin.H{
+			"type":        resourceType,
+			"interaction": []gin.H{{"code": "read"}, {"code": "search-type"}},
+			"searchParam": params,
+		}
+	}
+
+	writeFHIR(c, http.StatusOK, gin.H{
+		"resourceType": "CapabilityStatement",
+		"status":       "active",
+		"date":         time.Now().UTC().Format("2006-01-02"),
+		"kind":         "instance",
+		"fhirVersion":  "4.0.1",
+		"format":       []string{"application/fhir+json"},
+		"implementation": gin.H{
+			"description": "Medical Report Analyzer FHIR API",
+			"url":         fhirBaseURL(c) + "/fhir/R4",
+		},
+		"rest": []gin.H{{
+			"mode":        "server",
+			"interaction": []gin.H{{"code": "transaction"}, {"code": "batch"}},
+			"resource": []gin.H{
+				resource("Patient", []gin.H{{"name": "_id", "type": "token"}, {"name": "name", "type": "string"}, {"name": "identifier", "type": "token"}, {"name": "email", "type": "token"}}),
+				resource("Practitioner", []gin.H{{"name": "_id", "type": "token"}, {"name": "name", "type": "string"}, {"name": "identifier", "type": "token"}}),
+				resource("DiagnosticReport", searchParams),
+				resource("Condition", searchParams),
+				resource("MedicationStatement", searchParams),
+				resource("Observation", searchParams),
+				resource("ServiceRequest", searchParams),
+			},
+		}},
+	})
+}
+
+// Transaction ingests a transaction or batch Bundle of DiagnosticReport,
+// Observation, Condition, MedicationStatement, DocumentReference and Patient
+// resources. Transactions are all-or-nothing; batch entries succeed or fail
+// individually.
+// POST /fhir/R4
+// Body: Bundle (type "transaction" or "batch")
+func (ctrl *FHIRController) Transaction(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "invalid", "failed to read request body")
+		return
+	}
+
+	response, outcome, status := ctrl.ingestService.ProcessBundle(body)
+	if outcome != nil {
+		writeFHIR(c, status, outcome)
+		return
+	}
+	writeFHIR(c, http.StatusOK, response)
+}
+
+// ReadPatient returns a single Patient
+// GET /fhir/R4/Patient/:id
+func (ctrl *FHIRController) ReadPatient(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		writeOutcome(c, http.StatusNotFound, "not-found", "Patient not found")
+		return
+	}
+
+	collection := config.GetCollection("patients")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var patient models.Patient
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&patient); err != nil {
+		writeOutcome(c, http.StatusNotFound, "not-found", "Patient not found")
+		return
+	}
+	writeFHIR(c, http.StatusOK, ctrl.fhirService.PatientToFHIR(&patient))
+}
+
+// identifierValue extracts the value from a "system|value" token
+func identifierValue(token string) string {
+	if i := strings.LastIndex(token, "|"); i >= 0 {
+		return token[i+1:]
+	}
+	return token
+}
+
+// SearchPatients searches Patients by _id, name, identifier or email
+// GET /fhir/R4/Patient?name=smith
+func (ctrl *FHIRController) SearchPatients(c *gin.Context) {
+	offset, count, err := pagingParams(c)
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	filter := bson.M{}
+	if id := c.Query("_id"); id != "" {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			writeFHIR(c, http.StatusOK, searchBundle(c, "Patient", nil, nil, 0, offset, count))
+			return
+		}
+		filter["_id"] = objID
+	}
+	if identifier := identifierValue(c.Query("identifier")); identifier != "" {
+		// Match either our own ID or an external identifier (e.g. an MRN)
+		or := []bson.M{{"identifiers.value": identifier}}
+		if objID, err := primitive.ObjectIDFromHex(identifier); err == nil {
+			or = append(or, bson.M{"_id": objID})
+		}
+		filter["$or"] = or
+	}
+	if name := c.Query("name"); name != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(name), "$options": "i"}
+	}
+	if email := c.Query("email"); email != "" {
+		filter["email"] = email
+	}
+
+	collection := config.GetCollection("patients")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", "failed to count patients")
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetSkip(int64(offset)).SetLimit(int64(count))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", "failed to fetch patients")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var patients []models.Patient
+	if err = cursor.All(ctx, &patients); err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", "failed to decode patients")
+		return
+	}
+
+	var resources []interface{}
+	var ids []string
+	for i := range patients {
+		resources = append(resources, ctrl.fhirService.PatientToFHIR(&patients[i]))
+		ids = append(ids, patients[i].ID.Hex())
+	}
+	writeFHIR(c, http.StatusOK, searchBundle(c, "Patient", resources, ids, int(total), offset, count))
+}
+
+// ReadPractitioner returns a single Practitioner
+// GET /fhir/R4/Practitioner/:id
+func (ctrl *FHIRController) ReadPractitioner(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		writeOutcome(c, http.StatusNotFound, "not-found", "Practitioner not found")
+		return
+	}
+
+	collection := config.GetCollection("doctors")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var doctor models.Doctor
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&doctor); err != nil {
+		writeOutcome(c, http.StatusNotFound, "not-found", "Practitioner not found")
+		return
+	}
+	writeFHIR(c, http.StatusOK, ctrl.fhirService.DoctorToFHIR(&doctor))
+}
+
+// SearchPractitioners searches Practitioners by _id, name or license identifier
+// GET /fhir/R4/Practitioner?identifier=LIC123
+func (ctrl *FHIRController) SearchPractitioners(c *gin.Context) {
+	offset, count, err := pagingParams(c)
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	filter := bson.M{}
+	if id := c.Query("_id"); id != "" {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			writeFHIR(c, http.StatusOK, searchBundle(c, "Practitioner", nil, nil, 0, offset, count))
+			return
+		}
+		filter["_id"] = objID
+	}
+	if identifier := c.Query("identifier"); identifier != "" {
+		value := identifierValue(identifier)
+		if objID, err := primitive.ObjectIDFromHex(value); err == nil {
+			filter["$or"] = []bson.M{{"_id": objID}, {"license_number": value}}
+		} else {
+			filter["license_number"] = value
+		}
+	}
+	if name := c.Query("name"); name != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(name), "$options": "i"}
+	}
+
+	collection := config.GetCollection("doctors")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", "failed to count practitioners")
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetSkip(int64(offset)).SetLimit(int64(count))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", "failed to fetch practitioners")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var doctors []models.Doctor
+	if err = cursor.All(ctx, &doctors); err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", "failed to decode practitioners")
+		return
+	}
+
+	var resources []interface{}
+	var ids []string
+	for i := range doctors {
+		resources = append(resources, ctrl.fhirService.DoctorToFHIR(&doctors[i]))
+		ids = append(ids, doctors[i].ID.Hex())
+	}
+	writeFHIR(c, http.StatusOK, searchBundle(c, "Practitioner", resources, ids, int(total), offset, count))
+}
+
+// ReadDiagnosticReport returns a single DiagnosticReport
+// GET /fhir/R4/DiagnosticReport/:id
+func (ctrl *FHIRController) ReadDiagnosticReport(c *gin.Context) {
+	report, err := services.GetReportByID(c.Param("id"))
+	if err != nil {
+		writeOutcome(c, http.StatusNotFound, "not-found", "DiagnosticReport not found")
+		return
+	}
+	writeFHIR(c, http.StatusOK, ctrl.fhirService.ReportToDiagnosticReport(report, fhirBaseURL(c)))
+}
+
+// SearchDiagnosticReports searches DiagnosticReports by patient, date and status
+// GET /fhir/R4/DiagnosticReport?patient=xxx&date=ge2025-01-01&status=final
+func (ctrl *FHIRController) SearchDiagnosticReports(c *gin.Context) {
+	offset, count, err := pagingParams(c)
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	search, err := reportSearchParams(c)
+	if err != nil {
+		writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	reports, total, err := ctrl.fhirService.SearchReports(search, offset, count)
+	if err != nil {
+		writeOutcome(c, http.StatusInternalServerError, "exception", err.Error())
+		return
+	}
+
+	var resources []interface{}
+	var ids []string
+	for i := range reports {
+		resources = append(resources, ctrl.fhirService.ReportToDiagnosticReport(&reports[i], fhirBaseURL(c)))
+		ids = append(ids, reports[i].ID.Hex())
+	}
+	writeFHIR(c, http.StatusOK, searchBundle(c, "DiagnosticReport", resources, ids, total, offset, count))
+}
+
+// ReadDerived returns a resource derived from a report entity
+// GET /fhir/R4/{Condition|MedicationStatement|Observation|ServiceRequest}/:id
+func (ctrl *FHIRController) ReadDerived(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		reportID, _, _, err := services.ParseDerivedResourceID(id)
+		if err != nil {
+			writeOutcome(c, http.StatusNotFound, "not-found", resourceType+" not found")
+			return
+		}
+
+		report, err := services.GetReportByID(reportID.Hex())
+		if err != nil {
+			writeOutcome(c, http.StatusNotFound, "not-found", resourceType+" not found")
+			return
+		}
+
+		resource, ok := ctrl.fhirService.DerivedResource(report, resourceType, id)
+		if !ok {
+			writeOutcome(c, http.StatusNotFound, "not-found", resourceType+" not found")
+			return
+		}
+		writeFHIR(c, http.StatusOK, resource)
+	}
+}
+
+// SearchDerived searches resources derived from report entities
+// GET /fhir/R4/{Condition|MedicationStatement|Observation|ServiceRequest}?patient=xxx&date=ge2025-01-01
+func (ctrl *FHIRController) SearchDerived(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offset, count, err := pagingParams(c)
+		if err != nil {
+			writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+			return
+		}
+		search, err := reportSearchParams(c)
+		if err != nil {
+			writeOutcome(c, http.StatusBadRequest, "invalid", err.Error())
+			return
+		}
+
+		resources, total, err := ctrl.fhirService.SearchDerivedResources(resourceType, search, offset, count)
+		if err != nil {
+			writeOutcome(c, http.StatusInternalServerError, "exception", err.Error())
+			return
+		}
+
+		ids := make([]string, len(resources))
+		for i, resource := range resources {
+			ids[i] = derivedID(resource)
+		}
+		writeFHIR(c, http.StatusOK, searchBundle(c, resourceType, resources, ids, total, offset, count))
+	}
+}
+
+// derivedID returns the ID of a derived resource
+func derivedID(resource interface{}) string {
+	switch r := resource.(type) {
+	case models.FHIRCondition:
+		return r.ID
+	case models.FHIRMedicationStatement:
+		return r.ID
+	case models.FHIRObservation:
+		return r.ID
+	case models.FHIRServiceRequest:
+		return r.ID
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HL7Controller lets admins inspect and replay quarantined HL7 v2 messages
+type HL7Controller struct {
+	hl7Service *services.HL7Service
+}
+
+func NewHL7Controller() *HL7Controller {
+	return &HL7Controller{
+		hl7Service: &services.HL7Service{},
+	}
+}
+
+// ListQuarantine returns quarantined messages, newest first
+// GET /api/admin/hl7/quarantine?status=quarantined&limit=50
+func (ctrl *HL7Controller) ListQuarantine(c *gin.Context) {
+	status := c.DefaultQuery("status", "quarantined")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	filter := bson.M{}
+	if status != "all" {
+		filter["status"] = status
+	}
+
+	collection := config.GetCollection("hl7_quarantine")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count messages"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "received_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var messages []models.HL7QuarantinedMessage
+	if err = cursor.All(ctx, &messages); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"messages": messages,
+		"count":    len(messages),
+		"total":    total,
+	})
+}
+
+// GetQuarantined returns a single quarantined message including its raw content
+// GET /api/admin/hl7/quarantine/:id
+func (ctrl *HL7Controller) GetQuarantined(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	collection := config.GetCollection("hl7_quarantine")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var message models.HL7QuarantinedMessage
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&message); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entry":   message,
+	})
+}
+
+// ReplayQuarantined re-processes a quarantined message, optionally with a corrected body
+// POST /api/admin/hl7/quarantine/:id/replay
+// Body: { "admin_id": "xxx", "raw_message": "MSH|^~\\&|...", "patient_id": "xxx" }
+// patient_id reconciles a message that could not be matched to a patient safely
+func (ctrl *HL7Controller) ReplayQuarantined(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req struct {
+		AdminID    string `json:"admin_id" binding:"required"`
+		RawMessage string `json:"raw_message"`
+		PatientID  string `json:"patient_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	var patientID *primitive.ObjectID
+	if req.PatientID != "" {
+		id, err := primitive.ObjectIDFromHex(req.PatientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+			return
+		}
+		patientID = &id
+	}
+
+	message, report, err := ctrl.hl7Service.ReplayQuarantined(objID, adminObjID, req.RawMessage, patientID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Replay failed: " + err.Error(),
+			"entry": message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"entry":     message,
+		"report_id": report.ID,
+	})
+}
+
+// DiscardQuarantined marks a quarantined message as discarded
+// POST /api/admin/hl7/quarantine/:id/discard
+// Body: { "admin_id": "xxx" }
+func (ctrl *HL7Controller) DiscardQuarantined(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req struct {
+		AdminID string `json:"admin_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	collection := config.GetCollection("hl7_quarantine")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objID, "status": "quarantined"},
+		bson.M{"$set": bson.M{"status": "discarded", "resolved_at": time.Now(), "resolved_by": adminObjID}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to discard message"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quarantined message not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Message discarded",
+	})
+}
@@ -0,0 +1,332 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationController serves patient and doctor notification inboxes
+type NotificationController struct{}
+
+func NewNotificationController() *NotificationController {
+	return &NotificationController{}
+}
+
+// notificationUser parses the user_id and user_type of an inbox request,
+// writing the error response when they are invalid
+func notificationUser(c *gin.Context, userID, userType string) (primitive.ObjectID, bool) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return primitive.NilObjectID, false
+	}
+	if !services.ValidInboxOwner(userType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_type must be patient, doctor or admin"})
+		return primitive.NilObjectID, false
+	}
+	return objID, true
+}
+
+// ListNotifications returns a user's inbox, newest first
+// GET /api/notifications?user_id=xxx&user_type=patient&unread=true&limit=50
+func (ctrl *NotificationController) ListNotifications(c *gin.Context) {
+	userType := c.Query("user_type")
+	userObjID, ok := notificationUser(c, c.Query("user_id"), userType)
+	if !ok {
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	collection := config.GetCollection("notifications")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	owner := bson.M{"user_id": userObjID, "user_type": userType}
+	filter := bson.M{"user_id": userObjID, "user_type": userType}
+	if c.Query("unread") == "true" {
+		filter["read"] = false
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch notifications"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode notifications"})
+		return
+	}
+
+	owner["read"] = false
+	unread, err := collection.CountDocuments(ctx, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"count":         len(notifications),
+		"unread_count":  unread,
+		"notifications": notifications,
+	})
+}
+
+// setRead marks one of a user's notifications read or unread
+func setRead(c *gin.Context, read bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification id"})
+		return
+	}
+
+	var req struct {
+		UserID   string `json:"user_id" binding:"required"`
+		UserType string `json:"user_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userObjID, ok := notificationUser(c, req.UserID, req.UserType)
+	if !ok {
+		return
+	}
+
+	collection := config.GetCollection("notifications")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"read": true, "read_at": time.Now()}}
+	if !read {
+		update = bson.M{"$set": bson.M{"read": false}, "$unset": bson.M{"read_at": ""}}
+	}
+	// Matching on the owner keeps users from touching each other's inboxes
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID, "user_id": userObjID, "user_type": req.UserType}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update notification"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"read":    read,
+	})
+}
+
+// MarkRead marks a notification read
+// POST /api/notifications/:id/read
+// Body: { "user_id": "xxx", "user_type": "patient" }
+func (ctrl *NotificationController) MarkRead(c *gin.Context) {
+	setRead(c, true)
+}
+
+// MarkUnread marks a notification unread again
+// POST /api/notifications/:id/unread
+// Body: { "user_id": "xxx", "user_type": "patient" }
+func (ctrl *NotificationController) MarkUnread(c *gin.Context) {
+	setRead(c, false)
+}
+
+// MarkAllRead marks every notification in a user's inbox read
+// POST /api/notifications/read-all
+// Body: { "user_id": "xxx", "user_type": "patient" }
+func (ctrl *NotificationController) MarkAllRead(c *gin.Context) {
+	var req struct {
+		UserID   string `json:"user_id" binding:"required"`
+		UserType string `json:"user_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userObjID, ok := notificationUser(c, req.UserID, req.UserType)
+	if !ok {
+		return
+	}
+
+	collection := config.GetCollection("notifications")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"user_id": userObjID, "user_type": req.UserType, "read": false},
+		bson.M{"$set": bson.M{"read": true, "read_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"updated": result.ModifiedCount,
+	})
+}
+
+// GetPreferences returns a user's notification channels, or the defaults
+// GET /api/notifications/preferences?user_id=xxx&user_type=patient
+func (ctrl *NotificationController) GetPreferences(c *gin.Context) {
+	userType := c.Query("user_type")
+	userObjID, ok := notificationUser(c, c.Query("user_id"), userType)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	prefs, err := services.NotificationPreferencesFor(ctx, userObjID, userType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"preferences": prefs,
+		"events":      services.NotificationEvents,
+	})
+}
+
+// UpdatePreferences saves a user's notification channels. Channels apply to
+// every event unless the event has its own list; an empty list mutes the
+// event everywhere but the inbox.
+// PUT /api/notifications/preferences
+// Body: { "user_id": "xxx", "user_type": "patient", "channels": ["in-app", "email"], "events": { "report_reviewed": ["in-app", "sms"] }, "phone": "+15550100" }
+func (ctrl *NotificationController) UpdatePreferences(c *gin.Context) {
+	var req struct {
+		UserID   string              `json:"user_id" binding:"required"`
+		UserType string              `json:"user_type" binding:"required"`
+		Channels []string            `json:"channels"`
+		Events   map[string][]string `json:"events"`
+		Phone    string              `json:"phone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userObjID, ok := notificationUser(c, req.UserID, req.UserType)
+	if !ok {
+		return
+	}
+
+	if req.Channels == nil {
+		req.Channels = []string{}
+	}
+	for _, channel := range req.Channels {
+		if !services.ValidNotificationChannel(channel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown channel: " + channel})
+			return
+		}
+	}
+	for event, channels := range req.Events {
+		known := false
+		for _, e := range services.NotificationEvents {
+			known = known || e == event
+		}
+		if !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown event: " + event})
+			return
+		}
+		for _, channel := range channels {
+			if !services.ValidNotificationChannel(channel) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown channel: " + channel})
+				return
+			}
+		}
+	}
+
+	collection := config.GetCollection("notification_preferences")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	prefs := models.NotificationPreferences{
+		UserID:    userObjID,
+		UserType:  req.UserType,
+		Channels:  req.Channels,
+		Events:    req.Events,
+		Phone:     req.Phone,
+		UpdatedAt: time.Now(),
+	}
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userObjID, "user_type": req.UserType},
+		bson.M{"$set": bson.M{
+			"channels":   prefs.Channels,
+			"events":     prefs.Events,
+			"phone":      prefs.Phone,
+			"updated_at": prefs.UpdatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"preferences": prefs,
+	})
+}
+
+// StreamNotifications pushes a user's new notifications as server-sent
+// events while the connection stays open. Clients load the inbox first; the
+// stream carries only what arrives afterwards.
+// GET /api/notifications/stream?user_id=xxx&user_type=patient
+func (ctrl *NotificationController) StreamNotifications(c *gin.Context) {
+	userType := c.Query("user_type")
+	userObjID, ok := notificationUser(c, c.Query("user_id"), userType)
+	if !ok {
+		return
+	}
+
+	notifications, unsubscribe := services.SubscribeNotifications(userObjID, userType)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Keep-alives stop proxies from closing an idle stream
+	keepAlive := time.NewTicker(25 * time.Second)
+	defer keepAlive.Stop()
+
+	c.SSEvent("ready", gin.H{"user_id": userObjID.Hex(), "user_type": userType})
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case n := <-notifications:
+			c.SSEvent("notification", n)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReanalysisController lets admins re-run the analyzer over existing reports
+type ReanalysisController struct {
+	reanalysisService *services.ReanalysisService
+}
+
+func NewReanalysisController() *ReanalysisController {
+	return &ReanalysisController{
+		reanalysisService: &services.ReanalysisService{},
+	}
+}
+
+// StartJob starts a batch re-analysis job
+// POST /api/admin/reanalysis
+// Body: { "admin_id": "xxx", "dry_run": true, "include_reviewed": false, "report_ids": [], "statuses": [], "exclude_version": "1.1.0", "throttle_ms": 500 }
+func (ctrl *ReanalysisController) StartJob(c *gin.Context) {
+	var req struct {
+		AdminID         string   `json:"admin_id" binding:"required"`
+		DryRun          bool     `json:"dry_run"`
+		IncludeReviewed bool     `json:"include_reviewed"`
+		ReportIDs       []string `json:"report_ids"`
+		Statuses        []string `json:"statuses"`
+		ExcludeVersion  string   `json:"exclude_version"`
+		ThrottleMs      int      `json:"throttle_ms"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	job := models.ReanalysisJob{
+		RequestedBy:     adminObjID,
+		DryRun:          req.DryRun,
+		IncludeReviewed: req.IncludeReviewed,
+		Statuses:        req.Statuses,
+		ExcludeVersion:  req.ExcludeVersion,
+		ThrottleMs:      req.ThrottleMs,
+	}
+	for _, id := range req.ReportIDs {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID: " + id})
+			return
+		}
+		job.ReportIDs = append(job.ReportIDs, objID)
+	}
+
+	if err := ctrl.reanalysisService.StartJob(&job); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Re-analysis job started",
+		"job":     job,
+	})
+}
+
+// ListJobs returns re-analysis jobs, newest first
+// GET /api/admin/reanalysis
+func (ctrl *ReanalysisController) ListJobs(c *gin.Context) {
+	collection := config.GetCollection("reanalysis_jobs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch jobs"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.ReanalysisJob
+	if err = cursor.All(ctx, &jobs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"jobs":    jobs,
+		"count":   len(jobs),
+	})
+}
+
+// GetJob returns a job with its progress
+// GET /api/admin/reanalysis/:id
+func (ctrl *ReanalysisController) GetJob(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	collection := config.GetCollection("reanalysis_jobs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.ReanalysisJob
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&job); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	progress := 0.0
+	if job.Total > 0 {
+		progress = 100 * float64(job.Processed) / float64(job.Total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"job":      job,
+		"progress": progress,
+	})
+}
+
+// GetJobResults returns per-report results of a job
+// GET /api/admin/reanalysis/:id/results?changed=true
+func (ctrl *ReanalysisController) GetJobResults(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	collection := config.GetCollection("reanalysis_results")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"job_id": objID}
+	if c.Query("changed") == "true" {
+		filter["$or"] = []bson.M{
+			{"diff.entities_changed": true},
+			{"diff.recommendations_changed": true},
+		}
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch results"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.ReanalysisResult
+	if err = cursor.All(ctx, &results); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// CancelJob stops a running job after the current report
+// POST /api/admin/reanalysis/:id/cancel
+func (ctrl *ReanalysisController) CancelJob(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	cancelled, err := ctrl.reanalysisService.CancelJob(objID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No running job with this ID"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cancellation requested",
+	})
+}
@@ -18,23 +18,62 @@ import (
 )
 
 // ReportController handles patient report uploads and retrieval
-type ReportController struct{}
+type ReportController struct {
+	summaryService *services.SummaryPDFService
+}
 
 func NewReportController() *ReportController {
-	return &ReportController{}
+	return &ReportController{
+		summaryService: &services.SummaryPDFService{},
+	}
 }
 
-// UploadReport handles multipart PDF upload
+// reportActor resolves who is acting on a patient's reports. The caller is
+// the delegate when delegate_id is given, otherwise the patient named by
+// patient_id. Callers other than the report's patient need an active
+// delegation with the scope.
+func reportActor(c *gin.Context, ownerID primitive.ObjectID, patientID, delegateID, scope string) (*models.Actor, bool) {
+	callerID, name := patientID, "patient_id"
+	if delegateID != "" {
+		callerID, name = delegateID, "delegate_id"
+	}
+	callerObjID, err := primitive.ObjectIDFromHex(callerID)
+	if err != nil || callerObjID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " is required"})
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	actor, err := services.AuthorizeActor(ctx, callerObjID, ownerID, scope)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return actor, true
+}
+
+// UploadReport handles multipart PDF upload. A delegate uploading for a
+// patient sends delegate_id with the patient's patient_id. Reports of
+// patients who have not consented to AI analysis are stored unanalysed.
 // POST /api/patient/upload
 func (ctrl *ReportController) UploadReport(c *gin.Context) {
-	// Optional patient_id in form
-	patientIDStr := c.PostForm("patient_id")
-	var patientObjID primitive.ObjectID
-	if patientIDStr != "" {
-		objID, err := primitive.ObjectIDFromHex(patientIDStr)
-		if err == nil {
-			patientObjID = objID
-		}
+	patientObjID, err := primitive.ObjectIDFromHex(c.PostForm("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "patient_id is required"})
+		return
+	}
+	uploadedBy, ok := reportActor(c, patientObjID, c.PostForm("patient_id"), c.PostForm("delegate_id"), services.DelegateScopeUpload)
+	if !ok {
+		return
+	}
+
+	consentCtx, consentCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	consented, err := services.HasConsent(consentCtx, patientObjID, services.ConsentAIAnalysis)
+	consentCancel()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check consent"})
+		return
 	}
 
 	file, header, err := c.Request.FormFile("file")
@@ -69,23 +108,18 @@ func (ctrl *ReportController) UploadReport(c *gin.Context) {
 	}
 
 	// Call analysis service
-	analysis, err := services.AnalyzePDFReport(storedPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "analysis failed", "details": err.Error()})
-		return
+	uploadedAt := time.Now()
+	var analysis *models.AIAnalysis
+	if consented {
+		analysis, err = services.AnalyzePDFReport(storedPath, patientObjID, uploadedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "analysis failed", "details": err.Error()})
+			return
+		}
 	}
 
 	// Persist report to DB
-	report := models.Report{
-		ID:          primitive.NewObjectID(),
-		PatientID:   patientObjID,
-		PDFPath:     storedPath,
-		PDFFileName: header.Filename,
-		UploadedAt:  time.Now(),
-		AIAnalysis:  *analysis,
-		Status:      "pending",
-		UpdatedAt:   time.Now(),
-	}
+	report := services.NewUploadedReport(patientObjID, storedPath, header.Filename, uploadedAt, uploadedBy, analysis)
 
 	collection := config.GetCollection("reports")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -96,6 +130,24 @@ func (ctrl *ReportController) UploadReport(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save report"})
 		return
 	}
+	services.RecordDelegateActivity(uploadedBy, patientObjID, "upload_report", &report.ID)
+	services.PublishWebhookEvent(services.WebhookReportUploaded, services.ReportWebhookData(&report))
+
+	if !consented {
+		c.JSON(http.StatusOK, gin.H{
+			"success":          true,
+			"report_id":        report.ID.Hex(),
+			"analysis_skipped": report.AnalysisSkipped,
+			"consent":          services.ConsentAIAnalysis,
+			"message":          "report stored without analysis - the patient has not consented to AI analysis",
+		})
+		return
+	}
+
+	services.RaiseCriticalAlert(&report)
+	services.NotifyAnalysisComplete(&report)
+	services.SyncCareTasks(&report)
+	services.PublishWebhookEvent(services.WebhookAnalysisCompleted, services.ReportWebhookData(&report))
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
@@ -105,7 +157,7 @@ func (ctrl *ReportController) UploadReport(c *gin.Context) {
 }
 
 // GetReport returns structured analysis for a report
-// GET /api/patient/reports/:id
+// GET /api/patient/reports/:id?patient_id=xxx or ?delegate_id=yyy
 func (ctrl *ReportController) GetReport(c *gin.Context) {
 	reportID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(reportID)
@@ -124,6 +176,11 @@ func (ctrl *ReportController) GetReport(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
 		return
 	}
+	actor, ok := reportActor(c, report.PatientID, c.Query("patient_id"), c.Query("delegate_id"), services.DelegateScopeViewReports)
+	if !ok {
+		return
+	}
+	services.RecordDelegateActivity(actor, report.PatientID, "view_report", &report.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"report_id":    report.ID.Hex(),
@@ -134,7 +191,7 @@ func (ctrl *ReportController) GetReport(c *gin.Context) {
 }
 
 // DownloadReport serves the PDF file for download
-// GET /api/patient/reports/:id/download
+// GET /api/patient/reports/:id/download?patient_id=xxx or ?delegate_id=yyy
 func (ctrl *ReportController) DownloadReport(c *gin.Context) {
 	reportID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(reportID)
@@ -153,6 +210,10 @@ func (ctrl *ReportController) DownloadReport(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
 		return
 	}
+	actor, ok := reportActor(c, report.PatientID, c.Query("patient_id"), c.Query("delegate_id"), services.DelegateScopeViewReports)
+	if !ok {
+		return
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(report.PDFPath); os.IsNotExist(err) {
@@ -167,15 +228,69 @@ func (ctrl *ReportController) DownloadReport(c *gin.Context) {
 	c.Header("Content-Type", "application/pdf")
 
 	// Serve the file
+	services.RecordDelegateActivity(actor, report.PatientID, "download_report", &report.ID)
 	c.File(report.PDFPath)
 }
 
+// DownloadSummaryPDF renders the analysis summary of a report as a PDF using the
+// hospital template of the reviewing doctor, or the template given by ID
+// GET /api/patient/reports/:id/summary.pdf?patient_id=xxx&template=yyy&download=true
+func (ctrl *ReportController) DownloadSummaryPDF(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var report models.Report
+	if err := config.GetCollection("reports").FindOne(ctx, bson.M{"_id": objID}).Decode(&report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	actor, ok := reportActor(c, report.PatientID, c.Query("patient_id"), c.Query("delegate_id"), services.DelegateScopeViewReports)
+	if !ok {
+		return
+	}
+
+	var template *models.SummaryTemplate
+	if templateID := c.Query("template"); templateID != "" {
+		templateObjID, err := primitive.ObjectIDFromHex(templateID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+			return
+		}
+		template = &models.SummaryTemplate{}
+		if err := config.GetCollection("summary_templates").FindOne(ctx, bson.M{"_id": templateObjID}).Decode(template); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+			return
+		}
+	}
+
+	pdf, err := ctrl.summaryService.RenderReport(&report, template)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render summary: " + err.Error()})
+		return
+	}
+
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	services.RecordDelegateActivity(actor, report.PatientID, "download_summary", &report.ID)
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=summary_%s.pdf", disposition, report.ID.Hex()))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
 // ListReports returns all reports for a patient
 // POST /api/patient/reports
-// Body: { "patient_id": "xxx" }
+// Body: { "patient_id": "xxx", "delegate_id": "optional" }
 func (ctrl *ReportController) ListReports(c *gin.Context) {
 	var req struct {
-		PatientID string `json:"patient_id" binding:"required"`
+		PatientID  string `json:"patient_id" binding:"required"`
+		DelegateID string `json:"delegate_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -188,6 +303,11 @@ func (ctrl *ReportController) ListReports(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
 		return
 	}
+	actor, ok := reportActor(c, patientObjID, req.PatientID, req.DelegateID, services.DelegateScopeViewReports)
+	if !ok {
+		return
+	}
+	services.RecordDelegateActivity(actor, patientObjID, "list_reports", nil)
 
 	collection := config.GetCollection("reports")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
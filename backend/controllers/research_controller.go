@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ResearchController manages de-identified research dataset releases
+type ResearchController struct {
+	researchService *services.ResearchService
+}
+
+func NewResearchController() *ResearchController {
+	return &ResearchController{
+		researchService: &services.ResearchService{},
+	}
+}
+
+// CreateDataset starts building a new version of a de-identified dataset
+// POST /api/admin/research-datasets
+// Body: { "admin_id": "xxx", "name": "diabetes-cohort", "description": "...", "k": 5, "max_suppression": 0.1, "filters": { "from": "2025-01-01T00:00:00Z", "statuses": ["reviewed"], "diagnosis": "diabetes" } }
+func (ctrl *ResearchController) CreateDataset(c *gin.Context) {
+	var req struct {
+		AdminID        string               `json:"admin_id" binding:"required"`
+		Name           string               `json:"name" binding:"required"`
+		Description    string               `json:"description"`
+		K              int                  `json:"k"`
+		MaxSuppression float64              `json:"max_suppression"`
+		Filters        models.ExportFilters `json:"filters"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	ds := models.ResearchDataset{
+		Name:           req.Name,
+		Description:    req.Description,
+		RequestedBy:    adminObjID,
+		Filters:        req.Filters,
+		K:              req.K,
+		MaxSuppression: req.MaxSuppression,
+	}
+	if err := ctrl.researchService.ValidateDataset(&ds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ctrl.researchService.StartDataset(&ds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start research dataset"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Research dataset started",
+		"dataset": ds,
+	})
+}
+
+// ListDatasets returns research dataset versions, newest first
+// GET /api/admin/research-datasets?name=diabetes-cohort
+func (ctrl *ResearchController) ListDatasets(c *gin.Context) {
+	collection := config.GetCollection("research_datasets")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if name := c.Query("name"); name != "" {
+		filter["name"] = name
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(100)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch research datasets"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var datasets []models.ResearchDataset
+	if err = cursor.All(ctx, &datasets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode research datasets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"datasets": datasets,
+		"count":    len(datasets),
+	})
+}
+
+// GetDataset returns the status and manifest of a dataset version
+// GET /api/admin/research-datasets/:id
+func (ctrl *ResearchController) GetDataset(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dataset ID"})
+		return
+	}
+
+	ds, err := ctrl.researchService.GetDataset(objID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Research dataset not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch research dataset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dataset": ds,
+	})
+}
+
+// DownloadDataset downloads a completed dataset version as a zip containing
+// data.jsonl and manifest.json
+// GET /api/admin/research-datasets/:id/download
+func (ctrl *ResearchController) DownloadDataset(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dataset ID"})
+		return
+	}
+
+	ds, err := ctrl.researchService.GetDataset(objID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Research dataset not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch research dataset"})
+		return
+	}
+	if ds.Status != "completed" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Research dataset is " + ds.Status})
+		return
+	}
+	if _, err := os.Stat(ds.FilePath); err != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Research dataset files are no longer available"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", services.ResearchArchiveName(ds)))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := ctrl.researchService.WriteArchive(ds, c.Writer); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShareLinkController handles patient share links and their public use
+type ShareLinkController struct{}
+
+func NewShareLinkController() *ShareLinkController {
+	return &ShareLinkController{}
+}
+
+// ownedShareLink loads a share link of the patient given in patientID
+func ownedShareLink(c *gin.Context, ctx context.Context, patientID string) (*models.ShareLink, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid share link id"})
+		return nil, false
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(patientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return nil, false
+	}
+
+	var link models.ShareLink
+	if err := config.GetCollection("share_links").FindOne(ctx, bson.M{"_id": objID}).Decode(&link); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "share link not found"})
+		return nil, false
+	}
+	if link.PatientID != patientObjID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return &link, true
+}
+
+// CreateShareLink creates a link to one of the patient's reports. The URL is
+// returned only in this response.
+// POST /api/patient/reports/:id/share-links
+// Body: { "patient_id": "xxx", "scope": "view", "expires_in_hours": 72, "passcode": "optional", "max_accesses": 0, "label": "Dr. Smith" }
+func (ctrl *ShareLinkController) CreateShareLink(c *gin.Context) {
+	reportObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report id"})
+		return
+	}
+	var req struct {
+		PatientID      string `json:"patient_id" binding:"required"`
+		Scope          string `json:"scope"`
+		ExpiresInHours int    `json:"expires_in_hours"`
+		Passcode       string `json:"passcode"`
+		MaxAccesses    int    `json:"max_accesses"`
+		Label          string `json:"label"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	patientObjID, err := primitive.ObjectIDFromHex(req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = services.ShareScopeView
+	}
+	if !services.ValidShareScope(req.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be view or download"})
+		return
+	}
+	ttl := services.DefaultShareLinkTTL
+	if req.ExpiresInHours != 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if ttl <= 0 || ttl > services.MaxShareLinkTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_hours must be between 1 and 720"})
+		return
+	}
+	if !services.ValidSharePasscode(req.Passcode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passcode must be at least 4 characters"})
+		return
+	}
+	if req.MaxAccesses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_accesses can't be negative"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var report models.Report
+	if err := config.GetCollection("reports").FindOne(ctx, bson.M{"_id": reportObjID}).Decode(&report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return
+	}
+	if report.PatientID != patientObjID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	link := &models.ShareLink{
+		ReportID:    reportObjID,
+		PatientID:   patientObjID,
+		Label:       req.Label,
+		Scope:       req.Scope,
+		ExpiresAt:   time.Now().Add(ttl),
+		MaxAccesses: req.MaxAccesses,
+	}
+	token, err := services.CreateShareLink(link, req.Passcode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"link":    link,
+		"url":     "/api/shared/" + token,
+		"message": "Copy the link now - it can't be shown again",
+	})
+}
+
+// ListShareLinks returns a patient's share links, newest first
+// GET /api/patient/share-links?patient_id=xxx&report_id=yyy
+func (ctrl *ShareLinkController) ListShareLinks(c *gin.Context) {
+	patientObjID, err := primitive.ObjectIDFromHex(c.Query("patient_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient_id"})
+		return
+	}
+	filter := bson.M{"patient_id": patientObjID}
+	if reportID := c.Query("report_id"); reportID != "" {
+		reportObjID, err := primitive.ObjectIDFromHex(reportID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report_id"})
+			return
+		}
+		filter["report_id"] = reportObjID
+	}
+
+	collection := config.GetCollection("share_links")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(200))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch share links"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.ShareLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode share links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   len(links),
+		"links":   links,
+	})
+}
+
+// RevokeShareLink stops a link from working
+// POST /api/patient/share-links/:id/revoke
+// Body: { "patient_id": "xxx" }
+func (ctrl *ShareLinkController) RevokeShareLink(c *gin.Context) {
+	var req struct {
+		PatientID string `json:"patient_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	link, ok := ownedShareLink(c, ctx, req.PatientID)
+	if !ok {
+		return
+	}
+	if link.RevokedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "Share link already revoked"})
+		return
+	}
+
+	_, err := config.GetCollection("share_links").UpdateOne(ctx,
+		bson.M{"_id": link.ID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": "revoked by patient"}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke share link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Share link revoked",
+	})
+}
+
+// GetShareLinkAccesses returns the access log of a link, newest first
+// GET /api/patient/share-links/:id/accesses?patient_id=xxx
+func (ctrl *ShareLinkController) GetShareLinkAccesses(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	link, ok := ownedShareLink(c, ctx, c.Query("patient_id"))
+	if !ok {
+		return
+	}
+
+	cursor, err := config.GetCollection("share_link_accesses").Find(ctx,
+		bson.M{"link_id": link.ID},
+		options.Find().SetSort(bson.M{"at": -1}).SetLimit(500),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch access log"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	accesses := []models.ShareLinkAccess{}
+	if err := cursor.All(ctx, &accesses); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode access log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"link":     link,
+		"count":    len(accesses),
+		"accesses": accesses,
+	})
+}
+
+// openShareLink resolves the link in the path for an action, writing the
+// error response when access is refused
+func openShareLink(c *gin.Context, action string) (*models.ShareLink, *models.Report, bool) {
+	// Shared pages must not be cached or leak the token to other sites
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+
+	link, report, err := services.OpenShareLink(services.ShareAccess{
+		Token:     c.Param("token"),
+		Passcode:  c.GetHeader("X-Share-Passcode"),
+		Action:    action,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err == nil {
+		return link, report, true
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrShareLinkNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrShareLinkRevoked), errors.Is(err, services.ErrShareLinkExpired),
+		errors.Is(err, services.ErrShareLinkExhausted):
+		status = http.StatusGone
+	case errors.Is(err, services.ErrSharePasscodeRequired), errors.Is(err, services.ErrSharePasscodeIncorrect):
+		status = http.StatusUnauthorized
+	case errors.Is(err, services.ErrShareScope):
+		status = http.StatusForbidden
+	default:
+		c.JSON(status, gin.H{"error": "failed to open share link"})
+		return nil, nil, false
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+	return nil, nil, false
+}
+
+// ViewSharedReport shows a shared report to someone without an account.
+// Protected links need the passcode in the X-Share-Passcode header.
+// GET /api/shared/:token
+func (ctrl *ShareLinkController) ViewSharedReport(c *gin.Context) {
+	link, report, ok := openShareLink(c, services.ShareScopeView)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"report_id":     report.ID.Hex(),
+		"pdf_filename":  report.PDFFileName,
+		"uploaded_at":   report.UploadedAt,
+		"analysis":      report.AIAnalysis,
+		"doctor_review": report.DoctorReview,
+		"scope":         link.Scope,
+		"expires_at":    link.ExpiresAt,
+	})
+}
+
+// DownloadSharedReport serves the original PDF through a download link
+// GET /api/shared/:token/download
+func (ctrl *ShareLinkController) DownloadSharedReport(c *gin.Context) {
+	_, report, ok := openShareLink(c, services.ShareScopeDownload)
+	if !ok {
+		return
+	}
+
+	if _, err := os.Stat(report.PDFPath); os.IsNotExist(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "PDF file not found on server"})
+		return
+	}
+
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", report.PDFFileName))
+	c.Header("Content-Type", "application/pdf")
+	c.File(report.PDFPath)
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// SummaryTemplateController lets admins manage per-hospital summary PDF templates
+type SummaryTemplateController struct{}
+
+func NewSummaryTemplateController() *SummaryTemplateController {
+	return &SummaryTemplateController{}
+}
+
+// validateSummaryTemplate returns a message describing the first invalid field
+func validateSummaryTemplate(template *models.SummaryTemplate) string {
+	if template.AccentColor != "" && !hexColorPattern.MatchString(template.AccentColor) {
+		return "accent_color must be a hex color such as #1f4e8c"
+	}
+	for _, section := range template.Sections {
+		if !services.IsValidSummarySection(section) {
+			return "unknown section: " + section
+		}
+	}
+	return ""
+}
+
+// clearOtherDefaults ensures only one template is marked as default
+func clearOtherDefaults(ctx context.Context, keep primitive.ObjectID) error {
+	_, err := config.GetCollection("summary_templates").UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$ne": keep}, "is_default": true},
+		bson.M{"$set": bson.M{"is_default": false, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// ListTemplates returns all summary templates
+// GET /api/admin/summary-templates
+func (ctrl *SummaryTemplateController) ListTemplates(c *gin.Context) {
+	collection := config.GetCollection("summary_templates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "hospital", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch summary templates"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var templates []models.SummaryTemplate
+	if err = cursor.All(ctx, &templates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode summary templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"templates": templates,
+		"count":     len(templates),
+		"sections":  services.DefaultSummarySections,
+	})
+}
+
+// CreateTemplate creates a summary template
+// POST /api/admin/summary-templates
+func (ctrl *SummaryTemplateController) CreateTemplate(c *gin.Context) {
+	var template models.SummaryTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateSummaryTemplate(&template); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	template.ID = primitive.NewObjectID()
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	collection := config.GetCollection("summary_templates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if template.Hospital != "" {
+		count, err := collection.CountDocuments(ctx, bson.M{"hospital": template.Hospital})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing templates"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A template already exists for this hospital"})
+			return
+		}
+	}
+
+	if _, err := collection.InsertOne(ctx, template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create summary template"})
+		return
+	}
+	if template.IsDefault {
+		if err := clearOtherDefaults(ctx, template.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update default template"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"template": template,
+	})
+}
+
+// UpdateTemplate replaces an existing summary template
+// PUT /api/admin/summary-templates/:id
+func (ctrl *SummaryTemplateController) UpdateTemplate(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var template models.SummaryTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateSummaryTemplate(&template); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	collection := config.GetCollection("summary_templates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if template.Hospital != "" {
+		count, err := collection.CountDocuments(ctx, bson.M{"hospital": template.Hospital, "_id": bson.M{"$ne": objID}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing templates"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A template already exists for this hospital"})
+			return
+		}
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":            template.Name,
+			"hospital":        template.Hospital,
+			"is_default":      template.IsDefault,
+			"title":           template.Title,
+			"header_left":     template.HeaderLeft,
+			"header_right":    template.HeaderRight,
+			"footer_left":     template.FooterLeft,
+			"footer_right":    template.FooterRight,
+			"accent_color":    template.AccentColor,
+			"sections":        template.Sections,
+			"show_confidence": template.ShowConfidence,
+			"disclaimer":      template.Disclaimer,
+			"updated_at":      time.Now(),
+		},
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update summary template"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Summary template not found"})
+		return
+	}
+	if template.IsDefault {
+		if err := clearOtherDefaults(ctx, objID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update default template"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Summary template updated successfully",
+	})
+}
+
+// DeleteTemplate deletes a summary template
+// DELETE /api/admin/summary-templates/:id
+func (ctrl *SummaryTemplateController) DeleteTemplate(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	collection := config.GetCollection("summary_templates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete summary template"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Summary template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Summary template deleted successfully",
+	})
+}
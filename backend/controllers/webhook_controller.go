@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookController lets admins manage outbound webhook subscriptions
+type WebhookController struct{}
+
+func NewWebhookController() *WebhookController {
+	return &WebhookController{}
+}
+
+// validateWebhook returns a message describing the first invalid field
+func validateWebhook(rawURL string, events []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL must be an absolute http or https URL"
+	}
+	if len(events) == 0 {
+		return "At least one event is required"
+	}
+	for _, event := range events {
+		if !services.ValidWebhookEvent(event) {
+			return "Unknown event: " + event
+		}
+	}
+	return ""
+}
+
+// ListWebhooks returns all webhook subscriptions
+// GET /api/admin/webhooks
+func (ctrl *WebhookController) ListWebhooks(c *gin.Context) {
+	collection := config.GetCollection("webhook_subscriptions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.WebhookSubscription{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"count":    len(webhooks),
+		"webhooks": webhooks,
+		"events":   services.WebhookEvents,
+	})
+}
+
+// CreateWebhook subscribes an endpoint to events. The signing secret is
+// generated unless given, and returned only in this response.
+// POST /api/admin/webhooks
+// Body: { "admin_id": "xxx", "name": "EHR", "url": "https://...", "events": ["report.reviewed"], "secret": "optional" }
+func (ctrl *WebhookController) CreateWebhook(c *gin.Context) {
+	var req struct {
+		AdminID string   `json:"admin_id" binding:"required"`
+		Name    string   `json:"name" binding:"required"`
+		URL     string   `json:"url" binding:"required"`
+		Events  []string `json:"events" binding:"required"`
+		Secret  string   `json:"secret"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if msg := validateWebhook(req.URL, req.Events); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if req.Secret == "" {
+		req.Secret = services.NewWebhookSecret()
+	}
+
+	webhook := models.WebhookSubscription{
+		ID:        primitive.NewObjectID(),
+		Name:      req.Name,
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		Active:    true,
+		CreatedBy: adminObjID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("webhook_subscriptions").InsertOne(ctx, webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+	})
+}
+
+// UpdateWebhook changes a subscription's endpoint, events or active flag
+// PUT /api/admin/webhooks/:id
+// Body: { "admin_id": "xxx", "name": "EHR", "url": "https://...", "events": ["*"], "active": false }
+func (ctrl *WebhookController) UpdateWebhook(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var req struct {
+		AdminID string   `json:"admin_id" binding:"required"`
+		Name    string   `json:"name" binding:"required"`
+		URL     string   `json:"url" binding:"required"`
+		Events  []string `json:"events" binding:"required"`
+		Active  bool     `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := primitive.ObjectIDFromHex(req.AdminID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	if msg := validateWebhook(req.URL, req.Events); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	collection := config.GetCollection("webhook_subscriptions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{
+		"name":       req.Name,
+		"url":        req.URL,
+		"events":     req.Events,
+		"active":     req.Active,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook updated successfully",
+	})
+}
+
+// DeleteWebhook removes a subscription; its delivery log is kept
+// DELETE /api/admin/webhooks/:id
+func (ctrl *WebhookController) DeleteWebhook(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("webhook_subscriptions").DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GetDeliveries returns a subscription's delivery log, newest first
+// GET /api/admin/webhooks/:id/deliveries?status=failed
+func (ctrl *WebhookController) GetDeliveries(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	filter := bson.M{"subscription_id": objID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	collection := config.GetCollection("webhook_deliveries")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(200)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"count":      len(deliveries),
+		"deliveries": deliveries,
+	})
+}
+
+// RedeliverWebhook sends a past delivery's payload again and returns the new
+// delivery with the result of its first attempt
+// POST /api/admin/webhook-deliveries/:id/redeliver
+// Body: { "admin_id": "xxx" }
+func (ctrl *WebhookController) RedeliverWebhook(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	var req struct {
+		AdminID string `json:"admin_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adminObjID, err := primitive.ObjectIDFromHex(req.AdminID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	delivery, err := services.RedeliverWebhook(objID, adminObjID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery or webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeliver webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"delivery": delivery,
+	})
+}
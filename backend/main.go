@@ -3,12 +3,14 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
 	"github.com/Aashishvatwani/Medical-Report-Analyzer/routes"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/services"
 )
 
 func main() {
@@ -21,6 +23,24 @@ func main() {
 	// Connect to MongoDB
 	config.ConnectDB()
 
+	// Give doctors relationships for patients they treated before scoping existed
+	if err := services.BackfillCareRelationships(); err != nil {
+		log.Println("❌ Care relationship backfill failed:", err)
+	}
+
+	// Publish default consent documents and keep analysing the reports of
+	// patients who uploaded before consent was recorded
+	if err := services.SeedConsentDocuments(); err != nil {
+		log.Println("❌ Consent document seeding failed:", err)
+	} else if err := services.BackfillConsents(); err != nil {
+		log.Println("❌ Consent backfill failed:", err)
+	}
+
+	// Free the re-analysis slot held by jobs a previous run left behind
+	if err := services.RecoverReanalysisJobs(); err != nil {
+		log.Println("❌ Re-analysis job recovery failed:", err)
+	}
+
 	// Setup Gin router
 	r := gin.Default()
 
@@ -28,7 +48,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Share-Passcode")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -75,12 +95,38 @@ func main() {
 	r.StaticFile("/doctor.html", "./public/doctor.html")
 
 	// Register all routes
-	routes.AuthRoutes(r)    // Authentication for patients and doctors
-	routes.ChatbotRoutes(r) // Medical report chatbot
-	routes.ReportRoutes(r)  // Patient upload & report retrieval
-	routes.DoctorRoutes(r)  // Doctor portal for reviewing reports
-	routes.PatientRoutes(r) // Patient portal for viewing reports
-	routes.AdminRoutes(r)   // Admin portal for system management
+	routes.AuthRoutes(r)         // Authentication for patients and doctors
+	routes.ChatbotRoutes(r)      // Medical report chatbot
+	routes.ReportRoutes(r)       // Patient upload & report retrieval
+	routes.DoctorRoutes(r)       // Doctor portal for reviewing reports
+	routes.PatientRoutes(r)      // Patient portal for viewing reports
+	routes.AdminRoutes(r)        // Admin portal for system management
+	routes.FHIRRoutes(r)         // HL7 FHIR R4 API for partner integrations
+	routes.NotificationRoutes(r) // Notification inbox, preferences and live stream
+	routes.CalendarRoutes(r)     // Doctor appointment .ics feeds
+	routes.SharedReportRoutes(r) // Public report share links
+
+	// HL7 v2 MLLP listener for lab systems (e.g. HL7_MLLP_ADDR=:2575)
+	if mllpAddr := os.Getenv("HL7_MLLP_ADDR"); mllpAddr != "" {
+		listener := &services.MLLPListener{Addr: mllpAddr, Service: &services.HL7Service{}}
+		go func() {
+			if err := listener.ListenAndServe(); err != nil {
+				log.Println("❌ HL7 MLLP listener stopped:", err)
+			}
+		}()
+	}
+
+	// Re-send unacknowledged critical value alerts
+	go services.RunAlertRenotifier(time.Minute)
+
+	// Retry failed outbound webhook deliveries with backoff
+	go services.RunWebhookRetrier(15 * time.Second)
+
+	// Remind patients and doctors of due and overdue care tasks
+	go services.RunCareTaskReminders(5 * time.Minute)
+
+	// Poll CLINICAL_RULES_PATH so rule edits apply without a restart
+	go services.ClinicalRules().Watch(services.RulesReloadInterval())
 
 	log.Println("✅ Server running on port:", port)
 	log.Println("📊 Health check: http://localhost:" + port + "/health")
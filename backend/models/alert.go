@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CriticalThreshold defines when a lab result is a critical value. Results
+// are matched by code, or by name when the observation has no code.
+type CriticalThreshold struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name" binding:"required"` // e.g. "Potassium"
+	Aliases   []string           `bson:"aliases,omitempty" json:"aliases,omitempty"`
+	Code      string             `bson:"code,omitempty" json:"code,omitempty"` // LOINC
+	Unit      string             `bson:"unit,omitempty" json:"unit,omitempty"` // Results in other units are not compared
+	Low       *float64           `bson:"low,omitempty" json:"low,omitempty"`   // Critical below this value
+	High      *float64           `bson:"high,omitempty" json:"high,omitempty"` // Critical above this value
+	Enabled   bool               `bson:"enabled" json:"enabled"`
+	UpdatedBy primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CriticalFinding is one reason a report raised a critical alert
+type CriticalFinding struct {
+	Kind        string   `bson:"kind" json:"kind"` // "lab", "lab-flag", "urgency"
+	Description string   `bson:"description" json:"description"`
+	Value       *float64 `bson:"value,omitempty" json:"value,omitempty"`
+	Threshold   string   `bson:"threshold,omitempty" json:"threshold,omitempty"` // e.g. "> 6.5 mmol/L"
+}
+
+// CriticalAlert is raised for a report with critical findings and re-sent to
+// its recipients until a doctor acknowledges it
+type CriticalAlert struct {
+	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	ReportID       primitive.ObjectID   `bson:"report_id" json:"report_id"`
+	PatientID      primitive.ObjectID   `bson:"patient_id" json:"patient_id"`
+	Findings       []CriticalFinding    `bson:"findings" json:"findings"`
+	RecipientIDs   []primitive.ObjectID `bson:"recipient_ids" json:"recipient_ids"`
+	Status         string               `bson:"status" json:"status"` // "open", "acknowledged"
+	NotifyCount    int                  `bson:"notify_count" json:"notify_count"`
+	LastNotifiedAt *time.Time           `bson:"last_notified_at,omitempty" json:"last_notified_at,omitempty"`
+	NextNotifyAt   *time.Time           `bson:"next_notify_at,omitempty" json:"next_notify_at,omitempty"` // Nil once acknowledged or out of attempts
+	Deliveries     []AlertDelivery      `bson:"deliveries,omitempty" json:"deliveries,omitempty"`
+	AcknowledgedBy *primitive.ObjectID  `bson:"acknowledged_by,omitempty" json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time           `bson:"acknowledged_at,omitempty" json:"acknowledged_at,omitempty"`
+	AckNote        string               `bson:"ack_note,omitempty" json:"ack_note,omitempty"`
+	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// AlertDelivery records one attempt to notify through a channel
+type AlertDelivery struct {
+	Channel string    `bson:"channel" json:"channel"` // "in-app", "email", "webhook", ...
+	Attempt int       `bson:"attempt" json:"attempt"`
+	SentAt  time.Time `bson:"sent_at" json:"sent_at"`
+	Error   string    `bson:"error,omitempty" json:"error,omitempty"`
+}
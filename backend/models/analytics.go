@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EntityTypeAccuracy holds precision/recall estimates for one entity type,
+// treating the doctor's corrected analysis as ground truth
+type EntityTypeAccuracy struct {
+	EntityType    string  `json:"entity_type"`
+	Accepted      int     `json:"accepted"` // Kept by the doctor (true positives)
+	Removed       int     `json:"removed"`  // Removed by the doctor (false positives)
+	Added         int     `json:"added"`    // Added by the doctor (false negatives)
+	Precision     float64 `json:"precision"`
+	Recall        float64 `json:"recall"`
+	F1            float64 `json:"f1"`
+	FeedbackCount int     `json:"feedback_count"`
+}
+
+// EntityCorrection counts how often a specific entity was removed or added
+type EntityCorrection struct {
+	EntityType string `json:"entity_type"`
+	Entity     string `json:"entity"`
+	Count      int    `json:"count"`
+}
+
+// CalibrationBin compares predicted confidence with doctor acceptance
+type CalibrationBin struct {
+	LowerBound        float64 `json:"lower_bound"`
+	UpperBound        float64 `json:"upper_bound"`
+	Count             int     `json:"count"`
+	MeanConfidence    float64 `json:"mean_confidence"` // 0-100
+	AcceptanceRate    float64 `json:"acceptance_rate"` // 0-100
+	CalibrationGapAbs float64 `json:"calibration_gap_abs"`
+}
+
+// CalibrationCurve is the reliability curve for recommendation confidences
+type CalibrationCurve struct {
+	Bins                     []CalibrationBin `json:"bins"`
+	ExpectedCalibrationError float64          `json:"expected_calibration_error"`
+	SampleCount              int              `json:"sample_count"`
+}
+
+// AccuracyTrendPoint summarizes model accuracy over one time period
+type AccuracyTrendPoint struct {
+	Period        string  `json:"period"`
+	FeedbackCount int     `json:"feedback_count"`
+	Precision     float64 `json:"precision"`
+	Recall        float64 `json:"recall"`
+	F1            float64 `json:"f1"`
+}
+
+// FeedbackAnalytics is the full analytics report over doctor feedback
+type FeedbackAnalytics struct {
+	FeedbackCount int                  `json:"feedback_count"`
+	EntityTypes   []EntityTypeAccuracy `json:"entity_types"`
+	MostRemoved   []EntityCorrection   `json:"most_removed"`
+	MostAdded     []EntityCorrection   `json:"most_added"`
+	Calibration   CalibrationCurve     `json:"calibration"`
+	Trends        []AccuracyTrendPoint `json:"trends"`
+	GeneratedAt   time.Time            `json:"generated_at"`
+}
+
+// TrainingExample is one labelled entity decision exported for model retraining
+type TrainingExample struct {
+	FeedbackID          primitive.ObjectID `json:"feedback_id"`
+	ReportID            primitive.ObjectID `json:"report_id"`
+	DoctorID            primitive.ObjectID `json:"doctor_id"`
+	CreatedAt           time.Time          `json:"created_at"`
+	EntityType          string             `json:"entity_type"`
+	Entity              string             `json:"entity"`
+	Label               string             `json:"label"` // "accepted", "removed" or "added"
+	PredictedConfidence *float64           `json:"predicted_confidence,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AvailabilitySlot is a period a doctor offers for consultations. A slot
+// holds at most one booked appointment.
+type AvailabilitySlot struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	DoctorID      primitive.ObjectID  `bson:"doctor_id" json:"doctor_id"`
+	StartsAt      time.Time           `bson:"starts_at" json:"starts_at"`
+	EndsAt        time.Time           `bson:"ends_at" json:"ends_at"`
+	Mode          string              `bson:"mode" json:"mode"` // "in-person", "video", "phone"
+	Location      string              `bson:"location,omitempty" json:"location,omitempty"`
+	AppointmentID *primitive.ObjectID `bson:"appointment_id,omitempty" json:"appointment_id,omitempty"` // Set while booked
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// Appointment is a consultation booked by a patient about reviewed reports
+type Appointment struct {
+	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	DoctorID        primitive.ObjectID   `bson:"doctor_id" json:"doctor_id"`
+	PatientID       primitive.ObjectID   `bson:"patient_id" json:"patient_id"`
+	SlotID          primitive.ObjectID   `bson:"slot_id" json:"slot_id"`
+	ReportIDs       []primitive.ObjectID `bson:"report_ids" json:"report_ids"`
+	StartsAt        time.Time            `bson:"starts_at" json:"starts_at"`
+	EndsAt          time.Time            `bson:"ends_at" json:"ends_at"`
+	Mode            string               `bson:"mode" json:"mode"`
+	Location        string               `bson:"location,omitempty" json:"location,omitempty"`
+	Reason          string               `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status          string               `bson:"status" json:"status"` // "booked", "cancelled"
+	RescheduleCount int                  `bson:"reschedule_count" json:"reschedule_count"`
+	Sequence        int                  `bson:"sequence" json:"sequence"`                             // iCalendar SEQUENCE, bumped on every change
+	CancelledBy     string               `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"` // "patient", "doctor"
+	CancelReason    string               `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"`
+	CancelledAt     *time.Time           `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// CalendarFeed is the secret token that lets a calendar app subscribe to a
+// doctor's appointments without signing in
+type CalendarFeed struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DoctorID  primitive.ObjectID `bson:"doctor_id" json:"doctor_id"`
+	Token     string             `bson:"token" json:"token"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
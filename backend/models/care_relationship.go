@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CareRelationship links a doctor to a patient they treat. Doctor access to
+// a patient's records is scoped to active relationships.
+type CareRelationship struct {
+	ID        primitive.ObjectID       `bson:"_id,omitempty" json:"id"`
+	DoctorID  primitive.ObjectID       `bson:"doctor_id" json:"doctor_id"`
+	PatientID primitive.ObjectID       `bson:"patient_id" json:"patient_id"`
+	Status    string                   `bson:"status" json:"status"` // "active", "ended"
+	Sources   []CareRelationshipSource `bson:"sources" json:"sources"`
+	EndsAt    *time.Time               `bson:"ends_at,omitempty" json:"ends_at,omitempty"` // Optional, set by admins
+	EndedAt   *time.Time               `bson:"ended_at,omitempty" json:"ended_at,omitempty"`
+	EndedBy   *CareRelationshipEndedBy `bson:"ended_by,omitempty" json:"ended_by,omitempty"`
+	CreatedAt time.Time                `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time                `bson:"updated_at" json:"updated_at"`
+}
+
+// CareRelationshipSource records why a relationship exists; a relationship
+// collects one entry per referral, appointment, assignment or admin grant
+type CareRelationshipSource struct {
+	Type   string              `bson:"type" json:"type"`                         // "referral", "appointment", "assignment", "admin"
+	RefID  *primitive.ObjectID `bson:"ref_id,omitempty" json:"ref_id,omitempty"` // Referral, appointment or report
+	ByID   *primitive.ObjectID `bson:"by_id,omitempty" json:"by_id,omitempty"`   // Referring doctor or admin
+	ByType string              `bson:"by_type,omitempty" json:"by_type,omitempty"`
+	At     time.Time           `bson:"at" json:"at"`
+}
+
+// CareRelationshipEndedBy records who ended a relationship and why
+type CareRelationshipEndedBy struct {
+	ID     primitive.ObjectID `bson:"id" json:"id"`
+	Type   string             `bson:"type" json:"type"` // "doctor", "admin"
+	Reason string             `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// BreakGlassAccess is an emergency grant a doctor gives themselves to a
+// patient they have no relationship with. It is short-lived, every use is
+// recorded, and admins review it afterwards.
+type BreakGlassAccess struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	DoctorID   primitive.ObjectID  `bson:"doctor_id" json:"doctor_id"`
+	PatientID  primitive.ObjectID  `bson:"patient_id" json:"patient_id"`
+	Reason     string              `bson:"reason" json:"reason"`
+	StartedAt  time.Time           `bson:"started_at" json:"started_at"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"`
+	Uses       []BreakGlassUse     `bson:"uses" json:"uses"`
+	ReviewedBy *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	ReviewNote string              `bson:"review_note,omitempty" json:"review_note,omitempty"`
+}
+
+// BreakGlassUse is one read made under a break-glass grant
+type BreakGlassUse struct {
+	At       time.Time           `bson:"at" json:"at"`
+	Action   string              `bson:"action" json:"action"` // e.g. "patient_reports"
+	ReportID *primitive.ObjectID `bson:"report_id,omitempty" json:"report_id,omitempty"`
+}
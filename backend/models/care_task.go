@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CareTask tracks a recommended follow-up test until it is done, declined or cancelled
+type CareTask struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PatientID primitive.ObjectID `bson:"patient_id" json:"patient_id"`
+	ReportID  primitive.ObjectID `bson:"report_id" json:"report_id"` // Report whose recommendation created the task
+	Test      string             `bson:"test" json:"test"`
+	Code      string             `bson:"code,omitempty" json:"code,omitempty"` // LOINC code of the test, when coded
+	Reason    string             `bson:"reason" json:"reason"`
+	Urgency   string             `bson:"urgency" json:"urgency"`
+	RuleID    string             `bson:"rule_id,omitempty" json:"rule_id,omitempty"`
+	// Date of the source report; only reports dated after it complete the task
+	RecommendedAt time.Time          `bson:"recommended_at" json:"recommended_at"`
+	Assignees     []CareTaskAssignee `bson:"assignees" json:"assignees"`
+	Status        string             `bson:"status" json:"status"` // "open", "scheduled", "completed", "declined", "cancelled"
+	DueAt         time.Time          `bson:"due_at" json:"due_at"`
+	// Set while scheduled
+	ScheduledFor *time.Time `bson:"scheduled_for,omitempty" json:"scheduled_for,omitempty"`
+	// Set when completed; CompletedByReport is the later report that contained the test
+	CompletedAt       *time.Time          `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CompletedByReport *primitive.ObjectID `bson:"completed_by_report,omitempty" json:"completed_by_report,omitempty"`
+	DeclinedReason    string              `bson:"declined_reason,omitempty" json:"declined_reason,omitempty"`
+	ReminderCount     int                 `bson:"reminder_count" json:"reminder_count"`
+	LastRemindedAt    *time.Time          `bson:"last_reminded_at,omitempty" json:"last_reminded_at,omitempty"`
+	NextReminderAt    *time.Time          `bson:"next_reminder_at,omitempty" json:"next_reminder_at,omitempty"` // Nil once closed or out of reminders
+	History           []CareTaskEvent     `bson:"history" json:"history"`
+	CreatedAt         time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CareTaskAssignee is a patient or doctor responsible for a care task
+type CareTaskAssignee struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	UserType string             `bson:"user_type" json:"user_type"` // "patient", "doctor"
+}
+
+// CareTaskEvent records a change to a care task
+type CareTaskEvent struct {
+	At       time.Time           `bson:"at" json:"at"`
+	Status   string              `bson:"status" json:"status"`
+	ByID     *primitive.ObjectID `bson:"by_id,omitempty" json:"by_id,omitempty"` // Nil for automatic changes
+	ByType   string              `bson:"by_type" json:"by_type"`                 // "patient", "doctor", "system"
+	Note     string              `bson:"note,omitempty" json:"note,omitempty"`
+	ReportID *primitive.ObjectID `bson:"report_id,omitempty" json:"report_id,omitempty"`
+}
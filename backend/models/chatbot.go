@@ -11,6 +11,7 @@ type ChatMessage struct {
 	Role      string    `bson:"role" json:"role"` // "user" or "assistant"
 	Content   string    `bson:"content" json:"content"`
 	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+	SentBy    *Actor    `bson:"sent_by,omitempty" json:"sent_by,omitempty"` // Who sent a user message
 }
 
 // ChatSession represents a conversation session about a specific report
@@ -25,9 +26,10 @@ type ChatSession struct {
 
 // ChatRequest represents the incoming chat request from the user
 type ChatRequest struct {
-	PatientID string `json:"patient_id"` // Optional if auth middleware is present
-	ReportID  string `json:"report_id" binding:"required"`
-	Message   string `json:"message" binding:"required"`
+	PatientID  string `json:"patient_id"`  // Optional if auth middleware is present
+	DelegateID string `json:"delegate_id"` // Set when a delegate chats for the patient
+	ReportID   string `json:"report_id" binding:"required"`
+	Message    string `json:"message" binding:"required"`
 }
 
 // ChatResponse represents the chatbot's response
@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// ClinicalRuleSet is a versioned set of test recommendation rules maintained
+// by clinicians in YAML or JSON
+type ClinicalRuleSet struct {
+	Name        string         `yaml:"name" json:"name"`
+	Version     string         `yaml:"version" json:"version"`
+	Description string         `yaml:"description,omitempty" json:"description,omitempty"`
+	Rules       []ClinicalRule `yaml:"rules" json:"rules"`
+}
+
+// ClinicalRule recommends a test when its condition holds. When the
+// contraindication holds as well the test is reported as not recommended.
+type ClinicalRule struct {
+	ID              string         `yaml:"id" json:"id"`
+	Description     string         `yaml:"description,omitempty" json:"description,omitempty"`
+	Test            string         `yaml:"test" json:"test"`
+	Reason          string         `yaml:"reason" json:"reason"`
+	Urgency         string         `yaml:"urgency" json:"urgency"`       // "routine", "urgent", "emergent"
+	Confidence      float64        `yaml:"confidence" json:"confidence"` // 0-100
+	When            RuleCondition  `yaml:"when" json:"when"`
+	Contraindicated *RuleCondition `yaml:"contraindicated,omitempty" json:"contraindicated,omitempty"`
+	Disabled        bool           `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// RuleCondition is one node of a rule's condition tree. Exactly one field is
+// set: a combinator (all, any, not) or a test against the report, patient or
+// prior reports.
+type RuleCondition struct {
+	All []RuleCondition `yaml:"all,omitempty" json:"all,omitempty"`
+	Any []RuleCondition `yaml:"any,omitempty" json:"any,omitempty"`
+	Not *RuleCondition  `yaml:"not,omitempty" json:"not,omitempty"`
+
+	// Entity tests match any of the terms; negated and hypothetical entities never match
+	Symptom    []string `yaml:"symptom,omitempty" json:"symptom,omitempty"`
+	Diagnosis  []string `yaml:"diagnosis,omitempty" json:"diagnosis,omitempty"`
+	Medication []string `yaml:"medication,omitempty" json:"medication,omitempty"`
+	Test       []string `yaml:"test,omitempty" json:"test,omitempty"`
+
+	Lab    *LabCondition   `yaml:"lab,omitempty" json:"lab,omitempty"`
+	Age    *RangeCondition `yaml:"age,omitempty" json:"age,omitempty"`
+	Gender string          `yaml:"gender,omitempty" json:"gender,omitempty"` // "female" or "male"
+	Prior  *PriorCondition `yaml:"prior,omitempty" json:"prior,omitempty"`
+}
+
+// LabCondition tests a structured lab observation by name or code, against a
+// value or its interpretation flag
+type LabCondition struct {
+	Name           []string `yaml:"name,omitempty" json:"name,omitempty"`
+	Code           string   `yaml:"code,omitempty" json:"code,omitempty"`
+	Op             string   `yaml:"op,omitempty" json:"op,omitempty"` // "<", "<=", ">", ">=", "=="
+	Value          *float64 `yaml:"value,omitempty" json:"value,omitempty"`
+	Interpretation []string `yaml:"interpretation,omitempty" json:"interpretation,omitempty"` // e.g. ["H", "HH"]
+}
+
+// RangeCondition is an inclusive range; a zero bound is open
+type RangeCondition struct {
+	Min int `yaml:"min,omitempty" json:"min,omitempty"`
+	Max int `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// PriorCondition holds when enough of the patient's earlier reports within the
+// window satisfy the nested condition
+type PriorCondition struct {
+	WithinDays int           `yaml:"within_days,omitempty" json:"within_days,omitempty"` // 0 for any time
+	MinCount   int           `yaml:"min_count,omitempty" json:"min_count,omitempty"`     // Defaults to 1
+	When       RuleCondition `yaml:"when" json:"when"`
+}
+
+// RuleTrace explains how a rule was evaluated against a report
+type RuleTrace struct {
+	RuleID          string          `bson:"rule_id" json:"rule_id"`
+	Test            string          `bson:"test" json:"test"`
+	Matched         bool            `bson:"matched" json:"matched"`
+	Contraindicated bool            `bson:"contraindicated" json:"contraindicated"`
+	Steps           []RuleTraceStep `bson:"steps" json:"steps"`
+}
+
+// RuleTraceStep is one evaluated condition, indented by Depth
+type RuleTraceStep struct {
+	Depth     int    `bson:"depth" json:"depth"`
+	Condition string `bson:"condition" json:"condition"`
+	Result    bool   `bson:"result" json:"result"`
+	Evidence  string `bson:"evidence,omitempty" json:"evidence,omitempty"`
+}
+
+// ClinicalRuleSetStatus describes the rule set currently loaded
+type ClinicalRuleSetStatus struct {
+	Name       string     `json:"name"`
+	Version    string     `json:"version"`
+	Source     string     `json:"source"` // File path, or "bundled"
+	RuleCount  int        `json:"rule_count"`
+	LoadedAt   time.Time  `json:"loaded_at"`
+	LastError  string     `json:"last_error,omitempty"` // From the most recent failed reload
+	LastFailAt *time.Time `json:"last_fail_at,omitempty"`
+}
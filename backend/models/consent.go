@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConsentDocument is one published version of the text a patient agrees to
+// for a purpose. Versions are never edited; a change publishes a new one.
+type ConsentDocument struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Purpose           string             `bson:"purpose" json:"purpose"` // "ai_analysis", "cloud_chat", "research"
+	Version           int                `bson:"version" json:"version"` // Increments per purpose
+	Title             string             `bson:"title" json:"title"`
+	Body              string             `bson:"body" json:"body"`
+	RequiresReconsent bool               `bson:"requires_reconsent" json:"requires_reconsent"` // Consents to earlier versions stop counting
+	PublishedBy       primitive.ObjectID `bson:"published_by" json:"published_by"`
+	PublishedAt       time.Time          `bson:"published_at" json:"published_at"`
+}
+
+// PatientConsent is a patient's current decision for one purpose, with the
+// full history of grants and withdrawals
+type PatientConsent struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PatientID       primitive.ObjectID `bson:"patient_id" json:"patient_id"`
+	Purpose         string             `bson:"purpose" json:"purpose"`
+	Status          string             `bson:"status" json:"status"` // "granted", "withdrawn"
+	DocumentID      primitive.ObjectID `bson:"document_id" json:"document_id"`
+	DocumentVersion int                `bson:"document_version" json:"document_version"` // Version last granted
+	History         []ConsentEvent     `bson:"history" json:"history"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ConsentEvent is one grant or withdrawal of consent
+type ConsentEvent struct {
+	Action          string             `bson:"action" json:"action"` // "granted", "withdrawn"
+	DocumentID      primitive.ObjectID `bson:"document_id" json:"document_id"`
+	DocumentVersion int                `bson:"document_version" json:"document_version"`
+	ByID            primitive.ObjectID `bson:"by_id" json:"by_id"`
+	ByType          string             `bson:"by_type" json:"by_type"` // "patient", "admin", "system"
+	Note            string             `bson:"note,omitempty" json:"note,omitempty"`
+	At              time.Time          `bson:"at" json:"at"`
+}
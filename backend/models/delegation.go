@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Delegation lets another account act on a patient's records, e.g. a parent
+// for a minor or a carer for an elderly patient
+type Delegation struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PatientID     primitive.ObjectID  `bson:"patient_id" json:"patient_id"`
+	DelegateID    primitive.ObjectID  `bson:"delegate_id" json:"delegate_id"` // A patient account
+	DelegateName  string              `bson:"delegate_name" json:"delegate_name"`
+	DelegateEmail string              `bson:"delegate_email" json:"delegate_email"`
+	Relationship  string              `bson:"relationship,omitempty" json:"relationship,omitempty"` // e.g. "parent", "guardian", "carer"
+	Scopes        []string            `bson:"scopes" json:"scopes"`                                 // "view_reports", "chat", "upload"
+	GrantedByID   primitive.ObjectID  `bson:"granted_by_id" json:"granted_by_id"`
+	GrantedByType string              `bson:"granted_by_type" json:"granted_by_type"` // "patient", "admin"
+	ExpiresAt     *time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt     *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RevokedByID   *primitive.ObjectID `bson:"revoked_by_id,omitempty" json:"revoked_by_id,omitempty"`
+	RevokedByType string              `bson:"revoked_by_type,omitempty" json:"revoked_by_type,omitempty"`
+	RevokeReason  string              `bson:"revoke_reason,omitempty" json:"revoke_reason,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Actor identifies who performed an action on a patient's records: the
+// patient themselves or a delegate acting for them
+type Actor struct {
+	ID           primitive.ObjectID  `bson:"id" json:"id"`
+	Type         string              `bson:"type" json:"type"` // "patient", "delegate"
+	DelegationID *primitive.ObjectID `bson:"delegation_id,omitempty" json:"delegation_id,omitempty"`
+}
+
+// DelegateActivity records an action a delegate took for a patient
+type DelegateActivity struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	DelegationID primitive.ObjectID  `bson:"delegation_id" json:"delegation_id"`
+	PatientID    primitive.ObjectID  `bson:"patient_id" json:"patient_id"`
+	DelegateID   primitive.ObjectID  `bson:"delegate_id" json:"delegate_id"`
+	Action       string              `bson:"action" json:"action"` // e.g. "view_report", "upload_report", "chat_message"
+	ReportID     *primitive.ObjectID `bson:"report_id,omitempty" json:"report_id,omitempty"`
+	At           time.Time           `bson:"at" json:"at"`
+}
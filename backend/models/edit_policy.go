@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EditPolicy defines who may edit an AI analysis and under which conditions
+type EditPolicy struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name            string             `bson:"name" json:"name" binding:"required"`
+	Description     string             `bson:"description,omitempty" json:"description,omitempty"`
+	Roles           []string           `bson:"roles,omitempty" json:"roles,omitempty"`                     // Doctor roles this policy applies to (empty = all)
+	Specializations []string           `bson:"specializations,omitempty" json:"specializations,omitempty"` // Specializations this policy applies to (empty = all)
+	MaxConfidence   float64            `bson:"max_confidence" json:"max_confidence"`                       // Edits allowed below this score (0 = no limit)
+	AllowedStatuses []string           `bson:"allowed_statuses,omitempty" json:"allowed_statuses,omitempty"`
+	AllowOverride   bool               `bson:"allow_override" json:"allow_override"` // Doctor may override a denial with a justification
+	Priority        int                `bson:"priority" json:"priority"`             // Higher priority policies are evaluated first
+	Enabled         bool               `bson:"enabled" json:"enabled"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// EditDecision is the result of evaluating edit policies for a doctor and report
+type EditDecision struct {
+	Allowed         bool   `json:"allowed"`
+	OverrideAllowed bool   `json:"override_allowed"`
+	PolicyName      string `json:"policy_name,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// EditAuditEntry records an analysis edit made through the policy override path
+type EditAuditEntry struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReportID        primitive.ObjectID `bson:"report_id" json:"report_id"`
+	DoctorID        primitive.ObjectID `bson:"doctor_id" json:"doctor_id"`
+	FeedbackID      primitive.ObjectID `bson:"feedback_id" json:"feedback_id"`
+	ConfidenceScore float64            `bson:"confidence_score" json:"confidence_score"`
+	ReportStatus    string             `bson:"report_status" json:"report_status"`
+	DeniedReason    string             `bson:"denied_reason" json:"denied_reason"`
+	PolicyName      string             `bson:"policy_name,omitempty" json:"policy_name,omitempty"`
+	Justification   string             `bson:"justification" json:"justification"`
+	FlaggedForAudit bool               `bson:"flagged_for_audit" json:"flagged_for_audit"`
+	AuditedBy       primitive.ObjectID `bson:"audited_by,omitempty" json:"audited_by,omitempty"`
+	AuditedAt       *time.Time         `bson:"audited_at,omitempty" json:"audited_at,omitempty"`
+	AuditNotes      string             `bson:"audit_notes,omitempty" json:"audit_notes,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+}
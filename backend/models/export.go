@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExportFilters narrows the rows of a bulk export
+type ExportFilters struct {
+	From      *time.Time          `bson:"from,omitempty" json:"from,omitempty"` // inclusive
+	To        *time.Time          `bson:"to,omitempty" json:"to,omitempty"`     // exclusive
+	Statuses  []string            `bson:"statuses,omitempty" json:"statuses,omitempty"`
+	Diagnosis string              `bson:"diagnosis,omitempty" json:"diagnosis,omitempty"` // Case-insensitive substring
+	DoctorID  *primitive.ObjectID `bson:"doctor_id,omitempty" json:"doctor_id,omitempty"` // Reviewing doctor
+}
+
+// ExportJob is a bulk CSV/XLSX export generated in the background
+type ExportJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RequestedBy primitive.ObjectID `bson:"requested_by" json:"requested_by"`
+	Dataset     string             `bson:"dataset" json:"dataset"` // "reports", "patients", "doctors"
+	Format      string             `bson:"format" json:"format"`   // "csv", "xlsx"
+	Columns     []string           `bson:"columns" json:"columns"`
+	Filters     ExportFilters      `bson:"filters" json:"filters"`
+	Status      string             `bson:"status" json:"status"` // "queued", "running", "completed", "failed", "expired"
+	RowCount    int                `bson:"row_count" json:"row_count"`
+	FilePath    string             `bson:"file_path,omitempty" json:"-"`
+	FileName    string             `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	FileSize    int64              `bson:"file_size,omitempty" json:"file_size,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // Download no longer available after this time
+}
@@ -0,0 +1,254 @@
+package models
+
+// Minimal HL7 FHIR R4 resource structures used by the /fhir/R4 API.
+// Only the elements the backend can populate are modelled.
+
+type FHIRMeta struct {
+	VersionID   string   `json:"versionId,omitempty"`
+	LastUpdated string   `json:"lastUpdated,omitempty"`
+	Profile     []string `json:"profile,omitempty"`
+}
+
+type FHIRCoding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type FHIRCodeableConcept struct {
+	Coding []FHIRCoding `json:"coding,omitempty"`
+	Text   string       `json:"text,omitempty"`
+}
+
+type FHIRIdentifier struct {
+	Use    string `json:"use,omitempty"`
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+type FHIRReference struct {
+	Reference  string          `json:"reference,omitempty"`
+	Identifier *FHIRIdentifier `json:"identifier,omitempty"`
+	Display    string          `json:"display,omitempty"`
+}
+
+type FHIRHumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type FHIRContactPoint struct {
+	System string `json:"system,omitempty"` // "phone", "email"
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type FHIRExtension struct {
+	URL          string `json:"url"`
+	ValueInteger *int   `json:"valueInteger,omitempty"`
+	ValueString  string `json:"valueString,omitempty"`
+}
+
+type FHIRAnnotation struct {
+	Text string `json:"text"`
+	Time string `json:"time,omitempty"`
+}
+
+type FHIRAttachment struct {
+	ContentType string `json:"contentType,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Data        string `json:"data,omitempty"` // base64
+	Title       string `json:"title,omitempty"`
+	Creation    string `json:"creation,omitempty"`
+}
+
+type FHIRPatient struct {
+	ResourceType string             `json:"resourceType"`
+	ID           string             `json:"id,omitempty"`
+	Meta         *FHIRMeta          `json:"meta,omitempty"`
+	Extension    []FHIRExtension    `json:"extension,omitempty"`
+	Identifier   []FHIRIdentifier   `json:"identifier,omitempty"`
+	Active       bool               `json:"active"`
+	Name         []FHIRHumanName    `json:"name,omitempty"`
+	Telecom      []FHIRContactPoint `json:"telecom,omitempty"`
+	Gender       string             `json:"gender,omitempty"`
+	BirthDate    string             `json:"birthDate,omitempty"`
+}
+
+type FHIRPractitionerQualification struct {
+	Code FHIRCodeableConcept `json:"code"`
+}
+
+type FHIRPractitioner struct {
+	ResourceType  string                          `json:"resourceType"`
+	ID            string                          `json:"id,omitempty"`
+	Meta          *FHIRMeta                       `json:"meta,omitempty"`
+	Identifier    []FHIRIdentifier                `json:"identifier,omitempty"`
+	Active        bool                            `json:"active"`
+	Name          []FHIRHumanName                 `json:"name,omitempty"`
+	Telecom       []FHIRContactPoint              `json:"telecom,omitempty"`
+	Qualification []FHIRPractitionerQualification `json:"qualification,omitempty"`
+}
+
+type FHIRDiagnosticReport struct {
+	ResourceType       string                `json:"resourceType"`
+	ID                 string                `json:"id,omitempty"`
+	Meta               *FHIRMeta             `json:"meta,omitempty"`
+	Identifier         []FHIRIdentifier      `json:"identifier,omitempty"`
+	Status             string                `json:"status"`
+	Category           []FHIRCodeableConcept `json:"category,omitempty"`
+	Code               FHIRCodeableConcept   `json:"code"`
+	Subject            *FHIRReference        `json:"subject,omitempty"`
+	EffectiveDateTime  string                `json:"effectiveDateTime,omitempty"`
+	Issued             string                `json:"issued,omitempty"`
+	Performer          []FHIRReference       `json:"performer,omitempty"`
+	ResultsInterpreter []FHIRReference       `json:"resultsInterpreter,omitempty"`
+	Result             []FHIRReference       `json:"result,omitempty"`
+	Conclusion         string                `json:"conclusion,omitempty"`
+	ConclusionCode     []FHIRCodeableConcept `json:"conclusionCode,omitempty"`
+	PresentedForm      []FHIRAttachment      `json:"presentedForm,omitempty"`
+}
+
+type FHIRCondition struct {
+	ResourceType       string                  `json:"resourceType"`
+	ID                 string                  `json:"id,omitempty"`
+	Meta               *FHIRMeta               `json:"meta,omitempty"`
+	ClinicalStatus     *FHIRCodeableConcept    `json:"clinicalStatus,omitempty"`
+	VerificationStatus *FHIRCodeableConcept    `json:"verificationStatus,omitempty"`
+	Category           []FHIRCodeableConcept   `json:"category,omitempty"`
+	Code               FHIRCodeableConcept     `json:"code"`
+	Subject            FHIRReference           `json:"subject"`
+	RecordedDate       string                  `json:"recordedDate,omitempty"`
+	Evidence           []FHIRConditionEvidence `json:"evidence,omitempty"`
+	Note               []FHIRAnnotation        `json:"note,omitempty"`
+}
+
+type FHIRConditionEvidence struct {
+	Detail []FHIRReference `json:"detail,omitempty"`
+}
+
+type FHIRMedicationStatement struct {
+	ResourceType              string              `json:"resourceType"`
+	ID                        string              `json:"id,omitempty"`
+	Meta                      *FHIRMeta           `json:"meta,omitempty"`
+	Status                    string              `json:"status"`
+	MedicationCodeableConcept FHIRCodeableConcept `json:"medicationCodeableConcept"`
+	Subject                   FHIRReference       `json:"subject"`
+	DateAsserted              string              `json:"dateAsserted,omitempty"`
+	DerivedFrom               []FHIRReference     `json:"derivedFrom,omitempty"`
+}
+
+type FHIRQuantity struct {
+	Value  *float64 `json:"value,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+	System string   `json:"system,omitempty"`
+	Code   string   `json:"code,omitempty"`
+}
+
+type FHIRObservation struct {
+	ResourceType      string                `json:"resourceType"`
+	ID                string                `json:"id,omitempty"`
+	Meta              *FHIRMeta             `json:"meta,omitempty"`
+	Status            string                `json:"status"`
+	Category          []FHIRCodeableConcept `json:"category,omitempty"`
+	Code              FHIRCodeableConcept   `json:"code"`
+	Subject           FHIRReference         `json:"subject"`
+	EffectiveDateTime string                `json:"effectiveDateTime,omitempty"`
+	Issued            string                `json:"issued,omitempty"`
+	ValueQuantity     *FHIRQuantity         `json:"valueQuantity,omitempty"`
+	ValueString       string                `json:"valueString,omitempty"`
+	Interpretation    []FHIRCodeableConcept `json:"interpretation,omitempty"`
+	ReferenceRange    []FHIRReferenceRange  `json:"referenceRange,omitempty"`
+	DerivedFrom       []FHIRReference       `json:"derivedFrom,omitempty"`
+}
+
+type FHIRReferenceRange struct {
+	Low  *FHIRQuantity `json:"low,omitempty"`
+	High *FHIRQuantity `json:"high,omitempty"`
+	Text string        `json:"text,omitempty"`
+}
+
+type FHIRDocumentReferenceContent struct {
+	Attachment FHIRAttachment `json:"attachment"`
+}
+
+type FHIRDocumentReference struct {
+	ResourceType string                         `json:"resourceType"`
+	ID           string                         `json:"id,omitempty"`
+	Meta         *FHIRMeta                      `json:"meta,omitempty"`
+	Identifier   []FHIRIdentifier               `json:"identifier,omitempty"`
+	Status       string                         `json:"status"`
+	Type         *FHIRCodeableConcept           `json:"type,omitempty"`
+	Subject      *FHIRReference                 `json:"subject,omitempty"`
+	Date         string                         `json:"date,omitempty"`
+	Description  string                         `json:"description,omitempty"`
+	Content      []FHIRDocumentReferenceContent `json:"content"`
+}
+
+type FHIRServiceRequest struct {
+	ResourceType   string                `json:"resourceType"`
+	ID             string                `json:"id,omitempty"`
+	Meta           *FHIRMeta             `json:"meta,omitempty"`
+	Status         string                `json:"status"`
+	Intent         string                `json:"intent"`
+	Priority       string                `json:"priority,omitempty"`
+	Code           FHIRCodeableConcept   `json:"code"`
+	Subject        FHIRReference         `json:"subject"`
+	AuthoredOn     string                `json:"authoredOn,omitempty"`
+	ReasonCode     []FHIRCodeableConcept `json:"reasonCode,omitempty"`
+	Note           []FHIRAnnotation      `json:"note,omitempty"`
+	SupportingInfo []FHIRReference       `json:"supportingInfo,omitempty"`
+}
+
+type FHIRBundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type FHIRBundleSearch struct {
+	Mode string `json:"mode,omitempty"` // "match", "include"
+}
+
+type FHIRBundleRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type FHIRBundleResponse struct {
+	Status       string      `json:"status"`
+	Location     string      `json:"location,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Outcome      interface{} `json:"outcome,omitempty"`
+}
+
+type FHIRBundleEntry struct {
+	FullURL  string              `json:"fullUrl,omitempty"`
+	Resource interface{}         `json:"resource,omitempty"`
+	Search   *FHIRBundleSearch   `json:"search,omitempty"`
+	Request  *FHIRBundleRequest  `json:"request,omitempty"`
+	Response *FHIRBundleResponse `json:"response,omitempty"`
+}
+
+type FHIRBundle struct {
+	ResourceType string            `json:"resourceType"`
+	ID           string            `json:"id,omitempty"`
+	Type         string            `json:"type"` // "searchset", "transaction-response", ...
+	Timestamp    string            `json:"timestamp,omitempty"`
+	Total        *int              `json:"total,omitempty"`
+	Link         []FHIRBundleLink  `json:"link,omitempty"`
+	Entry        []FHIRBundleEntry `json:"entry,omitempty"`
+}
+
+type FHIROperationOutcomeIssue struct {
+	Severity    string   `json:"severity"` // "fatal", "error", "warning", "information"
+	Code        string   `json:"code"`     // "invalid", "not-found", "processing", ...
+	Diagnostics string   `json:"diagnostics,omitempty"`
+	Expression  []string `json:"expression,omitempty"`
+}
+
+type FHIROperationOutcome struct {
+	ResourceType string                      `json:"resourceType"`
+	Issue        []FHIROperationOutcomeIssue `json:"issue"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HL7QuarantinedMessage is an inbound HL7 v2 message that could not be processed.
+// Admins can inspect it, replay it (optionally corrected) or discard it.
+type HL7QuarantinedMessage struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	RawMessage  string              `bson:"raw_message" json:"raw_message"`
+	RemoteAddr  string              `bson:"remote_addr,omitempty" json:"remote_addr,omitempty"`
+	ControlID   string              `bson:"control_id,omitempty" json:"control_id,omitempty"`             // MSH-10
+	MessageType string              `bson:"message_type,omitempty" json:"message_type,omitempty"`         // MSH-9, e.g. "ORU^R01"
+	SendingApp  string              `bson:"sending_app,omitempty" json:"sending_app,omitempty"`           // MSH-3
+	SendingFac  string              `bson:"sending_facility,omitempty" json:"sending_facility,omitempty"` // MSH-4
+	Error       string              `bson:"error" json:"error"`
+	Status      string              `bson:"status" json:"status"` // "quarantined", "replayed", "discarded"
+	Attempts    int                 `bson:"attempts" json:"attempts"`
+	ReceivedAt  time.Time           `bson:"received_at" json:"received_at"`
+	ResolvedAt  *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	ResolvedBy  *primitive.ObjectID `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ReportID    *primitive.ObjectID `bson:"report_id,omitempty" json:"report_id,omitempty"` // Report created by a successful replay
+}
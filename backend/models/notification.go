@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification is an entry in a patient's or doctor's inbox
+type Notification struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID     `bson:"user_id" json:"user_id"`
+	UserType   string                 `bson:"user_type" json:"user_type"` // "patient", "doctor"
+	Event      string                 `bson:"event" json:"event"`         // e.g. "analysis_complete", "report_reviewed"
+	Title      string                 `bson:"title" json:"title"`
+	Body       string                 `bson:"body" json:"body"`
+	ReportID   *primitive.ObjectID    `bson:"report_id,omitempty" json:"report_id,omitempty"`
+	Read       bool                   `bson:"read" json:"read"`
+	ReadAt     *time.Time             `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	Deliveries []NotificationDelivery `bson:"deliveries,omitempty" json:"deliveries,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// NotificationDelivery records sending a notification through an external
+// channel; the inbox itself needs no delivery
+type NotificationDelivery struct {
+	Channel string    `bson:"channel" json:"channel"` // "email", "sms", ...
+	SentAt  time.Time `bson:"sent_at" json:"sent_at"`
+	Error   string    `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// NotificationPreferences selects the channels a user is notified through.
+// Every notification is kept in the inbox; "in-app" controls live delivery
+// to open sessions.
+type NotificationPreferences struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	UserType  string              `bson:"user_type" json:"user_type"`
+	Channels  []string            `bson:"channels" json:"channels"`                 // Default for all events
+	Events    map[string][]string `bson:"events,omitempty" json:"events,omitempty"` // Per-event overrides
+	Phone     string              `bson:"phone,omitempty" json:"phone,omitempty"`   // SMS number; defaults to the patient's phone
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// SecondOpinionRequest asks another doctor to look at a report
+type SecondOpinionRequest struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReportID     primitive.ObjectID `bson:"report_id" json:"report_id"`
+	RequestedBy  primitive.ObjectID `bson:"requested_by" json:"requested_by"`
+	ConsultantID primitive.ObjectID `bson:"consultant_id" json:"consultant_id"`
+	Note         string             `bson:"note,omitempty" json:"note,omitempty"`
+	Status       string             `bson:"status" json:"status"` // "requested"
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ChatEscalation is a patient's request to hand a chatbot conversation over
+// to a doctor
+type ChatEscalation struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	ReportID  primitive.ObjectID   `bson:"report_id" json:"report_id"`
+	PatientID primitive.ObjectID   `bson:"patient_id" json:"patient_id"`
+	SessionID primitive.ObjectID   `bson:"session_id" json:"session_id"`
+	Message   string               `bson:"message" json:"message"`
+	DoctorIDs []primitive.ObjectID `bson:"doctor_ids" json:"doctor_ids"`
+	RaisedBy  *Actor               `bson:"raised_by,omitempty" json:"raised_by,omitempty"`
+	Status    string               `bson:"status" json:"status"` // "open"
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+}
@@ -7,15 +7,23 @@ import (
 )
 
 type Patient struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name" json:"name" binding:"required"`
-	Email     string             `bson:"email" json:"email" binding:"required,email"`
-	Password  string             `bson:"password" json:"-"` // Never expose in JSON
-	Age       int                `bson:"age" json:"age"`
-	Gender    string             `bson:"gender" json:"gender"`
-	Phone     string             `bson:"phone" json:"phone"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name     string             `bson:"name" json:"name" binding:"required"`
+	Email    string             `bson:"email" json:"email" binding:"required,email"`
+	Password string             `bson:"password" json:"-"` // Never expose in JSON
+	Age      int                `bson:"age" json:"age"`
+	Gender   string             `bson:"gender" json:"gender"`
+	Phone    string             `bson:"phone" json:"phone"`
+	// External identifiers (e.g. hospital MRNs) used to match incoming records
+	Identifiers []PatientIdentifier `bson:"identifiers,omitempty" json:"identifiers,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// PatientIdentifier is an identifier issued to a patient by an external system
+type PatientIdentifier struct {
+	System string `bson:"system" json:"system"`
+	Value  string `bson:"value" json:"value"`
 }
 
 type Doctor struct {
@@ -24,8 +32,10 @@ type Doctor struct {
 	Email          string             `bson:"email" json:"email" binding:"required,email"`
 	Password       string             `bson:"password" json:"-"`
 	Specialization string             `bson:"specialization" json:"specialization"`
+	Role           string             `bson:"role,omitempty" json:"role,omitempty"` // e.g. "attending", "resident"; set by admins
 	LicenseNumber  string             `bson:"license_number" json:"license_number"`
 	Hospital       string             `bson:"hospital" json:"hospital"`
+	OnCall         bool               `bson:"on_call,omitempty" json:"on_call,omitempty"` // Set by admins; triages patients with no care team
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnalyzerInfo identifies the analyzer that produced an AIAnalysis
+type AnalyzerInfo struct {
+	Name       string    `bson:"name" json:"name"`
+	Version    string    `bson:"version" json:"version"`
+	AnalyzedAt time.Time `bson:"analyzed_at" json:"analyzed_at"`
+}
+
+// ReanalysisJob is an admin-triggered batch re-analysis of existing reports
+type ReanalysisJob struct {
+	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	RequestedBy     primitive.ObjectID   `bson:"requested_by" json:"requested_by"`
+	Status          string               `bson:"status" json:"status"` // "queued", "running", "cancelling", "cancelled", "completed", "failed"
+	DryRun          bool                 `bson:"dry_run" json:"dry_run"`
+	IncludeReviewed bool                 `bson:"include_reviewed" json:"include_reviewed"` // Opt-in to overwrite doctor-reviewed reports
+	ReportIDs       []primitive.ObjectID `bson:"report_ids,omitempty" json:"report_ids,omitempty"`
+	Statuses        []string             `bson:"statuses,omitempty" json:"statuses,omitempty"`
+	ExcludeVersion  string               `bson:"exclude_version,omitempty" json:"exclude_version,omitempty"` // Skip reports already analysed by this version
+	ThrottleMs      int                  `bson:"throttle_ms" json:"throttle_ms"`
+	Total           int                  `bson:"total" json:"total"`
+	Processed       int                  `bson:"processed" json:"processed"`
+	Changed         int                  `bson:"changed" json:"changed"`
+	Applied         int                  `bson:"applied" json:"applied"`
+	Skipped         int                  `bson:"skipped" json:"skipped"`
+	Failed          int                  `bson:"failed" json:"failed"`
+	Error           string               `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+	StartedAt       *time.Time           `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt     *time.Time           `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	HeartbeatAt     *time.Time           `bson:"heartbeat_at,omitempty" json:"heartbeat_at,omitempty"` // Refreshed as reports are processed; stale jobs are failed
+}
+
+// AnalysisDiff lists what changed between two analyses of the same report
+type AnalysisDiff struct {
+	AddedEntities          map[string][]string `bson:"added_entities,omitempty" json:"added_entities,omitempty"`
+	RemovedEntities        map[string][]string `bson:"removed_entities,omitempty" json:"removed_entities,omitempty"`
+	AddedRecommendations   []string            `bson:"added_recommendations,omitempty" json:"added_recommendations,omitempty"`
+	RemovedRecommendations []string            `bson:"removed_recommendations,omitempty" json:"removed_recommendations,omitempty"`
+	EntitiesChanged        bool                `bson:"entities_changed" json:"entities_changed"`
+	RecommendationsChanged bool                `bson:"recommendations_changed" json:"recommendations_changed"`
+}
+
+// ReanalysisResult is the outcome of re-analysing one report within a job
+type ReanalysisResult struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	JobID           primitive.ObjectID `bson:"job_id" json:"job_id"`
+	ReportID        primitive.ObjectID `bson:"report_id" json:"report_id"`
+	PreviousVersion string             `bson:"previous_version,omitempty" json:"previous_version,omitempty"`
+	NewVersion      string             `bson:"new_version,omitempty" json:"new_version,omitempty"`
+	Diff            *AnalysisDiff      `bson:"diff,omitempty" json:"diff,omitempty"`
+	Applied         bool               `bson:"applied" json:"applied"`
+	SkipReason      string             `bson:"skip_reason,omitempty" json:"skip_reason,omitempty"`
+	Error           string             `bson:"error,omitempty" json:"error,omitempty"`
+	ProcessedAt     time.Time          `bson:"processed_at" json:"processed_at"`
+}
+
+// AnalysisHistoryEntry preserves an analysis replaced by re-analysis
+type AnalysisHistoryEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReportID   primitive.ObjectID `bson:"report_id" json:"report_id"`
+	JobID      primitive.ObjectID `bson:"job_id" json:"job_id"`
+	Analysis   AIAnalysis         `bson:"analysis" json:"analysis"`
+	ReplacedAt time.Time          `bson:"replaced_at" json:"replaced_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RedactionReport records what PHI was replaced before text was sent to an
+// external service. It never contains the original values.
+type RedactionReport struct {
+	ID           primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	Destination  string                `bson:"destination" json:"destination"` // "python-analyzer", "azure-openai"
+	Purpose      string                `bson:"purpose" json:"purpose"`         // "report-analysis", "chat"
+	ReportID     *primitive.ObjectID   `bson:"report_id,omitempty" json:"report_id,omitempty"`
+	PatientID    *primitive.ObjectID   `bson:"patient_id,omitempty" json:"patient_id,omitempty"`
+	Counts       map[string]int        `bson:"counts" json:"counts"` // Replacements per PHI category
+	Total        int                   `bson:"total" json:"total"`
+	Placeholders []RedactedPlaceholder `bson:"placeholders" json:"placeholders"`
+	CharsSent    int                   `bson:"chars_sent" json:"chars_sent"`
+	Reidentified int                   `bson:"reidentified" json:"reidentified"` // Placeholders restored in the response
+	CreatedAt    time.Time             `bson:"created_at" json:"created_at"`
+}
+
+// RedactedPlaceholder is one placeholder used in an outbound request
+type RedactedPlaceholder struct {
+	Placeholder string `bson:"placeholder" json:"placeholder"` // e.g. "[NAME_1]"
+	Category    string `bson:"category" json:"category"`
+	Occurrences int    `bson:"occurrences" json:"occurrences"`
+}
@@ -17,35 +17,135 @@ type AIAnalysis struct {
 		Urgency          []string `bson:"urgency,omitempty" json:"urgency,omitempty"`
 		FunctionalImpact []string `bson:"functional_impact,omitempty" json:"functional_impact,omitempty"`
 	} `bson:"entities" json:"entities"`
-	Recommendations []struct {
-		Test              string   `bson:"test" json:"test"`
-		Reason            string   `bson:"reason" json:"reason"`
-		Contraindications []string `bson:"contraindications" json:"contraindications"`
-		Confidence        float64  `bson:"confidence" json:"confidence"`
-		Urgency           string   `bson:"urgency" json:"urgency"`
-		Explanation       string   `bson:"explanation,omitempty" json:"explanation,omitempty"`
-	} `bson:"recommendations" json:"recommendations"`
-	Warnings        []string `bson:"warnings,omitempty" json:"warnings,omitempty"`
-	ConfidenceScore float64  `bson:"confidence_score" json:"confidence_score"` // Overall 0-100
+	Recommendations        []Recommendation        `bson:"recommendations" json:"recommendations"`
+	Warnings               []string                `bson:"warnings,omitempty" json:"warnings,omitempty"`
+	DroppedRecommendations []DroppedRecommendation `bson:"dropped_recommendations,omitempty" json:"dropped_recommendations,omitempty"` // Removed because their findings are negated
+	ConfidenceScore        float64                 `bson:"confidence_score" json:"confidence_score"`                                   // Overall 0-100
+	ConfidenceBreakdown    *ConfidenceBreakdown    `bson:"confidence_breakdown,omitempty" json:"confidence_breakdown,omitempty"`
+	Analyzer               *AnalyzerInfo           `bson:"analyzer,omitempty" json:"analyzer,omitempty"`
+	CodedEntities          []CodedEntity           `bson:"coded_entities,omitempty" json:"coded_entities,omitempty"`
+	TerminologyVersion     string                  `bson:"terminology_version,omitempty" json:"terminology_version,omitempty"`
+	Assertions             []EntityAssertion       `bson:"assertions,omitempty" json:"assertions,omitempty"`
+	Interactions           []InteractionFinding    `bson:"interactions,omitempty" json:"interactions,omitempty"`
+	InteractionsVersion    string                  `bson:"interactions_version,omitempty" json:"interactions_version,omitempty"`
+	RuleSetVersion         string                  `bson:"rule_set_version,omitempty" json:"rule_set_version,omitempty"` // Clinical rules that contributed recommendations
+	RuleTraces             []RuleTrace             `bson:"rule_traces,omitempty" json:"rule_traces,omitempty"`           // Rules that fired
+}
+
+// InteractionFinding is a drug interaction or contraindication found by the
+// interaction checker, with the reviewing doctor's decision
+type InteractionFinding struct {
+	ID         string              `bson:"id" json:"id"`
+	RuleID     string              `bson:"rule_id" json:"rule_id"`
+	Type       string              `bson:"type" json:"type"`         // "drug-drug", "drug-condition", "drug-age"
+	Severity   string              `bson:"severity" json:"severity"` // "contraindicated", "major", "moderate", "minor"
+	Drugs      []string            `bson:"drugs" json:"drugs"`
+	Condition  string              `bson:"condition,omitempty" json:"condition,omitempty"` // Diagnosis or demographic that triggered the rule
+	Effect     string              `bson:"effect" json:"effect"`
+	Management string              `bson:"management" json:"management"`
+	Status     string              `bson:"status" json:"status"` // "open", "acknowledged", "dismissed"
+	ReviewedBy *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	Comment    string              `bson:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// EntityAssertion is the context of an extracted entity in the report text
+type EntityAssertion struct {
+	Category string `bson:"category" json:"category"` // "diagnosis", "symptom", "test", "medication"
+	Text     string `bson:"text" json:"text"`
+	Status   string `bson:"status" json:"status"`                       // "affirmed", "negated", "historical", "hypothetical", "family"
+	Trigger  string `bson:"trigger,omitempty" json:"trigger,omitempty"` // Cue phrase that set the status
+	Mentions int    `bson:"mentions" json:"mentions"`                   // Occurrences found in the text
+}
+
+// CodedEntity is an extracted entity mapped to a standard terminology
+type CodedEntity struct {
+	Category   string  `bson:"category" json:"category"`                     // "diagnosis", "symptom", "test", "medication"
+	Text       string  `bson:"text" json:"text"`                             // As extracted
+	Expanded   string  `bson:"expanded,omitempty" json:"expanded,omitempty"` // With abbreviations expanded
+	System     string  `bson:"system,omitempty" json:"system,omitempty"`     // Code system URI
+	Code       string  `bson:"code,omitempty" json:"code,omitempty"`
+	Display    string  `bson:"display,omitempty" json:"display,omitempty"`
+	Confidence float64 `bson:"confidence" json:"confidence"` // 0-100 match confidence
+	MatchType  string  `bson:"match_type" json:"match_type"` // "exact", "abbreviation", "partial", "none"
+}
+
+type Recommendation struct {
+	Test              string   `bson:"test" json:"test"`
+	Reason            string   `bson:"reason" json:"reason"`
+	Contraindications []string `bson:"contraindications" json:"contraindications"`
+	Confidence        float64  `bson:"confidence" json:"confidence"` // 0-100
+	Urgency           string   `bson:"urgency" json:"urgency"`
+	Explanation       string   `bson:"explanation,omitempty" json:"explanation,omitempty"`
+	RuleID            string   `bson:"rule_id,omitempty" json:"rule_id,omitempty"` // Clinical rule that produced it
+}
+
+// DroppedRecommendation is a recommendation removed by assertion detection
+// because every finding its reason cites is negated
+type DroppedRecommendation struct {
+	Test            string   `bson:"test" json:"test"`
+	Reason          string   `bson:"reason" json:"reason"`
+	NegatedFindings []string `bson:"negated_findings" json:"negated_findings"`
+}
+
+// ConfidenceBreakdown explains how the overall confidence score was derived
+type ConfidenceBreakdown struct {
+	ScorerVersion string             `bson:"scorer_version" json:"scorer_version"`
+	Factors       []ConfidenceFactor `bson:"factors" json:"factors"`
+	Penalty       float64            `bson:"penalty" json:"penalty"` // Points subtracted for extraction warnings
+	Score         float64            `bson:"score" json:"score"`
+}
+
+// ConfidenceFactor is a single weighted input to the confidence score
+type ConfidenceFactor struct {
+	Name         string  `bson:"name" json:"name"`
+	Value        float64 `bson:"value" json:"value"` // 0-100
+	Weight       float64 `bson:"weight" json:"weight"`
+	Contribution float64 `bson:"contribution" json:"contribution"` // Value * Weight
+	Detail       string  `bson:"detail,omitempty" json:"detail,omitempty"`
 }
 
 type DoctorReview struct {
-	ReviewedBy   primitive.ObjectID     `bson:"reviewed_by" json:"reviewed_by"`
-	ReviewedAt   time.Time              `bson:"reviewed_at" json:"reviewed_at"`
-	EditedFields map[string]interface{} `bson:"edited_fields,omitempty" json:"edited_fields,omitempty"`
-	Notes        string                 `bson:"notes,omitempty" json:"notes,omitempty"`
+	ReviewedBy    primitive.ObjectID     `bson:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt    time.Time              `bson:"reviewed_at" json:"reviewed_at"`
+	EditedFields  map[string]interface{} `bson:"edited_fields,omitempty" json:"edited_fields,omitempty"`
+	Notes         string                 `bson:"notes,omitempty" json:"notes,omitempty"`
+	Override      bool                   `bson:"override,omitempty" json:"override,omitempty"` // Edit made via policy override
+	Justification string                 `bson:"justification,omitempty" json:"justification,omitempty"`
 }
 
 type Report struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	PatientID    primitive.ObjectID `bson:"patient_id" json:"patient_id"`
-	PDFPath      string             `bson:"pdf_path" json:"pdf_path"`
-	PDFFileName  string             `bson:"pdf_filename" json:"pdf_filename"`
-	UploadedAt   time.Time          `bson:"uploaded_at" json:"uploaded_at"`
-	AIAnalysis   AIAnalysis         `bson:"ai_analysis" json:"ai_analysis"`
-	DoctorReview *DoctorReview      `bson:"doctor_review,omitempty" json:"doctor_review,omitempty"`
-	Status       string             `bson:"status" json:"status"` // "pending", "reviewed", "edited"
-	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PatientID       primitive.ObjectID `bson:"patient_id" json:"patient_id"`
+	PDFPath         string             `bson:"pdf_path" json:"pdf_path"`
+	PDFFileName     string             `bson:"pdf_filename" json:"pdf_filename"`
+	UploadedAt      time.Time          `bson:"uploaded_at" json:"uploaded_at"`
+	AIAnalysis      AIAnalysis         `bson:"ai_analysis" json:"ai_analysis"`
+	DoctorReview    *DoctorReview      `bson:"doctor_review,omitempty" json:"doctor_review,omitempty"`
+	Status          string             `bson:"status" json:"status"` // "pending", "reviewed", "edited", "signed", "amended"
+	Signature       *ReportSignature   `bson:"signature,omitempty" json:"signature,omitempty"`
+	Amendments      []ReportAmendment  `bson:"amendments,omitempty" json:"amendments,omitempty"`
+	Source          string             `bson:"source,omitempty" json:"source,omitempty"`           // "upload" (default), "fhir", "hl7v2"
+	ExternalID      string             `bson:"external_id,omitempty" json:"external_id,omitempty"` // Identifier assigned by the sending system
+	Observations    []LabObservation   `bson:"observations,omitempty" json:"observations,omitempty"`
+	UploadedBy      *Actor             `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"`           // Set for uploads through the patient portal
+	AnalysisSkipped string             `bson:"analysis_skipped,omitempty" json:"analysis_skipped,omitempty"` // Why the report was stored without AI analysis
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// LabObservation is a structured result received from an external system
+type LabObservation struct {
+	Code           string     `bson:"code,omitempty" json:"code,omitempty"`
+	CodeSystem     string     `bson:"code_system,omitempty" json:"code_system,omitempty"`
+	Display        string     `bson:"display" json:"display"`
+	Category       string     `bson:"category,omitempty" json:"category,omitempty"` // "laboratory", "vital-signs", ...
+	Value          *float64   `bson:"value,omitempty" json:"value,omitempty"`
+	ValueString    string     `bson:"value_string,omitempty" json:"value_string,omitempty"`
+	Unit           string     `bson:"unit,omitempty" json:"unit,omitempty"`
+	ReferenceRange string     `bson:"reference_range,omitempty" json:"reference_range,omitempty"`
+	Interpretation string     `bson:"interpretation,omitempty" json:"interpretation,omitempty"` // e.g. "H", "L", "HH", "LL", "A"
+	Status         string     `bson:"status,omitempty" json:"status,omitempty"`
+	EffectiveAt    *time.Time `bson:"effective_at,omitempty" json:"effective_at,omitempty"`
 }
 
 type Feedback struct {
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResearchDataset is a versioned, de-identified release of analysis data
+type ResearchDataset struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name           string             `bson:"name" json:"name"`
+	Version        int                `bson:"version" json:"version"` // Increments per name
+	Description    string             `bson:"description,omitempty" json:"description,omitempty"`
+	RequestedBy    primitive.ObjectID `bson:"requested_by" json:"requested_by"`
+	Filters        ExportFilters      `bson:"filters" json:"filters"`
+	K              int                `bson:"k" json:"k"`                             // Minimum patients per quasi-identifier class
+	MaxSuppression float64            `bson:"max_suppression" json:"max_suppression"` // Largest share of records that may be suppressed (0-1)
+	Status         string             `bson:"status" json:"status"`                   // "queued", "running", "completed", "rejected", "failed"
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	Manifest       *ResearchManifest  `bson:"manifest,omitempty" json:"manifest,omitempty"`
+	FilePath       string             `bson:"file_path,omitempty" json:"-"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt    *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// ResearchManifest describes the contents and de-identification of a dataset release
+type ResearchManifest struct {
+	Dataset          string          `bson:"dataset" json:"dataset"`
+	Version          int             `bson:"version" json:"version"`
+	SchemaVersion    string          `bson:"schema_version" json:"schema_version"`
+	GeneratedAt      time.Time       `bson:"generated_at" json:"generated_at"`
+	Method           string          `bson:"method" json:"method"`
+	RecordCount      int             `bson:"record_count" json:"record_count"`
+	PatientCount     int             `bson:"patient_count" json:"patient_count"`
+	SourceRecords    int             `bson:"source_records" json:"source_records"`
+	Suppressed       int             `bson:"suppressed" json:"suppressed"`
+	ConsentExcluded  int             `bson:"consent_excluded" json:"consent_excluded"` // Matching reports left out for lack of research consent
+	QuasiIdentifiers []string        `bson:"quasi_identifiers" json:"quasi_identifiers"`
+	AgeBandYears     int             `bson:"age_band_years" json:"age_band_years"`
+	K                int             `bson:"k" json:"k"`
+	MinClassSize     int             `bson:"min_class_size" json:"min_class_size"`
+	ClassCount       int             `bson:"class_count" json:"class_count"`
+	Fields           []ResearchField `bson:"fields" json:"fields"`
+	Filters          ExportFilters   `bson:"filters" json:"filters"`
+	DataFile         string          `bson:"data_file" json:"data_file"`
+	DataSHA256       string          `bson:"data_sha256" json:"data_sha256"`
+}
+
+// ResearchField documents one field of a research record and how it was transformed
+type ResearchField struct {
+	Name           string `bson:"name" json:"name"`
+	Description    string `bson:"description" json:"description"`
+	Transformation string `bson:"transformation" json:"transformation"`
+}
+
+// ResearchRecord is one de-identified report in a research dataset
+type ResearchRecord struct {
+	PatientCode     string                   `json:"patient_code"`
+	RecordCode      string                   `json:"record_code"`
+	AgeGroup        string                   `json:"age_group"`
+	Gender          string                   `json:"gender"`
+	Year            int                      `json:"year"`
+	Status          string                   `json:"status"`
+	Source          string                   `json:"source"`
+	Diagnoses       []string                 `json:"diagnoses"`
+	Symptoms        []string                 `json:"symptoms"`
+	Medications     []string                 `json:"medications"`
+	Tests           []string                 `json:"tests"`
+	Vitals          []string                 `json:"vitals"`
+	Severity        []string                 `json:"severity"`
+	Warnings        []string                 `json:"warnings"`
+	Observations    []ResearchObservation    `json:"observations,omitempty"`
+	Recommendations []ResearchRecommendation `json:"recommendations"`
+	ConfidenceScore float64                  `json:"confidence_score"`
+	Reviewed        bool                     `json:"reviewed"`
+	ReviewNotes     string                   `json:"review_notes,omitempty"`
+}
+
+// ResearchObservation is a structured result without dates or identifiers
+type ResearchObservation struct {
+	Code           string   `json:"code,omitempty"`
+	CodeSystem     string   `json:"code_system,omitempty"`
+	Display        string   `json:"display"`
+	Value          *float64 `json:"value,omitempty"`
+	ValueString    string   `json:"value_string,omitempty"`
+	Unit           string   `json:"unit,omitempty"`
+	Interpretation string   `json:"interpretation,omitempty"`
+}
+
+// ResearchRecommendation is a recommendation with scrubbed free text
+type ResearchRecommendation struct {
+	Test       string  `json:"test"`
+	Urgency    string  `json:"urgency"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLink gives someone without an account time-limited access to one
+// report. Only hashes of the token and passcode are stored.
+type ShareLink struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReportID        primitive.ObjectID `bson:"report_id" json:"report_id"`
+	PatientID       primitive.ObjectID `bson:"patient_id" json:"patient_id"`
+	TokenHash       string             `bson:"token_hash" json:"-"`
+	PasscodeHash    string             `bson:"passcode_hash,omitempty" json:"-"`
+	Protected       bool               `bson:"protected" json:"protected"` // Requires a passcode
+	Label           string             `bson:"label,omitempty" json:"label,omitempty"`
+	Scope           string             `bson:"scope" json:"scope"` // "view", "download"
+	ExpiresAt       time.Time          `bson:"expires_at" json:"expires_at"`
+	MaxAccesses     int                `bson:"max_accesses,omitempty" json:"max_accesses,omitempty"` // 0 = unlimited
+	AccessCount     int                `bson:"access_count" json:"access_count"`
+	FailedPasscodes int                `bson:"failed_passcodes" json:"failed_passcodes"`
+	LastAccessedAt  *time.Time         `bson:"last_accessed_at,omitempty" json:"last_accessed_at,omitempty"`
+	RevokedAt       *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RevokedReason   string             `bson:"revoked_reason,omitempty" json:"revoked_reason,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ShareLinkAccess records one use of a share link, allowed or not
+type ShareLinkAccess struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LinkID    primitive.ObjectID `bson:"link_id" json:"link_id"`
+	ReportID  primitive.ObjectID `bson:"report_id" json:"report_id"`
+	PatientID primitive.ObjectID `bson:"patient_id" json:"patient_id"`
+	Action    string             `bson:"action" json:"action"`   // "view", "download"
+	Outcome   string             `bson:"outcome" json:"outcome"` // "granted" or why it was refused
+	IP        string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	At        time.Time          `bson:"at" json:"at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DoctorKey is a per-doctor signing key pair managed by the backend.
+// The private key is stored encrypted with the server master key.
+type DoctorKey struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DoctorID            primitive.ObjectID `bson:"doctor_id" json:"doctor_id"`
+	Algorithm           string             `bson:"algorithm" json:"algorithm"`   // "Ed25519"
+	PublicKey           string             `bson:"public_key" json:"public_key"` // base64
+	EncryptedPrivateKey string             `bson:"encrypted_private_key" json:"-"`
+	Active              bool               `bson:"active" json:"active"`
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReportSignature is a doctor's digital sign-off on a reviewed report
+type ReportSignature struct {
+	DoctorID       primitive.ObjectID `bson:"doctor_id" json:"doctor_id"`
+	KeyID          primitive.ObjectID `bson:"key_id" json:"key_id"`
+	Algorithm      string             `bson:"algorithm" json:"algorithm"`
+	PayloadVersion int                `bson:"payload_version,omitempty" json:"payload_version,omitempty"` // Canonical payload layout; missing means 1
+	PayloadHash    string             `bson:"payload_hash" json:"payload_hash"`                           // hex SHA-256 of canonical payload
+	Signature      string             `bson:"signature" json:"signature"`                                 // base64
+	SignedAt       time.Time          `bson:"signed_at" json:"signed_at"`
+}
+
+// ReportAmendment records a formal amendment that unlocked a signed report
+type ReportAmendment struct {
+	AmendedBy         primitive.ObjectID `bson:"amended_by" json:"amended_by"`
+	AmendedAt         time.Time          `bson:"amended_at" json:"amended_at"`
+	Reason            string             `bson:"reason" json:"reason"`
+	PreviousSignature ReportSignature    `bson:"previous_signature" json:"previous_signature"`
+}
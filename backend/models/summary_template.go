@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SummaryTemplate controls the branding and layout of server-rendered summary
+// PDFs. Templates are chosen by hospital; header and footer text may use the
+// placeholders {{hospital}}, {{patient_name}}, {{report_id}}, {{generated_at}},
+// {{page}} and {{pages}}.
+type SummaryTemplate struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name           string             `bson:"name" json:"name" binding:"required"`
+	Hospital       string             `bson:"hospital,omitempty" json:"hospital,omitempty"` // Matched against the reviewing doctor's hospital
+	IsDefault      bool               `bson:"is_default" json:"is_default"`                 // Used when no hospital template matches
+	Title          string             `bson:"title,omitempty" json:"title,omitempty"`
+	HeaderLeft     string             `bson:"header_left,omitempty" json:"header_left,omitempty"`
+	HeaderRight    string             `bson:"header_right,omitempty" json:"header_right,omitempty"`
+	FooterLeft     string             `bson:"footer_left,omitempty" json:"footer_left,omitempty"`
+	FooterRight    string             `bson:"footer_right,omitempty" json:"footer_right,omitempty"`
+	AccentColor    string             `bson:"accent_color,omitempty" json:"accent_color,omitempty"` // Hex, e.g. "#1f6feb"
+	Sections       []string           `bson:"sections,omitempty" json:"sections,omitempty"`         // Order of sections; empty uses the default order
+	ShowConfidence bool               `bson:"show_confidence" json:"show_confidence"`
+	Disclaimer     string             `bson:"disclaimer,omitempty" json:"disclaimer,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
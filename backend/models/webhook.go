@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookSubscription sends report lifecycle events to a partner endpoint
+type WebhookSubscription struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	URL       string             `bson:"url" json:"url"`
+	Events    []string           `bson:"events" json:"events"` // e.g. "report.uploaded"; "*" for all
+	Secret    string             `bson:"secret" json:"-"`      // HMAC-SHA256 signing key, shown only on creation
+	Active    bool               `bson:"active" json:"active"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent to one subscription, retried with backoff
+// until it succeeds or runs out of attempts
+type WebhookDelivery struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	SubscriptionID primitive.ObjectID  `bson:"subscription_id" json:"subscription_id"`
+	EventID        string              `bson:"event_id" json:"event_id"` // Shared by every delivery of the same event
+	Event          string              `bson:"event" json:"event"`
+	Payload        string              `bson:"payload" json:"payload"`   // Exact JSON body that is signed and sent
+	Status         string              `bson:"status" json:"status"`     // "pending", "retrying", "succeeded", "failed"
+	Attempts       []WebhookAttempt    `bson:"attempts" json:"attempts"` // Delivery log
+	NextAttemptAt  *time.Time          `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	RedeliveryOf   *primitive.ObjectID `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	RequestedBy    *primitive.ObjectID `bson:"requested_by,omitempty" json:"requested_by,omitempty"` // Admin who asked for a redelivery
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookAttempt records one HTTP request of a delivery
+type WebhookAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+}
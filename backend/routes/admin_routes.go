@@ -7,6 +7,19 @@ import (
 
 func AdminRoutes(r *gin.Engine) {
 	ctrl := controllers.NewAdminController()
+	policyCtrl := controllers.NewEditPolicyController()
+	analyticsCtrl := controllers.NewAnalyticsController()
+	reanalysisCtrl := controllers.NewReanalysisController()
+	hl7Ctrl := controllers.NewHL7Controller()
+	templateCtrl := controllers.NewSummaryTemplateController()
+	exportCtrl := controllers.NewExportController()
+	researchCtrl := controllers.NewResearchController()
+	rulesCtrl := controllers.NewClinicalRulesController()
+	alertCtrl := controllers.NewAlertController()
+	webhookCtrl := controllers.NewWebhookController()
+	delegationCtrl := controllers.NewDelegationController()
+	careCtrl := controllers.NewCareRelationshipController()
+	consentCtrl := controllers.NewConsentController()
 
 	admin := r.Group("/api/admin")
 	{
@@ -21,8 +34,99 @@ func AdminRoutes(r *gin.Engine) {
 		admin.GET("/doctors", ctrl.GetAllDoctors)
 		admin.DELETE("/patient/:id", ctrl.DeletePatient)
 		admin.DELETE("/doctor/:id", ctrl.DeleteDoctor)
+		admin.POST("/doctor/:id/role", ctrl.SetDoctorRole) // Roles are never self-assigned at signup
 
 		// Report Management
 		admin.GET("/reports", ctrl.GetAllReports)
+
+		// PHI redaction reports of outbound AI requests
+		admin.GET("/redactions", ctrl.GetRedactionAudit)
+
+		// Bulk CSV/XLSX exports; large exports run as background jobs
+		admin.GET("/export/:dataset", exportCtrl.StreamExport)
+		admin.GET("/exports/columns", exportCtrl.GetColumns)
+		admin.POST("/exports", exportCtrl.CreateExportJob)
+		admin.GET("/exports", exportCtrl.ListExportJobs)
+		admin.GET("/exports/:id", exportCtrl.GetExportJob)
+		admin.GET("/exports/:id/download", exportCtrl.DownloadExport)
+
+		// Versioned de-identified research datasets
+		admin.POST("/research-datasets", researchCtrl.CreateDataset)
+		admin.GET("/research-datasets", researchCtrl.ListDatasets)
+		admin.GET("/research-datasets/:id", researchCtrl.GetDataset)
+		admin.GET("/research-datasets/:id/download", researchCtrl.DownloadDataset)
+
+		// Declarative test recommendation rules
+		admin.GET("/clinical-rules", rulesCtrl.GetRules)
+		admin.POST("/clinical-rules/reload", rulesCtrl.ReloadRules)
+		admin.POST("/clinical-rules/test", rulesCtrl.TestRules)
+
+		// Critical value alerting
+		admin.GET("/critical-alerts", alertCtrl.GetAllAlerts)
+		admin.GET("/critical-thresholds", alertCtrl.ListThresholds)
+		admin.POST("/critical-thresholds", alertCtrl.CreateThreshold)
+		admin.PUT("/critical-thresholds/:id", alertCtrl.UpdateThreshold)
+		admin.DELETE("/critical-thresholds/:id", alertCtrl.DeleteThreshold)
+
+		// Outbound webhooks for report lifecycle events
+		admin.GET("/webhooks", webhookCtrl.ListWebhooks)
+		admin.POST("/webhooks", webhookCtrl.CreateWebhook)
+		admin.PUT("/webhooks/:id", webhookCtrl.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", webhookCtrl.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", webhookCtrl.GetDeliveries)
+		admin.POST("/webhook-deliveries/:id/redeliver", webhookCtrl.RedeliverWebhook)
+
+		// Guardian and caregiver delegations, e.g. for minors
+		admin.GET("/delegations", delegationCtrl.AdminListDelegations)
+		admin.POST("/delegations", delegationCtrl.AdminGrantDelegation)
+		admin.POST("/delegations/:id/revoke", delegationCtrl.AdminRevokeDelegation)
+
+		// Doctor-patient care relationships and break-glass review
+		admin.GET("/care-relationships", careCtrl.AdminListRelationships)
+		admin.POST("/care-relationships", careCtrl.AdminCreateRelationship)
+		admin.POST("/care-relationships/:id/end", careCtrl.AdminEndRelationship)
+		admin.GET("/break-glass", careCtrl.ListBreakGlass)
+		admin.POST("/break-glass/:id/review", careCtrl.ReviewBreakGlass)
+		admin.POST("/doctor/:id/on-call", careCtrl.SetDoctorOnCall) // Triage patients with no care team
+
+		// Versioned consent documents and consent recorded outside the app
+		admin.GET("/consent-documents", consentCtrl.ListConsentDocuments)
+		admin.POST("/consent-documents", consentCtrl.PublishConsentDocument)
+		admin.GET("/patient/:id/consents", consentCtrl.AdminGetPatientConsents)
+		admin.POST("/patient/:id/consents", consentCtrl.AdminRecordConsent)
+
+		// Edit permission policies
+		admin.GET("/edit-policies", policyCtrl.ListPolicies)
+		admin.POST("/edit-policies", policyCtrl.CreatePolicy)
+		admin.PUT("/edit-policies/:id", policyCtrl.UpdatePolicy)
+		admin.DELETE("/edit-policies/:id", policyCtrl.DeletePolicy)
+		admin.GET("/edit-overrides", policyCtrl.GetOverrideAudit)
+		admin.POST("/edit-overrides/:id/resolve", policyCtrl.ResolveOverrideAudit)
+
+		// Model accuracy analytics from doctor feedback
+		admin.GET("/analytics/summary", analyticsCtrl.GetSummary)
+		admin.GET("/analytics/entities", analyticsCtrl.GetEntityAccuracy)
+		admin.GET("/analytics/calibration", analyticsCtrl.GetCalibration)
+		admin.GET("/analytics/trends", analyticsCtrl.GetTrends)
+		admin.GET("/analytics/export", analyticsCtrl.ExportTrainingData)
+
+		// Batch re-analysis after analyzer upgrades
+		admin.POST("/reanalysis", reanalysisCtrl.StartJob)
+		admin.GET("/reanalysis", reanalysisCtrl.ListJobs)
+		admin.GET("/reanalysis/:id", reanalysisCtrl.GetJob)
+		admin.GET("/reanalysis/:id/results", reanalysisCtrl.GetJobResults)
+		admin.POST("/reanalysis/:id/cancel", reanalysisCtrl.CancelJob)
+
+		// Quarantined HL7 v2 messages from the MLLP listener
+		admin.GET("/hl7/quarantine", hl7Ctrl.ListQuarantine)
+		admin.GET("/hl7/quarantine/:id", hl7Ctrl.GetQuarantined)
+		admin.POST("/hl7/quarantine/:id/replay", hl7Ctrl.ReplayQuarantined)
+		admin.POST("/hl7/quarantine/:id/discard", hl7Ctrl.DiscardQuarantined)
+
+		// Per-hospital templates for summary PDFs
+		admin.GET("/summary-templates", templateCtrl.ListTemplates)
+		admin.POST("/summary-templates", templateCtrl.CreateTemplate)
+		admin.PUT("/summary-templates/:id", templateCtrl.UpdateTemplate)
+		admin.DELETE("/summary-templates/:id", templateCtrl.DeleteTemplate)
 	}
 }
@@ -16,5 +16,8 @@ func ChatbotRoutes(router *gin.Engine) {
 
 		// Get chat history for a specific report
 		chatbot.GET("/history/:report_id", chatCtrl.GetChatHistory)
+
+		// Hand the conversation over to the patient's doctor
+		chatbot.POST("/escalate", chatCtrl.EscalateChat)
 	}
 }
@@ -7,18 +7,67 @@ import (
 
 func DoctorRoutes(r *gin.Engine) {
 	ctrl := controllers.NewDoctorController()
+	alertCtrl := controllers.NewAlertController()
+	taskCtrl := controllers.NewCareTaskController()
+	apptCtrl := controllers.NewAppointmentController()
+	careCtrl := controllers.NewCareRelationshipController()
+	chatCtrl := controllers.NewChatbotController()
 
 	doctor := r.Group("/api/doctor")
 	{
 		// Report management
-		doctor.GET("/reports", ctrl.GetAllReports)            // List all reports (with optional status filter)
-		doctor.GET("/reports/:id", ctrl.GetReportByID)        // Get specific report details
-		doctor.POST("/reports/:id/review", ctrl.SubmitReview) // Submit review for a report
-		doctor.PUT("/reports/:id/edit", ctrl.EditAnalysis)    // Edit AI analysis (only if confidence < 90%)
+		doctor.GET("/reports", ctrl.GetAllReports)              // List all reports (with optional status filter)
+		doctor.GET("/reports/:id", ctrl.GetReportByID)          // Get specific report details
+		doctor.POST("/reports/:id/review", ctrl.SubmitReview)   // Submit review for a report
+		doctor.PUT("/reports/:id/edit", ctrl.EditAnalysis)      // Edit AI analysis (subject to edit policy)
+		doctor.POST("/reports/:id/sign", ctrl.SignReport)       // Digitally sign the reviewed report
+		doctor.GET("/reports/:id/verify", ctrl.VerifySignature) // Check a signature against the current content
+		doctor.POST("/reports/:id/amend", ctrl.AmendReport)     // Unlock a signed report for amendment
+		doctor.POST("/reports/:id/second-opinion", ctrl.RequestSecondOpinion)
+
+		// Acknowledge or dismiss drug interaction findings during review
+		doctor.POST("/reports/:id/interactions/:finding_id", ctrl.ReviewInteraction)
 
 		// Patient management
+		// List patients the doctor cares for
 		doctor.GET("/patients", ctrl.GetPatients)
-		// List all patients
 		doctor.GET("/patients/:patient_id/reports", ctrl.GetPatientReports) // Get all reports for a patient
+
+		// Care relationships scope patient access; break-glass is logged and reviewed
+		doctor.GET("/care-relationships", careCtrl.GetDoctorRelationships)
+		doctor.POST("/care-relationships/:id/end", careCtrl.EndDoctorRelationship)
+		doctor.POST("/patients/:patient_id/referrals", careCtrl.ReferPatient)
+		doctor.POST("/patients/:patient_id/break-glass", careCtrl.BreakGlass)
+
+		// Critical value alerts; re-sent until acknowledged
+		doctor.GET("/alerts", alertCtrl.GetDoctorAlerts)
+		doctor.POST("/alerts/:id/acknowledge", alertCtrl.AcknowledgeAlert)
+
+		// Chatbot conversations patients handed over to their care team
+		doctor.GET("/chat-escalations", chatCtrl.GetDoctorEscalations)
+		doctor.GET("/chat-escalations/:id", chatCtrl.GetDoctorEscalation)
+
+		// Follow-up care tasks from recommendations
+		doctor.GET("/care-tasks", taskCtrl.GetDoctorTasks)
+		doctor.POST("/care-tasks/:id/status", taskCtrl.UpdateDoctorTask)
+		doctor.POST("/care-tasks/:id/assignees", taskCtrl.AssignTask)
+
+		// Availability slots and booked appointments
+		doctor.GET("/availability", apptCtrl.GetAvailability)
+		doctor.POST("/availability", apptCtrl.CreateAvailability)
+		doctor.DELETE("/availability/:id", apptCtrl.DeleteAvailability)
+		doctor.GET("/appointments", apptCtrl.GetDoctorAppointments)
+		doctor.POST("/appointments/:id/cancel", apptCtrl.CancelDoctorAppointment)
+		doctor.POST("/calendar-feed", apptCtrl.CreateCalendarFeed) // Private .ics subscription URL
+
+		// Terminology coding (ICD-10-CM, LOINC, RxNorm) of free-text terms
+		doctor.GET("/terminology/lookup", ctrl.LookupTerm)
 	}
 }
+
+// CalendarRoutes registers the tokenised .ics feeds calendar apps subscribe to
+func CalendarRoutes(r *gin.Engine) {
+	ctrl := controllers.NewAppointmentController()
+
+	r.GET("/api/calendar/:token/appointments.ics", ctrl.GetCalendarFeed)
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+// FHIRRoutes registers the HL7 FHIR R4 API
+func FHIRRoutes(r *gin.Engine) {
+	ctrl := controllers.NewFHIRController()
+
+	fhir := r.Group("/fhir/R4")
+	{
+		fhir.GET("/metadata", ctrl.Metadata)
+
+		// Ingestion of transaction and batch bundles
+		fhir.POST("", ctrl.Transaction)
+
+		// Administrative resources
+		fhir.GET("/Patient", ctrl.SearchPatients)
+		fhir.GET("/Patient/:id", ctrl.ReadPatient)
+		fhir.GET("/Practitioner", ctrl.SearchPractitioners)
+		fhir.GET("/Practitioner/:id", ctrl.ReadPractitioner)
+
+		// Reports and resources derived from extracted entities
+		// Search parameters: patient, date, status, _count, _offset
+		fhir.GET("/DiagnosticReport", ctrl.SearchDiagnosticReports)
+		fhir.GET("/DiagnosticReport/:id", ctrl.ReadDiagnosticReport)
+		for _, resourceType := range []string{"Condition", "MedicationStatement", "Observation", "ServiceRequest"} {
+			fhir.GET("/"+resourceType, ctrl.SearchDerived(resourceType))
+			fhir.GET("/"+resourceType+"/:id", ctrl.ReadDerived(resourceType))
+		}
+	}
+}
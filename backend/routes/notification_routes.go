@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationRoutes registers the notification inbox shared by patients and doctors
+func NotificationRoutes(r *gin.Engine) {
+	ctrl := controllers.NewNotificationController()
+
+	notifications := r.Group("/api/notifications")
+	{
+		notifications.GET("", ctrl.ListNotifications)
+		notifications.POST("/:id/read", ctrl.MarkRead)
+		notifications.POST("/:id/unread", ctrl.MarkUnread)
+		notifications.POST("/read-all", ctrl.MarkAllRead)
+
+		// Channel preferences (in-app, email, sms), overall and per event
+		notifications.GET("/preferences", ctrl.GetPreferences)
+		notifications.PUT("/preferences", ctrl.UpdatePreferences)
+
+		// Server-sent events stream of new notifications
+		notifications.GET("/stream", ctrl.StreamNotifications)
+	}
+}
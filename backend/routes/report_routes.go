@@ -8,6 +8,12 @@ import (
 // ReportRoutes registers patient-facing report routes
 func ReportRoutes(router *gin.Engine) {
 	reportCtrl := controllers.NewReportController()
+	taskCtrl := controllers.NewCareTaskController()
+	apptCtrl := controllers.NewAppointmentController()
+	shareCtrl := controllers.NewShareLinkController()
+	delegationCtrl := controllers.NewDelegationController()
+	careCtrl := controllers.NewCareRelationshipController()
+	consentCtrl := controllers.NewConsentController()
 
 	report := router.Group("/api/patient")
 	{
@@ -15,5 +21,51 @@ func ReportRoutes(router *gin.Engine) {
 		report.POST("/reports", reportCtrl.ListReports)
 		report.GET("/reports/:id", reportCtrl.GetReport)
 		report.GET("/reports/:id/download", reportCtrl.DownloadReport)
+		report.GET("/reports/:id/summary.pdf", reportCtrl.DownloadSummaryPDF)
+
+		// Time-limited share links for people without an account
+		report.POST("/reports/:id/share-links", shareCtrl.CreateShareLink)
+		report.GET("/share-links", shareCtrl.ListShareLinks)
+		report.POST("/share-links/:id/revoke", shareCtrl.RevokeShareLink)
+		report.GET("/share-links/:id/accesses", shareCtrl.GetShareLinkAccesses)
+
+		// Follow-up care tasks from recommendations
+		report.GET("/care-tasks", taskCtrl.GetPatientTasks)
+		report.POST("/care-tasks/:id/status", taskCtrl.UpdatePatientTask)
+
+		// Caregiver and guardian access; delegates pass delegate_id on report and chat calls
+		report.GET("/delegations", delegationCtrl.GetPatientDelegations)
+		report.GET("/delegations/held", delegationCtrl.GetHeldDelegations)
+		report.POST("/delegations", delegationCtrl.GrantDelegation)
+		report.POST("/delegations/:id/revoke", delegationCtrl.RevokeDelegation)
+		report.GET("/delegations/:id/activity", delegationCtrl.GetDelegationActivity)
+
+		// Doctors caring for the patient and any emergency access to their records
+		report.GET("/care-team", careCtrl.GetCareTeam)
+
+		// Consent to AI analysis, cloud chat and research use
+		report.GET("/consent-documents", consentCtrl.GetConsentDocuments)
+		report.GET("/consents", consentCtrl.GetPatientConsents)
+		report.POST("/consents", consentCtrl.GrantConsent)
+		report.POST("/consents/:purpose/withdraw", consentCtrl.WithdrawConsent)
+
+		// Appointments with doctors about reviewed reports
+		report.GET("/doctors/:doctor_id/availability", apptCtrl.GetOpenSlots)
+		report.GET("/appointments", apptCtrl.GetPatientAppointments)
+		report.POST("/appointments", apptCtrl.BookAppointment)
+		report.POST("/appointments/:id/cancel", apptCtrl.CancelPatientAppointment)
+		report.POST("/appointments/:id/reschedule", apptCtrl.RescheduleAppointment)
+	}
+}
+
+// SharedReportRoutes registers the public side of share links; the token in
+// the path is the credential
+func SharedReportRoutes(router *gin.Engine) {
+	ctrl := controllers.NewShareLinkController()
+
+	shared := router.Group("/api/shared")
+	{
+		shared.GET("/:token", ctrl.ViewSharedReport)
+		shared.GET("/:token/download", ctrl.DownloadSharedReport)
 	}
 }
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+// AlertChannel delivers a critical alert to its recipients. Channels are
+// registered with RegisterAlertChannel; a failing channel does not stop the
+// others.
+type AlertChannel interface {
+	Name() string
+	Send(alert *models.CriticalAlert, recipients []models.Doctor) error
+}
+
+var (
+	alertChannelsOnce sync.Once
+	alertChannelsMu   sync.RWMutex
+	alertChannels     []AlertChannel
+)
+
+// RegisterAlertChannel adds a delivery channel for critical alerts
+func RegisterAlertChannel(channel AlertChannel) {
+	loadAlertChannels()
+	alertChannelsMu.Lock()
+	defer alertChannelsMu.Unlock()
+	alertChannels = append(alertChannels, channel)
+}
+
+// AlertChannels returns the registered channels. In-app delivery is always
+// on; email and webhook delivery are enabled by their environment settings.
+func AlertChannels() []AlertChannel {
+	loadAlertChannels()
+	alertChannelsMu.RLock()
+	defer alertChannelsMu.RUnlock()
+	return append([]AlertChannel(nil), alertChannels...)
+}
+
+func loadAlertChannels() {
+	alertChannelsOnce.Do(func() {
+		alertChannels = []AlertChannel{InAppAlertChannel{}}
+		if host := os.Getenv("SMTP_HOST"); host != "" {
+			alertChannels = append(alertChannels, NewSMTPAlertChannel())
+		}
+		if url := os.Getenv("CRITICAL_ALERT_WEBHOOK_URL"); url != "" {
+			alertChannels = append(alertChannels, &WebhookAlertChannel{URL: url, Client: &http.Client{Timeout: 10 * time.Second}})
+		}
+	})
+}
+
+// InAppAlertChannel puts each alert, and each reminder, in the recipients'
+// notification inboxes
+type InAppAlertChannel struct{}
+
+func (InAppAlertChannel) Name() string { return "in-app" }
+
+func (InAppAlertChannel) Send(alert *models.CriticalAlert, recipients []models.Doctor) error {
+	reportID := alert.ReportID
+	for _, doctor := range recipients {
+		NotifyInApp(&models.Notification{
+			UserID:   doctor.ID,
+			UserType: RecipientDoctor,
+			Event:    EventCriticalAlert,
+			Title:    "Critical findings need acknowledgement",
+			Body:     fmt.Sprintf("Report %s has %d critical finding(s). Notification %d.", alert.ReportID.Hex(), len(alert.Findings), alert.NotifyCount),
+			ReportID: &reportID,
+		})
+	}
+	return nil
+}
+
+// SMTPAlertChannel emails alerts to each recipient
+type SMTPAlertChannel struct {
+	Addr string // host:port
+	From string
+	Auth smtp.Auth
+}
+
+// NewSMTPAlertChannel reads SMTP_HOST, SMTP_PORT (default 587), SMTP_USER,
+// SMTP_PASSWORD and SMTP_FROM
+func NewSMTPAlertChannel() *SMTPAlertChannel {
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USER")
+	}
+	channel := &SMTPAlertChannel{Addr: host + ":" + port, From: from}
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		channel.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return channel
+}
+
+func (c *SMTPAlertChannel) Name() string { return "email" }
+
+func (c *SMTPAlertChannel) Send(alert *models.CriticalAlert, recipients []models.Doctor) error {
+	var to []string
+	for _, doctor := range recipients {
+		if doctor.Email != "" {
+			to = append(to, doctor.Email)
+		}
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipient has an email address")
+	}
+
+	// The email names no patient; the link leads to the authenticated portal
+	var body strings.Builder
+	fmt.Fprintf(&body, "A report has critical findings that need acknowledgement.\r\n\r\n")
+	for _, f := range alert.Findings {
+		fmt.Fprintf(&body, "- %s\r\n", f.Description)
+	}
+	fmt.Fprintf(&body, "\r\nReport: %s\r\nAlert: %s\r\nNotification %d\r\n", alert.ReportID.Hex(), alert.ID.Hex(), alert.NotifyCount)
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [CRITICAL] Report %s needs attention\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		c.From, strings.Join(to, ", "), alert.ReportID.Hex(), body.String())
+	return smtp.SendMail(c.Addr, c.Auth, c.From, to, []byte(msg))
+}
+
+// WebhookAlertChannel posts alerts as JSON, e.g. to a paging service
+type WebhookAlertChannel struct {
+	URL    string
+	Client *http.Client
+}
+
+func (c *WebhookAlertChannel) Name() string { return "webhook" }
+
+func (c *WebhookAlertChannel) Send(alert *models.CriticalAlert, recipients []models.Doctor) error {
+	type recipient struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	payload := struct {
+		Event      string                   `json:"event"`
+		AlertID    string                   `json:"alert_id"`
+		ReportID   string                   `json:"report_id"`
+		Findings   []models.CriticalFinding `json:"findings"`
+		Recipients []recipient              `json:"recipients"`
+		Attempt    int                      `json:"attempt"`
+		CreatedAt  time.Time                `json:"created_at"`
+	}{
+		Event:     "critical_alert",
+		AlertID:   alert.ID.Hex(),
+		ReportID:  alert.ReportID.Hex(),
+		Findings:  alert.Findings,
+		Attempt:   alert.NotifyCount,
+		CreatedAt: alert.CreatedAt,
+	}
+	for _, doctor := range recipients {
+		payload.Recipients = append(payload.Recipients, recipient{ID: doctor.ID.Hex(), Name: doctor.Name, Email: doctor.Email})
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Client.Post(c.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Critical alert states
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+)
+
+// Re-notification defaults, overridden by CRITICAL_ALERT_RENOTIFY (a Go
+// duration) and CRITICAL_ALERT_MAX_NOTIFICATIONS
+const (
+	DefaultAlertRenotifyInterval = 15 * time.Minute
+	DefaultAlertMaxNotifications = 8
+)
+
+// criticalUrgencies are urgency entities and recommendation urgencies that
+// make a report critical on their own
+var criticalUrgencies = map[string]bool{"emergent": true, "stat": true, "immediate": true}
+
+// criticalFlags are lab interpretation flags that are critical whatever the
+// configured thresholds
+var criticalFlags = map[string]string{"HH": "critically high", "LL": "critically low", "AA": "critically abnormal"}
+
+func threshold(v float64) *float64 { return &v }
+
+// defaultCriticalThresholds seed the thresholds collection. They follow common
+// laboratory critical value lists in conventional units.
+func defaultCriticalThresholds() []models.CriticalThreshold {
+	return []models.CriticalThreshold{
+		{Name: "Potassium", Aliases: []string{"serum potassium", "k"}, Code: "2823-3", Unit: "mmol/L", Low: threshold(2.5), High: threshold(6.5)},
+		{Name: "Sodium", Aliases: []string{"serum sodium", "na"}, Code: "2951-2", Unit: "mmol/L", Low: threshold(120), High: threshold(160)},
+		{Name: "Glucose", Aliases: []string{"blood glucose", "blood sugar"}, Code: "2345-7", Unit: "mg/dL", Low: threshold(40), High: threshold(500)},
+		{Name: "Calcium", Aliases: []string{"serum calcium"}, Code: "17861-6", Unit: "mg/dL", Low: threshold(6), High: threshold(13)},
+		{Name: "Hemoglobin", Aliases: []string{"haemoglobin", "hb", "hgb"}, Code: "718-7", Unit: "g/dL", Low: threshold(7)},
+		{Name: "Platelets", Aliases: []string{"platelet count"}, Code: "777-3", Unit: "10*3/uL", Low: threshold(20), High: threshold(1000)},
+		{Name: "White blood cells", Aliases: []string{"wbc", "leukocytes", "white blood cell count"}, Code: "6690-2", Unit: "10*3/uL", Low: threshold(1), High: threshold(50)},
+		{Name: "INR", Aliases: []string{"international normalized ratio"}, Code: "6301-6", High: threshold(5)},
+	}
+}
+
+// CriticalThresholds returns the configured thresholds, seeding the defaults
+// into an empty collection
+func CriticalThresholds(ctx context.Context, enabledOnly bool) ([]models.CriticalThreshold, error) {
+	collection := config.GetCollection("critical_thresholds")
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		now := time.Now()
+		var docs []interface{}
+		for _, t := range defaultCriticalThresholds() {
+			t.ID = primitive.NewObjectID()
+			t.Enabled = true
+			t.CreatedAt, t.UpdatedAt = now, now
+			docs = append(docs, t)
+		}
+		if _, err := collection.InsertMany(ctx, docs); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := bson.M{}
+	if enabledOnly {
+		filter["enabled"] = true
+	}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var thresholds []models.CriticalThreshold
+	if err := cursor.All(ctx, &thresholds); err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// thresholdMatches reports whether an observation is the analyte of a
+// threshold, by code or by name when the observation has no code
+func thresholdMatches(t *models.CriticalThreshold, obs *models.LabObservation) bool {
+	if obs.Code != "" && t.Code != "" {
+		return obs.Code == t.Code
+	}
+	display := normalizeTerm(obs.Display)
+	for _, name := range append([]string{t.Name}, t.Aliases...) {
+		if display == normalizeTerm(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateCriticalFindings checks a report's lab results against the
+// thresholds and its urgency entities and recommendations for emergent care
+func EvaluateCriticalFindings(report *models.Report, thresholds []models.CriticalThreshold) []models.CriticalFinding {
+	var findings []models.CriticalFinding
+	for i := range report.Observations {
+		obs := &report.Observations[i]
+		flagged := false
+		if label, ok := criticalFlags[strings.ToUpper(obs.Interpretation)]; ok {
+			findings = append(findings, models.CriticalFinding{
+				Kind:        "lab-flag",
+				Description: fmt.Sprintf("%s flagged %s by the laboratory", ObservationEntityText(*obs), label),
+				Value:       obs.Value,
+			})
+			flagged = true
+		}
+		if obs.Value == nil || flagged {
+			continue
+		}
+		for j := range thresholds {
+			t := &thresholds[j]
+			if !thresholdMatches(t, obs) {
+				continue
+			}
+			// Values in another unit can't be compared safely
+			if t.Unit != "" && obs.Unit != "" && !strings.EqualFold(t.Unit, obs.Unit) {
+				continue
+			}
+			var limit string
+			switch {
+			case t.Low != nil && *obs.Value < *t.Low:
+				limit = fmt.Sprintf("< %g %s", *t.Low, t.Unit)
+			case t.High != nil && *obs.Value > *t.High:
+				limit = fmt.Sprintf("> %g %s", *t.High, t.Unit)
+			default:
+				continue
+			}
+			findings = append(findings, models.CriticalFinding{
+				Kind:        "lab",
+				Description: fmt.Sprintf("Critical %s: %s", t.Name, ObservationEntityText(*obs)),
+				Value:       obs.Value,
+				Threshold:   strings.TrimSpace(limit),
+			})
+			break
+		}
+	}
+
+	for _, urgency := range report.AIAnalysis.Entities.Urgency {
+		if criticalUrgencies[strings.ToLower(strings.TrimSpace(urgency))] {
+			findings = append(findings, models.CriticalFinding{
+				Kind:        "urgency",
+				Description: fmt.Sprintf("Report marked %q", urgency),
+			})
+			break
+		}
+	}
+	for _, rec := range report.AIAnalysis.Recommendations {
+		if criticalUrgencies[strings.ToLower(rec.Urgency)] {
+			findings = append(findings, models.CriticalFinding{
+				Kind:        "urgency",
+				Description: fmt.Sprintf("%s recommended with %s urgency: %s", rec.Test, rec.Urgency, rec.Reason),
+			})
+		}
+	}
+	return findings
+}
+
+// ResponsibleDoctors picks the doctors responsible for a report: its
+// reviewer, else the patient's care team, else the on-call doctors in
+// CRITICAL_ALERT_ONCALL, else the doctors admins put on call. It returns
+// none when nobody is responsible.
+func ResponsibleDoctors(ctx context.Context, report *models.Report) []primitive.ObjectID {
+	if report.DoctorReview != nil && !report.DoctorReview.ReviewedBy.IsZero() {
+		return []primitive.ObjectID{report.DoctorReview.ReviewedBy}
+	}
+	if team, err := CareTeam(ctx, report.PatientID); err == nil && len(team) > 0 {
+		return team
+	}
+
+	var onCall []primitive.ObjectID
+	for _, id := range strings.Split(os.Getenv("CRITICAL_ALERT_ONCALL"), ",") {
+		if objID, err := primitive.ObjectIDFromHex(strings.TrimSpace(id)); err == nil {
+			onCall = append(onCall, objID)
+		}
+	}
+	if len(onCall) > 0 {
+		return onCall
+	}
+	onCall, err := OnCallDoctors(ctx)
+	if err != nil {
+		log.Printf("critical alerts: failed to load on-call doctors: %v", err)
+	}
+	return onCall
+}
+
+// criticalFindingKey identifies a finding across alerts of the same report
+func criticalFindingKey(f *models.CriticalFinding) string {
+	return f.Kind + "|" + f.Description
+}
+
+// newCriticalFindings drops findings an earlier alert of the report already
+// raised, so re-analysis does not page doctors again about known values
+func newCriticalFindings(ctx context.Context, reportID primitive.ObjectID, findings []models.CriticalFinding) ([]models.CriticalFinding, error) {
+	cursor, err := config.GetCollection("critical_alerts").Find(ctx, bson.M{"report_id": reportID},
+		options.Find().SetProjection(bson.M{"findings": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var previous []models.CriticalAlert
+	if err := cursor.All(ctx, &previous); err != nil {
+		return nil, err
+	}
+	raised := map[string]bool{}
+	for i := range previous {
+		for j := range previous[i].Findings {
+			raised[criticalFindingKey(&previous[i].Findings[j])] = true
+		}
+	}
+	var fresh []models.CriticalFinding
+	for i := range findings {
+		if !raised[criticalFindingKey(&findings[i])] {
+			fresh = append(fresh, findings[i])
+		}
+	}
+	return fresh, nil
+}
+
+// RaiseCriticalAlert evaluates a saved report and, when it has critical
+// findings not alerted before, stores an alert and notifies its recipients in
+// the background. A report keeps at most one open alert. Alerts nobody is
+// responsible for go to admins to assign.
+func RaiseCriticalAlert(report *models.Report) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	thresholds, err := CriticalThresholds(ctx, true)
+	if err != nil {
+		log.Printf("critical alerts: failed to load thresholds: %v", err)
+	}
+	findings := EvaluateCriticalFindings(report, thresholds)
+	if len(findings) == 0 {
+		return
+	}
+
+	collection := config.GetCollection("critical_alerts")
+	if n, err := collection.CountDocuments(ctx, bson.M{"report_id": report.ID, "status": AlertOpen}); err == nil && n > 0 {
+		return
+	}
+	findings, err = newCriticalFindings(ctx, report.ID, findings)
+	if err != nil {
+		log.Printf("critical alerts: failed to load earlier alerts of report %s: %v", report.ID.Hex(), err)
+		return
+	}
+	if len(findings) == 0 {
+		return
+	}
+
+	// The first notification goes out below; the renotifier takes over after
+	now := time.Now()
+	next := now.Add(alertRenotifyInterval())
+	alert := &models.CriticalAlert{
+		ID:           primitive.NewObjectID(),
+		ReportID:     report.ID,
+		PatientID:    report.PatientID,
+		Findings:     findings,
+		RecipientIDs: ResponsibleDoctors(ctx, report),
+		Status:       AlertOpen,
+		NextNotifyAt: &next,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := collection.InsertOne(ctx, alert); err != nil {
+		log.Printf("critical alerts: failed to save alert for report %s: %v", report.ID.Hex(), err)
+		return
+	}
+	if len(alert.RecipientIDs) == 0 {
+		NotifyAdmins(models.Notification{
+			Event:    EventCriticalAlert,
+			Title:    "Critical alert has no responsible doctor",
+			Body:     fmt.Sprintf("Report %s has critical findings but the patient has no care team and no doctor is on call. Assign a doctor.", report.ID.Hex()),
+			ReportID: &report.ID,
+		})
+	}
+	go NotifyCriticalAlert(alert)
+}
+
+func alertRenotifyInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("CRITICAL_ALERT_RENOTIFY")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultAlertRenotifyInterval
+}
+
+func alertMaxNotifications() int {
+	if n, err := strconv.Atoi(os.Getenv("CRITICAL_ALERT_MAX_NOTIFICATIONS")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultAlertMaxNotifications
+}
+
+// NotifyCriticalAlert sends an open alert through every channel and schedules
+// the next reminder
+func NotifyCriticalAlert(alert *models.CriticalAlert) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var recipients []models.Doctor
+	if len(alert.RecipientIDs) > 0 {
+		cursor, err := config.GetCollection("doctors").Find(ctx, bson.M{"_id": bson.M{"$in": alert.RecipientIDs}})
+		if err == nil {
+			cursor.All(ctx, &recipients)
+		}
+	}
+
+	now := time.Now()
+	alert.NotifyCount++
+	var deliveries []models.AlertDelivery
+	for _, channel := range AlertChannels() {
+		delivery := models.AlertDelivery{Channel: channel.Name(), Attempt: alert.NotifyCount, SentAt: time.Now()}
+		if err := channel.Send(alert, recipients); err != nil {
+			delivery.Error = err.Error()
+			log.Printf("critical alerts: %s delivery of alert %s failed: %v", channel.Name(), alert.ID.Hex(), err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	set := bson.M{"notify_count": alert.NotifyCount, "last_notified_at": now, "updated_at": now}
+	unset := bson.M{}
+	if alert.NotifyCount < alertMaxNotifications() {
+		set["next_notify_at"] = now.Add(alertRenotifyInterval())
+	} else {
+		unset["next_notify_at"] = ""
+	}
+	update := bson.M{"$set": set, "$push": bson.M{"deliveries": bson.M{"$each": deliveries}}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	// Acknowledgement may have happened while sending; don't reschedule then
+	_, err := config.GetCollection("critical_alerts").UpdateOne(ctx, bson.M{"_id": alert.ID, "status": AlertOpen}, update)
+	if err != nil {
+		log.Printf("critical alerts: failed to record delivery of alert %s: %v", alert.ID.Hex(), err)
+	}
+}
+
+// RunAlertRenotifier re-sends open alerts whose reminder is due until they are
+// acknowledged or run out of attempts
+func RunAlertRenotifier(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		cursor, err := config.GetCollection("critical_alerts").Find(ctx, bson.M{
+			"status":         AlertOpen,
+			"next_notify_at": bson.M{"$lte": time.Now()},
+		})
+		var due []models.CriticalAlert
+		if err == nil {
+			err = cursor.All(ctx, &due)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("critical alerts: failed to load due alerts: %v", err)
+			continue
+		}
+		for i := range due {
+			NotifyCriticalAlert(&due[i])
+		}
+	}
+}
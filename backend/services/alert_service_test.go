@@ -0,0 +1,106 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestThresholdMatches(t *testing.T) {
+	potassium := &models.CriticalThreshold{Name: "Potassium", Aliases: []string{"serum potassium", "k"}, Code: "2823-3"}
+	uncoded := &models.CriticalThreshold{Name: "Lactate"}
+
+	tests := []struct {
+		name      string
+		threshold *models.CriticalThreshold
+		obs       models.LabObservation
+		want      bool
+	}{
+		{"code", potassium, models.LabObservation{Code: "2823-3", Display: "K+"}, true},
+		{"other code wins over name", potassium, models.LabObservation{Code: "2951-2", Display: "Potassium"}, false},
+		{"name without code", potassium, models.LabObservation{Display: "potassium"}, true},
+		{"alias without code", potassium, models.LabObservation{Display: "Serum Potassium"}, true},
+		{"threshold without code", uncoded, models.LabObservation{Code: "2524-7", Display: "Lactate"}, true},
+		{"different analyte", potassium, models.LabObservation{Display: "Sodium"}, false},
+	}
+	for _, tt := range tests {
+		if got := thresholdMatches(tt.threshold, &tt.obs); got != tt.want {
+			t.Errorf("%s: thresholdMatches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateCriticalFindings(t *testing.T) {
+	thresholds := defaultCriticalThresholds()
+	obs := func(display string, value float64, unit, interpretation string) models.LabObservation {
+		return models.LabObservation{Display: display, Value: &value, Unit: unit, Interpretation: interpretation}
+	}
+
+	tests := []struct {
+		name   string
+		report models.Report
+		want   []string // Finding kind and threshold
+	}{
+		{"normal", models.Report{Observations: []models.LabObservation{obs("Potassium", 4.2, "mmol/L", "")}}, nil},
+		{"above high", models.Report{Observations: []models.LabObservation{obs("Potassium", 7.1, "mmol/L", "")}}, []string{"lab > 6.5 mmol/L"}},
+		{"below low", models.Report{Observations: []models.LabObservation{obs("Hb", 5.9, "g/dL", "")}}, []string{"lab < 7 g/dL"}},
+		{"limit is not critical", models.Report{Observations: []models.LabObservation{obs("Sodium", 160, "mmol/L", "")}}, nil},
+		{"other unit not compared", models.Report{Observations: []models.LabObservation{obs("Glucose", 30, "mmol/L", "")}}, nil},
+		{"lab flag only reported once", models.Report{Observations: []models.LabObservation{obs("Potassium", 7.1, "mmol/L", "hh")}}, []string{"lab-flag "}},
+		{"unflagged value without number", models.Report{Observations: []models.LabObservation{{Display: "Potassium", ValueString: "haemolysed"}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range EvaluateCriticalFindings(&tt.report, thresholds) {
+				got = append(got, f.Kind+" "+f.Threshold)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("urgency", func(t *testing.T) {
+		var report models.Report
+		report.AIAnalysis.Entities.Urgency = []string{"routine", " STAT ", "emergent"}
+		report.AIAnalysis.Recommendations = []models.Recommendation{
+			{Test: "CT head", Urgency: "immediate", Reason: "head injury"},
+			{Test: "Lipid panel", Urgency: "routine"},
+		}
+		findings := EvaluateCriticalFindings(&report, thresholds)
+		if len(findings) != 2 {
+			t.Fatalf("findings = %+v, want one urgency entity and one recommendation", findings)
+		}
+		if !strings.Contains(findings[1].Description, "CT head") {
+			t.Errorf("recommendation finding = %q", findings[1].Description)
+		}
+	})
+}
+
+func TestCriticalFindingKey(t *testing.T) {
+	value := 7.1
+	a := models.CriticalFinding{Kind: "lab", Description: "Critical Potassium: Potassium 7.1 mmol/L", Value: &value}
+	b := models.CriticalFinding{Kind: "lab", Description: a.Description}
+	if criticalFindingKey(&a) != criticalFindingKey(&b) {
+		t.Error("value changed the finding key")
+	}
+	b.Kind = "lab-flag"
+	if criticalFindingKey(&a) == criticalFindingKey(&b) {
+		t.Error("kind did not change the finding key")
+	}
+}
+
+func TestAlertSettingsFromEnv(t *testing.T) {
+	t.Setenv("CRITICAL_ALERT_RENOTIFY", "5m")
+	t.Setenv("CRITICAL_ALERT_MAX_NOTIFICATIONS", "3")
+	if alertRenotifyInterval().Minutes() != 5 || alertMaxNotifications() != 3 {
+		t.Errorf("settings = %v, %d, want 5m, 3", alertRenotifyInterval(), alertMaxNotifications())
+	}
+	t.Setenv("CRITICAL_ALERT_RENOTIFY", "-1m")
+	t.Setenv("CRITICAL_ALERT_MAX_NOTIFICATIONS", "none")
+	if alertRenotifyInterval() != DefaultAlertRenotifyInterval || alertMaxNotifications() != DefaultAlertMaxNotifications {
+		t.Errorf("invalid settings not replaced by the defaults: %v, %d", alertRenotifyInterval(), alertMaxNotifications())
+	}
+}
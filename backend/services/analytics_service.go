@@ -0,0 +1,443 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// analyticsEntityTypes are the entity categories compared in doctor feedback.
+// "recommendations" is compared by test name.
+var analyticsEntityTypes = []string{
+	"symptoms", "diagnoses", "medications", "tests", "vitals",
+	"severity", "urgency", "functional_impact", "recommendations",
+}
+
+// AnalyticsService computes model accuracy analytics from doctor feedback
+type AnalyticsService struct{}
+
+// FeedbackRecord is a feedback document with the original analysis decoded
+type FeedbackRecord struct {
+	ID                primitive.ObjectID `bson:"_id"`
+	ReportID          primitive.ObjectID `bson:"report_id"`
+	DoctorID          primitive.ObjectID `bson:"doctor_id"`
+	OriginalAnalysis  models.AIAnalysis  `bson:"original_analysis"`
+	CorrectedAnalysis bson.M             `bson:"corrected_analysis"`
+	CreatedAt         time.Time          `bson:"created_at"`
+}
+
+// entityDecision is one entity compared between the original and corrected analysis
+type entityDecision struct {
+	EntityType string
+	Entity     string
+	Label      string // "accepted", "removed", "added"
+	Confidence *float64
+}
+
+// LoadFeedback fetches feedback created within [from, to). Zero times are unbounded.
+func (s *AnalyticsService) LoadFeedback(from, to time.Time) ([]FeedbackRecord, error) {
+	collection := config.GetCollection("feedback")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	dateFilter := bson.M{}
+	if !from.IsZero() {
+		dateFilter["$gte"] = from
+	}
+	if !to.IsZero() {
+		dateFilter["$lt"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["created_at"] = dateFilter
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feedback: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []FeedbackRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode feedback: %w", err)
+	}
+	return records, nil
+}
+
+// ComputeAnalytics builds the full analytics report over feedback records.
+// period is "day", "week" or "month"; bins is the number of calibration bins.
+func (s *AnalyticsService) ComputeAnalytics(records []FeedbackRecord, period string, bins, topN int) models.FeedbackAnalytics {
+	type counts struct{ accepted, removed, added, feedback int }
+	perType := map[string]*counts{}
+	removed := map[string]int{}
+	added := map[string]int{}
+	perPeriod := map[string]*counts{}
+	periodFeedback := map[string]int{}
+
+	var calibrationSamples []calibrationSample
+
+	for _, record := range records {
+		periodKey := periodLabel(record.CreatedAt, period)
+		periodFeedback[periodKey]++
+		if perPeriod[periodKey] == nil {
+			perPeriod[periodKey] = &counts{}
+		}
+
+		seenTypes := map[string]bool{}
+		for _, d := range compareFeedback(record) {
+			c := perType[d.EntityType]
+			if c == nil {
+				c = &counts{}
+				perType[d.EntityType] = c
+			}
+			if !seenTypes[d.EntityType] {
+				c.feedback++
+				seenTypes[d.EntityType] = true
+			}
+
+			key := d.EntityType + "\x00" + d.Entity
+			switch d.Label {
+			case "accepted":
+				c.accepted++
+				perPeriod[periodKey].accepted++
+			case "removed":
+				c.removed++
+				perPeriod[periodKey].removed++
+				removed[key]++
+			case "added":
+				c.added++
+				perPeriod[periodKey].added++
+				added[key]++
+			}
+
+			if d.Confidence != nil && d.Label != "added" {
+				calibrationSamples = append(calibrationSamples, calibrationSample{
+					confidence: *d.Confidence,
+					accepted:   d.Label == "accepted",
+				})
+			}
+		}
+	}
+
+	result := models.FeedbackAnalytics{
+		FeedbackCount: len(records),
+		GeneratedAt:   time.Now(),
+	}
+
+	for _, entityType := range analyticsEntityTypes {
+		c := perType[entityType]
+		if c == nil {
+			continue
+		}
+		precision, recall, f1 := precisionRecall(c.accepted, c.removed, c.added)
+		result.EntityTypes = append(result.EntityTypes, models.EntityTypeAccuracy{
+			EntityType:    entityType,
+			Accepted:      c.accepted,
+			Removed:       c.removed,
+			Added:         c.added,
+			Precision:     precision,
+			Recall:        recall,
+			F1:            f1,
+			FeedbackCount: c.feedback,
+		})
+	}
+
+	result.MostRemoved = topCorrections(removed, topN)
+	result.MostAdded = topCorrections(added, topN)
+	result.Calibration = buildCalibrationCurve(calibrationSamples, bins)
+
+	periods := make([]string, 0, len(perPeriod))
+	for key := range perPeriod {
+		periods = append(periods, key)
+	}
+	sort.Strings(periods)
+	for _, key := range periods {
+		c := perPeriod[key]
+		precision, recall, f1 := precisionRecall(c.accepted, c.removed, c.added)
+		result.Trends = append(result.Trends, models.AccuracyTrendPoint{
+			Period:        key,
+			FeedbackCount: periodFeedback[key],
+			Precision:     precision,
+			Recall:        recall,
+			F1:            f1,
+		})
+	}
+
+	return result
+}
+
+// TrainingExamples flattens feedback into labelled entity decisions for retraining
+func (s *AnalyticsService) TrainingExamples(records []FeedbackRecord) []models.TrainingExample {
+	var examples []models.TrainingExample
+	for _, record := range records {
+		for _, d := range compareFeedback(record) {
+			examples = append(examples, models.TrainingExample{
+				FeedbackID:          record.ID,
+				ReportID:            record.ReportID,
+				DoctorID:            record.DoctorID,
+				CreatedAt:           record.CreatedAt,
+				EntityType:          d.EntityType,
+				Entity:              d.Entity,
+				Label:               d.Label,
+				PredictedConfidence: d.Confidence,
+			})
+		}
+	}
+	return examples
+}
+
+// compareFeedback diffs the original analysis against the doctor's corrections.
+// Categories the doctor did not submit are treated as accepted unchanged.
+func compareFeedback(record FeedbackRecord) []entityDecision {
+	var decisions []entityDecision
+
+	for _, entityType := range analyticsEntityTypes {
+		original, confidences := originalEntities(&record.OriginalAnalysis, entityType)
+		corrected, ok := correctedEntities(record.CorrectedAnalysis, entityType)
+		if !ok {
+			corrected = original
+		}
+
+		correctedSet := map[string]bool{}
+		for _, e := range corrected {
+			correctedSet[e] = true
+		}
+		originalSet := map[string]bool{}
+
+		for _, e := range original {
+			if originalSet[e] {
+				continue
+			}
+			originalSet[e] = true
+
+			label := "removed"
+			if correctedSet[e] {
+				label = "accepted"
+			}
+			d := entityDecision{EntityType: entityType, Entity: e, Label: label}
+			if conf, ok := confidences[e]; ok {
+				c := conf
+				d.Confidence = &c
+			}
+			decisions = append(decisions, d)
+		}
+
+		addedSet := map[string]bool{}
+		for _, e := range corrected {
+			if originalSet[e] || addedSet[e] {
+				continue
+			}
+			addedSet[e] = true
+			decisions = append(decisions, entityDecision{EntityType: entityType, Entity: e, Label: "added"})
+		}
+	}
+
+	return decisions
+}
+
+// originalEntities returns normalized entities of a type and, for
+// recommendations, the predicted confidence keyed by test name
+func originalEntities(analysis *models.AIAnalysis, entityType string) ([]string, map[string]float64) {
+	if entityType == "recommendations" {
+		// Analyses scored by the confidence scorer store 0-100 confidences.
+		// Older ones hold the analyzer's raw 0-1 fractions.
+		legacy := analysis.ConfidenceBreakdown == nil
+		var tests []string
+		confidences := map[string]float64{}
+		for _, rec := range analysis.Recommendations {
+			name := normalizeEntity(rec.Test)
+			if name == "" {
+				continue
+			}
+			tests = append(tests, name)
+			if legacy {
+				confidences[name] = NormalizeConfidence(rec.Confidence)
+			} else {
+				confidences[name] = clampScore(rec.Confidence)
+			}
+		}
+		return tests, confidences
+	}
+
+	var entities []string
+	for _, e := range entityCategory(analysis, entityType) {
+		if n := normalizeEntity(e); n != "" {
+			entities = append(entities, n)
+		}
+	}
+	return entities, nil
+}
+
+// correctedEntities reads a category from the doctor's edited fields, which
+// may be flat ({"symptoms": [...]}) or nested ({"entities": {"symptoms": [...]}})
+func correctedEntities(corrected bson.M, entityType string) ([]string, bool) {
+	value, ok := corrected[entityType]
+	if !ok {
+		nested, isMap := asMap(corrected["entities"])
+		if !isMap {
+			return nil, false
+		}
+		value, ok = nested[entityType]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	items, isList := value.(primitive.A)
+	if !isList {
+		if list, isSlice := value.([]interface{}); isSlice {
+			items = list
+		} else {
+			return nil, false
+		}
+	}
+
+	var entities []string
+	for _, item := range items {
+		var name string
+		if entityType == "recommendations" {
+			if m, isMap := asMap(item); isMap {
+				name, _ = m["test"].(string)
+			}
+		} else {
+			name, _ = item.(string)
+		}
+		if n := normalizeEntity(name); n != "" {
+			entities = append(entities, n)
+		}
+	}
+	return entities, true
+}
+
+// asMap converts the document representations produced by the BSON decoder to a map
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case bson.M:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	case primitive.D:
+		return v.Map(), true
+	}
+	return nil, false
+}
+
+func normalizeEntity(entity string) string {
+	return strings.ToLower(strings.TrimSpace(entity))
+}
+
+// precisionRecall returns precision, recall and F1 as percentages
+func precisionRecall(tp, fp, fn int) (float64, float64, float64) {
+	var precision, recall, f1 float64
+	if tp+fp > 0 {
+		precision = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		recall = float64(tp) / float64(tp+fn)
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return round1(precision * 100), round1(recall * 100), round1(f1 * 100)
+}
+
+// topCorrections returns the most frequent entity corrections
+func topCorrections(counts map[string]int, topN int) []models.EntityCorrection {
+	corrections := make([]models.EntityCorrection, 0, len(counts))
+	for key, count := range counts {
+		parts := strings.SplitN(key, "\x00", 2)
+		corrections = append(corrections, models.EntityCorrection{
+			EntityType: parts[0],
+			Entity:     parts[1],
+			Count:      count,
+		})
+	}
+	sort.Slice(corrections, func(i, j int) bool {
+		if corrections[i].Count != corrections[j].Count {
+			return corrections[i].Count > corrections[j].Count
+		}
+		if corrections[i].EntityType != corrections[j].EntityType {
+			return corrections[i].EntityType < corrections[j].EntityType
+		}
+		return corrections[i].Entity < corrections[j].Entity
+	})
+	if topN > 0 && len(corrections) > topN {
+		corrections = corrections[:topN]
+	}
+	return corrections
+}
+
+type calibrationSample struct {
+	confidence float64 // 0-100
+	accepted   bool
+}
+
+// buildCalibrationCurve bins predicted confidences and compares them with acceptance
+func buildCalibrationCurve(samples []calibrationSample, bins int) models.CalibrationCurve {
+	if bins <= 0 {
+		bins = 10
+	}
+	width := 100.0 / float64(bins)
+
+	type bucket struct {
+		count, accepted int
+		sum             float64
+	}
+	buckets := make([]bucket, bins)
+	for _, sample := range samples {
+		i := int(sample.confidence / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		buckets[i].count++
+		buckets[i].sum += sample.confidence
+		if sample.accepted {
+			buckets[i].accepted++
+		}
+	}
+
+	curve := models.CalibrationCurve{SampleCount: len(samples)}
+	var ece float64
+	for i, b := range buckets {
+		bin := models.CalibrationBin{
+			LowerBound: round1(float64(i) * width),
+			UpperBound: round1(float64(i+1) * width),
+			Count:      b.count,
+		}
+		if b.count > 0 {
+			mean := b.sum / float64(b.count)
+			rate := 100 * float64(b.accepted) / float64(b.count)
+			gap := math.Abs(mean - rate)
+			bin.MeanConfidence = round1(mean)
+			bin.AcceptanceRate = round1(rate)
+			bin.CalibrationGapAbs = round1(gap)
+			ece += gap * float64(b.count) / float64(len(samples))
+		}
+		curve.Bins = append(curve.Bins, bin)
+	}
+	curve.ExpectedCalibrationError = round1(ece)
+	return curve
+}
+
+// periodLabel buckets a time by day ("2006-01-02"), ISO week ("2006-W01") or month ("2006-01")
+func periodLabel(t time.Time, period string) string {
+	t = t.UTC()
+	switch period {
+	case "day":
+		return t.Format("2006-01-02")
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		return t.Format("2006-01")
+	}
+}
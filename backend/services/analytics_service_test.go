@@ -0,0 +1,140 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCompareFeedback(t *testing.T) {
+	var original models.AIAnalysis
+	original.Entities.Symptoms = []string{"Fever", "cough", "fever "}
+	original.Entities.Diagnoses = []string{"asthma"}
+	original.Recommendations = []models.Recommendation{{Test: "CBC", Confidence: 80}, {Test: "ECG", Confidence: 60}}
+	original.ConfidenceBreakdown = &models.ConfidenceBreakdown{}
+
+	record := FeedbackRecord{
+		OriginalAnalysis: original,
+		CorrectedAnalysis: bson.M{
+			"symptoms":        primitive.A{"fever", "headache"},
+			"entities":        bson.M{"diagnoses": []interface{}{"Asthma"}},
+			"recommendations": primitive.A{bson.M{"test": "cbc"}},
+		},
+	}
+
+	got := map[string]string{}
+	for _, d := range compareFeedback(record) {
+		got[d.EntityType+":"+d.Entity] = d.Label
+		if d.EntityType == "recommendations" && d.Entity == "cbc" && (d.Confidence == nil || *d.Confidence != 80) {
+			t.Errorf("cbc confidence = %v, want 80", d.Confidence)
+		}
+	}
+	want := map[string]string{
+		"symptoms:fever":      "accepted",
+		"symptoms:cough":      "removed",
+		"symptoms:headache":   "added",
+		"diagnoses:asthma":    "accepted",
+		"recommendations:cbc": "accepted",
+		"recommendations:ecg": "removed",
+	}
+	if len(got) != len(want) {
+		t.Errorf("decisions = %v, want %v", got, want)
+	}
+	for key, label := range want {
+		if got[key] != label {
+			t.Errorf("%s = %q, want %q", key, got[key], label)
+		}
+	}
+}
+
+func TestOriginalEntitiesConfidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored float64
+		scored bool // Analysis went through the confidence scorer
+		want   float64
+	}{
+		{"scored 1 stays 1%", 1, true, 1},
+		{"scored 0.5 stays 0.5%", 0.5, true, 0.5},
+		{"scored percentage", 85, true, 85},
+		{"scored out of range", 140, true, 100},
+		{"legacy fraction", 0.85, false, 85},
+		{"legacy 1 is 100%", 1, false, 100},
+		{"legacy percentage", 85, false, 85},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := models.AIAnalysis{Recommendations: []models.Recommendation{{Test: "CBC", Confidence: tt.stored}}}
+			if tt.scored {
+				analysis.ConfidenceBreakdown = &models.ConfidenceBreakdown{}
+			}
+			_, confidences := originalEntities(&analysis, "recommendations")
+			if got := confidences["cbc"]; got != tt.want {
+				t.Errorf("confidence = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrecisionRecall(t *testing.T) {
+	tests := []struct {
+		tp, fp, fn                int
+		precision, recall, wantF1 float64
+	}{
+		{0, 0, 0, 0, 0, 0},
+		{10, 0, 0, 100, 100, 100},
+		{8, 2, 0, 80, 100, 88.9},
+		{6, 2, 4, 75, 60, 66.7},
+		{0, 5, 5, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		p, r, f1 := precisionRecall(tt.tp, tt.fp, tt.fn)
+		if p != tt.precision || r != tt.recall || f1 != tt.wantF1 {
+			t.Errorf("precisionRecall(%d, %d, %d) = %v, %v, %v, want %v, %v, %v",
+				tt.tp, tt.fp, tt.fn, p, r, f1, tt.precision, tt.recall, tt.wantF1)
+		}
+	}
+}
+
+func TestBuildCalibrationCurve(t *testing.T) {
+	samples := []calibrationSample{
+		{confidence: 5, accepted: false},
+		{confidence: 15, accepted: true},
+		{confidence: 95, accepted: true},
+		{confidence: 85, accepted: true},
+		{confidence: 100, accepted: false}, // Top edge falls in the last bin
+	}
+	curve := buildCalibrationCurve(samples, 5)
+
+	if len(curve.Bins) != 5 || curve.SampleCount != 5 {
+		t.Fatalf("curve has %d bins and %d samples", len(curve.Bins), curve.SampleCount)
+	}
+	want := []models.CalibrationBin{
+		{LowerBound: 0, UpperBound: 20, Count: 2, MeanConfidence: 10, AcceptanceRate: 50, CalibrationGapAbs: 40},
+		{LowerBound: 20, UpperBound: 40},
+		{LowerBound: 40, UpperBound: 60},
+		{LowerBound: 60, UpperBound: 80},
+		{LowerBound: 80, UpperBound: 100, Count: 3, MeanConfidence: 93.3, AcceptanceRate: 66.7, CalibrationGapAbs: 26.7},
+	}
+	for i := range want {
+		if curve.Bins[i] != want[i] {
+			t.Errorf("bin %d = %+v, want %+v", i, curve.Bins[i], want[i])
+		}
+	}
+	// (2*40 + 3*26.67) / 5
+	if curve.ExpectedCalibrationError != 32 {
+		t.Errorf("ECE = %v, want 32", curve.ExpectedCalibrationError)
+	}
+
+	empty := buildCalibrationCurve(nil, 0)
+	if len(empty.Bins) != 10 || empty.SampleCount != 0 || empty.ExpectedCalibrationError != 0 {
+		t.Errorf("empty curve = %+v, want 10 empty bins", empty)
+	}
+	for _, bin := range empty.Bins {
+		if bin.Count != 0 || bin.MeanConfidence != 0 || bin.AcceptanceRate != 0 {
+			t.Errorf("empty bin = %+v", bin)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Appointment states
+const (
+	AppointmentBooked    = "booked"
+	AppointmentCancelled = "cancelled"
+)
+
+// Booking rule defaults, overridden by APPOINTMENT_CANCEL_NOTICE (a Go
+// duration) and APPOINTMENT_MAX_RESCHEDULES
+const (
+	DefaultAppointmentCancelNotice   = 24 * time.Hour
+	DefaultAppointmentMaxReschedules = 2
+	maxSlotLength                    = 8 * time.Hour
+	calendarFeedWindow               = 90 * 24 * time.Hour // How far back a feed reaches
+)
+
+var slotModes = map[string]bool{"in-person": true, "video": true, "phone": true}
+
+// Appointment errors returned by the booking functions
+var (
+	ErrSlotNotFound          = errors.New("slot not found")
+	ErrSlotUnavailable       = errors.New("slot is already booked or has passed")
+	ErrSlotOverlap           = errors.New("slot overlaps another slot of the doctor")
+	ErrAppointmentNotFound   = errors.New("appointment not found")
+	ErrAppointmentConflict   = errors.New("patient already has an appointment at that time")
+	ErrAppointmentClosed     = errors.New("appointment is cancelled or has already started")
+	ErrReportNotReviewed     = errors.New("appointments must be booked against reports the doctor has reviewed")
+	ErrCancelNoticePassed    = errors.New("too late to change the appointment online - contact the clinic")
+	ErrRescheduleLimit       = errors.New("appointment has been rescheduled too many times")
+	ErrAppointmentNotAllowed = errors.New("access denied")
+)
+
+func appointmentCancelNotice() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("APPOINTMENT_CANCEL_NOTICE")); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultAppointmentCancelNotice
+}
+
+func appointmentMaxReschedules() int {
+	if n, err := strconv.Atoi(os.Getenv("APPOINTMENT_MAX_RESCHEDULES")); err == nil && n >= 0 {
+		return n
+	}
+	return DefaultAppointmentMaxReschedules
+}
+
+// overlaps reports whether [aStart, aEnd) and [bStart, bEnd) intersect
+func overlaps(aStart, aEnd, bStart, bEnd time.Time) bool {
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
+}
+
+// ValidateSlot returns a message describing why a slot can't be offered
+func ValidateSlot(slot *models.AvailabilitySlot, now time.Time) string {
+	switch {
+	case !slot.EndsAt.After(slot.StartsAt):
+		return "ends_at must be after starts_at"
+	case slot.EndsAt.Sub(slot.StartsAt) > maxSlotLength:
+		return "slots can't be longer than 8 hours"
+	case !slot.StartsAt.After(now):
+		return "slots must start in the future"
+	case !slotModes[slot.Mode]:
+		return "mode must be in-person, video or phone"
+	}
+	return ""
+}
+
+// CreateSlots offers new availability for a doctor. Slots may not overlap
+// each other or the doctor's existing slots.
+func CreateSlots(doctorID primitive.ObjectID, slots []models.AvailabilitySlot) error {
+	if len(slots) == 0 {
+		return nil
+	}
+	first, last := slots[0].StartsAt, slots[0].EndsAt
+	for i := range slots {
+		for j := i + 1; j < len(slots); j++ {
+			if overlaps(slots[i].StartsAt, slots[i].EndsAt, slots[j].StartsAt, slots[j].EndsAt) {
+				return ErrSlotOverlap
+			}
+		}
+		if slots[i].StartsAt.Before(first) {
+			first = slots[i].StartsAt
+		}
+		if slots[i].EndsAt.After(last) {
+			last = slots[i].EndsAt
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := config.GetCollection("availability_slots")
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"doctor_id": doctorID,
+		"starts_at": bson.M{"$lt": last},
+		"ends_at":   bson.M{"$gt": first},
+	})
+	if err != nil {
+		return err
+	}
+	var existing []models.AvailabilitySlot
+	if err := cursor.All(ctx, &existing); err != nil {
+		return err
+	}
+	for i := range slots {
+		for j := range existing {
+			if overlaps(slots[i].StartsAt, slots[i].EndsAt, existing[j].StartsAt, existing[j].EndsAt) {
+				return ErrSlotOverlap
+			}
+		}
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(slots))
+	for i := range slots {
+		slots[i].ID = primitive.NewObjectID()
+		slots[i].DoctorID = doctorID
+		slots[i].AppointmentID = nil
+		slots[i].CreatedAt = now
+		docs[i] = slots[i]
+	}
+	_, err = collection.InsertMany(ctx, docs)
+	return err
+}
+
+// claimSlot marks a free future slot as booked by an appointment
+func claimSlot(ctx context.Context, slotID, appointmentID primitive.ObjectID) (*models.AvailabilitySlot, error) {
+	var slot models.AvailabilitySlot
+	err := config.GetCollection("availability_slots").FindOneAndUpdate(ctx,
+		bson.M{"_id": slotID, "appointment_id": bson.M{"$exists": false}, "starts_at": bson.M{"$gt": time.Now()}},
+		bson.M{"$set": bson.M{"appointment_id": appointmentID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&slot)
+	if err == mongo.ErrNoDocuments {
+		if n, _ := config.GetCollection("availability_slots").CountDocuments(ctx, bson.M{"_id": slotID}); n == 0 {
+			return nil, ErrSlotNotFound
+		}
+		return nil, ErrSlotUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+// releaseSlot frees a slot held by an appointment
+func releaseSlot(ctx context.Context, slotID, appointmentID primitive.ObjectID) {
+	_, err := config.GetCollection("availability_slots").UpdateOne(ctx,
+		bson.M{"_id": slotID, "appointment_id": appointmentID},
+		bson.M{"$unset": bson.M{"appointment_id": ""}},
+	)
+	if err != nil {
+		log.Printf("appointments: failed to release slot %s: %v", slotID.Hex(), err)
+	}
+}
+
+// patientHasConflict reports whether the patient has another booked
+// appointment overlapping the period
+func patientHasConflict(ctx context.Context, patientID primitive.ObjectID, start, end time.Time, except primitive.ObjectID) (bool, error) {
+	n, err := config.GetCollection("appointments").CountDocuments(ctx, bson.M{
+		"_id":        bson.M{"$ne": except},
+		"patient_id": patientID,
+		"status":     AppointmentBooked,
+		"starts_at":  bson.M{"$lt": end},
+		"ends_at":    bson.M{"$gt": start},
+	})
+	return n > 0, err
+}
+
+// checkBookingReports verifies the reports belong to the patient and that the
+// doctor reviewed at least one of them
+func checkBookingReports(ctx context.Context, patientID, doctorID primitive.ObjectID, reportIDs []primitive.ObjectID) error {
+	cursor, err := config.GetCollection("reports").Find(ctx, bson.M{"_id": bson.M{"$in": reportIDs}})
+	if err != nil {
+		return err
+	}
+	var reports []models.Report
+	if err := cursor.All(ctx, &reports); err != nil {
+		return err
+	}
+	if len(reports) != len(reportIDs) {
+		return fmt.Errorf("%w: a report was not found", ErrReportNotReviewed)
+	}
+	reviewedByDoctor := false
+	for _, report := range reports {
+		if report.PatientID != patientID {
+			return ErrAppointmentNotAllowed
+		}
+		if report.DoctorReview == nil {
+			return fmt.Errorf("%w: report %s has not been reviewed", ErrReportNotReviewed, report.ID.Hex())
+		}
+		reviewedByDoctor = reviewedByDoctor || report.DoctorReview.ReviewedBy == doctorID
+	}
+	if !reviewedByDoctor {
+		return fmt.Errorf("%w: the doctor reviewed none of the reports", ErrReportNotReviewed)
+	}
+	return nil
+}
+
+// BookAppointment books a free slot for a patient about their reviewed reports
+func BookAppointment(patientID, slotID primitive.ObjectID, reportIDs []primitive.ObjectID, reason string) (*models.Appointment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var slot models.AvailabilitySlot
+	if err := config.GetCollection("availability_slots").FindOne(ctx, bson.M{"_id": slotID}).Decode(&slot); err != nil {
+		return nil, ErrSlotNotFound
+	}
+	if err := checkBookingReports(ctx, patientID, slot.DoctorID, reportIDs); err != nil {
+		return nil, err
+	}
+	conflict, err := patientHasConflict(ctx, patientID, slot.StartsAt, slot.EndsAt, primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if conflict {
+		return nil, ErrAppointmentConflict
+	}
+
+	appointmentID := primitive.NewObjectID()
+	claimed, err := claimSlot(ctx, slotID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	appointment := &models.Appointment{
+		ID:        appointmentID,
+		DoctorID:  claimed.DoctorID,
+		PatientID: patientID,
+		SlotID:    claimed.ID,
+		ReportIDs: reportIDs,
+		StartsAt:  claimed.StartsAt,
+		EndsAt:    claimed.EndsAt,
+		Mode:      claimed.Mode,
+		Location:  claimed.Location,
+		Reason:    reason,
+		Status:    AppointmentBooked,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := config.GetCollection("appointments").InsertOne(ctx, appointment); err != nil {
+		releaseSlot(ctx, claimed.ID, appointmentID)
+		return nil, err
+	}
+
+	EnsureCareRelationship(appointment.DoctorID, patientID, models.CareRelationshipSource{
+		Type: CareSourceAppointment, RefID: &appointment.ID, ByID: &patientID, ByType: RecipientPatient,
+	}, nil)
+	notifyAppointment(appointment, appointment.DoctorID, RecipientDoctor, "New appointment booked",
+		fmt.Sprintf("A patient booked a %s consultation on %s.", appointment.Mode, appointment.StartsAt.Format("2 Jan 2006 15:04")))
+	return appointment, nil
+}
+
+// loadAppointment returns a booked, future appointment the actor may change
+func loadAppointment(ctx context.Context, appointmentID, actorID primitive.ObjectID, actorType string) (*models.Appointment, error) {
+	var appointment models.Appointment
+	if err := config.GetCollection("appointments").FindOne(ctx, bson.M{"_id": appointmentID}).Decode(&appointment); err != nil {
+		return nil, ErrAppointmentNotFound
+	}
+	if (actorType == RecipientPatient && appointment.PatientID != actorID) ||
+		(actorType == RecipientDoctor && appointment.DoctorID != actorID) {
+		return nil, ErrAppointmentNotAllowed
+	}
+	if appointment.Status != AppointmentBooked || !appointment.StartsAt.After(time.Now()) {
+		return nil, ErrAppointmentClosed
+	}
+	return &appointment, nil
+}
+
+// CancelAppointment cancels a booked appointment and frees its slot. Patients
+// must give the configured notice; doctors may cancel at any time.
+func CancelAppointment(appointmentID, actorID primitive.ObjectID, actorType, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	appointment, err := loadAppointment(ctx, appointmentID, actorID, actorType)
+	if err != nil {
+		return err
+	}
+	if actorType == RecipientPatient && time.Until(appointment.StartsAt) < appointmentCancelNotice() {
+		return ErrCancelNoticePassed
+	}
+
+	now := time.Now()
+	result, err := config.GetCollection("appointments").UpdateOne(ctx,
+		bson.M{"_id": appointmentID, "status": AppointmentBooked},
+		bson.M{
+			"$set": bson.M{
+				"status":        AppointmentCancelled,
+				"cancelled_by":  actorType,
+				"cancel_reason": reason,
+				"cancelled_at":  now,
+				"updated_at":    now,
+			},
+			"$inc": bson.M{"sequence": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAppointmentClosed
+	}
+	releaseSlot(ctx, appointment.SlotID, appointment.ID)
+
+	body := fmt.Sprintf("The appointment on %s was cancelled.", appointment.StartsAt.Format("2 Jan 2006 15:04"))
+	if reason != "" {
+		body += " Reason: " + reason
+	}
+	if actorType == RecipientPatient {
+		notifyAppointment(appointment, appointment.DoctorID, RecipientDoctor, "Appointment cancelled", body)
+	} else {
+		notifyAppointment(appointment, appointment.PatientID, RecipientPatient, "Appointment cancelled", body)
+	}
+	return nil
+}
+
+// RescheduleAppointment moves a patient's appointment to another free slot of
+// the same doctor, within the notice and reschedule limits
+func RescheduleAppointment(appointmentID, patientID, slotID primitive.ObjectID) (*models.Appointment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	appointment, err := loadAppointment(ctx, appointmentID, patientID, RecipientPatient)
+	if err != nil {
+		return nil, err
+	}
+	if time.Until(appointment.StartsAt) < appointmentCancelNotice() {
+		return nil, ErrCancelNoticePassed
+	}
+	if appointment.RescheduleCount >= appointmentMaxReschedules() {
+		return nil, ErrRescheduleLimit
+	}
+
+	var slot models.AvailabilitySlot
+	if err := config.GetCollection("availability_slots").FindOne(ctx, bson.M{"_id": slotID}).Decode(&slot); err != nil {
+		return nil, ErrSlotNotFound
+	}
+	if slot.DoctorID != appointment.DoctorID {
+		return nil, fmt.Errorf("%w: slot belongs to another doctor", ErrSlotUnavailable)
+	}
+	conflict, err := patientHasConflict(ctx, patientID, slot.StartsAt, slot.EndsAt, appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if conflict {
+		return nil, ErrAppointmentConflict
+	}
+
+	claimed, err := claimSlot(ctx, slotID, appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	previousStart := appointment.StartsAt
+	result, err := config.GetCollection("appointments").UpdateOne(ctx,
+		bson.M{"_id": appointment.ID, "status": AppointmentBooked, "slot_id": appointment.SlotID},
+		bson.M{
+			"$set": bson.M{
+				"slot_id":    claimed.ID,
+				"starts_at":  claimed.StartsAt,
+				"ends_at":    claimed.EndsAt,
+				"mode":       claimed.Mode,
+				"location":   claimed.Location,
+				"updated_at": time.Now(),
+			},
+			"$inc": bson.M{"reschedule_count": 1, "sequence": 1},
+		},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		releaseSlot(ctx, claimed.ID, appointment.ID)
+		if err == nil {
+			err = ErrAppointmentClosed
+		}
+		return nil, err
+	}
+	releaseSlot(ctx, appointment.SlotID, appointment.ID)
+
+	appointment.SlotID, appointment.StartsAt, appointment.EndsAt = claimed.ID, claimed.StartsAt, claimed.EndsAt
+	appointment.Mode, appointment.Location = claimed.Mode, claimed.Location
+	appointment.RescheduleCount++
+	appointment.Sequence++
+	notifyAppointment(appointment, appointment.DoctorID, RecipientDoctor, "Appointment rescheduled",
+		fmt.Sprintf("An appointment moved from %s to %s.", previousStart.Format("2 Jan 2006 15:04"), appointment.StartsAt.Format("2 Jan 2006 15:04")))
+	return appointment, nil
+}
+
+// notifyAppointment tells one party about a change to an appointment
+func notifyAppointment(appointment *models.Appointment, userID primitive.ObjectID, userType, title, body string) {
+	n := &models.Notification{
+		UserID:   userID,
+		UserType: userType,
+		Event:    EventAppointment,
+		Title:    title,
+		Body:     body,
+	}
+	if len(appointment.ReportIDs) > 0 {
+		n.ReportID = &appointment.ReportIDs[0]
+	}
+	Notify(n)
+}
+
+// CalendarFeedToken returns the doctor's calendar feed token, creating it on
+// first use or replacing it when rotate is set so old subscriptions stop working
+func CalendarFeedToken(doctorID primitive.ObjectID, rotate bool) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := config.GetCollection("calendar_feeds")
+
+	if !rotate {
+		var feed models.CalendarFeed
+		err := collection.FindOne(ctx, bson.M{"doctor_id": doctorID}).Decode(&feed)
+		if err == nil {
+			return feed.Token, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return "", err
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"doctor_id": doctorID},
+		bson.M{"$set": bson.M{"token": token, "created_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CalendarFeedDoctor returns the doctor a feed token belongs to
+func CalendarFeedDoctor(token string) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var feed models.CalendarFeed
+	if err := config.GetCollection("calendar_feeds").FindOne(ctx, bson.M{"token": token}).Decode(&feed); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return feed.DoctorID, nil
+}
+
+// CalendarFeedAppointments returns a doctor's appointments from the feed
+// window onwards, cancelled ones included
+func CalendarFeedAppointments(doctorID primitive.ObjectID) ([]models.Appointment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cursor, err := config.GetCollection("appointments").Find(ctx,
+		bson.M{"doctor_id": doctorID, "starts_at": bson.M{"$gte": time.Now().Add(-calendarFeedWindow)}},
+		options.Find().SetSort(bson.M{"starts_at": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	appointments := []models.Appointment{}
+	err = cursor.All(ctx, &appointments)
+	return appointments, err
+}
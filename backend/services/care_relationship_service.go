@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Care relationship sources
+const (
+	CareSourceReferral    = "referral"
+	CareSourceAppointment = "appointment"
+	CareSourceAssignment  = "assignment"
+	CareSourceAdmin       = "admin"
+)
+
+// Care relationship states
+const (
+	CareRelationshipActive = "active"
+	CareRelationshipEnded  = "ended"
+)
+
+// DefaultBreakGlassDuration is how long an emergency grant lasts, overridden
+// by BREAK_GLASS_DURATION (a Go duration)
+const DefaultBreakGlassDuration = time.Hour
+
+// MinBreakGlassReason is the shortest justification accepted for break-glass
+// access
+const MinBreakGlassReason = 20
+
+// ErrNoCareRelationship is returned when a doctor has neither a care
+// relationship with a patient nor an active break-glass grant
+var ErrNoCareRelationship = errors.New("no care relationship with this patient")
+
+func breakGlassDuration() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("BREAK_GLASS_DURATION")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultBreakGlassDuration
+}
+
+// activeRelationshipFilter matches relationships in force now
+func activeRelationshipFilter(now time.Time) bson.M {
+	return bson.M{
+		"status": CareRelationshipActive,
+		"$or": []bson.M{
+			{"ends_at": bson.M{"$exists": false}},
+			{"ends_at": bson.M{"$gt": now}},
+		},
+	}
+}
+
+// EnsureCareRelationship records that a doctor cares for a patient, adding
+// the source to an existing active relationship or starting a new one.
+// endsAt limits the relationship; nil leaves it open-ended.
+func EnsureCareRelationship(doctorID, patientID primitive.ObjectID, source models.CareRelationshipSource, endsAt *time.Time) {
+	if doctorID.IsZero() || patientID.IsZero() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := config.GetCollection("care_relationships")
+
+	now := time.Now()
+	source.At = now
+	key := bson.M{"doctor_id": doctorID, "patient_id": patientID, "status": CareRelationshipActive}
+
+	var existing models.CareRelationship
+	if err := collection.FindOne(ctx, key).Decode(&existing); err == nil {
+		for _, s := range existing.Sources {
+			if s.Type == source.Type && s.RefID != nil && source.RefID != nil && *s.RefID == *source.RefID {
+				return
+			}
+		}
+	}
+
+	update := bson.M{
+		"$setOnInsert": bson.M{"created_at": now},
+		"$set":         bson.M{"updated_at": now},
+		"$push":        bson.M{"sources": source},
+	}
+	if endsAt != nil {
+		update["$set"].(bson.M)["ends_at"] = *endsAt
+	} else {
+		update["$unset"] = bson.M{"ends_at": ""}
+	}
+	if _, err := collection.UpdateOne(ctx, key, update, options.Update().SetUpsert(true)); err != nil {
+		log.Printf("care relationships: failed to record %s for doctor %s and patient %s: %v", source.Type, doctorID.Hex(), patientID.Hex(), err)
+	}
+}
+
+// HasCareRelationship reports whether a doctor has an active relationship
+// with a patient
+func HasCareRelationship(ctx context.Context, doctorID, patientID primitive.ObjectID) (bool, error) {
+	filter := activeRelationshipFilter(time.Now())
+	filter["doctor_id"], filter["patient_id"] = doctorID, patientID
+	n, err := config.GetCollection("care_relationships").CountDocuments(ctx, filter)
+	return n > 0, err
+}
+
+// RelatedPatientIDs returns the patients a doctor has active relationships with
+func RelatedPatientIDs(ctx context.Context, doctorID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := activeRelationshipFilter(time.Now())
+	filter["doctor_id"] = doctorID
+	cursor, err := config.GetCollection("care_relationships").Find(ctx, filter, options.Find().SetProjection(bson.M{"patient_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var relationships []models.CareRelationship
+	if err := cursor.All(ctx, &relationships); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(relationships))
+	for i, r := range relationships {
+		ids[i] = r.PatientID
+	}
+	return ids, nil
+}
+
+// CareTeam returns the doctors with an active relationship to a patient
+func CareTeam(ctx context.Context, patientID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := activeRelationshipFilter(time.Now())
+	filter["patient_id"] = patientID
+	values, err := config.GetCollection("care_relationships").Distinct(ctx, "doctor_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	return objectIDs(values), nil
+}
+
+// patientsWithCareTeam returns every patient with at least one active relationship
+func patientsWithCareTeam(ctx context.Context) ([]primitive.ObjectID, error) {
+	values, err := config.GetCollection("care_relationships").Distinct(ctx, "patient_id", activeRelationshipFilter(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return objectIDs(values), nil
+}
+
+func objectIDs(values []interface{}) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// OnCallDoctors returns the doctors admins have put on call for triage
+func OnCallDoctors(ctx context.Context) ([]primitive.ObjectID, error) {
+	values, err := config.GetCollection("doctors").Distinct(ctx, "_id", bson.M{"on_call": true})
+	if err != nil {
+		return nil, err
+	}
+	return objectIDs(values), nil
+}
+
+// DoctorReportFilter limits a report query to the patients a doctor cares
+// for. On-call doctors also see reports of patients with no care team yet so
+// new patients can be triaged.
+func DoctorReportFilter(ctx context.Context, doctor *models.Doctor) (bson.M, error) {
+	related, err := RelatedPatientIDs(ctx, doctor.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !doctor.OnCall {
+		return bson.M{"patient_id": bson.M{"$in": related}}, nil
+	}
+	assigned, err := patientsWithCareTeam(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$or": []bson.M{
+		{"patient_id": bson.M{"$in": related}},
+		{"patient_id": bson.M{"$nin": assigned}},
+	}}, nil
+}
+
+// DoctorMayReview reports whether a doctor may read and review a patient's
+// reports: they care for the patient, or they are on call and the patient has
+// no care team yet. Break-glass grants do not allow reviews.
+func DoctorMayReview(ctx context.Context, doctor *models.Doctor, patientID primitive.ObjectID) (bool, error) {
+	related, err := HasCareRelationship(ctx, doctor.ID, patientID)
+	if err != nil || related || !doctor.OnCall {
+		return related, err
+	}
+	team, err := CareTeam(ctx, patientID)
+	if err != nil {
+		return false, err
+	}
+	return len(team) == 0, nil
+}
+
+// DoctorPatientAccess checks a doctor may read a patient's records. It
+// returns the break-glass grant being used when there is no relationship.
+func DoctorPatientAccess(ctx context.Context, doctorID, patientID primitive.ObjectID) (*models.BreakGlassAccess, error) {
+	related, err := HasCareRelationship(ctx, doctorID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if related {
+		return nil, nil
+	}
+
+	var grant models.BreakGlassAccess
+	err = config.GetCollection("break_glass_access").FindOne(ctx,
+		bson.M{"doctor_id": doctorID, "patient_id": patientID, "expires_at": bson.M{"$gt": time.Now()}},
+		options.FindOne().SetSort(bson.M{"expires_at": -1}),
+	).Decode(&grant)
+	if err != nil {
+		return nil, ErrNoCareRelationship
+	}
+	return &grant, nil
+}
+
+// RecordBreakGlassUse appends a read to a break-glass grant's log
+func RecordBreakGlassUse(grant *models.BreakGlassAccess, action string, reportID *primitive.ObjectID) {
+	if grant == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	use := models.BreakGlassUse{At: time.Now(), Action: action, ReportID: reportID}
+	if _, err := config.GetCollection("break_glass_access").UpdateOne(ctx,
+		bson.M{"_id": grant.ID},
+		bson.M{"$push": bson.M{"uses": use}},
+	); err != nil {
+		log.Printf("care relationships: failed to log break-glass use of %s: %v", grant.ID.Hex(), err)
+	}
+}
+
+// StartBreakGlass gives a doctor short-lived emergency access to a patient
+// and tells the patient and all admins
+func StartBreakGlass(doctor *models.Doctor, patientID primitive.ObjectID, reason string) (*models.BreakGlassAccess, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	grant := &models.BreakGlassAccess{
+		ID:        primitive.NewObjectID(),
+		DoctorID:  doctor.ID,
+		PatientID: patientID,
+		Reason:    reason,
+		StartedAt: now,
+		ExpiresAt: now.Add(breakGlassDuration()),
+		Uses:      []models.BreakGlassUse{},
+	}
+	if _, err := config.GetCollection("break_glass_access").InsertOne(ctx, grant); err != nil {
+		return nil, err
+	}
+
+	Notify(&models.Notification{
+		UserID:   patientID,
+		UserType: RecipientPatient,
+		Event:    EventBreakGlass,
+		Title:    "Emergency access to your records",
+		Body:     fmt.Sprintf("Dr. %s used emergency access to view your records. Reason given: %s", doctor.Name, reason),
+	})
+	NotifyAdmins(models.Notification{
+		Event: EventBreakGlass,
+		Title: "Break-glass access needs review",
+		Body:  fmt.Sprintf("Dr. %s used emergency access to patient %s. Reason given: %s", doctor.Name, patientID.Hex(), reason),
+	})
+	return grant, nil
+}
+
+// careRelationshipBackfill names the one-off migration in the migrations collection
+const careRelationshipBackfill = "care_relationships_backfill"
+
+// BackfillCareRelationships creates relationships from reviews, booked
+// appointments and second opinion requests made before relationships were
+// recorded. It runs once; later runs see the migration record and return.
+func BackfillCareRelationships() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	migrations := config.GetCollection("migrations")
+	if n, err := migrations.CountDocuments(ctx, bson.M{"_id": careRelationshipBackfill}); err != nil || n > 0 {
+		return err
+	}
+
+	cursor, err := config.GetCollection("reports").Find(ctx,
+		bson.M{"doctor_review.reviewed_by": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"patient_id": 1, "doctor_review.reviewed_by": 1}))
+	if err != nil {
+		return err
+	}
+	var reports []models.Report
+	if err := cursor.All(ctx, &reports); err != nil {
+		return err
+	}
+	for i := range reports {
+		r := &reports[i]
+		EnsureCareRelationship(r.DoctorReview.ReviewedBy, r.PatientID, models.CareRelationshipSource{
+			Type: CareSourceAssignment, RefID: &r.ID,
+		}, nil)
+	}
+
+	cursor, err = config.GetCollection("appointments").Find(ctx, bson.M{"status": AppointmentBooked})
+	if err != nil {
+		return err
+	}
+	var appointments []models.Appointment
+	if err := cursor.All(ctx, &appointments); err != nil {
+		return err
+	}
+	for i := range appointments {
+		a := &appointments[i]
+		EnsureCareRelationship(a.DoctorID, a.PatientID, models.CareRelationshipSource{
+			Type: CareSourceAppointment, RefID: &a.ID, ByID: &a.PatientID, ByType: RecipientPatient,
+		}, nil)
+	}
+
+	cursor, err = config.GetCollection("second_opinion_requests").Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var requests []models.SecondOpinionRequest
+	if err := cursor.All(ctx, &requests); err != nil {
+		return err
+	}
+	for i := range requests {
+		req := &requests[i]
+		var report models.Report
+		if err := config.GetCollection("reports").FindOne(ctx, bson.M{"_id": req.ReportID}).Decode(&report); err != nil {
+			continue
+		}
+		EnsureCareRelationship(req.ConsultantID, report.PatientID, models.CareRelationshipSource{
+			Type: CareSourceReferral, RefID: &req.ID, ByID: &req.RequestedBy, ByType: RecipientDoctor,
+		}, nil)
+	}
+
+	_, err = migrations.InsertOne(ctx, bson.M{"_id": careRelationshipBackfill, "applied_at": time.Now()})
+	log.Printf("care relationships: backfilled from %d reviews, %d appointments and %d second opinions",
+		len(reports), len(appointments), len(requests))
+	return err
+}
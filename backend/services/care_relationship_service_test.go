@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestActiveRelationshipFilter(t *testing.T) {
+	now := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	filter := activeRelationshipFilter(now)
+	if filter["status"] != CareRelationshipActive {
+		t.Errorf("status = %v, want %s", filter["status"], CareRelationshipActive)
+	}
+	or, ok := filter["$or"].([]bson.M)
+	if !ok || len(or) != 2 {
+		t.Fatalf("$or = %v, want open-ended or not yet ended", filter["$or"])
+	}
+	if or[1]["ends_at"].(bson.M)["$gt"] != now {
+		t.Errorf("end bound = %v, want after %v", or[1]["ends_at"], now)
+	}
+}
+
+func TestObjectIDs(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	got := objectIDs([]interface{}{a, "not an id", nil, b})
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("objectIDs() = %v, want [%s %s]", got, a.Hex(), b.Hex())
+	}
+	if got := objectIDs(nil); got == nil || len(got) != 0 {
+		t.Errorf("objectIDs(nil) = %#v, want an empty slice", got)
+	}
+}
+
+func TestBreakGlassDuration(t *testing.T) {
+	t.Setenv("BREAK_GLASS_DURATION", "30m")
+	if got := breakGlassDuration(); got != 30*time.Minute {
+		t.Errorf("breakGlassDuration() = %v, want 30m", got)
+	}
+	for _, value := range []string{"", "0s", "an hour"} {
+		t.Setenv("BREAK_GLASS_DURATION", value)
+		if got := breakGlassDuration(); got != DefaultBreakGlassDuration {
+			t.Errorf("BREAK_GLASS_DURATION=%q: breakGlassDuration() = %v, want the default", value, got)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Care task states
+const (
+	CareTaskOpen      = "open"
+	CareTaskScheduled = "scheduled"
+	CareTaskCompleted = "completed"
+	CareTaskDeclined  = "declined"
+	CareTaskCancelled = "cancelled" // Recommendation withdrawn by the reviewing doctor
+)
+
+// careTaskBySystem attributes automatic changes in a task's history
+const careTaskBySystem = "system"
+
+// careTaskDueWithin maps recommendation urgencies to the time allowed before
+// a task is due. Unknown urgencies get DefaultCareTaskDueWithin.
+var careTaskDueWithin = map[string]time.Duration{
+	"emergent":  24 * time.Hour,
+	"stat":      24 * time.Hour,
+	"immediate": 24 * time.Hour,
+	"urgent":    7 * 24 * time.Hour,
+	"high":      7 * 24 * time.Hour,
+	"soon":      30 * 24 * time.Hour,
+	"moderate":  30 * 24 * time.Hour,
+	"routine":   90 * 24 * time.Hour,
+	"low":       90 * 24 * time.Hour,
+}
+
+// Reminder defaults, overridden by CARE_TASK_REMINDER_LEAD and
+// CARE_TASK_OVERDUE_REMINDER (Go durations) and CARE_TASK_MAX_REMINDERS
+const (
+	DefaultCareTaskDueWithin       = 30 * 24 * time.Hour
+	DefaultCareTaskReminderLead    = 24 * time.Hour
+	DefaultCareTaskOverdueInterval = 72 * time.Hour
+	DefaultCareTaskMaxReminders    = 6
+)
+
+// CareTaskDueDate returns when a task of the given urgency created at from is due
+func CareTaskDueDate(urgency string, from time.Time) time.Time {
+	if within, ok := careTaskDueWithin[strings.ToLower(strings.TrimSpace(urgency))]; ok {
+		return from.Add(within)
+	}
+	return from.Add(DefaultCareTaskDueWithin)
+}
+
+func careTaskReminderLead() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("CARE_TASK_REMINDER_LEAD")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultCareTaskReminderLead
+}
+
+func careTaskOverdueInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("CARE_TASK_OVERDUE_REMINDER")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultCareTaskOverdueInterval
+}
+
+func careTaskMaxReminders() int {
+	if n, err := strconv.Atoi(os.Getenv("CARE_TASK_MAX_REMINDERS")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultCareTaskMaxReminders
+}
+
+// NextCareTaskReminder returns when an active task should next be reminded
+// about, or nil when it should not be. Reminders go out a lead time before
+// the task is due (or scheduled), on the day itself, then at the overdue
+// interval until the task closes or runs out of reminders.
+func NextCareTaskReminder(task *models.CareTask, now time.Time) *time.Time {
+	if task.Status != CareTaskOpen && task.Status != CareTaskScheduled {
+		return nil
+	}
+	if task.ReminderCount >= careTaskMaxReminders() {
+		return nil
+	}
+
+	anchor := task.DueAt
+	if task.Status == CareTaskScheduled && task.ScheduledFor != nil {
+		anchor = *task.ScheduledFor
+	}
+	lead := anchor.Add(-careTaskReminderLead())
+
+	var next time.Time
+	switch {
+	case task.LastRemindedAt == nil || task.LastRemindedAt.Before(lead):
+		next = lead
+	case task.LastRemindedAt.Before(anchor):
+		next = anchor
+	default:
+		next = task.LastRemindedAt.Add(careTaskOverdueInterval())
+	}
+	if next.Before(now) {
+		next = now
+	}
+	return &next
+}
+
+// CareTaskTransitionAllowed reports whether a task may move between states.
+// Completed, declined and cancelled tasks are closed; a scheduled task may be
+// rescheduled or returned to open.
+func CareTaskTransitionAllowed(from, to string) bool {
+	switch from {
+	case CareTaskOpen:
+		return to == CareTaskScheduled || to == CareTaskCompleted || to == CareTaskDeclined
+	case CareTaskScheduled:
+		return to == CareTaskOpen || to == CareTaskScheduled || to == CareTaskCompleted || to == CareTaskDeclined
+	}
+	return false
+}
+
+// testCode returns the LOINC code of a recommended test when it codes with
+// enough confidence
+func testCode(test string) string {
+	coded := CodeEntity(TermCategoryTest, test)
+	if coded.Code == "" || coded.Confidence < TerminologyCodingThreshold {
+		return ""
+	}
+	return coded.Code
+}
+
+// ReportContainsTest reports whether a report shows a task's test was done:
+// an affirmed test entity or a lab observation matching the task's code or
+// naming the test. A test that is only ordered, planned or pending is
+// hypothetical and does not count.
+func ReportContainsTest(report *models.Report, task *models.CareTask) bool {
+	analysis := &report.AIAnalysis
+	for _, test := range analysis.Entities.Tests {
+		if status := EntityStatus(analysis, TermCategoryTest, test); status == AssertionNegated || status == AssertionHypothetical {
+			continue
+		}
+		if task.Code != "" {
+			if coded := EntityCoding(analysis, TermCategoryTest, test); coded != nil && coded.Code == task.Code {
+				return true
+			}
+		}
+		if termsMatch(test, []string{task.Test}) {
+			return true
+		}
+	}
+	for _, obs := range report.Observations {
+		if task.Code != "" && obs.Code == task.Code {
+			return true
+		}
+		if termsMatch(obs.Display, []string{task.Test}) {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncCareTasks updates a patient's care tasks for a newly saved report: it
+// completes earlier tasks whose test the report contains, then opens tasks
+// for the report's own recommendations.
+func SyncCareTasks(report *models.Report) {
+	if report.PatientID.IsZero() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := config.GetCollection("care_tasks")
+	cursor, err := collection.Find(ctx, bson.M{
+		"patient_id":     report.PatientID,
+		"status":         bson.M{"$in": []string{CareTaskOpen, CareTaskScheduled}},
+		"report_id":      bson.M{"$ne": report.ID},
+		"recommended_at": bson.M{"$lt": report.UploadedAt},
+	})
+	var active []models.CareTask
+	if err == nil {
+		err = cursor.All(ctx, &active)
+	}
+	if err != nil {
+		log.Printf("care tasks: failed to load tasks of patient %s: %v", report.PatientID.Hex(), err)
+		return
+	}
+
+	now := time.Now()
+	for i := range active {
+		task := &active[i]
+		if !ReportContainsTest(report, task) {
+			continue
+		}
+		reportID := report.ID
+		event := models.CareTaskEvent{At: now, Status: CareTaskCompleted, ByType: careTaskBySystem, ReportID: &reportID,
+			Note: "Test found in a later report"}
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": task.ID, "status": task.Status},
+			bson.M{
+				"$set":   bson.M{"status": CareTaskCompleted, "completed_at": now, "completed_by_report": reportID, "updated_at": now},
+				"$unset": bson.M{"next_reminder_at": ""},
+				"$push":  bson.M{"history": event},
+			},
+		)
+		if err != nil {
+			log.Printf("care tasks: failed to complete task %s: %v", task.ID.Hex(), err)
+		}
+	}
+
+	createCareTasks(ctx, report)
+}
+
+// createCareTasks opens a task per recommendation of the report. A test that
+// already has an active task is not duplicated; the existing task is brought
+// forward when the new recommendation is more urgent.
+func createCareTasks(ctx context.Context, report *models.Report) {
+	collection := config.GetCollection("care_tasks")
+	assignees := []models.CareTaskAssignee{{UserID: report.PatientID, UserType: RecipientPatient}}
+	if report.DoctorReview != nil && !report.DoctorReview.ReviewedBy.IsZero() {
+		assignees = append(assignees, models.CareTaskAssignee{UserID: report.DoctorReview.ReviewedBy, UserType: RecipientDoctor})
+	}
+
+	now := time.Now()
+	for _, rec := range report.AIAnalysis.Recommendations {
+		// Contraindicated tests are shown as "not recommended" and need no follow-up
+		if strings.EqualFold(rec.Urgency, "not recommended") || strings.TrimSpace(rec.Test) == "" {
+			continue
+		}
+		task := models.CareTask{
+			ID:            primitive.NewObjectID(),
+			PatientID:     report.PatientID,
+			ReportID:      report.ID,
+			Test:          rec.Test,
+			Code:          testCode(rec.Test),
+			Reason:        rec.Reason,
+			Urgency:       rec.Urgency,
+			RuleID:        rec.RuleID,
+			RecommendedAt: report.UploadedAt,
+			Assignees:     assignees,
+			Status:        CareTaskOpen,
+			DueAt:         CareTaskDueDate(rec.Urgency, now),
+			History:       []models.CareTaskEvent{{At: now, Status: CareTaskOpen, ByType: careTaskBySystem, ReportID: &report.ID}},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+
+		if existing := findActiveTask(ctx, &task); existing != nil {
+			if task.DueAt.Before(existing.DueAt) {
+				existing.DueAt = task.DueAt
+				existing.Urgency = task.Urgency
+				update := bson.M{"due_at": existing.DueAt, "urgency": existing.Urgency, "updated_at": now}
+				if next := NextCareTaskReminder(existing, now); next != nil {
+					update["next_reminder_at"] = *next
+				}
+				if _, err := collection.UpdateOne(ctx, bson.M{"_id": existing.ID}, bson.M{"$set": update}); err != nil {
+					log.Printf("care tasks: failed to bring task %s forward: %v", existing.ID.Hex(), err)
+				}
+			}
+			continue
+		}
+
+		task.NextReminderAt = NextCareTaskReminder(&task, now)
+		if _, err := collection.InsertOne(ctx, task); err != nil {
+			log.Printf("care tasks: failed to create task for %s on report %s: %v", rec.Test, report.ID.Hex(), err)
+			continue
+		}
+		notifyCareTask(&task, "New follow-up: "+task.Test,
+			fmt.Sprintf("%s was recommended (%s). Please arrange it by %s.", task.Test, task.Reason, task.DueAt.Format("2 Jan 2006")))
+	}
+}
+
+// findActiveTask returns the patient's open or scheduled task for the same test
+func findActiveTask(ctx context.Context, task *models.CareTask) *models.CareTask {
+	filter := bson.M{
+		"patient_id": task.PatientID,
+		"status":     bson.M{"$in": []string{CareTaskOpen, CareTaskScheduled}},
+	}
+	if task.Code != "" {
+		filter["$or"] = []bson.M{{"code": task.Code}, {"test": task.Test}}
+	} else {
+		filter["test"] = task.Test
+	}
+	var existing models.CareTask
+	if err := config.GetCollection("care_tasks").FindOne(ctx, filter).Decode(&existing); err != nil {
+		return nil
+	}
+	return &existing
+}
+
+// notifyCareTask sends a care task notification to each assignee
+func notifyCareTask(task *models.CareTask, title, body string) {
+	reportID := task.ReportID
+	for _, assignee := range task.Assignees {
+		Notify(&models.Notification{
+			UserID:   assignee.UserID,
+			UserType: assignee.UserType,
+			Event:    EventCareTask,
+			Title:    title,
+			Body:     body,
+			ReportID: &reportID,
+		})
+	}
+}
+
+// AssignCareTaskReviewer adds the reviewing doctor to the tasks created from
+// a report, so follow-up is shared once a doctor has looked at it
+func AssignCareTaskReviewer(reportID, doctorID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := config.GetCollection("care_tasks").UpdateMany(ctx,
+		bson.M{"report_id": reportID, "status": bson.M{"$in": []string{CareTaskOpen, CareTaskScheduled}}},
+		bson.M{"$addToSet": bson.M{"assignees": models.CareTaskAssignee{UserID: doctorID, UserType: RecipientDoctor}}},
+	)
+	if err != nil {
+		log.Printf("care tasks: failed to assign reviewer of report %s: %v", reportID.Hex(), err)
+	}
+}
+
+// EditedRecommendationTests returns the tests of the recommendations a doctor
+// kept in an edit, and false when the edit leaves recommendations unchanged.
+// Recommendations are given as objects with a "test" or as plain test names.
+func EditedRecommendationTests(fields map[string]interface{}) ([]string, bool) {
+	raw, ok := fields["recommendations"]
+	if !ok {
+		return nil, false
+	}
+	items, _ := raw.([]interface{})
+	tests := []string{}
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			tests = append(tests, v)
+		case map[string]interface{}:
+			if test, ok := v["test"].(string); ok {
+				tests = append(tests, test)
+			}
+		}
+	}
+	return tests, true
+}
+
+// CancelRemovedCareTasks cancels the active tasks of a report whose test is
+// no longer among its recommendations
+func CancelRemovedCareTasks(reportID, doctorID primitive.ObjectID, kept []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := config.GetCollection("care_tasks")
+	cursor, err := collection.Find(ctx, bson.M{
+		"report_id": reportID,
+		"status":    bson.M{"$in": []string{CareTaskOpen, CareTaskScheduled}},
+	})
+	var active []models.CareTask
+	if err == nil {
+		err = cursor.All(ctx, &active)
+	}
+	if err != nil {
+		log.Printf("care tasks: failed to load tasks of report %s: %v", reportID.Hex(), err)
+		return
+	}
+
+	now := time.Now()
+	for _, task := range active {
+		if termsMatch(task.Test, kept) {
+			continue
+		}
+		event := models.CareTaskEvent{At: now, Status: CareTaskCancelled, ByID: &doctorID, ByType: RecipientDoctor,
+			Note: "Recommendation removed by the reviewing doctor"}
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": task.ID, "status": task.Status},
+			bson.M{
+				"$set":   bson.M{"status": CareTaskCancelled, "updated_at": now},
+				"$unset": bson.M{"next_reminder_at": ""},
+				"$push":  bson.M{"history": event},
+			},
+		)
+		if err != nil {
+			log.Printf("care tasks: failed to cancel task %s: %v", task.ID.Hex(), err)
+		}
+	}
+}
+
+// RemindCareTask notifies the assignees of a task and schedules its next reminder
+func RemindCareTask(task *models.CareTask) {
+	now := time.Now()
+	var title, body string
+	switch {
+	case task.Status == CareTaskScheduled && task.ScheduledFor != nil:
+		title = "Upcoming: " + task.Test
+		body = fmt.Sprintf("%s is scheduled for %s.", task.Test, task.ScheduledFor.Format("2 Jan 2006 15:04"))
+	case now.After(task.DueAt):
+		title = "Overdue: " + task.Test
+		body = fmt.Sprintf("%s was due on %s. Schedule it, or decline it if it is no longer needed.", task.Test, task.DueAt.Format("2 Jan 2006"))
+	default:
+		title = "Reminder: " + task.Test
+		body = fmt.Sprintf("%s is due by %s.", task.Test, task.DueAt.Format("2 Jan 2006"))
+	}
+	notifyCareTask(task, title, body)
+
+	task.ReminderCount++
+	task.LastRemindedAt = &now
+	set := bson.M{"reminder_count": task.ReminderCount, "last_reminded_at": now, "updated_at": now}
+	update := bson.M{"$set": set}
+	if next := NextCareTaskReminder(task, now); next != nil {
+		set["next_reminder_at"] = *next
+	} else {
+		update["$unset"] = bson.M{"next_reminder_at": ""}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// The task may have closed while reminding; leave it closed
+	_, err := config.GetCollection("care_tasks").UpdateOne(ctx,
+		bson.M{"_id": task.ID, "status": task.Status}, update)
+	if err != nil {
+		log.Printf("care tasks: failed to record reminder of task %s: %v", task.ID.Hex(), err)
+	}
+}
+
+// RunCareTaskReminders sends due care task reminders
+func RunCareTaskReminders(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		cursor, err := config.GetCollection("care_tasks").Find(ctx, bson.M{
+			"status":           bson.M{"$in": []string{CareTaskOpen, CareTaskScheduled}},
+			"next_reminder_at": bson.M{"$lte": time.Now()},
+		})
+		var due []models.CareTask
+		if err == nil {
+			err = cursor.All(ctx, &due)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("care tasks: failed to load due reminders: %v", err)
+			continue
+		}
+		for i := range due {
+			RemindCareTask(&due[i])
+		}
+	}
+}
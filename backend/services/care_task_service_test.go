@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestReportContainsTest(t *testing.T) {
+	task := &models.CareTask{Test: "MRI"}
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"ordered", "MRI ordered for next week.", false},
+		{"pending", "MRI pending.", false},
+		{"awaiting results", "Awaiting MRI results.", false},
+		{"planned", "Plan: MRI recommended.", false},
+		{"scheduled", "MRI scheduled for Monday.", false},
+		{"negated", "No MRI was performed.", false},
+		{"resulted", "MRI brain showed no acute infarct.", true},
+		{"result and plan", "MRI brain: normal. Plan: repeat MRI recommended in 6 months.", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &models.Report{}
+			report.AIAnalysis.Entities.Tests = []string{"MRI"}
+			ApplyAssertions(&report.AIAnalysis, tt.text)
+			if got := ReportContainsTest(report, task); got != tt.want {
+				t.Errorf("ReportContainsTest(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("observation", func(t *testing.T) {
+		report := &models.Report{Observations: []models.LabObservation{{Display: "HbA1c"}}}
+		if !ReportContainsTest(report, &models.CareTask{Test: "HbA1c"}) {
+			t.Error("a matching lab observation should complete the task")
+		}
+	})
+}
+
+func TestEditedRecommendationTests(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   []string
+		edited bool
+	}{
+		{"not edited", map[string]interface{}{"notes": "ok"}, nil, false},
+		{"objects", map[string]interface{}{"recommendations": []interface{}{
+			map[string]interface{}{"test": "MRI", "reason": "headache"},
+		}}, []string{"MRI"}, true},
+		{"names", map[string]interface{}{"recommendations": []interface{}{"MRI", "CBC"}}, []string{"MRI", "CBC"}, true},
+		{"all removed", map[string]interface{}{"recommendations": []interface{}{}}, []string{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, edited := EditedRecommendationTests(tt.fields)
+			if edited != tt.edited || len(got) != len(tt.want) {
+				t.Fatalf("EditedRecommendationTests() = %v, %v, want %v, %v", got, edited, tt.want, tt.edited)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("test %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
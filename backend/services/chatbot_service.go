@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -55,8 +56,9 @@ func (s *ChatbotService) GetOrCreateSession(patientID, reportID primitive.Object
 	return &session, nil
 }
 
-// ProcessMessage handles a user message and returns AI response
-func (s *ChatbotService) ProcessMessage(sessionID, reportID, userMessage string) (string, error) {
+// ProcessMessage handles a user message and returns AI response. sentBy
+// records whether the patient or a delegate wrote the message.
+func (s *ChatbotService) ProcessMessage(sessionID, reportID, userMessage string, sentBy *models.Actor) (string, error) {
 	// Get the report to extract context
 	report, err := GetReportByID(reportID)
 	if err != nil {
@@ -81,10 +83,16 @@ func (s *ChatbotService) ProcessMessage(sessionID, reportID, userMessage string)
 	// Build conversation history
 	conversationHistory := s.buildConversationHistory(session.Messages)
 
-	// Call Azure OpenAI/GPT API
-	aiResponse, err := s.callAzureGPT(context, conversationHistory, userMessage)
-	if err != nil {
-		return "", fmt.Errorf("failed to get AI response: %w", err)
+	// Call Azure OpenAI/GPT API, or answer from local rules when the patient
+	// has not consented to cloud chat
+	var aiResponse string
+	if s.cloudChatAllowed(ctx, report.PatientID) {
+		aiResponse, err = s.callAzureGPT(report, context, conversationHistory, userMessage)
+		if err != nil {
+			return "", fmt.Errorf("failed to get AI response: %w", err)
+		}
+	} else {
+		aiResponse = s.generateSimpleResponse(context, userMessage)
 	}
 
 	// Update session with new messages
@@ -93,6 +101,7 @@ func (s *ChatbotService) ProcessMessage(sessionID, reportID, userMessage string)
 			Role:      "user",
 			Content:   userMessage,
 			Timestamp: time.Now(),
+			SentBy:    sentBy,
 		},
 		models.ChatMessage{
 			Role:      "assistant",
@@ -114,24 +123,38 @@ func (s *ChatbotService) ProcessMessage(sessionID, reportID, userMessage string)
 	return aiResponse, err
 }
 
+// cloudChatAllowed reports whether a patient's messages may be sent to the
+// cloud LLM. A failed consent lookup keeps the conversation local.
+func (s *ChatbotService) cloudChatAllowed(ctx context.Context, patientID primitive.ObjectID) bool {
+	consented, err := HasConsent(ctx, patientID, ConsentCloudChat)
+	if err != nil {
+		log.Printf("chatbot: failed to check cloud chat consent of %s: %v", patientID.Hex(), err)
+		return false
+	}
+	return consented
+}
+
 // buildReportContext creates a summary of the report for AI context
 func (s *ChatbotService) buildReportContext(report *models.Report) string {
 	var builder strings.Builder
 
 	builder.WriteString("Medical Report Summary:\n\n")
 
-	// Entities
-	if len(report.AIAnalysis.Entities.Symptoms) > 0 {
-		builder.WriteString(fmt.Sprintf("Symptoms: %s\n", strings.Join(report.AIAnalysis.Entities.Symptoms, ", ")))
-	}
-	if len(report.AIAnalysis.Entities.Diagnoses) > 0 {
-		builder.WriteString(fmt.Sprintf("Diagnoses: %s\n", strings.Join(report.AIAnalysis.Entities.Diagnoses, ", ")))
-	}
-	if len(report.AIAnalysis.Entities.Medications) > 0 {
-		builder.WriteString(fmt.Sprintf("Medications: %s\n", strings.Join(report.AIAnalysis.Entities.Medications, ", ")))
-	}
-	if len(report.AIAnalysis.Entities.Tests) > 0 {
-		builder.WriteString(fmt.Sprintf("Tests: %s\n", strings.Join(report.AIAnalysis.Entities.Tests, ", ")))
+	// Entities, leaving out negated findings
+	analysis := &report.AIAnalysis
+	for _, group := range []struct {
+		label    string
+		category string
+		values   []string
+	}{
+		{"Symptoms", TermCategorySymptom, analysis.Entities.Symptoms},
+		{"Diagnoses", TermCategoryDiagnosis, analysis.Entities.Diagnoses},
+		{"Medications", TermCategoryMedication, analysis.Entities.Medications},
+		{"Tests", TermCategoryTest, analysis.Entities.Tests},
+	} {
+		if values := contextEntities(analysis, group.category, group.values); len(values) > 0 {
+			builder.WriteString(fmt.Sprintf("%s: %s\n", group.label, strings.Join(values, ", ")))
+		}
 	}
 
 	// Recommendations
@@ -139,7 +162,7 @@ func (s *ChatbotService) buildReportContext(report *models.Report) string {
 		builder.WriteString("\nRecommended Tests:\n")
 		for i, rec := range report.AIAnalysis.Recommendations {
 			builder.WriteString(fmt.Sprintf("%d. %s - %s (Confidence: %.0f%%, Urgency: %s)\n",
-				i+1, rec.Test, rec.Reason, rec.Confidence*100, rec.Urgency))
+				i+1, rec.Test, rec.Reason, rec.Confidence, rec.Urgency))
 		}
 	}
 
@@ -148,6 +171,22 @@ func (s *ChatbotService) buildReportContext(report *models.Report) string {
 	return builder.String()
 }
 
+// contextEntities drops negated entities and marks historical, hypothetical
+// and family-history ones, e.g. "stroke (historical)"
+func contextEntities(analysis *models.AIAnalysis, category string, values []string) []string {
+	var entities []string
+	for _, value := range values {
+		switch status := EntityStatus(analysis, category, value); status {
+		case AssertionNegated:
+		case AssertionAffirmed:
+			entities = append(entities, value)
+		default:
+			entities = append(entities, fmt.Sprintf("%s (%s)", value, status))
+		}
+	}
+	return entities
+}
+
 // buildConversationHistory formats previous messages for AI
 func (s *ChatbotService) buildConversationHistory(messages []models.ChatMessage) []map[string]string {
 	var history []map[string]string
@@ -160,8 +199,10 @@ func (s *ChatbotService) buildConversationHistory(messages []models.ChatMessage)
 	return history
 }
 
-// callAzureGPT makes a request to Azure OpenAI API
-func (s *ChatbotService) callAzureGPT(reportContext string, conversationHistory []map[string]string, userMessage string) (string, error) {
+// callAzureGPT makes a request to Azure OpenAI API. Unless PHI redaction is
+// disabled, PHI in the context, history and message is replaced by placeholders
+// before the request and restored in the reply.
+func (s *ChatbotService) callAzureGPT(report *models.Report, reportContext string, conversationHistory []map[string]string, userMessage string) (string, error) {
 	apiKey := os.Getenv("AZURE_OPENAI_KEY")
 	endpoint := os.Getenv("AZURE_OPENAI_ENDPOINT")
 	deployment := os.Getenv("AZURE_OPENAI_DEPLOYMENT")
@@ -173,6 +214,28 @@ func (s *ChatbotService) callAzureGPT(reportContext string, conversationHistory
 
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=2023-05-15", endpoint, deployment)
 
+	// One redactor for the whole request so the model sees consistent placeholders
+	var redactor *PHIRedactor
+	if PHIRedactionEnabled() {
+		redactor = NewPHIRedactor(PHIKnownNames(report.PatientID))
+		reportContext = redactor.Redact(reportContext)
+		redactedHistory := make([]map[string]string, 0, len(conversationHistory))
+		for _, msg := range conversationHistory {
+			redactedHistory = append(redactedHistory, map[string]string{
+				"role":    msg["role"],
+				"content": redactor.Redact(msg["content"]),
+			})
+		}
+		conversationHistory = redactedHistory
+		userMessage = redactor.Redact(userMessage)
+	}
+	auditReport := func() *models.RedactionReport {
+		audit := redactor.Report("azure-openai", "chat")
+		audit.ReportID = &report.ID
+		audit.PatientID = &report.PatientID
+		return audit
+	}
+
 	// Build messages array
 	messages := []map[string]string{
 		{
@@ -204,9 +267,15 @@ func (s *ChatbotService) callAzureGPT(reportContext string, conversationHistory
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("api-key", apiKey)
+	if redactor != nil {
+		SetRedactionHeaders(req, auditReport())
+	}
 
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
+	if redactor != nil {
+		defer func() { SaveRedactionReport(auditReport()) }()
+	}
 	if err != nil {
 		return "", err
 	}
@@ -232,6 +301,9 @@ func (s *ChatbotService) callAzureGPT(reportContext string, conversationHistory
 		return "", fmt.Errorf("no response from AI")
 	}
 
+	if redactor != nil {
+		return redactor.Reidentify(response.Choices[0].Message.Content), nil
+	}
 	return response.Choices[0].Message.Content, nil
 }
 
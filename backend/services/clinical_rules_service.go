@@ -0,0 +1,665 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRulesReloadInterval is how often the rules file is checked for changes
+// unless CLINICAL_RULES_RELOAD overrides it
+const DefaultRulesReloadInterval = 30 * time.Second
+
+// maxPriorReports bounds how many earlier reports prior conditions look at
+const maxPriorReports = 200
+
+// Urgencies a rule may assign; "not recommended" is reserved for contraindications
+var ruleUrgencies = map[string]bool{"routine": true, "urgent": true, "emergent": true}
+
+var ruleLabOps = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+}
+
+//go:embed rules/default.yaml
+var bundledRules []byte
+
+// ClinicalRulesEngine holds the active rule set. It is loaded from
+// CLINICAL_RULES_PATH when set, otherwise from the bundled rules, and a failed
+// reload keeps the previous rule set in place.
+type ClinicalRulesEngine struct {
+	mu      sync.RWMutex
+	path    string
+	modTime time.Time
+	set     *models.ClinicalRuleSet
+	status  models.ClinicalRuleSetStatus
+}
+
+var (
+	clinicalRulesOnce   sync.Once
+	clinicalRulesEngine *ClinicalRulesEngine
+)
+
+// ClinicalRules returns the shared rules engine, loading it on first use
+func ClinicalRules() *ClinicalRulesEngine {
+	clinicalRulesOnce.Do(func() {
+		clinicalRulesEngine = &ClinicalRulesEngine{path: os.Getenv("CLINICAL_RULES_PATH")}
+		if err := clinicalRulesEngine.Reload(); err != nil {
+			log.Printf("clinical rules: %v; falling back to bundled rules", err)
+			set, _ := ParseRuleSet(bundledRules)
+			clinicalRulesEngine.set = set
+			clinicalRulesEngine.status.Name = set.Name
+			clinicalRulesEngine.status.Version = set.Version
+			clinicalRulesEngine.status.Source = "bundled"
+			clinicalRulesEngine.status.RuleCount = len(set.Rules)
+			clinicalRulesEngine.status.LoadedAt = time.Now()
+		}
+	})
+	return clinicalRulesEngine
+}
+
+// Reload reads and validates the rule set. On error the current rule set is
+// kept and the error recorded in the status.
+func (e *ClinicalRulesEngine) Reload() error {
+	data, source := bundledRules, "bundled"
+	var modTime time.Time
+	if e.path != "" {
+		info, err := os.Stat(e.path)
+		if err != nil {
+			return e.reloadFailed(fmt.Errorf("cannot read rules file: %w", err))
+		}
+		if data, err = os.ReadFile(e.path); err != nil {
+			return e.reloadFailed(fmt.Errorf("cannot read rules file: %w", err))
+		}
+		source, modTime = e.path, info.ModTime()
+	}
+
+	set, err := ParseRuleSet(data)
+	if err != nil {
+		return e.reloadFailed(err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.set = set
+	e.modTime = modTime
+	e.status = models.ClinicalRuleSetStatus{
+		Name:      set.Name,
+		Version:   set.Version,
+		Source:    source,
+		RuleCount: len(set.Rules),
+		LoadedAt:  time.Now(),
+	}
+	return nil
+}
+
+func (e *ClinicalRulesEngine) reloadFailed(err error) error {
+	now := time.Now()
+	e.mu.Lock()
+	e.status.LastError = err.Error()
+	e.status.LastFailAt = &now
+	e.mu.Unlock()
+	return err
+}
+
+// Watch polls the rules file and reloads it when its modification time
+// changes. It returns immediately when rules are bundled.
+func (e *ClinicalRulesEngine) Watch(interval time.Duration) {
+	if e.path == "" {
+		return
+	}
+	for range time.Tick(interval) {
+		info, err := os.Stat(e.path)
+		if err != nil {
+			continue
+		}
+		e.mu.RLock()
+		changed := !info.ModTime().Equal(e.modTime)
+		e.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := e.Reload(); err != nil {
+			log.Printf("clinical rules: reload failed, keeping previous rules: %v", err)
+			// Don't retry the same broken file every tick
+			e.mu.Lock()
+			e.modTime = info.ModTime()
+			e.mu.Unlock()
+			continue
+		}
+		status := e.Status()
+		log.Printf("clinical rules: reloaded %s@%s (%d rules)", status.Name, status.Version, status.RuleCount)
+	}
+}
+
+// RulesReloadInterval reads CLINICAL_RULES_RELOAD (a Go duration)
+func RulesReloadInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("CLINICAL_RULES_RELOAD")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultRulesReloadInterval
+}
+
+// RuleSet returns the active rule set
+func (e *ClinicalRulesEngine) RuleSet() *models.ClinicalRuleSet {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.set
+}
+
+// Status describes the active rule set and the last reload failure
+func (e *ClinicalRulesEngine) Status() models.ClinicalRuleSetStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.status
+}
+
+// ParseRuleSet decodes a YAML or JSON rule set (JSON is valid YAML) and
+// validates it. Unknown fields are rejected so typos don't silently disable
+// conditions.
+func ParseRuleSet(data []byte) (*models.ClinicalRuleSet, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var set models.ClinicalRuleSet
+	if err := decoder.Decode(&set); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("rule set is empty")
+		}
+		return nil, fmt.Errorf("invalid rule set: %w", err)
+	}
+	if err := ValidateRuleSet(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// ValidateRuleSet checks rule metadata and that every condition node sets
+// exactly one test
+func ValidateRuleSet(set *models.ClinicalRuleSet) error {
+	if strings.TrimSpace(set.Name) == "" || strings.TrimSpace(set.Version) == "" {
+		return fmt.Errorf("rule set name and version are required")
+	}
+	if len(set.Rules) == 0 {
+		return fmt.Errorf("rule set has no rules")
+	}
+	ids := map[string]bool{}
+	for i, rule := range set.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rule %d: id is required", i+1)
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("rule %s: duplicate id", rule.ID)
+		}
+		ids[rule.ID] = true
+		if strings.TrimSpace(rule.Test) == "" || strings.TrimSpace(rule.Reason) == "" {
+			return fmt.Errorf("rule %s: test and reason are required", rule.ID)
+		}
+		if !ruleUrgencies[rule.Urgency] {
+			return fmt.Errorf("rule %s: urgency must be routine, urgent or emergent", rule.ID)
+		}
+		if rule.Confidence <= 0 || rule.Confidence > 100 {
+			return fmt.Errorf("rule %s: confidence must be between 0 and 100", rule.ID)
+		}
+		if err := validateCondition(&rule.When, true); err != nil {
+			return fmt.Errorf("rule %s: when: %w", rule.ID, err)
+		}
+		if rule.Contraindicated != nil {
+			if err := validateCondition(rule.Contraindicated, true); err != nil {
+				return fmt.Errorf("rule %s: contraindicated: %w", rule.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateCondition(c *models.RuleCondition, allowPrior bool) error {
+	set := 0
+	for _, present := range []bool{
+		len(c.All) > 0, len(c.Any) > 0, c.Not != nil,
+		len(c.Symptom) > 0, len(c.Diagnosis) > 0, len(c.Medication) > 0, len(c.Test) > 0,
+		c.Lab != nil, c.Age != nil, c.Gender != "", c.Prior != nil,
+	} {
+		if present {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("each condition must set exactly one of all, any, not, symptom, diagnosis, medication, test, lab, age, gender or prior")
+	}
+
+	switch {
+	case len(c.All) > 0 || len(c.Any) > 0:
+		for i := range c.All {
+			if err := validateCondition(&c.All[i], allowPrior); err != nil {
+				return err
+			}
+		}
+		for i := range c.Any {
+			if err := validateCondition(&c.Any[i], allowPrior); err != nil {
+				return err
+			}
+		}
+	case c.Not != nil:
+		return validateCondition(c.Not, allowPrior)
+	case c.Lab != nil:
+		if len(c.Lab.Name) == 0 && c.Lab.Code == "" {
+			return fmt.Errorf("lab needs a name or code")
+		}
+		if (c.Lab.Op == "") != (c.Lab.Value == nil) {
+			return fmt.Errorf("lab op and value must be given together")
+		}
+		if c.Lab.Op != "" && ruleLabOps[c.Lab.Op] == nil {
+			return fmt.Errorf("lab op %q is not one of <, <=, >, >=, ==", c.Lab.Op)
+		}
+	case c.Age != nil:
+		if c.Age.Min == 0 && c.Age.Max == 0 {
+			return fmt.Errorf("age needs min or max")
+		}
+	case c.Gender != "":
+		if c.Gender != "female" && c.Gender != "male" {
+			return fmt.Errorf("gender must be female or male")
+		}
+	case c.Prior != nil:
+		if !allowPrior {
+			return fmt.Errorf("prior conditions cannot be nested")
+		}
+		if c.Prior.WithinDays < 0 || c.Prior.MinCount < 0 {
+			return fmt.Errorf("prior within_days and min_count cannot be negative")
+		}
+		return validateCondition(&c.Prior.When, false)
+	}
+	return nil
+}
+
+// RuleInput is the report, patient and history rules are evaluated against
+type RuleInput struct {
+	Analysis     *models.AIAnalysis
+	Observations []models.LabObservation
+	Patient      *models.Patient // Nil disables age and gender conditions
+	Prior        []models.Report // Earlier reports of the patient, newest first
+	Now          time.Time
+}
+
+// ruleEvaluation accumulates the trace of one rule
+type ruleEvaluation struct {
+	input *RuleInput
+	steps []models.RuleTraceStep
+}
+
+func (ev *ruleEvaluation) step(depth int, condition string) int {
+	ev.steps = append(ev.steps, models.RuleTraceStep{Depth: depth, Condition: condition})
+	return len(ev.steps) - 1
+}
+
+// evaluate evaluates a condition without short-circuiting so the trace shows
+// every branch
+func (ev *ruleEvaluation) evaluate(c *models.RuleCondition, depth int) bool {
+	var result bool
+	var evidence []string
+	var index int
+
+	switch {
+	case len(c.All) > 0:
+		index = ev.step(depth, "all of")
+		result = true
+		for i := range c.All {
+			if !ev.evaluate(&c.All[i], depth+1) {
+				result = false
+			}
+		}
+	case len(c.Any) > 0:
+		index = ev.step(depth, "any of")
+		for i := range c.Any {
+			if ev.evaluate(&c.Any[i], depth+1) {
+				result = true
+			}
+		}
+	case c.Not != nil:
+		index = ev.step(depth, "not")
+		result = !ev.evaluate(c.Not, depth+1)
+	case len(c.Symptom) > 0:
+		index = ev.step(depth, "symptom in ["+strings.Join(c.Symptom, ", ")+"]")
+		evidence = ev.entityMatches(TermCategorySymptom, ev.input.Analysis.Entities.Symptoms, c.Symptom)
+	case len(c.Diagnosis) > 0:
+		index = ev.step(depth, "diagnosis in ["+strings.Join(c.Diagnosis, ", ")+"]")
+		evidence = ev.entityMatches(TermCategoryDiagnosis, ev.input.Analysis.Entities.Diagnoses, c.Diagnosis)
+	case len(c.Medication) > 0:
+		index = ev.step(depth, "medication in ["+strings.Join(c.Medication, ", ")+"]")
+		evidence = ev.entityMatches(TermCategoryMedication, ev.input.Analysis.Entities.Medications, c.Medication)
+	case len(c.Test) > 0:
+		index = ev.step(depth, "test in ["+strings.Join(c.Test, ", ")+"]")
+		evidence = ev.entityMatches(TermCategoryTest, ev.input.Analysis.Entities.Tests, c.Test)
+		for _, obs := range ev.input.Observations {
+			if termsMatch(obs.Display, c.Test) {
+				evidence = append(evidence, obs.Display)
+			}
+		}
+	case c.Lab != nil:
+		index = ev.step(depth, describeLabCondition(c.Lab))
+		evidence = ev.labMatches(c.Lab)
+	case c.Age != nil:
+		index = ev.step(depth, describeAgeCondition(c.Age))
+		if p := ev.input.Patient; p != nil && p.Age > 0 &&
+			(c.Age.Min == 0 || p.Age >= c.Age.Min) && (c.Age.Max == 0 || p.Age <= c.Age.Max) {
+			evidence = []string{fmt.Sprintf("age %d", p.Age)}
+		}
+	case c.Gender != "":
+		index = ev.step(depth, "gender "+c.Gender)
+		if p := ev.input.Patient; p != nil && normalizeGender(p.Gender) == c.Gender {
+			evidence = []string{c.Gender}
+		}
+	case c.Prior != nil:
+		index = ev.step(depth, describePriorCondition(c.Prior))
+		evidence = ev.priorMatches(c.Prior, depth)
+	}
+
+	if len(evidence) > 0 {
+		result = true
+		ev.steps[index].Evidence = strings.Join(evidence, "; ")
+	}
+	ev.steps[index].Result = result
+	return result
+}
+
+// entityMatches returns the entities matching any of the terms. Negated,
+// hypothetical and family-history entities never match; historical ones only
+// match diagnoses, since a past diagnosis usually still applies.
+func (ev *ruleEvaluation) entityMatches(category string, entities, terms []string) []string {
+	var matches []string
+	for _, entity := range entities {
+		switch EntityStatus(ev.input.Analysis, category, entity) {
+		case AssertionAffirmed:
+		case AssertionHistorical:
+			if category != TermCategoryDiagnosis {
+				continue
+			}
+		default:
+			continue
+		}
+		if termsMatch(entity, terms) {
+			matches = append(matches, entity)
+		}
+	}
+	return matches
+}
+
+// termsMatch reports whether the text contains any of the terms as a phrase,
+// comparing abbreviation expansions as well
+func termsMatch(text string, terms []string) bool {
+	tokens := contextTokens(text)
+	if expanded, ok, _ := loadTerminology().expandAbbreviations(normalizeTerm(text)); ok {
+		tokens = append(tokens, contextTokens(expanded)...)
+	}
+	for _, term := range terms {
+		phrase := contextTokens(term)
+		for i := range tokens {
+			if tokensAt(tokens, phrase, i) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ev *ruleEvaluation) labMatches(lab *models.LabCondition) []string {
+	var matches []string
+	for _, obs := range ev.input.Observations {
+		if !(lab.Code != "" && obs.Code == lab.Code) && !(len(lab.Name) > 0 && termsMatch(obs.Display, lab.Name)) {
+			continue
+		}
+		if lab.Op != "" {
+			if obs.Value == nil || !ruleLabOps[lab.Op](*obs.Value, *lab.Value) {
+				continue
+			}
+		}
+		if len(lab.Interpretation) > 0 {
+			flagged := false
+			for _, flag := range lab.Interpretation {
+				if strings.EqualFold(flag, obs.Interpretation) {
+					flagged = true
+				}
+			}
+			if !flagged {
+				continue
+			}
+		}
+		matches = append(matches, ObservationEntityText(obs))
+	}
+	return matches
+}
+
+// priorMatches evaluates the nested condition against each earlier report in
+// the window, tracing each report that matched. Reports dated at or after the
+// one being evaluated are not prior, however the input was assembled.
+func (ev *ruleEvaluation) priorMatches(prior *models.PriorCondition, depth int) []string {
+	minCount := prior.MinCount
+	if minCount == 0 {
+		minCount = 1
+	}
+	var matched []string
+	for i := range ev.input.Prior {
+		report := &ev.input.Prior[i]
+		if !report.UploadedAt.Before(ev.input.Now) {
+			continue
+		}
+		if prior.WithinDays > 0 && ev.input.Now.Sub(report.UploadedAt) > time.Duration(prior.WithinDays)*24*time.Hour {
+			continue
+		}
+		nested := &ruleEvaluation{input: &RuleInput{
+			Analysis:     &report.AIAnalysis,
+			Observations: report.Observations,
+			Patient:      ev.input.Patient,
+			Now:          ev.input.Now,
+		}}
+		if nested.evaluate(&prior.When, depth+1) {
+			matched = append(matched, fmt.Sprintf("report %s (%s)", report.ID.Hex(), report.UploadedAt.Format("2006-01-02")))
+			ev.steps = append(ev.steps, nested.steps...)
+		}
+	}
+	if len(matched) < minCount {
+		return nil
+	}
+	return matched
+}
+
+func describeLabCondition(lab *models.LabCondition) string {
+	parts := []string{"lab"}
+	if len(lab.Name) > 0 {
+		parts = append(parts, "["+strings.Join(lab.Name, ", ")+"]")
+	}
+	if lab.Code != "" {
+		parts = append(parts, "code "+lab.Code)
+	}
+	if lab.Op != "" {
+		parts = append(parts, fmt.Sprintf("%s %g", lab.Op, *lab.Value))
+	}
+	if len(lab.Interpretation) > 0 {
+		parts = append(parts, "flagged "+strings.Join(lab.Interpretation, "/"))
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeAgeCondition(age *models.RangeCondition) string {
+	switch {
+	case age.Max == 0:
+		return fmt.Sprintf("age >= %d", age.Min)
+	case age.Min == 0:
+		return fmt.Sprintf("age <= %d", age.Max)
+	}
+	return fmt.Sprintf("age %d-%d", age.Min, age.Max)
+}
+
+func describePriorCondition(prior *models.PriorCondition) string {
+	window := "any earlier report"
+	if prior.WithinDays > 0 {
+		window = fmt.Sprintf("earlier report within %d days", prior.WithinDays)
+	}
+	if prior.MinCount > 1 {
+		window = fmt.Sprintf("%d+ %ss", prior.MinCount, window)
+	}
+	return window + " where"
+}
+
+// ruleEvidence lists the evidence of the leaf conditions that held
+func ruleEvidence(steps []models.RuleTraceStep) []string {
+	var evidence []string
+	for _, s := range steps {
+		if s.Result && s.Evidence != "" {
+			evidence = append(evidence, s.Evidence)
+		}
+	}
+	return evidence
+}
+
+// EvaluateRules runs every enabled rule of the set. It returns the
+// recommendations of the rules that fired and a trace for every rule.
+func EvaluateRules(set *models.ClinicalRuleSet, input *RuleInput) ([]models.Recommendation, []models.RuleTrace) {
+	if input.Now.IsZero() {
+		input.Now = time.Now()
+	}
+	var recommendations []models.Recommendation
+	var traces []models.RuleTrace
+	for i := range set.Rules {
+		rule := &set.Rules[i]
+		if rule.Disabled {
+			continue
+		}
+		ev := &ruleEvaluation{input: input}
+		trace := models.RuleTrace{RuleID: rule.ID, Test: rule.Test}
+		trace.Matched = ev.evaluate(&rule.When, 0)
+		whenSteps := len(ev.steps)
+		if rule.Contraindicated != nil {
+			ev.step(0, "contraindicated if")
+			trace.Contraindicated = ev.evaluate(rule.Contraindicated, 1)
+		}
+		trace.Steps = ev.steps
+		traces = append(traces, trace)
+		if !trace.Matched {
+			continue
+		}
+
+		source := fmt.Sprintf("rule %s (%s@%s)", rule.ID, set.Name, set.Version)
+		if trace.Contraindicated {
+			contraindications := ruleEvidence(ev.steps[whenSteps:])
+			recommendations = append(recommendations, models.Recommendation{
+				Test:              rule.Test,
+				Reason:            "Contraindication present: " + strings.Join(contraindications, ", "),
+				Contraindications: contraindications,
+				Confidence:        10,
+				Urgency:           "not recommended",
+				Explanation:       fmt.Sprintf("%s matched (%s) but its contraindication holds", source, strings.Join(ruleEvidence(ev.steps[:whenSteps]), "; ")),
+				RuleID:            rule.ID,
+			})
+			continue
+		}
+		recommendations = append(recommendations, models.Recommendation{
+			Test:              rule.Test,
+			Reason:            rule.Reason,
+			Contraindications: []string{},
+			Confidence:        rule.Confidence,
+			Urgency:           rule.Urgency,
+			Explanation:       fmt.Sprintf("Recommended by %s because: %s", source, strings.Join(ruleEvidence(ev.steps), "; ")),
+			RuleID:            rule.ID,
+		})
+	}
+	return recommendations, traces
+}
+
+// MergeRuleRecommendations adds rule recommendations to an analysis. A rule
+// recommendation replaces an analyzer recommendation for the same test, since
+// clinician-maintained rules take precedence.
+func MergeRuleRecommendations(analysis *models.AIAnalysis, recommendations []models.Recommendation) {
+	for _, rec := range recommendations {
+		replaced := false
+		for i := range analysis.Recommendations {
+			if normalizeTerm(analysis.Recommendations[i].Test) == normalizeTerm(rec.Test) {
+				analysis.Recommendations[i] = rec
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			analysis.Recommendations = append(analysis.Recommendations, rec)
+		}
+	}
+}
+
+// LoadPriorReports returns up to maxPriorReports of the patient's reports
+// uploaded before the report being analyzed, newest first
+func LoadPriorReports(patientID primitive.ObjectID, before time.Time) []models.Report {
+	if patientID.IsZero() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.M{"uploaded_at": -1}).SetLimit(maxPriorReports)
+	cursor, err := config.GetCollection("reports").Find(ctx, bson.M{"patient_id": patientID, "uploaded_at": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		log.Printf("clinical rules: failed to load prior reports: %v", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var prior []models.Report
+	for cursor.Next(ctx) {
+		var report models.Report
+		if err := cursor.Decode(&report); err != nil {
+			continue
+		}
+		prior = append(prior, report)
+	}
+	return prior
+}
+
+// ApplyClinicalRules evaluates the active rule set against an analysis of a
+// report dated asOf, merges the resulting recommendations and stores the
+// traces of the rules that fired. Only reports before asOf count as prior.
+func ApplyClinicalRules(analysis *models.AIAnalysis, observations []models.LabObservation, patientID primitive.ObjectID, asOf time.Time) {
+	set := ClinicalRules().RuleSet()
+	input := &RuleInput{
+		Analysis:     analysis,
+		Observations: observations,
+		Patient:      loadAnalysisPatient(patientID),
+		Prior:        LoadPriorReports(patientID, asOf),
+		Now:          asOf,
+	}
+	recommendations, traces := EvaluateRules(set, input)
+	MergeRuleRecommendations(analysis, recommendations)
+
+	analysis.RuleSetVersion = set.Name + "@" + set.Version
+	analysis.RuleTraces = nil
+	for _, trace := range traces {
+		if trace.Matched {
+			analysis.RuleTraces = append(analysis.RuleTraces, trace)
+		}
+	}
+}
+
+// RuleInputForReport builds the input for re-evaluating a stored report
+func RuleInputForReport(report *models.Report) *RuleInput {
+	return &RuleInput{
+		Analysis:     &report.AIAnalysis,
+		Observations: report.Observations,
+		Patient:      loadAnalysisPatient(report.PatientID),
+		Prior:        LoadPriorReports(report.PatientID, report.UploadedAt),
+		Now:          report.UploadedAt,
+	}
+}
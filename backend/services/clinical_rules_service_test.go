@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestEvaluateRulesPriorReports(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	set := &models.ClinicalRuleSet{Rules: []models.ClinicalRule{{
+		ID:   "repeat-hba1c",
+		Test: "HbA1c",
+		When: models.RuleCondition{Prior: &models.PriorCondition{
+			WithinDays: 180,
+			When:       models.RuleCondition{Diagnosis: []string{"diabetes"}},
+		}},
+	}}}
+	diabetic := func(at time.Time) models.Report {
+		r := models.Report{UploadedAt: at}
+		r.AIAnalysis.Entities.Diagnoses = []string{"diabetes"}
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		prior []models.Report
+		want  bool
+	}{
+		{"earlier report", []models.Report{diabetic(now.AddDate(0, -1, 0))}, true},
+		{"later report", []models.Report{diabetic(now.AddDate(0, 1, 0))}, false},
+		{"same time", []models.Report{diabetic(now)}, false},
+		{"outside window", []models.Report{diabetic(now.AddDate(-1, 0, 0))}, false},
+		{"no prior", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &RuleInput{Analysis: &models.AIAnalysis{}, Prior: tt.prior, Now: now}
+			recommendations, _ := EvaluateRules(set, input)
+			if got := len(recommendations) > 0; got != tt.want {
+				t.Errorf("rule fired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+// ConfidenceScorerVersion identifies the scoring formula stored with each breakdown
+const ConfidenceScorerVersion = "1.0"
+
+// Scoring weights and penalties. Weights sum to 1 so the weighted total stays in 0-100.
+const (
+	recommendationWeight = 0.6
+	coverageWeight       = 0.4
+	warningPenalty       = 10.0 // Points per extraction warning
+	maxWarningPenalty    = 30.0
+)
+
+// coverageCategories are the entity categories expected in a well-extracted report
+var coverageCategories = []string{"symptoms", "diagnoses", "medications", "tests", "vitals"}
+
+// NormalizeConfidence converts a confidence from the analyzer to the 0-100 range.
+// Values up to 1 are treated as fractions; anything else is already a percentage.
+func NormalizeConfidence(value float64) float64 {
+	if math.IsNaN(value) {
+		return 0
+	}
+	if value > 0 && value <= 1 {
+		value *= 100
+	}
+	return clampScore(value)
+}
+
+// ScoreAnalysis computes a bounded 0-100 confidence score for an analysis and
+// returns the per-factor breakdown. Recommendation confidences must already be
+// normalized to 0-100.
+func ScoreAnalysis(analysis *models.AIAnalysis) models.ConfidenceBreakdown {
+	// Factor 1: mean recommendation confidence
+	recValue := 0.0
+	recDetail := "no recommendations"
+	if len(analysis.Recommendations) > 0 {
+		var sum float64
+		for _, rec := range analysis.Recommendations {
+			sum += clampScore(rec.Confidence)
+		}
+		recValue = sum / float64(len(analysis.Recommendations))
+		recDetail = fmt.Sprintf("mean of %d recommendation(s)", len(analysis.Recommendations))
+	}
+
+	// Factor 2: share of expected entity categories that were extracted
+	present := 0
+	for _, category := range coverageCategories {
+		if len(entityCategory(analysis, category)) > 0 {
+			present++
+		}
+	}
+	coverageValue := 100 * float64(present) / float64(len(coverageCategories))
+	coverageDetail := fmt.Sprintf("%d of %d entity categories present", present, len(coverageCategories))
+
+	factors := []models.ConfidenceFactor{
+		{
+			Name:         "recommendation_confidence",
+			Value:        round1(recValue),
+			Weight:       recommendationWeight,
+			Contribution: round1(recValue * recommendationWeight),
+			Detail:       recDetail,
+		},
+		{
+			Name:         "entity_coverage",
+			Value:        round1(coverageValue),
+			Weight:       coverageWeight,
+			Contribution: round1(coverageValue * coverageWeight),
+			Detail:       coverageDetail,
+		},
+	}
+
+	// Extraction warnings reduce the score
+	penalty := math.Min(float64(len(analysis.Warnings))*warningPenalty, maxWarningPenalty)
+
+	total := recValue*recommendationWeight + coverageValue*coverageWeight - penalty
+
+	return models.ConfidenceBreakdown{
+		ScorerVersion: ConfidenceScorerVersion,
+		Factors:       factors,
+		Penalty:       penalty,
+		Score:         round1(clampScore(total)),
+	}
+}
+
+// ApplyConfidenceScore scores the analysis and stores the result on it
+func ApplyConfidenceScore(analysis *models.AIAnalysis) {
+	breakdown := ScoreAnalysis(analysis)
+	analysis.ConfidenceScore = breakdown.Score
+	analysis.ConfidenceBreakdown = &breakdown
+}
+
+// entityCategory returns the extracted entities for a category name
+func entityCategory(analysis *models.AIAnalysis, category string) []string {
+	switch category {
+	case "symptoms":
+		return analysis.Entities.Symptoms
+	case "diagnoses":
+		return analysis.Entities.Diagnoses
+	case "medications":
+		return analysis.Entities.Medications
+	case "tests":
+		return analysis.Entities.Tests
+	case "vitals":
+		return analysis.Entities.Vitals
+	case "severity":
+		return analysis.Entities.Severity
+	case "urgency":
+		return analysis.Entities.Urgency
+	case "functional_impact":
+		return analysis.Entities.FunctionalImpact
+	}
+	return nil
+}
+
+func clampScore(value float64) float64 {
+	return math.Max(0, math.Min(100, value))
+}
+
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestNormalizeConfidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		input float64
+		want  float64
+	}{
+		{"zero", 0, 0},
+		{"fraction", 0.95, 95},
+		{"one is a fraction", 1, 100},
+		{"percentage", 85, 85},
+		{"above range", 9500, 100},
+		{"negative", -5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeConfidence(tt.input); got != tt.want {
+				t.Errorf("NormalizeConfidence(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreAnalysis(t *testing.T) {
+	fullEntities := func(a *models.AIAnalysis) {
+		a.Entities.Symptoms = []string{"pain"}
+		a.Entities.Diagnoses = []string{"fracture"}
+		a.Entities.Medications = []string{"ibuprofen"}
+		a.Entities.Tests = []string{"x-ray"}
+		a.Entities.Vitals = []string{"bp 120/80"}
+	}
+
+	tests := []struct {
+		name        string
+		build       func(a *models.AIAnalysis)
+		wantScore   float64
+		wantPenalty float64
+	}{
+		{
+			name:      "empty analysis",
+			build:     func(a *models.AIAnalysis) {},
+			wantScore: 0,
+		},
+		{
+			name: "full coverage and confident recommendations",
+			build: func(a *models.AIAnalysis) {
+				fullEntities(a)
+				a.Recommendations = []models.Recommendation{{Confidence: 95}, {Confidence: 95}}
+			},
+			wantScore: 97,
+		},
+		{
+			name: "partial coverage",
+			build: func(a *models.AIAnalysis) {
+				a.Entities.Symptoms = []string{"pain"}
+				a.Entities.Tests = []string{"mri"}
+				a.Recommendations = []models.Recommendation{{Confidence: 50}}
+			},
+			wantScore: 46,
+		},
+		{
+			name: "warnings reduce score",
+			build: func(a *models.AIAnalysis) {
+				fullEntities(a)
+				a.Recommendations = []models.Recommendation{{Confidence: 100}}
+				a.Warnings = []string{"low OCR quality", "page 2 unreadable"}
+			},
+			wantScore:   80,
+			wantPenalty: 20,
+		},
+		{
+			name: "warning penalty is capped",
+			build: func(a *models.AIAnalysis) {
+				fullEntities(a)
+				a.Recommendations = []models.Recommendation{{Confidence: 100}}
+				a.Warnings = []string{"a", "b", "c", "d", "e"}
+			},
+			wantScore:   70,
+			wantPenalty: 30,
+		},
+		{
+			name: "score never goes negative",
+			build: func(a *models.AIAnalysis) {
+				a.Warnings = []string{"a", "b", "c"}
+			},
+			wantScore:   0,
+			wantPenalty: 30,
+		},
+		{
+			name: "out of range recommendation confidence is clamped",
+			build: func(a *models.AIAnalysis) {
+				fullEntities(a)
+				a.Recommendations = []models.Recommendation{{Confidence: 9500}}
+			},
+			wantScore: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var analysis models.AIAnalysis
+			tt.build(&analysis)
+
+			got := ScoreAnalysis(&analysis)
+			if got.Score != tt.wantScore {
+				t.Errorf("Score = %v, want %v", got.Score, tt.wantScore)
+			}
+			if got.Penalty != tt.wantPenalty {
+				t.Errorf("Penalty = %v, want %v", got.Penalty, tt.wantPenalty)
+			}
+			if got.Score < 0 || got.Score > 100 {
+				t.Errorf("Score %v out of 0-100 range", got.Score)
+			}
+			if len(got.Factors) != 2 {
+				t.Fatalf("expected 2 factors, got %d", len(got.Factors))
+			}
+		})
+	}
+}
+
+func TestApplyConfidenceScore(t *testing.T) {
+	var analysis models.AIAnalysis
+	analysis.Entities.Symptoms = []string{"pain"}
+	analysis.Recommendations = []models.Recommendation{{Confidence: 80}}
+
+	ApplyConfidenceScore(&analysis)
+
+	if analysis.ConfidenceBreakdown == nil {
+		t.Fatal("expected breakdown to be stored on the analysis")
+	}
+	if analysis.ConfidenceScore != analysis.ConfidenceBreakdown.Score {
+		t.Errorf("ConfidenceScore = %v, breakdown score = %v", analysis.ConfidenceScore, analysis.ConfidenceBreakdown.Score)
+	}
+	if analysis.ConfidenceBreakdown.ScorerVersion != ConfidenceScorerVersion {
+		t.Errorf("ScorerVersion = %q, want %q", analysis.ConfidenceBreakdown.ScorerVersion, ConfidenceScorerVersion)
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Consent purposes
+const (
+	ConsentAIAnalysis = "ai_analysis" // Automated analysis of uploaded reports
+	ConsentCloudChat  = "cloud_chat"  // Chatbot answers from the cloud LLM
+	ConsentResearch   = "research"    // De-identified research datasets
+)
+
+// ConsentPurposes lists the purposes a patient can consent to
+var ConsentPurposes = []string{ConsentAIAnalysis, ConsentCloudChat, ConsentResearch}
+
+// AnalysisSkippedNoConsent marks reports stored without AI analysis because
+// the patient had not consented to it
+const AnalysisSkippedNoConsent = "no_consent"
+
+// Consent states
+const (
+	ConsentGranted   = "granted"
+	ConsentWithdrawn = "withdrawn"
+)
+
+// consentBySystem attributes consent recorded by a migration
+const consentBySystem = "system"
+
+// defaultConsentDocuments are published when a purpose has no document yet,
+// so patients can consent before an admin publishes the hospital's own text
+var defaultConsentDocuments = []models.ConsentDocument{
+	{
+		Purpose: ConsentAIAnalysis,
+		Title:   "Automated analysis of your reports",
+		Body: "Reports you upload are read by an automated analyzer that extracts findings and suggests follow-up tests. " +
+			"A doctor reviews the results before they are final. You can withdraw this consent at any time; " +
+			"reports uploaded after that are stored without analysis.",
+	},
+	{
+		Purpose: ConsentCloudChat,
+		Title:   "Chat answers from a cloud AI service",
+		Body: "The report assistant can answer your questions using a cloud AI service. Your name, contact details and " +
+			"identifiers are removed before anything is sent. Without this consent the assistant gives basic answers only.",
+	},
+	{
+		Purpose: ConsentResearch,
+		Title:   "Use of your de-identified data in research",
+		Body: "Your reports may be included in research datasets after everything that identifies you is removed. " +
+			"Withdrawing consent keeps your data out of datasets created afterwards.",
+	},
+}
+
+var (
+	ErrConsentDocumentNotFound = errors.New("no consent document has been published for this purpose")
+	ErrConsentVersionOutdated  = errors.New("consent must be given to the current document version")
+	ErrConsentNotGranted       = errors.New("consent is not currently granted")
+)
+
+// ValidConsentPurpose reports whether a purpose can be consented to
+func ValidConsentPurpose(purpose string) bool {
+	for _, p := range ConsentPurposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishConsentDocument stores a new version of a purpose's consent document
+func PublishConsentDocument(ctx context.Context, doc *models.ConsentDocument) error {
+	version := 1
+	current, err := CurrentConsentDocument(ctx, doc.Purpose)
+	if err == nil {
+		version = current.Version + 1
+	} else if !errors.Is(err, ErrConsentDocumentNotFound) {
+		return err
+	}
+	doc.ID = primitive.NewObjectID()
+	doc.Version = version
+	doc.PublishedAt = time.Now()
+	_, err = config.GetCollection("consent_documents").InsertOne(ctx, doc)
+	return err
+}
+
+// CurrentConsentDocument returns the latest version of a purpose's document
+func CurrentConsentDocument(ctx context.Context, purpose string) (*models.ConsentDocument, error) {
+	var doc models.ConsentDocument
+	err := config.GetCollection("consent_documents").FindOne(ctx,
+		bson.M{"purpose": purpose},
+		options.FindOne().SetSort(bson.M{"version": -1}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrConsentDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// minConsentVersion returns the oldest document version a consent may have
+// been given to and still count: the latest version that required re-consent
+func minConsentVersion(ctx context.Context, purpose string) (int, error) {
+	var doc models.ConsentDocument
+	err := config.GetCollection("consent_documents").FindOne(ctx,
+		bson.M{"purpose": purpose, "requires_reconsent": true},
+		options.FindOne().SetSort(bson.M{"version": -1}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+// RecordConsent grants or withdraws a patient's consent and appends it to the
+// history. A grant must name the current document version.
+func RecordConsent(ctx context.Context, patientID primitive.ObjectID, purpose, action string, version int, event models.ConsentEvent) (*models.PatientConsent, error) {
+	collection := config.GetCollection("patient_consents")
+	key := bson.M{"patient_id": patientID, "purpose": purpose}
+
+	switch action {
+	case ConsentGranted:
+		doc, err := CurrentConsentDocument(ctx, purpose)
+		if err != nil {
+			return nil, err
+		}
+		if version != doc.Version {
+			return nil, ErrConsentVersionOutdated
+		}
+		event.DocumentID, event.DocumentVersion = doc.ID, doc.Version
+	case ConsentWithdrawn:
+		var existing models.PatientConsent
+		if err := collection.FindOne(ctx, key).Decode(&existing); err != nil || existing.Status != ConsentGranted {
+			return nil, ErrConsentNotGranted
+		}
+		event.DocumentID, event.DocumentVersion = existing.DocumentID, existing.DocumentVersion
+	default:
+		return nil, errors.New("action must be granted or withdrawn")
+	}
+
+	now := time.Now()
+	event.Action, event.At = action, now
+	set := bson.M{"status": action, "updated_at": now}
+	if action == ConsentGranted {
+		set["document_id"], set["document_version"] = event.DocumentID, event.DocumentVersion
+	}
+
+	var consent models.PatientConsent
+	err := collection.FindOneAndUpdate(ctx, key,
+		bson.M{
+			"$set":         set,
+			"$setOnInsert": bson.M{"created_at": now},
+			"$push":        bson.M{"history": event},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&consent)
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// ConsentCurrent reports whether a consent record is granted to a document
+// version that still counts
+func ConsentCurrent(consent *models.PatientConsent, minVersion int) bool {
+	return consent.Status == ConsentGranted && consent.DocumentVersion >= minVersion
+}
+
+// HasConsent reports whether a patient currently consents to a purpose.
+// Patients without a consent record have not consented.
+func HasConsent(ctx context.Context, patientID primitive.ObjectID, purpose string) (bool, error) {
+	if patientID.IsZero() {
+		return false, nil
+	}
+	minVersion, err := minConsentVersion(ctx, purpose)
+	if err != nil {
+		return false, err
+	}
+	var consent models.PatientConsent
+	err = config.GetCollection("patient_consents").FindOne(ctx, bson.M{"patient_id": patientID, "purpose": purpose}).Decode(&consent)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return ConsentCurrent(&consent, minVersion), nil
+}
+
+// ConsentedPatients returns the patients who currently consent to a purpose
+func ConsentedPatients(ctx context.Context, purpose string) (map[primitive.ObjectID]bool, error) {
+	minVersion, err := minConsentVersion(ctx, purpose)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := config.GetCollection("patient_consents").Find(ctx, bson.M{
+		"purpose":          purpose,
+		"status":           ConsentGranted,
+		"document_version": bson.M{"$gte": minVersion},
+	}, options.Find().SetProjection(bson.M{"patient_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var consents []models.PatientConsent
+	if err := cursor.All(ctx, &consents); err != nil {
+		return nil, err
+	}
+	patients := make(map[primitive.ObjectID]bool, len(consents))
+	for _, c := range consents {
+		patients[c.PatientID] = true
+	}
+	return patients, nil
+}
+
+// SeedConsentDocuments publishes the default document of every purpose that
+// has none
+func SeedConsentDocuments() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, doc := range defaultConsentDocuments {
+		_, err := CurrentConsentDocument(ctx, doc.Purpose)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrConsentDocumentNotFound) {
+			return err
+		}
+		doc := doc
+		if err := PublishConsentDocument(ctx, &doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const consentBackfill = "consent_backfill"
+
+// BackfillConsents records AI analysis consent for patients whose reports
+// were analysed before consent was recorded, so their existing reports keep
+// being analysed until they withdraw or a document requiring re-consent is
+// published. Other purposes are never assumed. It runs once, after
+// SeedConsentDocuments.
+func BackfillConsents() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	migrations := config.GetCollection("migrations")
+	if n, err := migrations.CountDocuments(ctx, bson.M{"_id": consentBackfill}); err != nil || n > 0 {
+		return err
+	}
+	doc, err := CurrentConsentDocument(ctx, ConsentAIAnalysis)
+	if err != nil {
+		return err
+	}
+
+	patientIDs, err := config.GetCollection("reports").Distinct(ctx, "patient_id", bson.M{"patient_id": bson.M{"$ne": primitive.NilObjectID}})
+	if err != nil {
+		return err
+	}
+	collection := config.GetCollection("patient_consents")
+	now := time.Now()
+	for _, value := range patientIDs {
+		patientID, ok := value.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		event := models.ConsentEvent{
+			Action: ConsentGranted, DocumentID: doc.ID, DocumentVersion: doc.Version, ByType: consentBySystem,
+			Note: "Reports were analysed before consent was recorded", At: now,
+		}
+		// Patients who already decided keep their decision
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"patient_id": patientID, "purpose": ConsentAIAnalysis},
+			bson.M{"$setOnInsert": models.PatientConsent{
+				ID: primitive.NewObjectID(), PatientID: patientID, Purpose: ConsentAIAnalysis, Status: ConsentGranted,
+				DocumentID: doc.ID, DocumentVersion: doc.Version, History: []models.ConsentEvent{event},
+				CreatedAt: now, UpdatedAt: now,
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = migrations.InsertOne(ctx, bson.M{"_id": consentBackfill, "applied_at": time.Now()})
+	return err
+}
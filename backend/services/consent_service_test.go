@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidConsentPurpose(t *testing.T) {
+	for _, purpose := range ConsentPurposes {
+		if !ValidConsentPurpose(purpose) {
+			t.Errorf("ValidConsentPurpose(%q) = false", purpose)
+		}
+	}
+	for _, purpose := range []string{"", "marketing", "AI_ANALYSIS"} {
+		if ValidConsentPurpose(purpose) {
+			t.Errorf("ValidConsentPurpose(%q) = true", purpose)
+		}
+	}
+}
+
+func TestConsentCurrent(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		version    int
+		minVersion int
+		want       bool
+	}{
+		{"granted current version", ConsentGranted, 2, 2, true},
+		{"granted newer version", ConsentGranted, 3, 2, true},
+		{"granted outdated version", ConsentGranted, 1, 2, false},
+		{"withdrawn", ConsentWithdrawn, 2, 2, false},
+	}
+	for _, tt := range tests {
+		consent := &models.PatientConsent{Status: tt.status, DocumentVersion: tt.version}
+		if got := ConsentCurrent(consent, tt.minVersion); got != tt.want {
+			t.Errorf("%s: ConsentCurrent() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultConsentDocuments(t *testing.T) {
+	seen := map[string]bool{}
+	for _, doc := range defaultConsentDocuments {
+		if !ValidConsentPurpose(doc.Purpose) || doc.Title == "" || doc.Body == "" {
+			t.Errorf("incomplete default document %+v", doc)
+		}
+		seen[doc.Purpose] = true
+	}
+	for _, purpose := range ConsentPurposes {
+		if !seen[purpose] {
+			t.Errorf("no default document for %s", purpose)
+		}
+	}
+}
+
+func TestNewUploadedReport(t *testing.T) {
+	patientID := primitive.NewObjectID()
+	uploadedAt := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	uploadedBy := &models.Actor{Type: ActorPatient, ID: patientID}
+
+	t.Run("with consent", func(t *testing.T) {
+		analysis := &models.AIAnalysis{ConfidenceScore: 72}
+		report := NewUploadedReport(patientID, "/tmp/r.pdf", "r.pdf", uploadedAt, uploadedBy, analysis)
+		if report.AnalysisSkipped != "" || report.AIAnalysis.ConfidenceScore != 72 {
+			t.Errorf("report = %+v, want the analysis stored", report)
+		}
+	})
+
+	t.Run("without consent", func(t *testing.T) {
+		report := NewUploadedReport(patientID, "/tmp/r.pdf", "r.pdf", uploadedAt, uploadedBy, nil)
+		if report.AnalysisSkipped != AnalysisSkippedNoConsent {
+			t.Errorf("analysis skipped = %q, want %q", report.AnalysisSkipped, AnalysisSkippedNoConsent)
+		}
+		if report.ID.IsZero() || report.PatientID != patientID || report.PDFPath != "/tmp/r.pdf" || report.Status != "pending" {
+			t.Errorf("report = %+v, want a stored pending report", report)
+		}
+		if !report.UploadedAt.Equal(uploadedAt) || report.UploadedBy != uploadedBy {
+			t.Errorf("upload details = %v, %+v", report.UploadedAt, report.UploadedBy)
+		}
+		if len(report.AIAnalysis.Recommendations) != 0 || report.AIAnalysis.ConfidenceScore != 0 {
+			t.Errorf("analysis = %+v, want none", report.AIAnalysis)
+		}
+	})
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+// Assertion statuses of an extracted entity
+const (
+	AssertionAffirmed     = "affirmed"
+	AssertionNegated      = "negated"
+	AssertionHistorical   = "historical"
+	AssertionHypothetical = "hypothetical"
+	AssertionFamily       = "family"
+)
+
+// contextScopeTokens is the largest distance, in tokens, between a trigger and
+// the entity it modifies
+const contextScopeTokens = 8
+
+// Trigger kinds. Pre-triggers modify the entities after them and post-triggers
+// those before them; pseudo-triggers look like triggers but modify nothing;
+// terminators end the scope of a trigger.
+const (
+	contextPre = iota
+	contextPost
+	contextPseudo
+	contextTerminate
+)
+
+type contextTrigger struct {
+	phrase   string
+	status   string
+	kind     int
+	category string // Only modifies entities of this category when set
+	tokens   []string
+}
+
+// contextTriggers is a NegEx/ConText-style lexicon. At each position the
+// longest matching phrase wins, so "no history of" is a negation and "family
+// history of" a family history rather than a plain history.
+var contextTriggers = compileContextTriggers([]contextTrigger{
+	// Negation
+	{phrase: "no", status: AssertionNegated, kind: contextPre},
+	{phrase: "not", status: AssertionNegated, kind: contextPre},
+	{phrase: "denies", status: AssertionNegated, kind: contextPre},
+	{phrase: "denied", status: AssertionNegated, kind: contextPre},
+	{phrase: "denying", status: AssertionNegated, kind: contextPre},
+	{phrase: "without", status: AssertionNegated, kind: contextPre},
+	{phrase: "negative for", status: AssertionNegated, kind: contextPre},
+	{phrase: "no evidence of", status: AssertionNegated, kind: contextPre},
+	{phrase: "no signs of", status: AssertionNegated, kind: contextPre},
+	{phrase: "no sign of", status: AssertionNegated, kind: contextPre},
+	{phrase: "no complaints of", status: AssertionNegated, kind: contextPre},
+	{phrase: "absence of", status: AssertionNegated, kind: contextPre},
+	{phrase: "free of", status: AssertionNegated, kind: contextPre},
+	{phrase: "never had", status: AssertionNegated, kind: contextPre},
+	{phrase: "no history of", status: AssertionNegated, kind: contextPre},
+	{phrase: "no family history of", status: AssertionNegated, kind: contextPre},
+	{phrase: "no known", status: AssertionNegated, kind: contextPre},
+	{phrase: "ruled out", status: AssertionNegated, kind: contextPost},
+	{phrase: "was ruled out", status: AssertionNegated, kind: contextPost},
+	{phrase: "is ruled out", status: AssertionNegated, kind: contextPost},
+	{phrase: "negative", status: AssertionNegated, kind: contextPost},
+	{phrase: "absent", status: AssertionNegated, kind: contextPost},
+	{phrase: "not seen", status: AssertionNegated, kind: contextPost},
+	{phrase: "not detected", status: AssertionNegated, kind: contextPost},
+	{phrase: "unlikely", status: AssertionNegated, kind: contextPost},
+
+	// History
+	{phrase: "history of", status: AssertionHistorical, kind: contextPre},
+	{phrase: "past history of", status: AssertionHistorical, kind: contextPre},
+	{phrase: "past medical history", status: AssertionHistorical, kind: contextPre},
+	{phrase: "h/o", status: AssertionHistorical, kind: contextPre},
+	{phrase: "hx of", status: AssertionHistorical, kind: contextPre},
+	{phrase: "pmh", status: AssertionHistorical, kind: contextPre},
+	{phrase: "previous", status: AssertionHistorical, kind: contextPre},
+	{phrase: "previously", status: AssertionHistorical, kind: contextPre},
+	{phrase: "prior", status: AssertionHistorical, kind: contextPre},
+	{phrase: "status post", status: AssertionHistorical, kind: contextPre},
+	{phrase: "s/p", status: AssertionHistorical, kind: contextPre},
+	{phrase: "old history of", status: AssertionHistorical, kind: contextPre},
+	{phrase: "in the past", status: AssertionHistorical, kind: contextPost},
+	{phrase: "years ago", status: AssertionHistorical, kind: contextPost},
+	{phrase: "months ago", status: AssertionHistorical, kind: contextPost},
+	{phrase: "resolved", status: AssertionHistorical, kind: contextPost},
+	{phrase: "in childhood", status: AssertionHistorical, kind: contextPost},
+
+	// Hypothetical and uncertain
+	{phrase: "if", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "in case of", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "return if", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "watch for", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "monitor for", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "rule out", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "to rule out", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "r/o", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "evaluate for", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "screen for", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "screening for", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "risk of", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "at risk for", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "possible", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "suspected", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "cannot exclude", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "may develop", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "should he develop", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "should she develop", status: AssertionHypothetical, kind: contextPre},
+	{phrase: "is suspected", status: AssertionHypothetical, kind: contextPost},
+
+	// Tests that are ordered or awaited rather than resulted
+	{phrase: "awaiting", status: AssertionHypothetical, kind: contextPre, category: TermCategoryTest},
+	{phrase: "plan", status: AssertionHypothetical, kind: contextPre, category: TermCategoryTest},
+	{phrase: "order", status: AssertionHypothetical, kind: contextPre, category: TermCategoryTest},
+	{phrase: "schedule", status: AssertionHypothetical, kind: contextPre, category: TermCategoryTest},
+	{phrase: "recommend", status: AssertionHypothetical, kind: contextPre, category: TermCategoryTest},
+	{phrase: "will need", status: AssertionHypothetical, kind: contextPre, category: TermCategoryTest},
+	{phrase: "ordered", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "pending", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "awaited", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "requested", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "recommended", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "scheduled", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "planned", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+	{phrase: "to be done", status: AssertionHypothetical, kind: contextPost, category: TermCategoryTest},
+
+	// Family history
+	{phrase: "family history of", status: AssertionFamily, kind: contextPre},
+	{phrase: "family history", status: AssertionFamily, kind: contextPre},
+	{phrase: "fh of", status: AssertionFamily, kind: contextPre},
+	{phrase: "fhx", status: AssertionFamily, kind: contextPre},
+	{phrase: "mother", status: AssertionFamily, kind: contextPre},
+	{phrase: "father", status: AssertionFamily, kind: contextPre},
+	{phrase: "mother's", status: AssertionFamily, kind: contextPre},
+	{phrase: "father's", status: AssertionFamily, kind: contextPre},
+	{phrase: "brother", status: AssertionFamily, kind: contextPre},
+	{phrase: "sister", status: AssertionFamily, kind: contextPre},
+	{phrase: "sibling", status: AssertionFamily, kind: contextPre},
+	{phrase: "parents", status: AssertionFamily, kind: contextPre},
+	{phrase: "grandmother", status: AssertionFamily, kind: contextPre},
+	{phrase: "grandfather", status: AssertionFamily, kind: contextPre},
+	{phrase: "maternal", status: AssertionFamily, kind: contextPre},
+	{phrase: "paternal", status: AssertionFamily, kind: contextPre},
+	{phrase: "in the family", status: AssertionFamily, kind: contextPost},
+	{phrase: "runs in the family", status: AssertionFamily, kind: contextPost},
+
+	// Pseudo-triggers
+	{phrase: "no increase", kind: contextPseudo},
+	{phrase: "no change", kind: contextPseudo},
+	{phrase: "no further", kind: contextPseudo},
+	{phrase: "not only", kind: contextPseudo},
+	{phrase: "not necessarily", kind: contextPseudo},
+	{phrase: "not ruled out", kind: contextPseudo},
+	{phrase: "without difficulty", kind: contextPseudo},
+	{phrase: "gram negative", kind: contextPseudo},
+	{phrase: "history and physical", kind: contextPseudo},
+	{phrase: "history and examination", kind: contextPseudo},
+	{phrase: "history of present illness", kind: contextPseudo},
+	{phrase: "clinical history", kind: contextPseudo},
+
+	// Terminators
+	{phrase: "but", kind: contextTerminate},
+	{phrase: "however", kind: contextTerminate},
+	{phrase: "although", kind: contextTerminate},
+	{phrase: "though", kind: contextTerminate},
+	{phrase: "except", kind: contextTerminate},
+	{phrase: "apart from", kind: contextTerminate},
+	{phrase: "aside from", kind: contextTerminate},
+	{phrase: "which", kind: contextTerminate},
+	{phrase: "who", kind: contextTerminate},
+	{phrase: "yet", kind: contextTerminate},
+	{phrase: "presents with", kind: contextTerminate},
+	{phrase: "complains of", kind: contextTerminate},
+})
+
+var (
+	contextSentenceSplit = regexp.MustCompile(`[.!?;](?:\s+|$)|\n`)
+	contextTokenPattern  = regexp.MustCompile(`[a-z0-9]+(?:['/-][a-z0-9]+)*`)
+)
+
+func compileContextTriggers(triggers []contextTrigger) []contextTrigger {
+	for i := range triggers {
+		triggers[i].tokens = contextTokens(triggers[i].phrase)
+	}
+	return triggers
+}
+
+func contextTokens(text string) []string {
+	return contextTokenPattern.FindAllString(strings.ToLower(text), -1)
+}
+
+// tokensAt reports whether phrase occurs in tokens at position i
+func tokensAt(tokens, phrase []string, i int) bool {
+	if len(phrase) == 0 || i+len(phrase) > len(tokens) {
+		return false
+	}
+	for j, token := range phrase {
+		if tokens[i+j] != token {
+			return false
+		}
+	}
+	return true
+}
+
+type contextMatch struct {
+	trigger    *contextTrigger
+	start, end int // Token span
+}
+
+// matchTriggers finds triggers left to right, preferring the longest phrase at
+// each position
+func matchTriggers(tokens []string) []contextMatch {
+	var matches []contextMatch
+	for i := 0; i < len(tokens); {
+		var best *contextTrigger
+		for t := range contextTriggers {
+			trigger := &contextTriggers[t]
+			if tokensAt(tokens, trigger.tokens, i) && (best == nil || len(trigger.tokens) > len(best.tokens)) {
+				best = trigger
+			}
+		}
+		if best == nil {
+			i++
+			continue
+		}
+		matches = append(matches, contextMatch{trigger: best, start: i, end: i + len(best.tokens)})
+		i += len(best.tokens)
+	}
+	return matches
+}
+
+// contextPriority orders statuses when several triggers cover one mention
+var contextPriority = map[string]int{
+	AssertionHistorical:   1,
+	AssertionHypothetical: 2,
+	AssertionFamily:       3,
+	AssertionNegated:      4,
+}
+
+// mentionStatus returns the status of the entity at tokens [start, end). A
+// trigger's scope ends at a terminator, at a trigger of another status or after
+// contextScopeTokens tokens.
+func mentionStatus(matches []contextMatch, start, end int) (string, string) {
+	status, cue := AssertionAffirmed, ""
+	for i, m := range matches {
+		if m.trigger.kind != contextPre && m.trigger.kind != contextPost {
+			continue
+		}
+		covered := false
+		if m.trigger.kind == contextPre && m.end <= start && start-m.end <= contextScopeTokens {
+			covered = true
+			for _, other := range matches[i+1:] {
+				if other.start >= start {
+					break
+				}
+				if other.trigger.kind == contextTerminate || (other.trigger.kind != contextPseudo && other.trigger.status != m.trigger.status) {
+					covered = false
+					break
+				}
+			}
+		}
+		if m.trigger.kind == contextPost && m.start >= end && m.start-end <= contextScopeTokens {
+			covered = true
+			for j := i - 1; j >= 0; j-- {
+				other := matches[j]
+				if other.end <= end {
+					break
+				}
+				if other.trigger.kind == contextTerminate || (other.trigger.kind != contextPseudo && other.trigger.status != m.trigger.status) {
+					covered = false
+					break
+				}
+			}
+		}
+		if covered && contextPriority[m.trigger.status] > contextPriority[status] {
+			status, cue = m.trigger.status, m.trigger.phrase
+		}
+	}
+	return status, cue
+}
+
+// contextAggregate orders statuses when combining mentions: one affirmed
+// mention makes the entity affirmed, and it is only negated when every
+// mention is
+var contextAggregate = map[string]int{
+	AssertionNegated:      1,
+	AssertionHypothetical: 2,
+	AssertionFamily:       3,
+	AssertionHistorical:   4,
+	AssertionAffirmed:     5,
+}
+
+// triggersFor drops the matches of triggers limited to another category
+func triggersFor(matches []contextMatch, category string) []contextMatch {
+	var kept []contextMatch
+	for _, m := range matches {
+		if m.trigger.category == "" || m.trigger.category == category {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// AssertEntity determines the status of an entity across all its mentions in
+// the tokenized sentences. Entities not found in the text are affirmed.
+func assertEntity(sentences [][]string, triggers [][]contextMatch, category, text string) models.EntityAssertion {
+	assertion := models.EntityAssertion{Category: category, Text: text, Status: AssertionAffirmed}
+	phrase := contextTokens(text)
+	best := 0
+	for s, tokens := range sentences {
+		for i := range tokens {
+			if !tokensAt(tokens, phrase, i) {
+				continue
+			}
+			// Skip mentions inside a trigger phrase, e.g. "family" in "family history"
+			insideTrigger := false
+			for _, m := range triggers[s] {
+				if i >= m.start && i < m.end {
+					insideTrigger = true
+					break
+				}
+			}
+			if insideTrigger {
+				continue
+			}
+			assertion.Mentions++
+			status, cue := mentionStatus(triggersFor(triggers[s], category), i, i+len(phrase))
+			if contextAggregate[status] > best {
+				best = contextAggregate[status]
+				assertion.Status, assertion.Trigger = status, cue
+			}
+		}
+	}
+	return assertion
+}
+
+// ApplyAssertions annotates the diagnoses, symptoms, tests and medications of
+// an analysis with their status in the report text, then drops recommendations
+// that rest only on negated findings
+func ApplyAssertions(analysis *models.AIAnalysis, text string) {
+	var sentences [][]string
+	var triggers [][]contextMatch
+	for _, sentence := range contextSentenceSplit.Split(text, -1) {
+		tokens := contextTokens(sentence)
+		if len(tokens) == 0 {
+			continue
+		}
+		sentences = append(sentences, tokens)
+		triggers = append(triggers, matchTriggers(tokens))
+	}
+
+	entities := analysis.Entities
+	analysis.Assertions = nil
+	for _, group := range []struct {
+		category string
+		values   []string
+	}{
+		{TermCategoryDiagnosis, entities.Diagnoses},
+		{TermCategorySymptom, entities.Symptoms},
+		{TermCategoryTest, entities.Tests},
+		{TermCategoryMedication, entities.Medications},
+	} {
+		for _, value := range group.values {
+			analysis.Assertions = append(analysis.Assertions, assertEntity(sentences, triggers, group.category, value))
+		}
+	}
+	dropNegatedRecommendations(analysis)
+}
+
+// EntityStatus returns the assertion status of an entity; entities without an
+// assertion are affirmed
+func EntityStatus(analysis *models.AIAnalysis, category, text string) string {
+	for _, a := range analysis.Assertions {
+		if a.Category == category && a.Text == text {
+			return a.Status
+		}
+	}
+	return AssertionAffirmed
+}
+
+// negatedEntities returns the lowercased text of every negated entity
+func negatedEntities(analysis *models.AIAnalysis) map[string]bool {
+	negated := map[string]bool{}
+	for _, a := range analysis.Assertions {
+		if a.Status == AssertionNegated {
+			negated[strings.ToLower(a.Text)] = true
+		}
+	}
+	// An entity affirmed in another category is not negated
+	for _, a := range analysis.Assertions {
+		if a.Status != AssertionNegated {
+			delete(negated, strings.ToLower(a.Text))
+		}
+	}
+	return negated
+}
+
+// dropNegatedRecommendations removes recommendations whose reason cites only
+// negated findings and strips negated findings from "label: a, b" reasons
+func dropNegatedRecommendations(analysis *models.AIAnalysis) {
+	negated := negatedEntities(analysis)
+	if len(negated) == 0 {
+		return
+	}
+	var known []string
+	for _, a := range analysis.Assertions {
+		known = append(known, a.Text)
+	}
+
+	kept := analysis.Recommendations[:0]
+	for _, rec := range analysis.Recommendations {
+		reasonTokens := contextTokens(rec.Reason)
+		var cited, citedNegated []string
+		seen := map[string]bool{}
+		for _, entity := range known {
+			key := strings.ToLower(entity)
+			if seen[key] {
+				continue
+			}
+			phrase := contextTokens(entity)
+			for i := range reasonTokens {
+				if tokensAt(reasonTokens, phrase, i) {
+					seen[key] = true
+					cited = append(cited, entity)
+					if negated[key] {
+						citedNegated = append(citedNegated, entity)
+					}
+					break
+				}
+			}
+		}
+
+		if len(cited) > 0 && len(citedNegated) == len(cited) {
+			// Kept apart from the warnings, which lower the confidence score
+			analysis.DroppedRecommendations = append(analysis.DroppedRecommendations, models.DroppedRecommendation{
+				Test:            rec.Test,
+				Reason:          rec.Reason,
+				NegatedFindings: citedNegated,
+			})
+			continue
+		}
+		if len(citedNegated) > 0 {
+			if label, items, ok := strings.Cut(rec.Reason, ":"); ok {
+				var remaining []string
+				for _, item := range strings.Split(items, ",") {
+					if item = strings.TrimSpace(item); item != "" && !negated[strings.ToLower(item)] {
+						remaining = append(remaining, item)
+					}
+				}
+				reason := label + ": " + strings.Join(remaining, ", ")
+				rec.Explanation = strings.Replace(rec.Explanation, rec.Reason, reason, 1)
+				rec.Reason = reason
+			}
+		}
+		kept = append(kept, rec)
+	}
+	analysis.Recommendations = kept
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestApplyAssertions(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		category string
+		entity   string
+		want     string
+	}{
+		// Negation
+		{"pre negation", "Patient denies chest pain.", TermCategorySymptom, "chest pain", AssertionNegated},
+		{"negation list", "No fever, cough or chest pain.", TermCategorySymptom, "chest pain", AssertionNegated},
+		{"post negation", "Pneumonia was ruled out.", TermCategoryDiagnosis, "pneumonia", AssertionNegated},
+		{"negation ends at terminator", "No fever but reports chest pain.", TermCategorySymptom, "chest pain", AssertionAffirmed},
+		{"negation ends at sentence", "No fever. Chest pain since morning.", TermCategorySymptom, "chest pain", AssertionAffirmed},
+		{"negation out of scope", "No fever was noted on the ward during the first three days, chest pain persists.", TermCategorySymptom, "chest pain", AssertionAffirmed},
+		{"pseudo negation", "No change in chest pain.", TermCategorySymptom, "chest pain", AssertionAffirmed},
+		{"affirmed mention wins", "No chest pain at rest. Chest pain on exertion.", TermCategorySymptom, "chest pain", AssertionAffirmed},
+
+		// History
+		{"history of", "History of asthma.", TermCategoryDiagnosis, "asthma", AssertionHistorical},
+		{"old history of", "Old history of tuberculosis.", TermCategoryDiagnosis, "tuberculosis", AssertionHistorical},
+		{"years ago", "Appendicitis 10 years ago.", TermCategoryDiagnosis, "appendicitis", AssertionHistorical},
+		{"age is not history", "65 year old male with diabetes and chest pain.", TermCategoryDiagnosis, "diabetes", AssertionAffirmed},
+		{"age is not history for symptoms", "65 year old male with diabetes and chest pain.", TermCategorySymptom, "chest pain", AssertionAffirmed},
+		{"no history of", "No history of asthma.", TermCategoryDiagnosis, "asthma", AssertionNegated},
+		{"history and physical", "History and physical: asthma.", TermCategoryDiagnosis, "asthma", AssertionAffirmed},
+
+		// Family history
+		{"family history of", "Family history of diabetes.", TermCategoryDiagnosis, "diabetes", AssertionFamily},
+		{"relative", "Mother had breast cancer.", TermCategoryDiagnosis, "breast cancer", AssertionFamily},
+		{"no family history of", "No family history of diabetes.", TermCategoryDiagnosis, "diabetes", AssertionNegated},
+		{"family scope ends at sentence", "Father has hypertension. Patient has diabetes.", TermCategoryDiagnosis, "diabetes", AssertionAffirmed},
+		{"family scope ends at terminator", "Mother had hypertension but patient has diabetes.", TermCategoryDiagnosis, "diabetes", AssertionAffirmed},
+
+		// Hypothetical
+		{"rule out", "Admitted to rule out pneumonia.", TermCategoryDiagnosis, "pneumonia", AssertionHypothetical},
+		{"return if", "Return if fever develops.", TermCategorySymptom, "fever", AssertionHypothetical},
+		{"ordered test", "MRI ordered.", TermCategoryTest, "MRI", AssertionHypothetical},
+		{"test cue only modifies tests", "Diabetes pending review.", TermCategoryDiagnosis, "diabetes", AssertionAffirmed},
+		{"not in text", "Unremarkable.", TermCategoryDiagnosis, "asthma", AssertionAffirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var analysis models.AIAnalysis
+			switch tt.category {
+			case TermCategoryDiagnosis:
+				analysis.Entities.Diagnoses = []string{tt.entity}
+			case TermCategorySymptom:
+				analysis.Entities.Symptoms = []string{tt.entity}
+			case TermCategoryTest:
+				analysis.Entities.Tests = []string{tt.entity}
+			}
+			ApplyAssertions(&analysis, tt.text)
+			if got := EntityStatus(&analysis, tt.category, tt.entity); got != tt.want {
+				t.Errorf("status of %q in %q = %s, want %s", tt.entity, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAssertionsDropsNegatedRecommendations(t *testing.T) {
+	var analysis models.AIAnalysis
+	analysis.Entities.Symptoms = []string{"chest pain", "fever"}
+	analysis.Recommendations = []models.Recommendation{
+		{Test: "ECG", Reason: "Symptoms: chest pain"},
+		{Test: "CBC", Reason: "Symptoms: fever"},
+	}
+	ApplyAssertions(&analysis, "Denies chest pain. Fever for three days.")
+
+	if len(analysis.Recommendations) != 1 || analysis.Recommendations[0].Test != "CBC" {
+		t.Errorf("recommendations = %+v, want only CBC", analysis.Recommendations)
+	}
+	if len(analysis.DroppedRecommendations) != 1 || analysis.DroppedRecommendations[0].Test != "ECG" {
+		t.Errorf("dropped recommendations = %+v, want ECG", analysis.DroppedRecommendations)
+	}
+	if len(analysis.Warnings) != 0 {
+		t.Errorf("warnings = %q, want none", analysis.Warnings)
+	}
+}
+
+func TestDroppedRecommendationsDoNotLowerConfidence(t *testing.T) {
+	const text = "Denies chest pain. Fever for three days."
+	build := func(recs ...models.Recommendation) models.AIAnalysis {
+		var analysis models.AIAnalysis
+		analysis.Entities.Symptoms = []string{"chest pain", "fever"}
+		analysis.Recommendations = recs
+		ApplyAssertions(&analysis, text)
+		return analysis
+	}
+	ecg := models.Recommendation{Test: "ECG", Reason: "Symptoms: chest pain", Confidence: 80}
+	cbc := models.Recommendation{Test: "CBC", Reason: "Symptoms: fever", Confidence: 80}
+
+	dropped, without := build(ecg, cbc), build(cbc)
+	if len(dropped.DroppedRecommendations) != 1 {
+		t.Fatalf("dropped recommendations = %+v, want ECG", dropped.DroppedRecommendations)
+	}
+	got, want := ScoreAnalysis(&dropped), ScoreAnalysis(&without)
+	if got.Score != want.Score || got.Penalty != 0 {
+		t.Errorf("score = %.1f (penalty %.1f), want %.1f with no penalty", got.Score, got.Penalty, want.Score)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Delegation scopes
+const (
+	DelegateScopeViewReports = "view_reports"
+	DelegateScopeChat        = "chat"
+	DelegateScopeUpload      = "upload"
+)
+
+// DelegateScopes lists the scopes a delegation can grant
+var DelegateScopes = []string{DelegateScopeViewReports, DelegateScopeChat, DelegateScopeUpload}
+
+// Actor types
+const (
+	ActorPatient  = "patient"
+	ActorDelegate = "delegate"
+	ActorAdmin    = "admin"
+)
+
+// AdultAge is the age from which patients manage their own delegations.
+// Delegations for younger patients are granted by admins.
+const AdultAge = 18
+
+// ErrDelegationDenied is returned when an account has no active delegation
+// with the needed scope
+var ErrDelegationDenied = errors.New("no active delegation for this action")
+
+// ValidDelegateScope reports whether a scope can be granted
+func ValidDelegateScope(scope string) bool {
+	for _, s := range DelegateScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMinor reports whether a patient's recorded age is below AdultAge. An age
+// of 0 means it was never recorded.
+func IsMinor(patient *models.Patient) bool {
+	return patient.Age > 0 && patient.Age < AdultAge
+}
+
+// DelegationActive reports whether a delegation is in force at a time
+func DelegationActive(d *models.Delegation, now time.Time) bool {
+	return d.RevokedAt == nil && (d.ExpiresAt == nil || now.Before(*d.ExpiresAt))
+}
+
+// ActiveDelegation returns the delegation that lets delegateID act on
+// patientID's records within scope
+func ActiveDelegation(ctx context.Context, patientID, delegateID primitive.ObjectID, scope string) (*models.Delegation, error) {
+	now := time.Now()
+	var delegation models.Delegation
+	err := config.GetCollection("delegations").FindOne(ctx, bson.M{
+		"patient_id":  patientID,
+		"delegate_id": delegateID,
+		"scopes":      scope,
+		"revoked_at":  bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": bson.M{"$gt": now}},
+		},
+	}).Decode(&delegation)
+	if err != nil {
+		return nil, ErrDelegationDenied
+	}
+	return &delegation, nil
+}
+
+// AuthorizeActor decides who is acting when actorID works on patientID's
+// records: the patient themselves, or a delegate with scope
+func AuthorizeActor(ctx context.Context, actorID, patientID primitive.ObjectID, scope string) (*models.Actor, error) {
+	if actorID == patientID {
+		return &models.Actor{ID: actorID, Type: ActorPatient}, nil
+	}
+	delegation, err := ActiveDelegation(ctx, patientID, actorID, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Actor{ID: actorID, Type: ActorDelegate, DelegationID: &delegation.ID}, nil
+}
+
+// RevokeAccountDelegations ends every delegation a deleted account granted
+// or held
+func RevokeAccountDelegations(accountID primitive.ObjectID, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	_, err := config.GetCollection("delegations").UpdateMany(ctx,
+		bson.M{
+			"$or":        []bson.M{{"patient_id": accountID}, {"delegate_id": accountID}},
+			"revoked_at": bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{"revoked_at": now, "revoked_by_type": ActorAdmin, "revoke_reason": reason, "updated_at": now}},
+	)
+	if err != nil {
+		log.Printf("delegations: failed to revoke delegations of %s: %v", accountID.Hex(), err)
+	}
+}
+
+// RecordDelegateActivity logs an action taken by a delegate so the patient
+// can see it. Patients' own actions are not logged.
+func RecordDelegateActivity(actor *models.Actor, patientID primitive.ObjectID, action string, reportID *primitive.ObjectID) {
+	if actor == nil || actor.Type != ActorDelegate || actor.DelegationID == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	activity := models.DelegateActivity{
+		ID:           primitive.NewObjectID(),
+		DelegationID: *actor.DelegationID,
+		PatientID:    patientID,
+		DelegateID:   actor.ID,
+		Action:       action,
+		ReportID:     reportID,
+		At:           time.Now(),
+	}
+	if _, err := config.GetCollection("delegate_activity").InsertOne(ctx, activity); err != nil {
+		log.Printf("delegations: failed to record %s by %s: %v", action, actor.ID.Hex(), err)
+	}
+}
+
+// NotifyDelegationGranted tells a delegate they can now act for a patient
+func NotifyDelegationGranted(d *models.Delegation, patientName string) {
+	Notify(&models.Notification{
+		UserID:   d.DelegateID,
+		UserType: RecipientPatient,
+		Event:    EventDelegation,
+		Title:    "You were given access to " + patientName + "'s records",
+		Body:     "You can now act for " + patientName + ". Your actions are recorded under your own account.",
+	})
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestValidDelegateScope(t *testing.T) {
+	for _, scope := range DelegateScopes {
+		if !ValidDelegateScope(scope) {
+			t.Errorf("ValidDelegateScope(%q) = false", scope)
+		}
+	}
+	for _, scope := range []string{"", "admin", "View_Reports"} {
+		if ValidDelegateScope(scope) {
+			t.Errorf("ValidDelegateScope(%q) = true", scope)
+		}
+	}
+}
+
+func TestIsMinor(t *testing.T) {
+	tests := []struct {
+		age  int
+		want bool
+	}{
+		{0, false}, // Not recorded
+		{1, true},
+		{AdultAge - 1, true},
+		{AdultAge, false},
+		{70, false},
+	}
+	for _, tt := range tests {
+		if got := IsMinor(&models.Patient{Age: tt.age}); got != tt.want {
+			t.Errorf("IsMinor(age %d) = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestDelegationActive(t *testing.T) {
+	now := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	before, after := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name       string
+		delegation models.Delegation
+		want       bool
+	}{
+		{"open-ended", models.Delegation{}, true},
+		{"expires later", models.Delegation{ExpiresAt: &after}, true},
+		{"expired", models.Delegation{ExpiresAt: &before}, false},
+		{"expires now", models.Delegation{ExpiresAt: &now}, false},
+		{"revoked", models.Delegation{RevokedAt: &before, ExpiresAt: &after}, false},
+	}
+	for _, tt := range tests {
+		if got := DelegationActive(&tt.delegation, now); got != tt.want {
+			t.Errorf("%s: DelegationActive() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MinJustificationLength is the minimum length of a written override justification
+const MinJustificationLength = 20
+
+// Reasons an edit may not go ahead
+var (
+	ErrEditDenied            = errors.New("edit denied by policy")
+	ErrJustificationTooShort = fmt.Errorf("override requires a written justification of at least %d characters", MinJustificationLength)
+)
+
+// DefaultDoctorRole is the least-privileged role given to doctors at signup.
+// Other roles are assigned by admins.
+const DefaultDoctorRole = "resident"
+
+// DefaultEditPolicy is used when no policies have been configured by an admin.
+// It keeps the original behaviour (edits only below 90% confidence) but allows
+// a justified override.
+var DefaultEditPolicy = models.EditPolicy{
+	Name:          "default",
+	Description:   "Edits allowed below 90% confidence; override requires justification",
+	MaxConfidence: 90.0,
+	AllowOverride: true,
+	Enabled:       true,
+}
+
+// EditPolicyService evaluates admin-defined edit permission policies
+type EditPolicyService struct{}
+
+// LoadPolicies returns all enabled policies, falling back to DefaultEditPolicy
+func (s *EditPolicyService) LoadPolicies() ([]models.EditPolicy, error) {
+	collection := config.GetCollection("edit_policies")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"enabled": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch edit policies: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var policies []models.EditPolicy
+	if err = cursor.All(ctx, &policies); err != nil {
+		return nil, fmt.Errorf("failed to decode edit policies: %w", err)
+	}
+
+	if len(policies) == 0 {
+		policies = []models.EditPolicy{DefaultEditPolicy}
+	}
+	return policies, nil
+}
+
+// Evaluate decides whether a doctor may edit the analysis of a report
+func (s *EditPolicyService) Evaluate(doctor *models.Doctor, report *models.Report) (*models.EditDecision, error) {
+	policies, err := s.LoadPolicies()
+	if err != nil {
+		return nil, err
+	}
+	decision := EvaluateEditPolicies(policies, doctor, report)
+	return &decision, nil
+}
+
+// EvaluateEditPolicies applies policies in priority order. The first applicable
+// policy whose conditions are met allows the edit; otherwise the edit is denied
+// and an override is offered if any applicable policy permits one.
+func EvaluateEditPolicies(policies []models.EditPolicy, doctor *models.Doctor, report *models.Report) models.EditDecision {
+	sorted := make([]models.EditPolicy, len(policies))
+	copy(sorted, policies)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	decision := models.EditDecision{
+		Reason: "no edit policy applies to this doctor",
+	}
+	matched := false
+
+	for _, policy := range sorted {
+		if !policy.Enabled || !policyAppliesTo(policy, doctor) {
+			continue
+		}
+
+		reason := policyDenialReason(policy, report)
+		if reason == "" {
+			return models.EditDecision{
+				Allowed:    true,
+				PolicyName: policy.Name,
+				Reason:     "allowed by policy " + policy.Name,
+			}
+		}
+
+		// Remember the highest priority denial as the reported reason
+		if !matched {
+			decision.PolicyName = policy.Name
+			decision.Reason = reason
+			matched = true
+		}
+		if policy.AllowOverride {
+			decision.OverrideAllowed = true
+		}
+	}
+
+	return decision
+}
+
+// AuthorizeEdit decides whether an edit may proceed given the policy decision.
+// A denied edit may only go ahead as a justified override, reported by
+// overridden.
+func AuthorizeEdit(decision *models.EditDecision, override bool, justification string) (overridden bool, err error) {
+	if decision.Allowed {
+		return false, nil
+	}
+	if !override || !decision.OverrideAllowed {
+		return false, ErrEditDenied
+	}
+	if len(strings.TrimSpace(justification)) < MinJustificationLength {
+		return false, ErrJustificationTooShort
+	}
+	return true, nil
+}
+
+// NewOverrideAuditEntry builds the audit log entry flagging an overridden edit
+func NewOverrideAuditEntry(report *models.Report, doctorID, feedbackID primitive.ObjectID, decision *models.EditDecision, justification string) models.EditAuditEntry {
+	return models.EditAuditEntry{
+		ID:              primitive.NewObjectID(),
+		ReportID:        report.ID,
+		DoctorID:        doctorID,
+		FeedbackID:      feedbackID,
+		ConfidenceScore: report.AIAnalysis.ConfidenceScore,
+		ReportStatus:    report.Status,
+		DeniedReason:    decision.Reason,
+		PolicyName:      decision.PolicyName,
+		Justification:   justification,
+		FlaggedForAudit: true,
+		CreatedAt:       time.Now(),
+	}
+}
+
+// policyAppliesTo checks the doctor's role and specialization against the policy
+func policyAppliesTo(policy models.EditPolicy, doctor *models.Doctor) bool {
+	if len(policy.Roles) > 0 && !containsFold(policy.Roles, doctor.Role) {
+		return false
+	}
+	if len(policy.Specializations) > 0 && !containsFold(policy.Specializations, doctor.Specialization) {
+		return false
+	}
+	return true
+}
+
+// policyDenialReason returns why the policy denies the edit, or "" if allowed
+func policyDenialReason(policy models.EditPolicy, report *models.Report) string {
+	if len(policy.AllowedStatuses) > 0 && !containsFold(policy.AllowedStatuses, report.Status) {
+		return fmt.Sprintf("report status %q is not editable under policy %s", report.Status, policy.Name)
+	}
+	if policy.MaxConfidence > 0 && report.AIAnalysis.ConfidenceScore >= policy.MaxConfidence {
+		return fmt.Sprintf("confidence score %.1f%% is >= %.1f%% under policy %s",
+			report.AIAnalysis.ConfidenceScore, policy.MaxConfidence, policy.Name)
+	}
+	return ""
+}
+
+// containsFold reports whether values contains target, ignoring case
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(target)) {
+			return true
+		}
+	}
+	return false
+}
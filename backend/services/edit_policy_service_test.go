@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEvaluateEditPolicies(t *testing.T) {
+	resident := &models.Doctor{Role: "resident", Specialization: "Cardiology"}
+	attending := &models.Doctor{Role: "Attending", Specialization: "cardiology"}
+	report := func(status string, confidence float64) *models.Report {
+		r := &models.Report{Status: status}
+		r.AIAnalysis.ConfidenceScore = confidence
+		return r
+	}
+
+	strict := models.EditPolicy{Name: "strict", MaxConfidence: 50, Priority: 10, Enabled: true}
+	lenient := models.EditPolicy{Name: "lenient", MaxConfidence: 95, Priority: 1, Enabled: true}
+	attendings := models.EditPolicy{Name: "attendings", Roles: []string{"attending"}, Priority: 20, Enabled: true}
+	cardiology := models.EditPolicy{Name: "cardiology", Specializations: []string{"Cardiology"}, AllowedStatuses: []string{"pending"}, AllowOverride: true, Priority: 5, Enabled: true}
+	disabled := models.EditPolicy{Name: "disabled", Priority: 100}
+	overridable := models.EditPolicy{Name: "overridable", MaxConfidence: 50, AllowOverride: true, Priority: 1, Enabled: true}
+
+	tests := []struct {
+		name         string
+		policies     []models.EditPolicy
+		doctor       *models.Doctor
+		report       *models.Report
+		wantAllowed  bool
+		wantOverride bool
+		wantPolicy   string
+	}{
+		{"default below threshold", []models.EditPolicy{DefaultEditPolicy}, resident, report("pending", 89.9), true, false, "default"},
+		{"default at threshold", []models.EditPolicy{DefaultEditPolicy}, resident, report("pending", 90), false, true, "default"},
+		{"lower priority policy allows", []models.EditPolicy{lenient, strict}, resident, report("pending", 70), true, false, "lenient"},
+		{"highest priority denial reported", []models.EditPolicy{lenient, strict}, resident, report("pending", 97), false, false, "strict"},
+		{"higher priority policy allows first", []models.EditPolicy{strict, lenient}, resident, report("pending", 40), true, false, "strict"},
+		{"role matched ignoring case", []models.EditPolicy{attendings, strict}, attending, report("pending", 99), true, false, "attendings"},
+		{"role not matched", []models.EditPolicy{attendings}, resident, report("pending", 10), false, false, ""},
+		{"status not allowed", []models.EditPolicy{cardiology}, resident, report("reviewed", 10), false, true, "cardiology"},
+		{"override offered by any applicable policy", []models.EditPolicy{strict, overridable}, resident, report("pending", 60), false, true, "strict"},
+		{"disabled policy skipped", []models.EditPolicy{disabled, strict}, resident, report("pending", 60), false, false, "strict"},
+		{"no policies", nil, resident, report("pending", 10), false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateEditPolicies(tt.policies, tt.doctor, tt.report)
+			if got.Allowed != tt.wantAllowed || got.OverrideAllowed != tt.wantOverride || got.PolicyName != tt.wantPolicy {
+				t.Errorf("EvaluateEditPolicies() = %+v, want allowed %v, override %v, policy %q",
+					got, tt.wantAllowed, tt.wantOverride, tt.wantPolicy)
+			}
+			if got.Reason == "" {
+				t.Error("decision has no reason")
+			}
+		})
+	}
+}
+
+func TestDefaultEditPolicy(t *testing.T) {
+	if DefaultEditPolicy.MaxConfidence != 90 || !DefaultEditPolicy.AllowOverride || !DefaultEditPolicy.Enabled {
+		t.Errorf("DefaultEditPolicy = %+v, want edits below 90%% with override", DefaultEditPolicy)
+	}
+	if MinJustificationLength != 20 {
+		t.Errorf("MinJustificationLength = %d, want 20", MinJustificationLength)
+	}
+}
+
+func TestAuthorizeEdit(t *testing.T) {
+	allowed := &models.EditDecision{Allowed: true}
+	overridable := &models.EditDecision{OverrideAllowed: true}
+	denied := &models.EditDecision{}
+	justification := strings.Repeat("j", MinJustificationLength)
+
+	tests := []struct {
+		name           string
+		decision       *models.EditDecision
+		override       bool
+		justification  string
+		wantOverridden bool
+		wantErr        error
+	}{
+		{"allowed", allowed, false, "", false, nil},
+		{"allowed ignores override", allowed, true, justification, false, nil},
+		{"denied without override", overridable, false, justification, false, ErrEditDenied},
+		{"override not permitted", denied, true, justification, false, ErrEditDenied},
+		{"override accepted", overridable, true, justification, true, nil},
+		{"justification too short", overridable, true, justification[1:], false, ErrJustificationTooShort},
+		{"whitespace does not count", overridable, true, "   " + justification[1:] + "   ", false, ErrJustificationTooShort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overridden, err := AuthorizeEdit(tt.decision, tt.override, tt.justification)
+			if overridden != tt.wantOverridden || !errors.Is(err, tt.wantErr) {
+				t.Errorf("AuthorizeEdit() = %v, %v, want %v, %v", overridden, err, tt.wantOverridden, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewOverrideAuditEntry(t *testing.T) {
+	report := &models.Report{ID: primitive.NewObjectID(), Status: "pending"}
+	report.AIAnalysis.ConfidenceScore = 93.5
+	doctorID, feedbackID := primitive.NewObjectID(), primitive.NewObjectID()
+	decision := &models.EditDecision{OverrideAllowed: true, PolicyName: "default", Reason: "confidence too high"}
+
+	entry := NewOverrideAuditEntry(report, doctorID, feedbackID, decision, "Lab value misread as a diagnosis")
+	if entry.ID.IsZero() || entry.CreatedAt.IsZero() {
+		t.Error("entry has no ID or timestamp")
+	}
+	if entry.ReportID != report.ID || entry.DoctorID != doctorID || entry.FeedbackID != feedbackID {
+		t.Errorf("entry references = %+v", entry)
+	}
+	if entry.ConfidenceScore != 93.5 || entry.ReportStatus != "pending" || entry.DeniedReason != decision.Reason || entry.PolicyName != "default" {
+		t.Errorf("entry context = %+v", entry)
+	}
+	if !entry.FlaggedForAudit || entry.Justification != "Lab value misread as a diagnosis" {
+		t.Errorf("entry not flagged with its justification: %+v", entry)
+	}
+}
@@ -0,0 +1,564 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportSyncRowLimit is the largest export streamed directly in the response;
+// bigger exports run as background jobs
+const ExportSyncRowLimit = 5000
+
+// ExportRecommendationColumns is how many recommendations are flattened into
+// numbered rec_N_* columns
+const ExportRecommendationColumns = 5
+
+// exportRetention is how long finished export files remain downloadable
+const exportRetention = 24 * time.Hour
+
+// exportSlots limits concurrently running export jobs
+var exportSlots = make(chan struct{}, 2)
+
+// exportColumn is one selectable export column
+type exportColumn struct {
+	name    string
+	numeric bool
+	value   func(row *exportRow) string
+}
+
+// exportRow is one record being exported with its looked-up relations
+type exportRow struct {
+	report   *models.Report
+	patient  *models.Patient
+	doctor   *models.Doctor
+	reviewer *models.Doctor
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatExportNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func joinEntities(values []string) string {
+	return strings.Join(values, "; ")
+}
+
+// reportExportColumns lists the report columns in default order
+func reportExportColumns() []exportColumn {
+	columns := []exportColumn{
+		{name: "report_id", value: func(r *exportRow) string { return r.report.ID.Hex() }},
+		{name: "patient_id", value: func(r *exportRow) string { return r.report.PatientID.Hex() }},
+		{name: "patient_name", value: func(r *exportRow) string {
+			if r.patient == nil {
+				return ""
+			}
+			return r.patient.Name
+		}},
+		{name: "patient_age", numeric: true, value: func(r *exportRow) string {
+			if r.patient == nil || r.patient.Age == 0 {
+				return ""
+			}
+			return strconv.Itoa(r.patient.Age)
+		}},
+		{name: "patient_gender", value: func(r *exportRow) string {
+			if r.patient == nil {
+				return ""
+			}
+			return r.patient.Gender
+		}},
+		{name: "uploaded_at", value: func(r *exportRow) string { return formatExportTime(r.report.UploadedAt) }},
+		{name: "status", value: func(r *exportRow) string { return r.report.Status }},
+		{name: "source", value: func(r *exportRow) string {
+			if r.report.Source == "" {
+				return "upload"
+			}
+			return r.report.Source
+		}},
+		{name: "pdf_filename", value: func(r *exportRow) string { return r.report.PDFFileName }},
+		{name: "confidence_score", numeric: true, value: func(r *exportRow) string {
+			return formatExportNumber(r.report.AIAnalysis.ConfidenceScore)
+		}},
+		{name: "analyzer_version", value: func(r *exportRow) string {
+			if r.report.AIAnalysis.Analyzer == nil {
+				return ""
+			}
+			return r.report.AIAnalysis.Analyzer.Name + " " + r.report.AIAnalysis.Analyzer.Version
+		}},
+		{name: "reviewed_by", value: func(r *exportRow) string {
+			if r.report.DoctorReview == nil {
+				return ""
+			}
+			return r.report.DoctorReview.ReviewedBy.Hex()
+		}},
+		{name: "reviewer_name", value: func(r *exportRow) string {
+			if r.reviewer == nil {
+				return ""
+			}
+			return r.reviewer.Name
+		}},
+		{name: "reviewed_at", value: func(r *exportRow) string {
+			if r.report.DoctorReview == nil {
+				return ""
+			}
+			return formatExportTime(r.report.DoctorReview.ReviewedAt)
+		}},
+		{name: "review_notes", value: func(r *exportRow) string {
+			if r.report.DoctorReview == nil {
+				return ""
+			}
+			return r.report.DoctorReview.Notes
+		}},
+		{name: "signed_at", value: func(r *exportRow) string {
+			if r.report.Signature == nil {
+				return ""
+			}
+			return formatExportTime(r.report.Signature.SignedAt)
+		}},
+		{name: "diagnoses", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Diagnoses) }},
+		{name: "diagnosis_codes", value: func(r *exportRow) string {
+			var codes []string
+			for _, dx := range r.report.AIAnalysis.Entities.Diagnoses {
+				if coded := EntityCoding(&r.report.AIAnalysis, TermCategoryDiagnosis, dx); coded != nil {
+					codes = append(codes, coded.Code)
+				}
+			}
+			return joinEntities(codes)
+		}},
+		{name: "symptoms", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Symptoms) }},
+		{name: "medications", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Medications) }},
+		{name: "tests", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Tests) }},
+		{name: "vitals", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Vitals) }},
+		{name: "severity", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Severity) }},
+		{name: "urgency", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Entities.Urgency) }},
+		{name: "functional_impact", value: func(r *exportRow) string {
+			return joinEntities(r.report.AIAnalysis.Entities.FunctionalImpact)
+		}},
+		{name: "warnings", value: func(r *exportRow) string { return joinEntities(r.report.AIAnalysis.Warnings) }},
+		{name: "recommendation_count", numeric: true, value: func(r *exportRow) string {
+			return strconv.Itoa(len(r.report.AIAnalysis.Recommendations))
+		}},
+	}
+
+	for i := 0; i < ExportRecommendationColumns; i++ {
+		i := i
+		rec := func(r *exportRow) *models.Recommendation {
+			if i >= len(r.report.AIAnalysis.Recommendations) {
+				return nil
+			}
+			return &r.report.AIAnalysis.Recommendations[i]
+		}
+		prefix := fmt.Sprintf("rec_%d_", i+1)
+		columns = append(columns,
+			exportColumn{name: prefix + "test", value: func(r *exportRow) string {
+				if rec := rec(r); rec != nil {
+					return rec.Test
+				}
+				return ""
+			}},
+			exportColumn{name: prefix + "urgency", value: func(r *exportRow) string {
+				if rec := rec(r); rec != nil {
+					return rec.Urgency
+				}
+				return ""
+			}},
+			exportColumn{name: prefix + "confidence", numeric: true, value: func(r *exportRow) string {
+				if rec := rec(r); rec != nil {
+					return formatExportNumber(rec.Confidence)
+				}
+				return ""
+			}},
+			exportColumn{name: prefix + "reason", value: func(r *exportRow) string {
+				if rec := rec(r); rec != nil {
+					return rec.Reason
+				}
+				return ""
+			}},
+		)
+	}
+	return columns
+}
+
+func patientExportColumns() []exportColumn {
+	return []exportColumn{
+		{name: "patient_id", value: func(r *exportRow) string { return r.patient.ID.Hex() }},
+		{name: "name", value: func(r *exportRow) string { return r.patient.Name }},
+		{name: "email", value: func(r *exportRow) string { return r.patient.Email }},
+		{name: "age", numeric: true, value: func(r *exportRow) string {
+			if r.patient.Age == 0 {
+				return ""
+			}
+			return strconv.Itoa(r.patient.Age)
+		}},
+		{name: "gender", value: func(r *exportRow) string { return r.patient.Gender }},
+		{name: "phone", value: func(r *exportRow) string { return r.patient.Phone }},
+		{name: "identifiers", value: func(r *exportRow) string {
+			var values []string
+			for _, id := range r.patient.Identifiers {
+				values = append(values, strings.TrimPrefix(id.System+"|"+id.Value, "|"))
+			}
+			return joinEntities(values)
+		}},
+		{name: "created_at", value: func(r *exportRow) string { return formatExportTime(r.patient.CreatedAt) }},
+	}
+}
+
+func doctorExportColumns() []exportColumn {
+	return []exportColumn{
+		{name: "doctor_id", value: func(r *exportRow) string { return r.doctor.ID.Hex() }},
+		{name: "name", value: func(r *exportRow) string { return r.doctor.Name }},
+		{name: "email", value: func(r *exportRow) string { return r.doctor.Email }},
+		{name: "specialization", value: func(r *exportRow) string { return r.doctor.Specialization }},
+		{name: "role", value: func(r *exportRow) string { return r.doctor.Role }},
+		{name: "license_number", value: func(r *exportRow) string { return r.doctor.LicenseNumber }},
+		{name: "hospital", value: func(r *exportRow) string { return r.doctor.Hospital }},
+		{name: "created_at", value: func(r *exportRow) string { return formatExportTime(r.doctor.CreatedAt) }},
+	}
+}
+
+// exportDatasets maps dataset names to their collection, date field and columns
+var exportDatasets = map[string]struct {
+	collection string
+	dateField  string
+	columns    func() []exportColumn
+}{
+	"reports":  {"reports", "uploaded_at", reportExportColumns},
+	"patients": {"patients", "created_at", patientExportColumns},
+	"doctors":  {"doctors", "created_at", doctorExportColumns},
+}
+
+// ExportColumns returns the selectable column names of a dataset
+func ExportColumns(dataset string) ([]string, bool) {
+	ds, ok := exportDatasets[dataset]
+	if !ok {
+		return nil, false
+	}
+	var names []string
+	for _, column := range ds.columns() {
+		names = append(names, column.name)
+	}
+	return names, true
+}
+
+// ExportService produces bulk CSV and XLSX exports
+type ExportService struct{}
+
+// ValidateJob checks the dataset, format and columns of an export, filling in
+// the default columns when none were selected
+func (s *ExportService) ValidateJob(job *models.ExportJob) error {
+	available, ok := ExportColumns(job.Dataset)
+	if !ok {
+		return fmt.Errorf("unknown dataset %q (expected reports, patients or doctors)", job.Dataset)
+	}
+	if job.Format != "csv" && job.Format != "xlsx" {
+		return fmt.Errorf("format must be csv or xlsx")
+	}
+	if len(job.Columns) == 0 {
+		job.Columns = available
+		return nil
+	}
+	known := map[string]bool{}
+	for _, name := range available {
+		known[name] = true
+	}
+	for _, name := range job.Columns {
+		if !known[name] {
+			return fmt.Errorf("unknown column %q for dataset %s", name, job.Dataset)
+		}
+	}
+	if job.Dataset != "reports" && (len(job.Filters.Statuses) > 0 || job.Filters.Diagnosis != "" || job.Filters.DoctorID != nil) {
+		return fmt.Errorf("status, diagnosis and doctor filters only apply to the reports dataset")
+	}
+	return nil
+}
+
+// exportFilter converts export filters to a MongoDB filter for a dataset
+func exportFilter(dataset string, filters models.ExportFilters) bson.M {
+	filter := bson.M{}
+	dateRange := bson.M{}
+	if filters.From != nil {
+		dateRange["$gte"] = *filters.From
+	}
+	if filters.To != nil {
+		dateRange["$lt"] = *filters.To
+	}
+	if len(dateRange) > 0 {
+		filter[exportDatasets[dataset].dateField] = dateRange
+	}
+	if dataset == "reports" {
+		if len(filters.Statuses) > 0 {
+			filter["status"] = bson.M{"$in": filters.Statuses}
+		}
+		if filters.Diagnosis != "" {
+			filter["ai_analysis.entities.diagnoses"] = bson.M{"$regex": regexp.QuoteMeta(filters.Diagnosis), "$options": "i"}
+		}
+		if filters.DoctorID != nil {
+			filter["doctor_review.reviewed_by"] = *filters.DoctorID
+		}
+	}
+	return filter
+}
+
+// CountRows returns how many rows an export would produce
+func (s *ExportService) CountRows(job *models.ExportJob) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	collection := config.GetCollection(exportDatasets[job.Dataset].collection)
+	return collection.CountDocuments(ctx, exportFilter(job.Dataset, job.Filters))
+}
+
+// Write streams the export to w and returns the number of rows written
+func (s *ExportService) Write(ctx context.Context, job *models.ExportJob, w io.Writer) (int, error) {
+	ds := exportDatasets[job.Dataset]
+	byName := map[string]exportColumn{}
+	for _, column := range ds.columns() {
+		byName[column.name] = column
+	}
+	columns := make([]exportColumn, len(job.Columns))
+	numeric := make([]bool, len(job.Columns))
+	for i, name := range job.Columns {
+		columns[i] = byName[name]
+		numeric[i] = columns[i].numeric
+	}
+
+	writer, err := newExportWriter(job.Format, w)
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.WriteHeader(job.Columns); err != nil {
+		return 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: ds.dateField, Value: 1}}).SetBatchSize(500)
+	cursor, err := config.GetCollection(ds.collection).Find(ctx, exportFilter(job.Dataset, job.Filters), opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s: %w", job.Dataset, err)
+	}
+	defer cursor.Close(ctx)
+
+	lookup := newExportLookup()
+	rows := 0
+	values := make([]string, len(columns))
+	for cursor.Next(ctx) {
+		row := &exportRow{}
+		switch job.Dataset {
+		case "reports":
+			row.report = &models.Report{}
+			if err := cursor.Decode(row.report); err != nil {
+				return rows, err
+			}
+			row.patient = lookup.patient(ctx, row.report.PatientID)
+			if row.report.DoctorReview != nil {
+				row.reviewer = lookup.doctor(ctx, row.report.DoctorReview.ReviewedBy)
+			}
+		case "patients":
+			row.patient = &models.Patient{}
+			if err := cursor.Decode(row.patient); err != nil {
+				return rows, err
+			}
+		case "doctors":
+			row.doctor = &models.Doctor{}
+			if err := cursor.Decode(row.doctor); err != nil {
+				return rows, err
+			}
+		}
+
+		for i, column := range columns {
+			values[i] = column.value(row)
+		}
+		if err := writer.WriteRow(values, numeric); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	if err := cursor.Err(); err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// exportLookup caches patients and doctors referenced by exported reports
+type exportLookup struct {
+	patients map[primitive.ObjectID]*models.Patient
+	doctors  map[primitive.ObjectID]*models.Doctor
+}
+
+func newExportLookup() *exportLookup {
+	return &exportLookup{
+		patients: map[primitive.ObjectID]*models.Patient{},
+		doctors:  map[primitive.ObjectID]*models.Doctor{},
+	}
+}
+
+func (l *exportLookup) patient(ctx context.Context, id primitive.ObjectID) *models.Patient {
+	if p, ok := l.patients[id]; ok {
+		return p
+	}
+	var patient models.Patient
+	var result *models.Patient
+	if err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": id}).Decode(&patient); err == nil {
+		result = &patient
+	}
+	l.patients[id] = result
+	return result
+}
+
+func (l *exportLookup) doctor(ctx context.Context, id primitive.ObjectID) *models.Doctor {
+	if d, ok := l.doctors[id]; ok {
+		return d
+	}
+	var doctor models.Doctor
+	var result *models.Doctor
+	if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": id}).Decode(&doctor); err == nil {
+		result = &doctor
+	}
+	l.doctors[id] = result
+	return result
+}
+
+// ExportFileName is the download name of an export
+func ExportFileName(job *models.ExportJob) string {
+	return fmt.Sprintf("%s_export_%s.%s", job.Dataset, job.CreatedAt.Format("20060102_150405"), job.Format)
+}
+
+// StartJob stores a queued export job and generates it in the background
+func (s *ExportService) StartJob(job *models.ExportJob) error {
+	job.ID = primitive.NewObjectID()
+	job.Status = "queued"
+	job.CreatedAt = time.Now()
+	job.FileName = ExportFileName(job)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("export_jobs").InsertOne(ctx, job); err != nil {
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	go s.run(*job)
+	return nil
+}
+
+// run generates the export file for a job
+func (s *ExportService) run(job models.ExportJob) {
+	exportSlots <- struct{}{}
+	defer func() { <-exportSlots }()
+
+	s.purgeExpired()
+
+	collection := config.GetCollection("export_jobs")
+	update := func(fields bson.M) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": job.ID}, bson.M{"$set": fields}); err != nil {
+			log.Printf("export job %s: failed to update status: %v", job.ID.Hex(), err)
+		}
+	}
+	fail := func(err error) {
+		update(bson.M{"status": "failed", "error": err.Error(), "completed_at": time.Now()})
+	}
+
+	update(bson.M{"status": "running", "started_at": time.Now()})
+
+	dir := filepath.Join(os.TempDir(), "exports")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		fail(err)
+		return
+	}
+	path := filepath.Join(dir, job.ID.Hex()+"."+job.Format)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	rows, err := s.Write(ctx, &job, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		fail(err)
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	now := time.Now()
+	update(bson.M{
+		"status":       "completed",
+		"row_count":    rows,
+		"file_path":    path,
+		"file_size":    info.Size(),
+		"completed_at": now,
+		"expires_at":   now.Add(exportRetention),
+	})
+}
+
+// purgeExpired deletes files of exports past their retention period
+func (s *ExportService) purgeExpired() {
+	collection := config.GetCollection("export_jobs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"status": "completed", "expires_at": bson.M{"$lt": time.Now()}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var expired []models.ExportJob
+	if err := cursor.All(ctx, &expired); err != nil {
+		return
+	}
+	for _, job := range expired {
+		if job.FilePath != "" {
+			os.Remove(job.FilePath)
+		}
+		collection.UpdateOne(ctx, bson.M{"_id": job.ID}, bson.M{
+			"$set":   bson.M{"status": "expired"},
+			"$unset": bson.M{"file_path": ""},
+		})
+	}
+}
+
+// GetJob loads an export job
+func (s *ExportService) GetJob(id primitive.ObjectID) (*models.ExportJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.ExportJob
+	err := config.GetCollection("export_jobs").FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export job: %w", err)
+	}
+	return &job, nil
+}
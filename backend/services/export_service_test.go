@@ -0,0 +1,145 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateExportJob(t *testing.T) {
+	doctorID := primitive.NewObjectID()
+	tests := []struct {
+		name    string
+		job     models.ExportJob
+		wantErr bool
+	}{
+		{"defaults columns", models.ExportJob{Dataset: "patients", Format: "csv"}, false},
+		{"known columns", models.ExportJob{Dataset: "reports", Format: "xlsx", Columns: []string{"report_id"}}, false},
+		{"report filters", models.ExportJob{Dataset: "reports", Format: "csv", Filters: models.ExportFilters{Diagnosis: "asthma", DoctorID: &doctorID}}, false},
+		{"unknown dataset", models.ExportJob{Dataset: "feedback", Format: "csv"}, true},
+		{"unknown format", models.ExportJob{Dataset: "reports", Format: "pdf"}, true},
+		{"unknown column", models.ExportJob{Dataset: "reports", Format: "csv", Columns: []string{"report_id", "ssn"}}, true},
+		{"report filter on patients", models.ExportJob{Dataset: "patients", Format: "csv", Columns: []string{"patient_id"}, Filters: models.ExportFilters{Statuses: []string{"pending"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := tt.job
+			err := (&ExportService{}).ValidateJob(&job)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(job.Columns) == 0 {
+				t.Error("no columns selected after validation")
+			}
+		})
+	}
+
+	job := models.ExportJob{Dataset: "doctors", Format: "csv"}
+	if err := (&ExportService{}).ValidateJob(&job); err != nil {
+		t.Fatal(err)
+	}
+	available, _ := ExportColumns("doctors")
+	if strings.Join(job.Columns, ",") != strings.Join(available, ",") {
+		t.Errorf("default columns = %v, want %v", job.Columns, available)
+	}
+}
+
+func TestExportFilter(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	doctorID := primitive.NewObjectID()
+	filters := models.ExportFilters{From: &from, Statuses: []string{"reviewed"}, Diagnosis: "a.b", DoctorID: &doctorID}
+
+	reports := exportFilter("reports", filters)
+	if reports["uploaded_at"].(bson.M)["$gte"] != from {
+		t.Errorf("reports date filter = %v", reports["uploaded_at"])
+	}
+	if reports["ai_analysis.entities.diagnoses"].(bson.M)["$regex"] != `a\.b` {
+		t.Errorf("diagnosis is not matched literally: %v", reports["ai_analysis.entities.diagnoses"])
+	}
+	if reports["doctor_review.reviewed_by"] != doctorID {
+		t.Errorf("doctor filter = %v", reports["doctor_review.reviewed_by"])
+	}
+
+	patients := exportFilter("patients", filters)
+	if _, ok := patients["created_at"]; !ok || len(patients) != 1 {
+		t.Errorf("patients filter = %v, want only the created_at range", patients)
+	}
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newExportWriter("csv", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteHeader([]string{"name", "score"})
+	w.WriteRow([]string{"=HYPERLINK(\"x\")", "-3.5"}, []bool{false, true})
+	w.WriteRow([]string{"plain, text", ""}, []bool{false, true})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"name", "score"}, {"'=HYPERLINK(\"x\")", "-3.5"}, {"plain, text", ""}}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestXLSXExportWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newExportWriter("xlsx", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteHeader([]string{"name", "score"})
+	w.WriteRow([]string{"A & B <x>\x01", "42"}, []bool{false, true})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, f := range z.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := f.Open()
+			b, _ := io.ReadAll(r)
+			sheet = string(b)
+		}
+	}
+	for _, want := range []string{"A &amp; B &lt;x&gt;</t>", "<c><v>42</v></c>", "</sheetData></worksheet>"} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet is missing %q:\n%s", want, sheet)
+		}
+	}
+	if len(z.File) != 6 {
+		t.Errorf("workbook has %d parts, want 6", len(z.File))
+	}
+
+	if _, err := newExportWriter("pdf", &buf); err == nil {
+		t.Error("unsupported format accepted")
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	job := &models.ExportJob{Dataset: "reports", Format: "xlsx", CreatedAt: time.Date(2026, 3, 12, 9, 30, 5, 0, time.UTC)}
+	if got := ExportFileName(job); got != "reports_export_20260312_093005.xlsx" {
+		t.Errorf("ExportFileName() = %q", got)
+	}
+}
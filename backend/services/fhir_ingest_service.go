@@ -0,0 +1,792 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FHIRIngestVersion is stamped on analyses built from structured FHIR data
+const FHIRIngestVersion = "1.0"
+
+// maxFHIRAttachmentSize limits embedded PDFs to 20 MB
+const maxFHIRAttachmentSize = 20 << 20
+
+// FHIRIngestService maps incoming FHIR transaction bundles into reports
+type FHIRIngestService struct{}
+
+type fhirInboundBundle struct {
+	ResourceType string             `json:"resourceType"`
+	Type         string             `json:"type"`
+	Entry        []fhirInboundEntry `json:"entry"`
+}
+
+type fhirInboundEntry struct {
+	FullURL  string                    `json:"fullUrl"`
+	Resource json.RawMessage           `json:"resource"`
+	Request  *models.FHIRBundleRequest `json:"request"`
+}
+
+// ingestEntry is a parsed bundle entry together with its processing outcome
+type ingestEntry struct {
+	index        int
+	fullURL      string
+	resourceType string
+	id           string
+
+	patient     *models.FHIRPatient
+	report      *models.FHIRDiagnosticReport
+	observation *models.FHIRObservation
+	condition   *models.FHIRCondition
+	medication  *models.FHIRMedicationStatement
+	document    *models.FHIRDocumentReference
+
+	patientID primitive.ObjectID // resolved subject
+	created   bool               // patient entry created a new patient
+	owner     *pendingReport     // report this entry was folded into
+	location  string
+	err       error
+}
+
+// pendingReport is a report being assembled from one or more bundle entries
+type pendingReport struct {
+	report  models.Report
+	entries []*ingestEntry
+	pdf     []byte
+	runNLP  bool
+}
+
+// ingestIssue is a validation problem with a bundle entry
+type ingestIssue struct {
+	index      int
+	expression string
+	message    string
+}
+
+// ProcessBundle validates and ingests a transaction or batch bundle. It returns
+// either a response bundle or an OperationOutcome with the HTTP status to send.
+func (s *FHIRIngestService) ProcessBundle(body []byte) (*models.FHIRBundle, *models.FHIROperationOutcome, int) {
+	var bundle fhirInboundBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return nil, fhirOutcome("invalid", "request body is not valid FHIR JSON: "+err.Error()), 400
+	}
+	if bundle.ResourceType != "Bundle" {
+		return nil, fhirOutcome("invalid", "resourceType must be Bundle"), 400
+	}
+	if bundle.Type != "transaction" && bundle.Type != "batch" {
+		return nil, fhirOutcome("invalid", "Bundle.type must be transaction or batch"), 400
+	}
+	if len(bundle.Entry) == 0 {
+		return nil, fhirOutcome("invalid", "Bundle has no entries"), 400
+	}
+	transaction := bundle.Type == "transaction"
+
+	// Parse and validate every entry before writing anything
+	entries := make([]*ingestEntry, len(bundle.Entry))
+	var issues []ingestIssue
+	for i, raw := range bundle.Entry {
+		entry, entryIssues := parseIngestEntry(i, raw)
+		entries[i] = entry
+		issues = append(issues, entryIssues...)
+		if len(entryIssues) > 0 {
+			entry.err = errors.New(entryIssues[0].message)
+		}
+	}
+	if transaction && len(issues) > 0 {
+		return nil, issuesOutcome(issues), 400
+	}
+
+	index := indexEntries(entries)
+
+	// Resolve the patient for every clinical resource
+	var createdPatients []primitive.ObjectID
+	for _, entry := range entries {
+		if entry.err != nil {
+			continue
+		}
+		created, err := s.resolvePatient(entry, index)
+		if created != nil {
+			createdPatients = append(createdPatients, *created)
+		}
+		if err != nil {
+			entry.err = err
+			if transaction {
+				s.rollback(createdPatients, nil)
+				return nil, issuesOutcome([]ingestIssue{{index: entry.index, expression: "subject", message: err.Error()}}), 422
+			}
+		}
+	}
+
+	// Assemble reports and write them
+	pending := s.assembleReports(entries, index)
+	if transaction {
+		for _, entry := range entries {
+			if entry.err != nil {
+				s.rollback(createdPatients, nil)
+				return nil, issuesOutcome([]ingestIssue{{index: entry.index, expression: "resource", message: entry.err.Error()}}), 422
+			}
+		}
+	}
+	var createdReports []primitive.ObjectID
+	var saved []*pendingReport
+	for _, p := range pending {
+		if err := s.saveReport(p); err != nil {
+			for _, entry := range p.entries {
+				entry.err = err
+			}
+			if transaction {
+				s.rollback(createdPatients, createdReports)
+				return nil, fhirOutcome("processing", err.Error()), 500
+			}
+			continue
+		}
+		createdReports = append(createdReports, p.report.ID)
+		saved = append(saved, p)
+	}
+	// Alert and notify only once a transaction can no longer roll back
+	for _, p := range saved {
+		RaiseCriticalAlert(&p.report)
+		NotifyAnalysisComplete(&p.report)
+		SyncCareTasks(&p.report)
+		PublishWebhookEvent(WebhookReportUploaded, ReportWebhookData(&p.report))
+		PublishWebhookEvent(WebhookAnalysisCompleted, ReportWebhookData(&p.report))
+	}
+
+	return buildTransactionResponse(bundle.Type, entries), nil, 200
+}
+
+// parseIngestEntry decodes and validates one bundle entry
+func parseIngestEntry(i int, raw fhirInboundEntry) (*ingestEntry, []ingestIssue) {
+	entry := &ingestEntry{index: i, fullURL: raw.FullURL}
+	var issues []ingestIssue
+	issue := func(expression, message string) {
+		issues = append(issues, ingestIssue{index: i, expression: expression, message: message})
+	}
+
+	if raw.Request != nil && raw.Request.Method != "" && raw.Request.Method != "POST" && raw.Request.Method != "PUT" {
+		issue("request.method", "only POST and PUT are supported")
+	}
+
+	var header struct {
+		ResourceType string `json:"resourceType"`
+		ID           string `json:"id"`
+	}
+	if len(raw.Resource) == 0 || json.Unmarshal(raw.Resource, &header) != nil || header.ResourceType == "" {
+		issue("resource", "entry has no valid resource")
+		return entry, issues
+	}
+	entry.resourceType = header.ResourceType
+	entry.id = header.ID
+
+	decode := func(target interface{}) bool {
+		if err := json.Unmarshal(raw.Resource, target); err != nil {
+			issue("resource", fmt.Sprintf("invalid %s: %v", header.ResourceType, err))
+			return false
+		}
+		return true
+	}
+
+	switch header.ResourceType {
+	case "Patient":
+		entry.patient = &models.FHIRPatient{}
+		if decode(entry.patient) && len(entry.patient.Identifier) == 0 {
+			issue("resource.identifier", "Patient requires at least one identifier")
+		}
+	case "DiagnosticReport":
+		entry.report = &models.FHIRDiagnosticReport{}
+		if decode(entry.report) {
+			if entry.report.Status == "" {
+				issue("resource.status", "DiagnosticReport.status is required")
+			}
+			if conceptText(entry.report.Code) == "" {
+				issue("resource.code", "DiagnosticReport.code is required")
+			}
+			if entry.report.Subject == nil {
+				issue("resource.subject", "DiagnosticReport.subject is required")
+			}
+		}
+	case "Observation":
+		entry.observation = &models.FHIRObservation{}
+		if decode(entry.observation) {
+			if entry.observation.Status == "" {
+				issue("resource.status", "Observation.status is required")
+			}
+			if conceptText(entry.observation.Code) == "" {
+				issue("resource.code", "Observation.code is required")
+			}
+		}
+	case "Condition":
+		entry.condition = &models.FHIRCondition{}
+		if decode(entry.condition) && conceptText(entry.condition.Code) == "" {
+			issue("resource.code", "Condition.code is required")
+		}
+	case "MedicationStatement":
+		entry.medication = &models.FHIRMedicationStatement{}
+		if decode(entry.medication) {
+			if entry.medication.Status == "" {
+				issue("resource.status", "MedicationStatement.status is required")
+			}
+			if conceptText(entry.medication.MedicationCodeableConcept) == "" {
+				issue("resource.medicationCodeableConcept", "MedicationStatement.medicationCodeableConcept is required")
+			}
+		}
+	case "DocumentReference":
+		entry.document = &models.FHIRDocumentReference{}
+		if decode(entry.document) {
+			if entry.document.Status == "" {
+				issue("resource.status", "DocumentReference.status is required")
+			}
+			if entry.document.Subject == nil {
+				issue("resource.subject", "DocumentReference.subject is required")
+			}
+			if _, err := pdfAttachment(documentAttachments(entry.document)); err != nil {
+				issue("resource.content", err.Error())
+			}
+		}
+	default:
+		issue("resource.resourceType", fmt.Sprintf("resource type %s is not supported", header.ResourceType))
+	}
+
+	return entry, issues
+}
+
+// indexEntries maps fullUrl and Type/id references to bundle entries
+func indexEntries(entries []*ingestEntry) map[string]*ingestEntry {
+	index := map[string]*ingestEntry{}
+	for _, entry := range entries {
+		if entry.fullURL != "" {
+			index[entry.fullURL] = entry
+		}
+		if entry.resourceType != "" && entry.id != "" {
+			index[entry.resourceType+"/"+entry.id] = entry
+		}
+	}
+	return index
+}
+
+// subjectOf returns the subject reference of a clinical resource
+func subjectOf(entry *ingestEntry) *models.FHIRReference {
+	switch {
+	case entry.report != nil:
+		return entry.report.Subject
+	case entry.observation != nil:
+		if entry.observation.Subject.Reference == "" && entry.observation.Subject.Identifier == nil {
+			return nil
+		}
+		return &entry.observation.Subject
+	case entry.condition != nil:
+		return &entry.condition.Subject
+	case entry.medication != nil:
+		return &entry.medication.Subject
+	case entry.document != nil:
+		return entry.document.Subject
+	}
+	return nil
+}
+
+// resolvePatient links an entry to a patient. Patient entries are matched or
+// created; clinical resources follow their subject reference. It returns the
+// ID of any patient created so transactions can be rolled back.
+func (s *FHIRIngestService) resolvePatient(entry *ingestEntry, index map[string]*ingestEntry) (*primitive.ObjectID, error) {
+	if entry.patient != nil {
+		if !entry.patientID.IsZero() {
+			return nil, nil
+		}
+		patient, created, err := MatchOrCreatePatient(fhirPatientIdentifiers(entry.patient), fhirDemographics(entry.patient))
+		if err != nil {
+			return nil, fmt.Errorf("could not match or create patient: %w", err)
+		}
+		entry.patientID = patient.ID
+		entry.location = "Patient/" + patient.ID.Hex()
+		if created {
+			entry.created = true
+			return &patient.ID, nil
+		}
+		return nil, nil
+	}
+
+	subject := subjectOf(entry)
+	if subject == nil {
+		// Observations referenced by a DiagnosticReport inherit its subject
+		if entry.observation != nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s has no subject", entry.resourceType)
+	}
+
+	// Subject is another entry in this bundle
+	if target, ok := index[subject.Reference]; ok && target.patient != nil {
+		if target.err != nil {
+			return nil, fmt.Errorf("referenced patient is invalid: %w", target.err)
+		}
+		created, err := s.resolvePatient(target, index)
+		if err != nil {
+			return created, err
+		}
+		entry.patientID = target.patientID
+		return created, nil
+	}
+
+	var identifiers []models.PatientIdentifier
+	if strings.HasPrefix(subject.Reference, "Patient/") {
+		identifiers = append(identifiers, models.PatientIdentifier{
+			System: FHIRPatientIdentifierSystem,
+			Value:  strings.TrimPrefix(subject.Reference, "Patient/"),
+		})
+	}
+	if subject.Identifier != nil {
+		identifiers = append(identifiers, models.PatientIdentifier{
+			System: subject.Identifier.System,
+			Value:  subject.Identifier.Value,
+		})
+	}
+
+	patient, err := FindPatientByIdentifiers(identifiers)
+	if err != nil {
+		return nil, fmt.Errorf("subject could not be linked to a patient: %w", err)
+	}
+	entry.patientID = patient.ID
+	return nil, nil
+}
+
+// assembleReports groups resolved entries into reports
+func (s *FHIRIngestService) assembleReports(entries []*ingestEntry, index map[string]*ingestEntry) []*pendingReport {
+	var pending []*pendingReport
+	byPatient := map[primitive.ObjectID][]*pendingReport{}
+
+	newReport := func(patientID primitive.ObjectID, fileName string) *pendingReport {
+		p := &pendingReport{report: models.Report{
+			ID:          primitive.NewObjectID(),
+			PatientID:   patientID,
+			PDFFileName: fileName,
+			UploadedAt:  time.Now(),
+			Status:      "pending",
+			Source:      "fhir",
+			UpdatedAt:   time.Now(),
+		}}
+		pending = append(pending, p)
+		return p
+	}
+	attach := func(p *pendingReport, entry *ingestEntry) {
+		entry.owner = p
+		p.entries = append(p.entries, entry)
+	}
+
+	// DiagnosticReports, with their referenced Observations
+	for _, entry := range entries {
+		if entry.err != nil || entry.report == nil {
+			continue
+		}
+		dr := entry.report
+		p := newReport(entry.patientID, "")
+		if len(dr.Identifier) > 0 {
+			p.report.ExternalID = dr.Identifier[0].Value
+		}
+		if t, err := time.Parse(time.RFC3339, dr.EffectiveDateTime); err == nil {
+			p.report.UploadedAt = t
+		}
+		attach(p, entry)
+		byPatient[entry.patientID] = append(byPatient[entry.patientID], p)
+
+		for _, ref := range dr.Result {
+			if target, ok := index[ref.Reference]; ok && target.observation != nil && target.err == nil && target.owner == nil {
+				if target.patientID.IsZero() {
+					target.patientID = entry.patientID
+				}
+				attach(p, target)
+			}
+		}
+
+		if pdf, err := pdfAttachment(dr.PresentedForm); err == nil {
+			p.pdf = pdf.data
+			p.report.PDFFileName = pdf.title
+		}
+	}
+
+	// DocumentReferences carry a PDF that goes through the NLP pipeline
+	for _, entry := range entries {
+		if entry.err != nil || entry.document == nil {
+			continue
+		}
+		pdf, _ := pdfAttachment(documentAttachments(entry.document))
+		p := newReport(entry.patientID, pdf.title)
+		if len(entry.document.Identifier) > 0 {
+			p.report.ExternalID = entry.document.Identifier[0].Value
+		}
+		p.pdf = pdf.data
+		p.runNLP = true
+		attach(p, entry)
+	}
+
+	// Remaining clinical resources join the patient's only DiagnosticReport in
+	// this bundle, or a report created for the patient's standalone resources
+	standalone := map[primitive.ObjectID]*pendingReport{}
+	for _, entry := range entries {
+		if entry.err != nil || entry.owner != nil {
+			continue
+		}
+		if entry.observation == nil && entry.condition == nil && entry.medication == nil {
+			continue
+		}
+		if entry.patientID.IsZero() {
+			entry.err = fmt.Errorf("%s has no subject", entry.resourceType)
+			continue
+		}
+
+		var p *pendingReport
+		if reports := byPatient[entry.patientID]; len(reports) == 1 {
+			p = reports[0]
+		} else if existing, ok := standalone[entry.patientID]; ok {
+			p = existing
+		} else {
+			p = newReport(entry.patientID, "")
+			standalone[entry.patientID] = p
+		}
+		attach(p, entry)
+	}
+
+	for _, p := range pending {
+		buildStructuredAnalysis(p)
+	}
+	return pending
+}
+
+// buildStructuredAnalysis fills the report's analysis from its structured entries.
+// Reports with structured data skip NLP even when a PDF is supplied.
+func buildStructuredAnalysis(p *pendingReport) {
+	analysis := &p.report.AIAnalysis
+	structured := false
+
+	for _, entry := range p.entries {
+		switch {
+		case entry.report != nil:
+			for _, code := range entry.report.ConclusionCode {
+				if text := conceptText(code); text != "" {
+					analysis.Entities.Diagnoses = append(analysis.Entities.Diagnoses, text)
+					structured = true
+				}
+			}
+			entry.location = "DiagnosticReport/" + p.report.ID.Hex()
+		case entry.observation != nil:
+			obs := labObservationFromFHIR(entry.observation)
+			p.report.Observations = append(p.report.Observations, obs)
+			entry.location = "Observation/" + DerivedResourceID(p.report.ID, FHIRKindObservation, len(p.report.Observations)-1)
+			structured = true
+		case entry.condition != nil:
+			analysis.Entities.Diagnoses = append(analysis.Entities.Diagnoses, conceptText(entry.condition.Code))
+			entry.location = "Condition/" + DerivedResourceID(p.report.ID, FHIRKindDiagnosis, len(analysis.Entities.Diagnoses)-1)
+			structured = true
+		case entry.medication != nil:
+			analysis.Entities.Medications = append(analysis.Entities.Medications, conceptText(entry.medication.MedicationCodeableConcept))
+			entry.location = "MedicationStatement/" + DerivedResourceID(p.report.ID, FHIRKindMedication, len(analysis.Entities.Medications)-1)
+			structured = true
+		case entry.document != nil:
+			entry.location = "DiagnosticReport/" + p.report.ID.Hex()
+		}
+	}
+
+	ApplyObservationsToAnalysis(analysis, p.report.Observations)
+
+	if !structured && len(p.pdf) > 0 {
+		p.runNLP = true
+	}
+	if !p.runNLP {
+		analysis.Analyzer = &models.AnalyzerInfo{Name: "fhir-ingest", Version: FHIRIngestVersion, AnalyzedAt: time.Now()}
+		ApplyTerminology(analysis)
+		ApplyClinicalRules(analysis, p.report.Observations, p.report.PatientID, p.report.UploadedAt)
+		ApplyConfidenceScore(analysis)
+		// After scoring: interaction warnings are not extraction problems
+		ApplyInteractionChecks(analysis, p.report.PatientID)
+	}
+}
+
+// ApplyObservationsToAnalysis records structured observations as test and vital
+// entities and flags abnormal results as warnings
+func ApplyObservationsToAnalysis(analysis *models.AIAnalysis, observations []models.LabObservation) {
+	for _, obs := range observations {
+		if obs.Category == "vital-signs" {
+			analysis.Entities.Vitals = append(analysis.Entities.Vitals, ObservationEntityText(obs))
+		} else {
+			analysis.Entities.Tests = append(analysis.Entities.Tests, obs.Display)
+		}
+		if flag := strings.ToUpper(obs.Interpretation); flag != "" && flag != "N" {
+			analysis.Warnings = append(analysis.Warnings,
+				fmt.Sprintf("Abnormal result (%s): %s", flag, ObservationEntityText(obs)))
+		}
+	}
+}
+
+// ObservationEntityText renders an observation as "Display value unit"
+func ObservationEntityText(obs models.LabObservation) string {
+	parts := []string{obs.Display}
+	if obs.Value != nil {
+		parts = append(parts, fmt.Sprintf("%g", *obs.Value))
+	} else if obs.ValueString != "" {
+		parts = append(parts, obs.ValueString)
+	}
+	if obs.Unit != "" {
+		parts = append(parts, obs.Unit)
+	}
+	return strings.Join(parts, " ")
+}
+
+// saveReport writes the PDF (if any), runs NLP when needed and inserts the report
+func (s *FHIRIngestService) saveReport(p *pendingReport) error {
+	if len(p.pdf) > 0 {
+		if p.report.PDFFileName == "" {
+			p.report.PDFFileName = fmt.Sprintf("fhir_%s.pdf", p.report.ID.Hex())
+		}
+		path := filepath.Join(os.TempDir(), fmt.Sprintf("report_%d_%s", time.Now().UnixNano(), filepath.Base(p.report.PDFFileName)))
+		if err := os.WriteFile(path, p.pdf, 0600); err != nil {
+			return fmt.Errorf("failed to save attachment: %w", err)
+		}
+		p.report.PDFPath = path
+
+		// Reports of patients who have not consented are stored unanalysed
+		if p.runNLP {
+			if !s.analysisConsented(p.report.PatientID) {
+				p.report.AnalysisSkipped = AnalysisSkippedNoConsent
+			} else {
+				analysis, err := AnalyzePDFReport(path, p.report.PatientID, p.report.UploadedAt)
+				if err != nil {
+					return fmt.Errorf("analysis failed: %w", err)
+				}
+				p.report.AIAnalysis = *analysis
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("reports").InsertOne(ctx, p.report); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	return nil
+}
+
+// analysisConsented reports whether a patient consents to AI analysis. A
+// failed lookup is treated as no consent.
+func (s *FHIRIngestService) analysisConsented(patientID primitive.ObjectID) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	consented, err := HasConsent(ctx, patientID, ConsentAIAnalysis)
+	if err != nil {
+		log.Printf("fhir ingest: failed to check AI analysis consent of %s: %v", patientID.Hex(), err)
+	}
+	return consented
+}
+
+// rollback removes patients and reports created by a failed transaction
+func (s *FHIRIngestService) rollback(patients, reports []primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if len(reports) > 0 {
+		config.GetCollection("reports").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": reports}})
+	}
+	if len(patients) > 0 {
+		config.GetCollection("patients").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": patients}})
+	}
+}
+
+// buildTransactionResponse builds the transaction-response or batch-response bundle
+func buildTransactionResponse(bundleType string, entries []*ingestEntry) *models.FHIRBundle {
+	response := &models.FHIRBundle{
+		ResourceType: "Bundle",
+		ID:           primitive.NewObjectID().Hex(),
+		Type:         bundleType + "-response",
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, entry := range entries {
+		var r models.FHIRBundleResponse
+		switch {
+		case entry.err != nil:
+			r = models.FHIRBundleResponse{
+				Status:  "422 Unprocessable Entity",
+				Outcome: fhirOutcome("processing", entry.err.Error()),
+			}
+		case entry.patient != nil && !entry.created:
+			r = models.FHIRBundleResponse{Status: "200 OK", Location: entry.location, LastModified: now}
+		default:
+			r = models.FHIRBundleResponse{Status: "201 Created", Location: entry.location, LastModified: now}
+		}
+		response.Entry = append(response.Entry, models.FHIRBundleEntry{Response: &r})
+	}
+	return response
+}
+
+// labObservationFromFHIR converts a FHIR Observation to a LabObservation
+func labObservationFromFHIR(o *models.FHIRObservation) models.LabObservation {
+	obs := models.LabObservation{
+		Display:     conceptText(o.Code),
+		Status:      o.Status,
+		ValueString: o.ValueString,
+	}
+	if len(o.Code.Coding) > 0 {
+		obs.Code = o.Code.Coding[0].Code
+		obs.CodeSystem = o.Code.Coding[0].System
+	}
+	for _, category := range o.Category {
+		if len(category.Coding) > 0 {
+			obs.Category = category.Coding[0].Code
+			break
+		}
+	}
+	if o.ValueQuantity != nil {
+		obs.Value = o.ValueQuantity.Value
+		obs.Unit = o.ValueQuantity.Unit
+		if obs.Unit == "" {
+			obs.Unit = o.ValueQuantity.Code
+		}
+	}
+	if len(o.Interpretation) > 0 && len(o.Interpretation[0].Coding) > 0 {
+		obs.Interpretation = o.Interpretation[0].Coding[0].Code
+	}
+	if len(o.ReferenceRange) > 0 {
+		rr := o.ReferenceRange[0]
+		switch {
+		case rr.Text != "":
+			obs.ReferenceRange = rr.Text
+		case rr.Low != nil && rr.High != nil && rr.Low.Value != nil && rr.High.Value != nil:
+			obs.ReferenceRange = fmt.Sprintf("%g-%g", *rr.Low.Value, *rr.High.Value)
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, o.EffectiveDateTime); err == nil {
+		obs.EffectiveAt = &t
+	}
+	return obs
+}
+
+// fhirPatientIdentifiers converts FHIR identifiers to patient identifiers
+func fhirPatientIdentifiers(p *models.FHIRPatient) []models.PatientIdentifier {
+	var identifiers []models.PatientIdentifier
+	for _, id := range p.Identifier {
+		identifiers = append(identifiers, models.PatientIdentifier{System: id.System, Value: id.Value})
+	}
+	return identifiers
+}
+
+// fhirDemographics extracts demographics from a FHIR Patient
+func fhirDemographics(p *models.FHIRPatient) *PatientDemographics {
+	demographics := &PatientDemographics{Gender: p.Gender}
+	if len(p.Name) > 0 {
+		name := p.Name[0]
+		demographics.Name = name.Text
+		if demographics.Name == "" {
+			demographics.Name = strings.TrimSpace(strings.Join(append(append([]string{}, name.Given...), name.Family), " "))
+		}
+	}
+	for _, telecom := range p.Telecom {
+		switch telecom.System {
+		case "phone":
+			demographics.Phone = telecom.Value
+		case "email":
+			demographics.Email = telecom.Value
+		}
+	}
+	if birth, err := time.Parse("2006-01-02", p.BirthDate); err == nil {
+		demographics.Age = ageAt(birth, time.Now())
+	}
+	return demographics
+}
+
+// ageAt returns the age in whole years on the given date
+func ageAt(birth, on time.Time) int {
+	age := on.Year() - birth.Year()
+	if on.YearDay() < birth.YearDay() {
+		age--
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// conceptText returns the text of a CodeableConcept, falling back to the first coding
+func conceptText(concept models.FHIRCodeableConcept) string {
+	if text := strings.TrimSpace(concept.Text); text != "" {
+		return text
+	}
+	for _, coding := range concept.Coding {
+		if coding.Display != "" {
+			return coding.Display
+		}
+		if coding.Code != "" {
+			return coding.Code
+		}
+	}
+	return ""
+}
+
+func documentAttachments(doc *models.FHIRDocumentReference) []models.FHIRAttachment {
+	var attachments []models.FHIRAttachment
+	for _, content := range doc.Content {
+		attachments = append(attachments, content.Attachment)
+	}
+	return attachments
+}
+
+type decodedAttachment struct {
+	data  []byte
+	title string
+}
+
+// pdfAttachment returns the first embedded PDF among the attachments
+func pdfAttachment(attachments []models.FHIRAttachment) (decodedAttachment, error) {
+	for _, attachment := range attachments {
+		if attachment.ContentType != "application/pdf" || attachment.Data == "" {
+			continue
+		}
+		if base64.StdEncoding.DecodedLen(len(attachment.Data)) > maxFHIRAttachmentSize {
+			return decodedAttachment{}, fmt.Errorf("embedded PDF exceeds %d MB", maxFHIRAttachmentSize>>20)
+		}
+		data, err := base64.StdEncoding.DecodeString(attachment.Data)
+		if err != nil {
+			return decodedAttachment{}, fmt.Errorf("attachment data is not valid base64")
+		}
+		if !strings.HasPrefix(string(data), "%PDF") {
+			return decodedAttachment{}, fmt.Errorf("attachment is not a PDF document")
+		}
+		return decodedAttachment{data: data, title: attachment.Title}, nil
+	}
+	return decodedAttachment{}, fmt.Errorf("an embedded application/pdf attachment is required")
+}
+
+// fhirOutcome builds a single-issue OperationOutcome
+func fhirOutcome(code, diagnostics string) *models.FHIROperationOutcome {
+	return &models.FHIROperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []models.FHIROperationOutcomeIssue{{
+			Severity:    "error",
+			Code:        code,
+			Diagnostics: diagnostics,
+		}},
+	}
+}
+
+// issuesOutcome builds an OperationOutcome from validation issues
+func issuesOutcome(issues []ingestIssue) *models.FHIROperationOutcome {
+	outcome := &models.FHIROperationOutcome{ResourceType: "OperationOutcome"}
+	for _, issue := range issues {
+		outcome.Issue = append(outcome.Issue, models.FHIROperationOutcomeIssue{
+			Severity:    "error",
+			Code:        "invalid",
+			Diagnostics: issue.message,
+			Expression:  []string{fmt.Sprintf("Bundle.entry[%d].%s", issue.index, issue.expression)},
+		})
+	}
+	return outcome
+}
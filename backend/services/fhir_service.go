@@ -0,0 +1,666 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Identifier systems for resources originating in this backend
+const (
+	FHIRPatientIdentifierSystem      = "urn:medical-report-analyzer:patient"
+	FHIRPractitionerIdentifierSystem = "urn:medical-report-analyzer:doctor"
+	FHIRLicenseIdentifierSystem      = "urn:medical-report-analyzer:license"
+	FHIRReportIdentifierSystem       = "urn:medical-report-analyzer:report"
+	FHIRPatientAgeExtensionURL       = "urn:medical-report-analyzer:fhir:patient-age"
+
+	fhirObservationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+	fhirConditionCategorySystem   = "http://terminology.hl7.org/CodeSystem/condition-category"
+	fhirVerificationStatusSystem  = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+	fhirClinicalStatusSystem      = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	fhirDiagnosticServiceSystem   = "http://terminology.hl7.org/CodeSystem/v2-0074"
+	fhirInterpretationSystem      = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+)
+
+// Derived resource kinds used in composite IDs: <reportID>-<kind>-<index>
+const (
+	FHIRKindDiagnosis   = "dx"
+	FHIRKindSymptom     = "sx"
+	FHIRKindMedication  = "med"
+	FHIRKindTest        = "test"
+	FHIRKindVital       = "vital"
+	FHIRKindRecommend   = "rec"
+	FHIRKindObservation = "obs" // structured lab observations
+)
+
+// FHIRService maps backend models to FHIR R4 resources
+type FHIRService struct{}
+
+// ReportSearch holds the supported DiagnosticReport search parameters
+type ReportSearch struct {
+	PatientID *primitive.ObjectID
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+	Statuses  []string  // internal report statuses
+}
+
+// DerivedResourceID builds the ID of a resource derived from a report entity
+func DerivedResourceID(reportID primitive.ObjectID, kind string, index int) string {
+	return fmt.Sprintf("%s-%s-%d", reportID.Hex(), kind, index)
+}
+
+// ParseDerivedResourceID splits a derived resource ID into its parts
+func ParseDerivedResourceID(id string) (primitive.ObjectID, string, int, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 3 {
+		return primitive.NilObjectID, "", 0, fmt.Errorf("invalid resource id")
+	}
+	reportID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return primitive.NilObjectID, "", 0, fmt.Errorf("invalid resource id")
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil || index < 0 {
+		return primitive.NilObjectID, "", 0, fmt.Errorf("invalid resource id")
+	}
+	return reportID, parts[1], index, nil
+}
+
+// DiagnosticReportStatus maps an internal report status to a FHIR status code
+func DiagnosticReportStatus(status string) string {
+	switch status {
+	case "pending":
+		return "preliminary"
+	case "reviewed", "signed":
+		return "final"
+	case "edited":
+		return "corrected"
+	case "amended":
+		return "amended"
+	}
+	return "unknown"
+}
+
+// InternalReportStatuses maps a FHIR DiagnosticReport status to internal statuses
+func InternalReportStatuses(fhirStatus string) []string {
+	var statuses []string
+	for _, status := range []string{"pending", "reviewed", "edited", "signed", "amended"} {
+		if DiagnosticReportStatus(status) == fhirStatus {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// ServiceRequestPriority maps recommendation urgency to a FHIR request priority
+func ServiceRequestPriority(urgency string) string {
+	switch strings.ToLower(strings.TrimSpace(urgency)) {
+	case "emergent", "stat", "critical":
+		return "stat"
+	case "high", "urgent":
+		return "urgent"
+	case "asap":
+		return "asap"
+	}
+	return "routine"
+}
+
+func fhirTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func fhirMeta(updated time.Time) *models.FHIRMeta {
+	if updated.IsZero() {
+		return nil
+	}
+	return &models.FHIRMeta{LastUpdated: fhirTime(updated)}
+}
+
+// splitName splits a display name into given names and family name
+func splitName(name string) models.FHIRHumanName {
+	fields := strings.Fields(name)
+	human := models.FHIRHumanName{Use: "official", Text: name}
+	if len(fields) > 1 {
+		human.Family = fields[len(fields)-1]
+		human.Given = fields[:len(fields)-1]
+	} else if len(fields) == 1 {
+		human.Given = fields
+	}
+	return human
+}
+
+// fhirGender maps free-text gender to the FHIR administrative gender value set
+func fhirGender(gender string) string {
+	switch strings.ToLower(strings.TrimSpace(gender)) {
+	case "male", "m":
+		return "male"
+	case "female", "f":
+		return "female"
+	case "":
+		return ""
+	case "other":
+		return "other"
+	}
+	return "unknown"
+}
+
+// PatientToFHIR maps a patient to a FHIR Patient
+func (s *FHIRService) PatientToFHIR(p *models.Patient) models.FHIRPatient {
+	resource := models.FHIRPatient{
+		ResourceType: "Patient",
+		ID:           p.ID.Hex(),
+		Meta:         fhirMeta(p.UpdatedAt),
+		Identifier: []models.FHIRIdentifier{
+			{Use: "official", System: FHIRPatientIdentifierSystem, Value: p.ID.Hex()},
+		},
+		Active: true,
+		Name:   []models.FHIRHumanName{splitName(p.Name)},
+		Gender: fhirGender(p.Gender),
+	}
+	for _, identifier := range p.Identifiers {
+		resource.Identifier = append(resource.Identifier, models.FHIRIdentifier{
+			Use:    "secondary",
+			System: identifier.System,
+			Value:  identifier.Value,
+		})
+	}
+	if p.Email != "" {
+		resource.Telecom = append(resource.Telecom, models.FHIRContactPoint{System: "email", Value: p.Email})
+	}
+	if p.Phone != "" {
+		resource.Telecom = append(resource.Telecom, models.FHIRContactPoint{System: "phone", Value: p.Phone})
+	}
+	if p.Age > 0 {
+		age := p.Age
+		resource.Extension = append(resource.Extension, models.FHIRExtension{
+			URL:          FHIRPatientAgeExtensionURL,
+			ValueInteger: &age,
+		})
+	}
+	return resource
+}
+
+// DoctorToFHIR maps a doctor to a FHIR Practitioner
+func (s *FHIRService) DoctorToFHIR(d *models.Doctor) models.FHIRPractitioner {
+	resource := models.FHIRPractitioner{
+		ResourceType: "Practitioner",
+		ID:           d.ID.Hex(),
+		Meta:         fhirMeta(d.UpdatedAt),
+		Identifier: []models.FHIRIdentifier{
+			{Use: "official", System: FHIRPractitionerIdentifierSystem, Value: d.ID.Hex()},
+		},
+		Active: true,
+		Name:   []models.FHIRHumanName{splitName(d.Name)},
+	}
+	if d.LicenseNumber != "" {
+		resource.Identifier = append(resource.Identifier, models.FHIRIdentifier{
+			System: FHIRLicenseIdentifierSystem,
+			Value:  d.LicenseNumber,
+		})
+	}
+	if d.Email != "" {
+		resource.Telecom = append(resource.Telecom, models.FHIRContactPoint{System: "email", Value: d.Email, Use: "work"})
+	}
+	if d.Specialization != "" {
+		resource.Qualification = append(resource.Qualification, models.FHIRPractitionerQualification{
+			Code: models.FHIRCodeableConcept{Text: d.Specialization},
+		})
+	}
+	return resource
+}
+
+// ReportToDiagnosticReport maps a report to a FHIR DiagnosticReport. baseURL is
+// the public API root used to link the original PDF.
+func (s *FHIRService) ReportToDiagnosticReport(r *models.Report, baseURL string) models.FHIRDiagnosticReport {
+	resource := models.FHIRDiagnosticReport{
+		ResourceType: "DiagnosticReport",
+		ID:           r.ID.Hex(),
+		Meta:         fhirMeta(r.UpdatedAt),
+		Identifier: []models.FHIRIdentifier{
+			{System: FHIRReportIdentifierSystem, Value: r.ID.Hex()},
+		},
+		Status: DiagnosticReportStatus(r.Status),
+		Category: []models.FHIRCodeableConcept{{
+			Coding: []models.FHIRCoding{{System: fhirDiagnosticServiceSystem, Code: "OTH", Display: "Other"}},
+		}},
+		Code:              models.FHIRCodeableConcept{Text: "AI-assisted medical report analysis"},
+		EffectiveDateTime: fhirTime(r.UploadedAt),
+		Issued:            fhirTime(r.UploadedAt),
+	}
+	if !r.PatientID.IsZero() {
+		resource.Subject = &models.FHIRReference{Reference: "Patient/" + r.PatientID.Hex()}
+	}
+	if r.DoctorReview != nil && !r.DoctorReview.ReviewedBy.IsZero() {
+		resource.ResultsInterpreter = []models.FHIRReference{{Reference: "Practitioner/" + r.DoctorReview.ReviewedBy.Hex()}}
+		resource.Conclusion = r.DoctorReview.Notes
+	}
+
+	for _, obs := range s.ReportObservations(r) {
+		resource.Result = append(resource.Result, models.FHIRReference{Reference: "Observation/" + obs.ID})
+	}
+	for _, dx := range r.AIAnalysis.Entities.Diagnoses {
+		resource.ConclusionCode = append(resource.ConclusionCode, entityConcept(r, TermCategoryDiagnosis, dx))
+	}
+	if r.PDFFileName != "" {
+		resource.PresentedForm = []models.FHIRAttachment{{
+			ContentType: "application/pdf",
+			URL:         fmt.Sprintf("%s/api/patient/reports/%s/download", strings.TrimRight(baseURL, "/"), r.ID.Hex()),
+			Title:       r.PDFFileName,
+			Creation:    fhirTime(r.UploadedAt),
+		}}
+	}
+	return resource
+}
+
+// reportReviewed reports whether findings have been confirmed by a doctor
+func reportReviewed(r *models.Report) bool {
+	return r.Status != "pending" && r.Status != ""
+}
+
+// entityConcept builds the concept of an extracted entity, adding its stored
+// terminology coding when the match is confident enough
+func entityConcept(r *models.Report, category, text string) models.FHIRCodeableConcept {
+	concept := models.FHIRCodeableConcept{Text: text}
+	if coded := EntityCoding(&r.AIAnalysis, category, text); coded != nil {
+		concept.Coding = []models.FHIRCoding{{System: coded.System, Code: coded.Code, Display: coded.Display}}
+	}
+	return concept
+}
+
+func patientReference(r *models.Report) models.FHIRReference {
+	if r.PatientID.IsZero() {
+		return models.FHIRReference{Display: "Unknown patient"}
+	}
+	return models.FHIRReference{Reference: "Patient/" + r.PatientID.Hex()}
+}
+
+func reportReference(r *models.Report) models.FHIRReference {
+	return models.FHIRReference{Reference: "DiagnosticReport/" + r.ID.Hex()}
+}
+
+// conditionStatus maps an entity's assertion to a Condition's clinical and
+// verification status. Affirmed findings are provisional until a doctor
+// reviews the report; an empty clinical status is left out.
+func conditionStatus(assertion string, reviewed bool) (clinical, verification string) {
+	switch assertion {
+	case AssertionNegated:
+		return "", "refuted"
+	case AssertionHypothetical:
+		return "", "unconfirmed"
+	case AssertionHistorical:
+		clinical = "inactive"
+	default:
+		clinical = "active"
+	}
+	verification = "provisional"
+	if reviewed {
+		verification = "confirmed"
+	}
+	return clinical, verification
+}
+
+// medicationStatus maps a medication's assertion to a MedicationStatement status
+func medicationStatus(assertion string) string {
+	switch assertion {
+	case AssertionNegated:
+		return "not-taken"
+	case AssertionHistorical:
+		return "completed"
+	case AssertionHypothetical:
+		return "intended"
+	}
+	return "unknown"
+}
+
+// ReportConditions maps extracted diagnoses and symptoms to FHIR Conditions.
+// Family history is not a condition of the patient and is left out.
+func (s *FHIRService) ReportConditions(r *models.Report) []models.FHIRCondition {
+	reviewed := reportReviewed(r)
+
+	build := func(kind, termCategory, text, category, categoryDisplay string, index int) models.FHIRCondition {
+		clinical, verification := conditionStatus(EntityStatus(&r.AIAnalysis, termCategory, text), reviewed)
+		condition := models.FHIRCondition{
+			ResourceType: "Condition",
+			ID:           DerivedResourceID(r.ID, kind, index),
+			Meta:         fhirMeta(r.UpdatedAt),
+			VerificationStatus: &models.FHIRCodeableConcept{
+				Coding: []models.FHIRCoding{{System: fhirVerificationStatusSystem, Code: verification}},
+			},
+			Category: []models.FHIRCodeableConcept{{
+				Coding: []models.FHIRCoding{{System: fhirConditionCategorySystem, Code: category, Display: categoryDisplay}},
+			}},
+			Code:         entityConcept(r, termCategory, text),
+			Subject:      patientReference(r),
+			RecordedDate: fhirTime(r.UploadedAt),
+			Evidence:     []models.FHIRConditionEvidence{{Detail: []models.FHIRReference{reportReference(r)}}},
+		}
+		if clinical != "" {
+			condition.ClinicalStatus = &models.FHIRCodeableConcept{
+				Coding: []models.FHIRCoding{{System: fhirClinicalStatusSystem, Code: clinical}},
+			}
+		}
+		return condition
+	}
+
+	var conditions []models.FHIRCondition
+	for i, dx := range r.AIAnalysis.Entities.Diagnoses {
+		if EntityStatus(&r.AIAnalysis, TermCategoryDiagnosis, dx) == AssertionFamily {
+			continue
+		}
+		conditions = append(conditions, build(FHIRKindDiagnosis, TermCategoryDiagnosis, dx, "encounter-diagnosis", "Encounter Diagnosis", i))
+	}
+	for i, sx := range r.AIAnalysis.Entities.Symptoms {
+		if EntityStatus(&r.AIAnalysis, TermCategorySymptom, sx) == AssertionFamily {
+			continue
+		}
+		conditions = append(conditions, build(FHIRKindSymptom, TermCategorySymptom, sx, "problem-list-item", "Problem List Item", i))
+	}
+	return conditions
+}
+
+// ReportMedicationStatements maps extracted medications to FHIR MedicationStatements
+func (s *FHIRService) ReportMedicationStatements(r *models.Report) []models.FHIRMedicationStatement {
+	var statements []models.FHIRMedicationStatement
+	for i, med := range r.AIAnalysis.Entities.Medications {
+		status := EntityStatus(&r.AIAnalysis, TermCategoryMedication, med)
+		if status == AssertionFamily {
+			continue
+		}
+		statements = append(statements, models.FHIRMedicationStatement{
+			ResourceType:              "MedicationStatement",
+			ID:                        DerivedResourceID(r.ID, FHIRKindMedication, i),
+			Meta:                      fhirMeta(r.UpdatedAt),
+			Status:                    medicationStatus(status),
+			MedicationCodeableConcept: entityConcept(r, TermCategoryMedication, med),
+			Subject:                   patientReference(r),
+			DateAsserted:              fhirTime(r.UploadedAt),
+			DerivedFrom:               []models.FHIRReference{reportReference(r)},
+		})
+	}
+	return statements
+}
+
+// ReportObservations maps structured lab observations and extracted tests and
+// vitals to FHIR Observations. Entities that merely restate a structured
+// observation are not repeated.
+func (s *FHIRService) ReportObservations(r *models.Report) []models.FHIRObservation {
+	status := "preliminary"
+	if reportReviewed(r) {
+		status = "final"
+	}
+
+	build := func(kind, text, category, categoryDisplay string, index int) models.FHIRObservation {
+		return models.FHIRObservation{
+			ResourceType: "Observation",
+			ID:           DerivedResourceID(r.ID, kind, index),
+			Meta:         fhirMeta(r.UpdatedAt),
+			Status:       status,
+			Category: []models.FHIRCodeableConcept{{
+				Coding: []models.FHIRCoding{{System: fhirObservationCategorySystem, Code: category, Display: categoryDisplay}},
+			}},
+			Code:              models.FHIRCodeableConcept{Text: text},
+			Subject:           patientReference(r),
+			EffectiveDateTime: fhirTime(r.UploadedAt),
+			Issued:            fhirTime(r.UploadedAt),
+			DerivedFrom:       []models.FHIRReference{reportReference(r)},
+		}
+	}
+
+	var observations []models.FHIRObservation
+	structured := map[string]bool{}
+	for i, lab := range r.Observations {
+		structured[lab.Display] = true
+		structured[ObservationEntityText(lab)] = true
+
+		category, categoryDisplay := "laboratory", "Laboratory"
+		if lab.Category == "vital-signs" {
+			category, categoryDisplay = "vital-signs", "Vital Signs"
+		}
+		obs := build(FHIRKindObservation, lab.Display, category, categoryDisplay, i)
+		if lab.Status != "" {
+			obs.Status = lab.Status
+		}
+		if lab.Code != "" {
+			obs.Code.Coding = []models.FHIRCoding{{System: lab.CodeSystem, Code: lab.Code, Display: lab.Display}}
+		}
+		if lab.EffectiveAt != nil {
+			obs.EffectiveDateTime = fhirTime(*lab.EffectiveAt)
+		}
+		if lab.Value != nil {
+			obs.ValueQuantity = &models.FHIRQuantity{Value: lab.Value, Unit: lab.Unit}
+		} else {
+			obs.ValueString = lab.ValueString
+		}
+		if lab.Interpretation != "" {
+			obs.Interpretation = []models.FHIRCodeableConcept{{
+				Coding: []models.FHIRCoding{{System: fhirInterpretationSystem, Code: lab.Interpretation}},
+			}}
+		}
+		if lab.ReferenceRange != "" {
+			obs.ReferenceRange = []models.FHIRReferenceRange{{Text: lab.ReferenceRange}}
+		}
+		observations = append(observations, obs)
+	}
+	for i, test := range r.AIAnalysis.Entities.Tests {
+		if structured[test] {
+			continue
+		}
+		obs := build(FHIRKindTest, test, "laboratory", "Laboratory", i)
+		obs.Code = entityConcept(r, TermCategoryTest, test)
+		observations = append(observations, obs)
+	}
+	for i, vital := range r.AIAnalysis.Entities.Vitals {
+		if structured[vital] {
+			continue
+		}
+		obs := build(FHIRKindVital, vital, "vital-signs", "Vital Signs", i)
+		obs.ValueString = vital
+		observations = append(observations, obs)
+	}
+	return observations
+}
+
+// ReportServiceRequests maps recommended tests to FHIR ServiceRequests
+func (s *FHIRService) ReportServiceRequests(r *models.Report) []models.FHIRServiceRequest {
+	status := "draft"
+	if reportReviewed(r) {
+		status = "active"
+	}
+
+	var requests []models.FHIRServiceRequest
+	for i, rec := range r.AIAnalysis.Recommendations {
+		req := models.FHIRServiceRequest{
+			ResourceType:   "ServiceRequest",
+			ID:             DerivedResourceID(r.ID, FHIRKindRecommend, i),
+			Meta:           fhirMeta(r.UpdatedAt),
+			Status:         status,
+			Intent:         "proposal",
+			Priority:       ServiceRequestPriority(rec.Urgency),
+			Code:           models.FHIRCodeableConcept{Text: rec.Test},
+			Subject:        patientReference(r),
+			AuthoredOn:     fhirTime(r.UploadedAt),
+			SupportingInfo: []models.FHIRReference{reportReference(r)},
+		}
+		if rec.Reason != "" {
+			req.ReasonCode = []models.FHIRCodeableConcept{{Text: rec.Reason}}
+		}
+		note := fmt.Sprintf("AI confidence: %.0f%%", rec.Confidence)
+		if len(rec.Contraindications) > 0 {
+			note += ". Contraindications: " + strings.Join(rec.Contraindications, ", ")
+		}
+		req.Note = []models.FHIRAnnotation{{Text: note}}
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// DerivedResource returns a single derived resource of a report by kind and index
+func (s *FHIRService) DerivedResource(r *models.Report, resourceType, id string) (interface{}, bool) {
+	switch resourceType {
+	case "Condition":
+		for _, res := range s.ReportConditions(r) {
+			if res.ID == id {
+				return res, true
+			}
+		}
+	case "MedicationStatement":
+		for _, res := range s.ReportMedicationStatements(r) {
+			if res.ID == id {
+				return res, true
+			}
+		}
+	case "Observation":
+		for _, res := range s.ReportObservations(r) {
+			if res.ID == id {
+				return res, true
+			}
+		}
+	case "ServiceRequest":
+		for _, res := range s.ReportServiceRequests(r) {
+			if res.ID == id {
+				return res, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// DerivedResources returns all resources of a type derived from a report
+func (s *FHIRService) DerivedResources(r *models.Report, resourceType string) []interface{} {
+	var resources []interface{}
+	switch resourceType {
+	case "Condition":
+		for _, res := range s.ReportConditions(r) {
+			resources = append(resources, res)
+		}
+	case "MedicationStatement":
+		for _, res := range s.ReportMedicationStatements(r) {
+			resources = append(resources, res)
+		}
+	case "Observation":
+		for _, res := range s.ReportObservations(r) {
+			resources = append(resources, res)
+		}
+	case "ServiceRequest":
+		for _, res := range s.ReportServiceRequests(r) {
+			resources = append(resources, res)
+		}
+	}
+	return resources
+}
+
+// reportFilter converts search parameters to a MongoDB filter
+func reportFilter(search ReportSearch) bson.M {
+	filter := bson.M{}
+	if search.PatientID != nil {
+		filter["patient_id"] = *search.PatientID
+	}
+	dateFilter := bson.M{}
+	if !search.From.IsZero() {
+		dateFilter["$gte"] = search.From
+	}
+	if !search.To.IsZero() {
+		dateFilter["$lt"] = search.To
+	}
+	if len(dateFilter) > 0 {
+		filter["uploaded_at"] = dateFilter
+	}
+	if search.Statuses != nil {
+		filter["status"] = bson.M{"$in": search.Statuses}
+	}
+	return filter
+}
+
+// SearchReports returns one page of reports matching the search and the total count
+func (s *FHIRService) SearchReports(search ReportSearch, offset, count int) ([]models.Report, int, error) {
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := reportFilter(search)
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "uploaded_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(count))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var reports []models.Report
+	if err = cursor.All(ctx, &reports); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode reports: %w", err)
+	}
+	return reports, int(total), nil
+}
+
+// SearchDerivedResources returns one page of resources of a type derived from
+// matching reports plus the total count. Reports are streamed newest first
+// without their audit-only fields and paged through the same mappers that
+// serve single resources, so search and read never disagree.
+func (s *FHIRService) SearchDerivedResources(resourceType string, search ReportSearch, offset, count int) ([]interface{}, int, error) {
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "uploaded_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(bson.M{
+			"amendments":                       0,
+			"signature":                        0,
+			"doctor_review.edited_fields":      0,
+			"ai_analysis.confidence_breakdown": 0,
+			"ai_analysis.rule_traces":          0,
+		})
+	cursor, err := collection.Find(ctx, reportFilter(search), opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search %s resources: %w", resourceType, err)
+	}
+	defer cursor.Close(ctx)
+
+	pager := derivedPager{offset: offset, count: count}
+	for cursor.Next(ctx) {
+		var report models.Report
+		if err := cursor.Decode(&report); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode report: %w", err)
+		}
+		pager.add(s.DerivedResources(&report, resourceType))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s resources: %w", resourceType, err)
+	}
+	return pager.page, pager.total, nil
+}
+
+// derivedPager counts derived resources and keeps those on the requested page
+type derivedPager struct {
+	offset, count int
+	total         int
+	page          []interface{}
+}
+
+func (p *derivedPager) add(resources []interface{}) {
+	for _, resource := range resources {
+		if p.total >= p.offset && len(p.page) < p.count {
+			p.page = append(p.page, resource)
+		}
+		p.total++
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReportConditionsStatus(t *testing.T) {
+	report := &models.Report{ID: primitive.NewObjectID(), Status: "pending"}
+	report.AIAnalysis.Entities.Diagnoses = []string{"pneumonia", "asthma", "sepsis", "diabetes", "hypertension"}
+	ApplyAssertions(&report.AIAnalysis, "Pneumonia. History of asthma. Rule out sepsis. No diabetes. Mother has hypertension.")
+
+	want := map[string]struct{ clinical, verification string }{
+		"pneumonia": {"active", "provisional"},
+		"asthma":    {"inactive", "provisional"},
+		"sepsis":    {"", "unconfirmed"},
+		"diabetes":  {"", "refuted"},
+	}
+
+	conditions := (&FHIRService{}).ReportConditions(report)
+	if len(conditions) != len(want) {
+		t.Fatalf("got %d conditions, want %d (family history left out)", len(conditions), len(want))
+	}
+	for _, c := range conditions {
+		w, ok := want[c.Code.Text]
+		if !ok {
+			t.Errorf("unexpected condition %q", c.Code.Text)
+			continue
+		}
+		clinical := ""
+		if c.ClinicalStatus != nil {
+			clinical = c.ClinicalStatus.Coding[0].Code
+		}
+		if clinical != w.clinical || c.VerificationStatus.Coding[0].Code != w.verification {
+			t.Errorf("%s: clinical %q verification %q, want %q %q", c.Code.Text, clinical, c.VerificationStatus.Coding[0].Code, w.clinical, w.verification)
+		}
+	}
+}
+
+func TestMedicationStatus(t *testing.T) {
+	tests := map[string]string{
+		AssertionAffirmed:     "unknown",
+		AssertionNegated:      "not-taken",
+		AssertionHistorical:   "completed",
+		AssertionHypothetical: "intended",
+	}
+	for assertion, want := range tests {
+		if got := medicationStatus(assertion); got != want {
+			t.Errorf("medicationStatus(%s) = %s, want %s", assertion, got, want)
+		}
+	}
+}
+
+func TestDerivedPagerPageBoundary(t *testing.T) {
+	first := models.Report{ID: primitive.NewObjectID()}
+	first.AIAnalysis.Entities.Diagnoses = []string{"anaemia", "asthma"}
+	first.AIAnalysis.Entities.Symptoms = []string{"fatigue"}
+	first.AIAnalysis.Assertions = []models.EntityAssertion{{Category: TermCategoryDiagnosis, Text: "asthma", Status: AssertionFamily}}
+	second := models.Report{ID: primitive.NewObjectID()}
+	second.AIAnalysis.Entities.Diagnoses = []string{"gout"}
+	reports := []models.Report{first, second}
+
+	s := &FHIRService{}
+	tests := []struct {
+		name          string
+		offset, count int
+		want          []string // Derived resource IDs
+	}{
+		{"first page ends before the filtered row", 0, 1, []string{DerivedResourceID(first.ID, FHIRKindDiagnosis, 0)}},
+		{"filtered row does not open the next page", 1, 1, []string{DerivedResourceID(first.ID, FHIRKindSymptom, 0)}},
+		{"page spans reports", 1, 2, []string{DerivedResourceID(first.ID, FHIRKindSymptom, 0), DerivedResourceID(second.ID, FHIRKindDiagnosis, 0)}},
+		{"past the end", 3, 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pager := derivedPager{offset: tt.offset, count: tt.count}
+			for i := range reports {
+				pager.add(s.DerivedResources(&reports[i], "Condition"))
+			}
+			if pager.total != 3 {
+				t.Errorf("total = %d, want 3 (family history left out)", pager.total)
+			}
+			var got []string
+			for _, resource := range pager.page {
+				got = append(got, derivedResourceID(resource))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("page = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func derivedResourceID(resource interface{}) string {
+	switch r := resource.(type) {
+	case models.FHIRCondition:
+		return r.ID
+	case models.FHIRMedicationStatement:
+		return r.ID
+	case models.FHIRObservation:
+		return r.ID
+	case models.FHIRServiceRequest:
+		return r.ID
+	}
+	return ""
+}
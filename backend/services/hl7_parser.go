@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// hl7Encoding holds the delimiters declared in MSH-1 and MSH-2
+type hl7Encoding struct {
+	field        byte
+	component    byte
+	repetition   byte
+	escape       byte
+	subcomponent byte
+}
+
+type hl7Segment struct {
+	name   string
+	fields []string // fields[0] is the segment name
+}
+
+// hl7Message is a parsed HL7 v2 message
+type hl7Message struct {
+	enc      hl7Encoding
+	segments []hl7Segment
+}
+
+// parseHL7 parses a pipe-delimited HL7 v2 message. Segments may be separated
+// by CR, LF or CRLF.
+func parseHL7(raw string) (*hl7Message, error) {
+	raw = strings.TrimSpace(strings.ReplaceAll(raw, "\r\n", "\r"))
+	raw = strings.ReplaceAll(raw, "\n", "\r")
+	if !strings.HasPrefix(raw, "MSH") || len(raw) < 8 {
+		return nil, fmt.Errorf("message does not start with an MSH segment")
+	}
+
+	enc := hl7Encoding{
+		field:        raw[3],
+		component:    raw[4],
+		repetition:   raw[5],
+		escape:       raw[6],
+		subcomponent: raw[7],
+	}
+	if enc.field == enc.component || enc.field == enc.repetition {
+		return nil, fmt.Errorf("invalid encoding characters in MSH-2")
+	}
+
+	msg := &hl7Message{enc: enc}
+	for _, line := range strings.Split(raw, "\r") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, string(enc.field))
+		if len(fields[0]) != 3 {
+			return nil, fmt.Errorf("invalid segment name %q", fields[0])
+		}
+		msg.segments = append(msg.segments, hl7Segment{name: fields[0], fields: fields})
+	}
+	return msg, nil
+}
+
+// segment returns the first segment with the given name
+func (m *hl7Message) segment(name string) *hl7Segment {
+	for i := range m.segments {
+		if m.segments[i].name == name {
+			return &m.segments[i]
+		}
+	}
+	return nil
+}
+
+// field returns the raw value of field n using HL7 numbering (MSH-1 is the
+// field separator itself)
+func (s *hl7Segment) field(n int) string {
+	if s == nil {
+		return ""
+	}
+	index := n
+	if s.name == "MSH" {
+		index = n - 1
+	}
+	if index <= 0 || index >= len(s.fields) {
+		return ""
+	}
+	return s.fields[index]
+}
+
+// repetitions splits a field into its repetitions
+func (m *hl7Message) repetitions(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, string(m.enc.repetition))
+}
+
+// component returns component n (1-based) of a field, unescaped. Only the
+// first repetition is considered.
+func (m *hl7Message) component(value string, n int) string {
+	if reps := m.repetitions(value); len(reps) > 0 {
+		value = reps[0]
+	}
+	components := strings.Split(value, string(m.enc.component))
+	if n <= 0 || n > len(components) {
+		return ""
+	}
+	// Ignore subcomponents beyond the first
+	sub := strings.SplitN(components[n-1], string(m.enc.subcomponent), 2)[0]
+	return m.unescape(sub)
+}
+
+// text returns a field with escapes resolved and delimiters left intact
+func (m *hl7Message) text(value string) string {
+	return m.unescape(value)
+}
+
+// unescape resolves the standard HL7 escape sequences
+func (m *hl7Message) unescape(value string) string {
+	esc := string(m.enc.escape)
+	if !strings.Contains(value, esc) {
+		return value
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(value, esc)
+		if start < 0 {
+			b.WriteString(value)
+			break
+		}
+		end := strings.Index(value[start+1:], esc)
+		if end < 0 {
+			b.WriteString(value)
+			break
+		}
+		b.WriteString(value[:start])
+		switch seq := value[start+1 : start+1+end]; seq {
+		case "F":
+			b.WriteByte(m.enc.field)
+		case "S":
+			b.WriteByte(m.enc.component)
+		case "T":
+			b.WriteByte(m.enc.subcomponent)
+		case "R":
+			b.WriteByte(m.enc.repetition)
+		case "E":
+			b.WriteByte(m.enc.escape)
+		case ".br":
+			b.WriteByte('\n')
+		}
+		value = value[start+end+2:]
+	}
+	return b.String()
+}
+
+// escapeHL7 escapes delimiters in text placed into an outbound message
+func escapeHL7(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\E\`,
+		"|", `\F\`,
+		"^", `\S\`,
+		"&", `\T\`,
+		"~", `\R\`,
+		"\r", " ",
+		"\n", " ",
+	)
+	return replacer.Replace(value)
+}
+
+// parseHL7Time parses an HL7 DTM/TS value (YYYY[MM[DD[HH[MM[SS[.S]]]]]][+/-ZZZZ])
+func parseHL7Time(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	loc := time.UTC
+	if i := strings.IndexAny(value, "+-"); i > 0 {
+		offset, err := time.Parse("-0700", value[i:])
+		if err == nil {
+			loc = offset.Location()
+		}
+		value = value[:i]
+	}
+	if i := strings.Index(value, "."); i > 0 {
+		value = value[:i]
+	}
+
+	layouts := map[int]string{
+		4:  "2006",
+		6:  "200601",
+		8:  "20060102",
+		10: "2006010215",
+		12: "200601021504",
+		14: "20060102150405",
+	}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+const testHL7Message = "MSH|^~\\&|LAB|HOSP|MRA|MRA|20260312093000||ORU^R01|MSG001|P|2.5\r" +
+	"PID|1||12345^^^HOSP^MR~98765^^^NATIONAL^NI||Rao^Asha^Devi||19720301|F|||||555-0100\r" +
+	"OBX|1|NM|718-7^Haemoglobin^LN||11.2|g/dL|12-16|L|||F"
+
+func TestParseHL7(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		segments []string
+		wantErr  bool
+	}{
+		{"CR separated", testHL7Message, []string{"MSH", "PID", "OBX"}, false},
+		{"LF separated", strings.ReplaceAll(testHL7Message, "\r", "\n"), []string{"MSH", "PID", "OBX"}, false},
+		{"CRLF separated", strings.ReplaceAll(testHL7Message, "\r", "\r\n"), []string{"MSH", "PID", "OBX"}, false},
+		{"blank lines skipped", "\r\n" + strings.ReplaceAll(testHL7Message, "\r", "\r\r") + "\r\n", []string{"MSH", "PID", "OBX"}, false},
+		{"no MSH", "PID|1||12345", nil, true},
+		{"truncated MSH", "MSH|^~", nil, true},
+		{"clashing delimiters", "MSH||~\\&|LAB", nil, true},
+		{"bad segment name", "MSH|^~\\&|LAB\rPIDX|1", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseHL7(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHL7() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var names []string
+			for _, s := range msg.segments {
+				names = append(names, s.name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.segments, ",") {
+				t.Errorf("segments = %v, want %v", names, tt.segments)
+			}
+		})
+	}
+}
+
+func TestHL7Fields(t *testing.T) {
+	msg, err := parseHL7(testHL7Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msh, pid, obx := msg.segment("MSH"), msg.segment("PID"), msg.segment("OBX")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"MSH-2 encoding characters", msh.field(2), "^~\\&"},
+		{"MSH-3 sending application", msh.field(3), "LAB"},
+		{"MSH-9 message type", msh.field(9), "ORU^R01"},
+		{"MSH-9.2 trigger event", msg.component(msh.field(9), 2), "R01"},
+		{"MSH-10 control ID", msh.field(10), "MSG001"},
+		{"PID-5.1 family name", msg.component(pid.field(5), 1), "Rao"},
+		{"PID-5.3 middle name", msg.component(pid.field(5), 3), "Devi"},
+		{"PID-8 sex", pid.field(8), "F"},
+		{"OBX-3.2 display", msg.component(obx.field(3), 2), "Haemoglobin"},
+		{"OBX-5 value", obx.field(5), "11.2"},
+		{"field past the end", obx.field(40), ""},
+		{"field zero", pid.field(0), ""},
+		{"component past the end", msg.component(obx.field(3), 9), ""},
+		{"missing segment", msg.segment("ZZZ").field(1), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestHL7Unescape(t *testing.T) {
+	msg, err := parseHL7("MSH|^~\\&|LAB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{`a\F\b`, "a|b"},
+		{`a\S\b`, "a^b"},
+		{`a\T\b`, "a&b"},
+		{`a\R\b`, "a~b"},
+		{`a\E\b`, `a\b`},
+		{`line\.br\break`, "line\nbreak"},
+		{`\H\bold\N\`, "bold"},
+		{`unterminated \F`, `unterminated \F`},
+	}
+	for _, tt := range tests {
+		if got := msg.unescape(tt.in); got != tt.want {
+			t.Errorf("unescape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// Escapes round-trip through escapeHL7
+	for _, text := range []string{`a|b^c&d~e\f`, "no delimiters"} {
+		if got := msg.unescape(escapeHL7(text)); got != text {
+			t.Errorf("unescape(escapeHL7(%q)) = %q", text, got)
+		}
+	}
+	// Escaped delimiters do not split components
+	if got := msg.component(`Smith\S\Jones^Ann`, 1); got != "Smith^Jones" {
+		t.Errorf("component of escaped value = %q, want %q", got, "Smith^Jones")
+	}
+}
+
+func TestHL7PatientIdentifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  string
+		want []models.PatientIdentifier
+	}{
+		{"one", "PID|1||12345^^^HOSP^MR", []models.PatientIdentifier{{System: "HOSP", Value: "12345"}}},
+		{"repetitions", "PID|1||12345^^^HOSP^MR~98765^^^NATIONAL^NI", []models.PatientIdentifier{
+			{System: "HOSP", Value: "12345"}, {System: "NATIONAL", Value: "98765"},
+		}},
+		{"authority subcomponents", "PID|1||12345^^^HOSP&1.2.3&ISO^MR", []models.PatientIdentifier{{System: "HOSP", Value: "12345"}}},
+		{"no authority", "PID|1||12345", []models.PatientIdentifier{{Value: "12345"}}},
+		{"empty repetition skipped", "PID|1||~12345^^^HOSP", []models.PatientIdentifier{{System: "HOSP", Value: "12345"}}},
+		{"PID-2 fallback", "PID|1|555^^^OLD|", []models.PatientIdentifier{{System: "OLD", Value: "555"}}},
+		{"PID-3 preferred over PID-2", "PID|1|555^^^OLD|12345^^^HOSP", []models.PatientIdentifier{{System: "HOSP", Value: "12345"}}},
+		{"none", "PID|1||", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseHL7("MSH|^~\\&|LAB\r" + tt.pid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := (&HL7Service{}).patientIdentifiers(msg, msg.segment("PID"))
+			if len(got) != len(tt.want) {
+				t.Fatalf("identifiers = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("identifier %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseHL7Time(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2026", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"20260312", time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC), true},
+		{"20260312093015", time.Date(2026, 3, 12, 9, 30, 15, 0, time.UTC), true},
+		{"20260312093015.1234", time.Date(2026, 3, 12, 9, 30, 15, 0, time.UTC), true},
+		{"202603120930+0530", time.Date(2026, 3, 12, 4, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"2026031", time.Time{}, false},
+		{"20261312", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHL7Time(tt.in)
+		if ok != tt.ok || (ok && !got.Equal(tt.want)) {
+			t.Errorf("parseHL7Time(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMLLPFraming(t *testing.T) {
+	frame := func(msg string) string {
+		var buf bytes.Buffer
+		if err := writeMLLPFrame(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	tests := []struct {
+		name    string
+		stream  string
+		want    []string
+		wantErr bool // error after the wanted frames
+	}{
+		{"one frame", frame("MSH|a"), []string{"MSH|a"}, false},
+		{"two frames", frame("MSH|a") + frame("MSH|b"), []string{"MSH|a", "MSH|b"}, false},
+		{"garbage before start block", "noise\r\n" + frame("MSH|a"), []string{"MSH|a"}, false},
+		{"segment CRs kept", frame("MSH|a\rPID|1"), []string{"MSH|a\rPID|1"}, false},
+		{"closed inside a frame", "\x0bMSH|a", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.stream))
+			for _, want := range tt.want {
+				got, err := readMLLPFrame(r)
+				if err != nil {
+					t.Fatalf("readMLLPFrame() error = %v", err)
+				}
+				if got != want {
+					t.Errorf("readMLLPFrame() = %q, want %q", got, want)
+				}
+			}
+			_, err := readMLLPFrame(r)
+			if err == nil {
+				t.Fatal("expected an error once the stream is exhausted")
+			}
+			if closedInFrame := strings.Contains(err.Error(), "inside a frame"); closedInFrame != tt.wantErr {
+				t.Errorf("readMLLPFrame() error = %v, want inside-frame error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := frame("MSH|a"); got != "\x0bMSH|a\x1c\r" {
+		t.Errorf("writeMLLPFrame() = %q", got)
+	}
+}
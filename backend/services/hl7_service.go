@@ -0,0 +1,460 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// HL7IngestVersion is stamped on analyses built from HL7 v2 results
+const HL7IngestVersion = "1.0"
+
+// HL7 acknowledgment codes (MSA-1)
+const (
+	HL7AckAccept = "AA" // processed
+	HL7AckError  = "AE" // temporary failure, sender should retry
+	HL7AckReject = "AR" // malformed or unsupported, message quarantined
+)
+
+// loincSystem is the FHIR URI for the LOINC coding system ("LN" in HL7 v2)
+const loincSystem = "http://loinc.org"
+
+// vitalSignLOINC are LOINC codes recorded as vitals rather than lab tests
+var vitalSignLOINC = map[string]bool{
+	"8867-4":  true, // Heart rate
+	"8480-6":  true, // Systolic blood pressure
+	"8462-4":  true, // Diastolic blood pressure
+	"85354-9": true, // Blood pressure panel
+	"8310-5":  true, // Body temperature
+	"9279-1":  true, // Respiratory rate
+	"59408-5": true, // Oxygen saturation by pulse oximetry
+	"2708-6":  true, // Oxygen saturation
+	"29463-7": true, // Body weight
+	"8302-2":  true, // Body height
+	"39156-5": true, // BMI
+}
+
+// hl7ResultStatus maps OBX-11 to an observation status
+var hl7ResultStatus = map[string]string{
+	"F": "final",
+	"C": "corrected",
+	"P": "preliminary",
+	"R": "registered",
+	"I": "registered",
+	"X": "cancelled",
+	"D": "entered-in-error",
+	"W": "entered-in-error",
+}
+
+// HL7RejectError marks a message as malformed or unsupported. Such messages are
+// quarantined and answered with AR; any other error is answered with AE.
+type HL7RejectError struct {
+	Reason string
+}
+
+func (e *HL7RejectError) Error() string { return e.Reason }
+
+func hl7Reject(format string, args ...interface{}) error {
+	return &HL7RejectError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// HL7Service ingests HL7 v2 ORU^R01 lab results
+type HL7Service struct{}
+
+// ProcessMessage ingests a raw HL7 v2 message received from remoteAddr and
+// returns the ACK message to send back. Rejected messages are quarantined.
+func (s *HL7Service) ProcessMessage(raw, remoteAddr string) string {
+	msg, err := parseHL7(raw)
+	if err != nil {
+		s.quarantine(raw, remoteAddr, nil, err)
+		return buildHL7Ack(nil, HL7AckReject, err.Error())
+	}
+
+	_, err = s.ingest(msg, nil)
+	if err != nil {
+		var reject *HL7RejectError
+		if errors.As(err, &reject) {
+			s.quarantine(raw, remoteAddr, msg, err)
+			return buildHL7Ack(msg, HL7AckReject, err.Error())
+		}
+		return buildHL7Ack(msg, HL7AckError, err.Error())
+	}
+	return buildHL7Ack(msg, HL7AckAccept, "")
+}
+
+// ingest validates an ORU^R01 message and creates its report. Resent messages
+// return the report created the first time. patientID, when set, is the
+// patient an admin reconciled the message to.
+func (s *HL7Service) ingest(msg *hl7Message, patientID *primitive.ObjectID) (*models.Report, error) {
+	msh := msg.segment("MSH")
+	messageType := msg.component(msh.field(9), 1)
+	trigger := msg.component(msh.field(9), 2)
+	if messageType != "ORU" || trigger != "R01" {
+		return nil, hl7Reject("unsupported message type %s^%s, expected ORU^R01", messageType, trigger)
+	}
+	controlID := msh.field(10)
+	if controlID == "" {
+		return nil, hl7Reject("MSH-10 message control ID is required")
+	}
+
+	pid := msg.segment("PID")
+	if pid == nil {
+		return nil, hl7Reject("PID segment is required")
+	}
+	obr := msg.segment("OBR")
+	if obr == nil {
+		return nil, hl7Reject("OBR segment is required")
+	}
+
+	observations, err := s.observations(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(observations) == 0 {
+		return nil, hl7Reject("message contains no OBX results")
+	}
+
+	identifiers := s.patientIdentifiers(msg, pid)
+	if len(identifiers) == 0 {
+		return nil, hl7Reject("PID-3 patient identifier is required")
+	}
+	var patient *models.Patient
+	if patientID != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		patient, err = LinkPatientIdentifiers(ctx, *patientID, identifiers)
+		cancel()
+	} else {
+		patient, _, err = MatchOrCreatePatient(identifiers, s.patientDemographics(msg, pid))
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPatientReconciliation):
+			return nil, hl7Reject("%v", err)
+		case errors.Is(err, ErrPatientNotFound) && patientID != nil:
+			return nil, hl7Reject("reconciled patient %s not found", patientID.Hex())
+		case errors.Is(err, ErrPatientNotFound):
+			return nil, hl7Reject("patient not found and PID-5 has no name to create one")
+		}
+		return nil, err
+	}
+
+	externalID := msg.component(obr.field(3), 1) // Filler order number
+	if externalID == "" {
+		externalID = msg.component(obr.field(2), 1) // Placer order number
+	}
+	if externalID == "" {
+		externalID = controlID
+	}
+
+	collection := config.GetCollection("reports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var existing models.Report
+	err = collection.FindOne(ctx, bson.M{"source": "hl7v2", "patient_id": patient.ID, "external_id": externalID}).Decode(&existing)
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to check for duplicate report: %w", err)
+	}
+
+	report := models.Report{
+		ID:           primitive.NewObjectID(),
+		PatientID:    patient.ID,
+		UploadedAt:   time.Now(),
+		Status:       "pending",
+		Source:       "hl7v2",
+		ExternalID:   externalID,
+		Observations: observations,
+		UpdatedAt:    time.Now(),
+	}
+	if t, ok := parseHL7Time(obr.field(7)); ok {
+		report.UploadedAt = t
+	}
+
+	ApplyObservationsToAnalysis(&report.AIAnalysis, observations)
+	report.AIAnalysis.Analyzer = &models.AnalyzerInfo{Name: "hl7v2-ingest", Version: HL7IngestVersion, AnalyzedAt: time.Now()}
+	ApplyTerminology(&report.AIAnalysis)
+	ApplyClinicalRules(&report.AIAnalysis, observations, patient.ID, report.UploadedAt)
+	ApplyConfidenceScore(&report.AIAnalysis)
+
+	if _, err := collection.InsertOne(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to save report: %w", err)
+	}
+	RaiseCriticalAlert(&report)
+	NotifyAnalysisComplete(&report)
+	SyncCareTasks(&report)
+	PublishWebhookEvent(WebhookReportUploaded, ReportWebhookData(&report))
+	PublishWebhookEvent(WebhookAnalysisCompleted, ReportWebhookData(&report))
+	return &report, nil
+}
+
+// observations converts every OBX segment to a LabObservation
+func (s *HL7Service) observations(msg *hl7Message) ([]models.LabObservation, error) {
+	var observations []models.LabObservation
+	var obrTime time.Time
+
+	for i := range msg.segments {
+		seg := &msg.segments[i]
+		switch seg.name {
+		case "OBR":
+			obrTime, _ = parseHL7Time(seg.field(7))
+		case "OBX":
+			obs, err := s.observation(msg, seg)
+			if err != nil {
+				return nil, err
+			}
+			if obs.Status == "cancelled" || obs.Status == "entered-in-error" {
+				continue
+			}
+			if obs.EffectiveAt == nil && !obrTime.IsZero() {
+				t := obrTime
+				obs.EffectiveAt = &t
+			}
+			observations = append(observations, obs)
+		}
+	}
+	return observations, nil
+}
+
+// observation converts one OBX segment
+func (s *HL7Service) observation(msg *hl7Message, obx *hl7Segment) (models.LabObservation, error) {
+	setID := obx.field(1)
+	code := msg.component(obx.field(3), 1)
+	display := msg.component(obx.field(3), 2)
+	if display == "" {
+		display = code
+	}
+	if display == "" {
+		return models.LabObservation{}, hl7Reject("OBX %s: OBX-3 observation identifier is required", setID)
+	}
+
+	obs := models.LabObservation{
+		Code:           code,
+		CodeSystem:     msg.component(obx.field(3), 3),
+		Display:        display,
+		Category:       "laboratory",
+		Unit:           msg.component(obx.field(6), 1),
+		ReferenceRange: msg.component(obx.field(7), 1),
+		Interpretation: msg.component(obx.field(8), 1),
+		Status:         "final",
+	}
+	if obs.CodeSystem == "LN" {
+		obs.CodeSystem = loincSystem
+		if vitalSignLOINC[code] {
+			obs.Category = "vital-signs"
+		}
+	}
+	if status, ok := hl7ResultStatus[obx.field(11)]; ok {
+		obs.Status = status
+	}
+	if t, ok := parseHL7Time(obx.field(14)); ok {
+		obs.EffectiveAt = &t
+	}
+
+	value := obx.field(5)
+	switch valueType := obx.field(2); valueType {
+	case "NM":
+		raw := msg.component(value, 1)
+		if raw == "" {
+			break
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return models.LabObservation{}, hl7Reject("OBX %s: value %q is not numeric", setID, raw)
+		}
+		obs.Value = &number
+	case "SN":
+		// Structured numeric: comparator^num1^separator^num2, e.g. "<^5" or "^10^-^20"
+		var parts []string
+		for n := 1; n <= 4; n++ {
+			if part := msg.component(value, n); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		obs.ValueString = strings.Join(parts, "")
+	case "CE", "CWE", "CNE":
+		obs.ValueString = msg.component(value, 2)
+		if obs.ValueString == "" {
+			obs.ValueString = msg.component(value, 1)
+		}
+	case "ST", "TX", "FT", "":
+		var texts []string
+		for _, rep := range msg.repetitions(value) {
+			texts = append(texts, msg.text(rep))
+		}
+		obs.ValueString = strings.Join(texts, "\n")
+	default:
+		return models.LabObservation{}, hl7Reject("OBX %s: unsupported value type %s", setID, valueType)
+	}
+	return obs, nil
+}
+
+// patientIdentifiers reads PID-3 (falling back to the deprecated PID-2). The
+// assigning authority becomes the identifier system.
+func (s *HL7Service) patientIdentifiers(msg *hl7Message, pid *hl7Segment) []models.PatientIdentifier {
+	var identifiers []models.PatientIdentifier
+	for _, n := range []int{3, 2} {
+		for _, rep := range msg.repetitions(pid.field(n)) {
+			value := msg.component(rep, 1)
+			if value == "" {
+				continue
+			}
+			identifiers = append(identifiers, models.PatientIdentifier{
+				System: msg.component(rep, 4),
+				Value:  value,
+			})
+		}
+		if len(identifiers) > 0 {
+			break
+		}
+	}
+	return identifiers
+}
+
+// patientDemographics reads name, birth date, sex and phone from PID
+func (s *HL7Service) patientDemographics(msg *hl7Message, pid *hl7Segment) *PatientDemographics {
+	family := msg.component(pid.field(5), 1)
+	given := msg.component(pid.field(5), 2)
+	middle := msg.component(pid.field(5), 3)
+
+	demographics := &PatientDemographics{
+		Name:  strings.Join(strings.Fields(strings.Join([]string{given, middle, family}, " ")), " "),
+		Phone: msg.component(pid.field(13), 1),
+	}
+	switch pid.field(8) {
+	case "M":
+		demographics.Gender = "male"
+	case "F":
+		demographics.Gender = "female"
+	case "O", "A":
+		demographics.Gender = "other"
+	}
+	if birth, ok := parseHL7Time(pid.field(7)); ok {
+		demographics.Age = ageAt(birth, time.Now())
+	}
+	return demographics
+}
+
+// quarantine stores a rejected message for admin review
+func (s *HL7Service) quarantine(raw, remoteAddr string, msg *hl7Message, cause error) {
+	entry := models.HL7QuarantinedMessage{
+		ID:         primitive.NewObjectID(),
+		RawMessage: raw,
+		RemoteAddr: remoteAddr,
+		Error:      cause.Error(),
+		Status:     "quarantined",
+		Attempts:   1,
+		ReceivedAt: time.Now(),
+	}
+	if msg != nil {
+		msh := msg.segment("MSH")
+		entry.ControlID = msh.field(10)
+		entry.MessageType = msg.text(msh.field(9))
+		entry.SendingApp = msg.component(msh.field(3), 1)
+		entry.SendingFac = msg.component(msh.field(4), 1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	config.GetCollection("hl7_quarantine").InsertOne(ctx, entry)
+}
+
+// ReplayQuarantined re-processes a quarantined message, optionally replacing
+// its content with a corrected version or linking it to the patient an admin
+// reconciled it to. On success the entry is marked as replayed and linked to
+// the created report.
+func (s *HL7Service) ReplayQuarantined(id, adminID primitive.ObjectID, corrected string, patientID *primitive.ObjectID) (*models.HL7QuarantinedMessage, *models.Report, error) {
+	collection := config.GetCollection("hl7_quarantine")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry models.HL7QuarantinedMessage
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&entry); err != nil {
+		return nil, nil, err
+	}
+	if entry.Status != "quarantined" {
+		return &entry, nil, fmt.Errorf("message is already %s", entry.Status)
+	}
+	if strings.TrimSpace(corrected) != "" {
+		entry.RawMessage = corrected
+	}
+
+	var report *models.Report
+	msg, err := parseHL7(entry.RawMessage)
+	if err == nil {
+		report, err = s.ingest(msg, patientID)
+	}
+
+	entry.Attempts++
+	update := bson.M{"raw_message": entry.RawMessage, "attempts": entry.Attempts}
+	if err != nil {
+		entry.Error = err.Error()
+		update["error"] = entry.Error
+	} else {
+		now := time.Now()
+		entry.Status = "replayed"
+		entry.ResolvedAt = &now
+		entry.ResolvedBy = &adminID
+		entry.ReportID = &report.ID
+		update["status"] = entry.Status
+		update["resolved_at"] = now
+		update["resolved_by"] = adminID
+		update["report_id"] = report.ID
+	}
+	if _, updateErr := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update}); updateErr != nil && err == nil {
+		err = updateErr
+	}
+	return &entry, report, err
+}
+
+// buildHL7Ack builds an ACK for msg. When msg could not be parsed the ACK has
+// no originating control ID.
+func buildHL7Ack(msg *hl7Message, code, text string) string {
+	var sendingApp, sendingFac, receivingApp, receivingFac, controlID, processingID, version string
+	if msg != nil {
+		msh := msg.segment("MSH")
+		sendingApp, sendingFac = msh.field(3), msh.field(4)
+		receivingApp, receivingFac = msh.field(5), msh.field(6)
+		controlID = msh.field(10)
+		processingID = msh.field(11)
+		version = msh.field(12)
+	}
+	if receivingApp == "" {
+		receivingApp = "MEDICAL-REPORT-ANALYZER"
+	}
+	if processingID == "" {
+		processingID = "P"
+	}
+	if version == "" {
+		version = "2.5.1"
+	}
+
+	segments := []string{
+		strings.Join([]string{
+			"MSH", `^~\&`, receivingApp, receivingFac, sendingApp, sendingFac,
+			time.Now().UTC().Format("20060102150405"), "", "ACK^R01^ACK",
+			primitive.NewObjectID().Hex(), processingID, version,
+		}, "|"),
+		strings.Join([]string{"MSA", code, controlID, escapeHL7(text)}, "|"),
+	}
+	if code != HL7AckAccept {
+		// ERR-3 uses HL7 table 0357: 100 segment sequence error, 207 application internal error
+		errorCode := "207^Application internal error^HL70357"
+		if code == HL7AckReject {
+			errorCode = "100^Segment sequence error^HL70357"
+		}
+		segments = append(segments, strings.Join([]string{"ERR", "", "", errorCode, "E", "", "", "", escapeHL7(text)}, "|"))
+	}
+	return strings.Join(segments, "\r") + "\r"
+}
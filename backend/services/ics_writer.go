@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+const icsTimeFormat = "20060102T150405Z"
+
+// icsEscape escapes TEXT values (RFC 5545 3.3.11)
+func icsEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// icsLine writes a content line folded at 75 octets, without splitting a
+// UTF-8 sequence
+func icsLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // The leading space counts toward the next line
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// WriteAppointmentsICS writes a doctor's appointments as an iCalendar feed.
+// Cancelled appointments stay in the feed with STATUS:CANCELLED so
+// subscribed calendars remove them. Summaries and descriptions carry no
+// patient details, so cancel reasons are left out.
+func WriteAppointmentsICS(out io.Writer, calendarName string, appointments []models.Appointment) error {
+	w := bufio.NewWriter(out)
+	icsLine(w, "BEGIN:VCALENDAR")
+	icsLine(w, "VERSION:2.0")
+	icsLine(w, "PRODID:-//Medical Report Analyzer//Appointments//EN")
+	icsLine(w, "CALSCALE:GREGORIAN")
+	icsLine(w, "METHOD:PUBLISH")
+	icsLine(w, "X-WR-CALNAME:"+icsEscape(calendarName))
+
+	for _, a := range appointments {
+		reportIDs := make([]string, len(a.ReportIDs))
+		for i, id := range a.ReportIDs {
+			reportIDs[i] = id.Hex()
+		}
+		description := fmt.Sprintf("Appointment %s\nReports: %s", a.ID.Hex(), strings.Join(reportIDs, ", "))
+
+		icsLine(w, "BEGIN:VEVENT")
+		icsLine(w, "UID:"+a.ID.Hex()+"@medical-report-analyzer")
+		icsLine(w, fmt.Sprintf("SEQUENCE:%d", a.Sequence))
+		icsLine(w, "DTSTAMP:"+a.UpdatedAt.UTC().Format(icsTimeFormat))
+		icsLine(w, "DTSTART:"+a.StartsAt.UTC().Format(icsTimeFormat))
+		icsLine(w, "DTEND:"+a.EndsAt.UTC().Format(icsTimeFormat))
+		icsLine(w, "SUMMARY:"+icsEscape("Patient consultation ("+a.Mode+")"))
+		icsLine(w, "DESCRIPTION:"+icsEscape(description))
+		if a.Location != "" {
+			icsLine(w, "LOCATION:"+icsEscape(a.Location))
+		}
+		if a.Status == AppointmentCancelled {
+			icsLine(w, "STATUS:CANCELLED")
+		} else {
+			icsLine(w, "STATUS:CONFIRMED")
+		}
+		icsLine(w, "END:VEVENT")
+	}
+
+	icsLine(w, "END:VCALENDAR")
+	return w.Flush()
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a,b;c", `a\,b\;c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+		{"crlf\r\nline", `crlf\nline`},
+	}
+	for _, tt := range tests {
+		if got := icsEscape(tt.in); got != tt.want {
+			t.Errorf("icsEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Consultation"},
+		{"exactly 75", "DESCRIPTION:" + strings.Repeat("a", 63)},
+		{"long ascii", "DESCRIPTION:" + strings.Repeat("a", 200)},
+		{"long multibyte", "LOCATION:" + strings.Repeat("é", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			icsLine(w, tt.line)
+			w.Flush()
+
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("line not terminated with CRLF: %q", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			var unfolded strings.Builder
+			for i, l := range lines {
+				if len(l) > 75 {
+					t.Errorf("line %d is %d octets", i, len(l))
+				}
+				if i > 0 {
+					if !strings.HasPrefix(l, " ") {
+						t.Fatalf("continuation line %d does not start with a space", i)
+					}
+					l = l[1:]
+				}
+				unfolded.WriteString(l)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded line = %q, want %q", unfolded.String(), tt.line)
+			}
+		})
+	}
+}
+
+func TestWriteAppointmentsICS(t *testing.T) {
+	start := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	booked := models.Appointment{
+		ID: primitive.NewObjectID(), StartsAt: start, EndsAt: start.Add(30 * time.Minute), UpdatedAt: start,
+		Mode: "video", Reason: "Chest pain follow-up", Status: AppointmentBooked, Sequence: 2,
+	}
+	cancelled := booked
+	cancelled.ID = primitive.NewObjectID()
+	cancelled.Status = AppointmentCancelled
+	cancelled.CancelReason = "Patient admitted with pneumonia"
+
+	var buf bytes.Buffer
+	if err := WriteAppointmentsICS(&buf, "Dr. Rao", []models.Appointment{booked, cancelled}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"UID:" + booked.ID.Hex() + "@medical-report-analyzer",
+		"SEQUENCE:2",
+		"DTSTART:20260312T093000Z",
+		"DTEND:20260312T100000Z",
+		"STATUS:CONFIRMED",
+		"STATUS:CANCELLED",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed is missing %q", want)
+		}
+	}
+	for _, leaked := range []string{"Chest pain", "pneumonia", "Cancelled:"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("feed leaks %q", leaked)
+		}
+	}
+}
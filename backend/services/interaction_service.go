@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Interaction finding types
+const (
+	InteractionDrugDrug      = "drug-drug"
+	InteractionDrugCondition = "drug-condition"
+	InteractionDrugAge       = "drug-age"
+)
+
+// Interaction severities, most severe first
+const (
+	SeverityContraindicated = "contraindicated"
+	SeverityMajor           = "major"
+	SeverityModerate        = "moderate"
+	SeverityMinor           = "minor"
+)
+
+// Review states of an interaction finding
+const (
+	InteractionOpen         = "open"
+	InteractionAcknowledged = "acknowledged"
+	InteractionDismissed    = "dismissed"
+)
+
+var severityRank = map[string]int{
+	SeverityContraindicated: 4,
+	SeverityMajor:           3,
+	SeverityModerate:        2,
+	SeverityMinor:           1,
+}
+
+//go:embed interactions/knowledge_base.json
+var interactionFiles embed.FS
+
+// interactionKB is the on-disk format of the interaction knowledge base. Drug
+// references are ingredient names or "class:<name>" for a class listed in
+// Classes.
+type interactionKB struct {
+	Name     string              `json:"name"`
+	Version  string              `json:"version"`
+	Classes  map[string][]string `json:"classes"`
+	DrugDrug []struct {
+		ID         string `json:"id"`
+		A          string `json:"a"`
+		B          string `json:"b"`
+		Severity   string `json:"severity"`
+		Effect     string `json:"effect"`
+		Management string `json:"management"`
+	} `json:"drug_drug"`
+	DrugCondition []struct {
+		ID         string   `json:"id"`
+		Drug       string   `json:"drug"`
+		Conditions []string `json:"conditions"`
+		ICD10      []string `json:"icd10"` // Code prefixes
+		Severity   string   `json:"severity"`
+		Effect     string   `json:"effect"`
+		Management string   `json:"management"`
+	} `json:"drug_condition"`
+	DrugAge []struct {
+		ID         string `json:"id"`
+		Drug       string `json:"drug"`
+		MinAge     int    `json:"min_age"` // Inclusive, 0 for no lower bound
+		MaxAge     int    `json:"max_age"` // Inclusive, 0 for no upper bound
+		Gender     string `json:"gender"`  // Empty for any gender
+		Severity   string `json:"severity"`
+		Effect     string `json:"effect"`
+		Management string `json:"management"`
+	} `json:"drug_age"`
+}
+
+var (
+	interactionOnce sync.Once
+	interactionData *interactionKB
+)
+
+// loadInteractions parses the bundled knowledge base. Like the terminology
+// tables it is compiled into the binary, so a malformed file panics.
+func loadInteractions() *interactionKB {
+	interactionOnce.Do(func() {
+		data, err := interactionFiles.ReadFile("interactions/knowledge_base.json")
+		if err != nil {
+			panic(fmt.Sprintf("interactions: %v", err))
+		}
+		var kb interactionKB
+		if err := json.Unmarshal(data, &kb); err != nil {
+			panic(fmt.Sprintf("interactions: invalid knowledge base: %v", err))
+		}
+		for class, members := range kb.Classes {
+			for i, member := range members {
+				members[i] = normalizeTerm(member)
+			}
+			kb.Classes[class] = members
+		}
+		interactionData = &kb
+	})
+	return interactionData
+}
+
+// InteractionsVersion identifies the bundled knowledge base version
+func InteractionsVersion() string {
+	kb := loadInteractions()
+	return kb.Name + "@" + kb.Version
+}
+
+// interactionDrug is a medication entity with the names it can be matched by
+type interactionDrug struct {
+	text  string
+	names []string
+}
+
+// matches reports whether the drug is the referenced ingredient or belongs to
+// the referenced class
+func (kb *interactionKB) matches(drug interactionDrug, ref string) bool {
+	if class, ok := strings.CutPrefix(ref, "class:"); ok {
+		for _, member := range kb.Classes[class] {
+			for _, name := range drug.names {
+				if name == member {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	ref = normalizeTerm(ref)
+	for _, name := range drug.names {
+		if name == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// interactionDrugs returns the medications of an analysis that are not
+// negated, each with its text, abbreviation expansion and RxNorm ingredient
+func interactionDrugs(analysis *models.AIAnalysis) []interactionDrug {
+	var drugs []interactionDrug
+	for _, text := range analysis.Entities.Medications {
+		if EntityStatus(analysis, TermCategoryMedication, text) == AssertionNegated {
+			continue
+		}
+		drug := interactionDrug{text: text, names: []string{normalizeTerm(text)}}
+		coded := CodeEntity(TermCategoryMedication, text)
+		if coded.Expanded != "" {
+			drug.names = append(drug.names, coded.Expanded)
+		}
+		if coded.Code != "" && coded.Confidence >= TerminologyCodingThreshold {
+			drug.names = append(drug.names, normalizeTerm(coded.Display))
+		}
+		drugs = append(drugs, drug)
+	}
+	return drugs
+}
+
+// interactionConditions returns the diagnoses that describe the patient: not
+// negated, hypothetical or family history
+func interactionConditions(analysis *models.AIAnalysis) []string {
+	var conditions []string
+	for _, text := range analysis.Entities.Diagnoses {
+		switch EntityStatus(analysis, TermCategoryDiagnosis, text) {
+		case AssertionAffirmed, AssertionHistorical:
+			conditions = append(conditions, text)
+		}
+	}
+	return conditions
+}
+
+// conditionMatches reports whether a diagnosis names one of the condition terms
+// or is coded under one of the ICD-10-CM prefixes
+func conditionMatches(analysis *models.AIAnalysis, diagnosis string, terms, icd10 []string) bool {
+	tokens := contextTokens(diagnosis)
+	if coded := CodeEntity(TermCategoryDiagnosis, diagnosis); coded.Expanded != "" {
+		tokens = append(tokens, contextTokens(coded.Expanded)...)
+	}
+	for _, term := range terms {
+		phrase := contextTokens(term)
+		for i := range tokens {
+			if tokensAt(tokens, phrase, i) {
+				return true
+			}
+		}
+	}
+	if coded := EntityCoding(analysis, TermCategoryDiagnosis, diagnosis); coded != nil {
+		for _, prefix := range icd10 {
+			if strings.HasPrefix(coded.Code, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interactionFindingID derives a stable identifier so re-running the checker
+// on the same entities yields the same IDs
+func interactionFindingID(ruleID string, drugs []string, condition string) string {
+	sum := sha1.Sum([]byte(ruleID + "|" + strings.Join(drugs, "|") + "|" + condition))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// CheckInteractions cross-checks the medications of an analysis against each
+// other and against the patient's diagnoses, age and gender. Findings are
+// stored on the analysis, most severe first, and summarized in its warnings.
+// The patient may be nil, in which case demographic rules are skipped.
+func CheckInteractions(analysis *models.AIAnalysis, patient *models.Patient) {
+	kb := loadInteractions()
+	drugs := interactionDrugs(analysis)
+	conditions := interactionConditions(analysis)
+
+	var findings []models.InteractionFinding
+	seen := map[string]bool{}
+	add := func(ruleID, kind, severity string, drugNames []string, condition, effect, management string) {
+		id := interactionFindingID(ruleID, drugNames, condition)
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		findings = append(findings, models.InteractionFinding{
+			ID:         id,
+			RuleID:     ruleID,
+			Type:       kind,
+			Severity:   severity,
+			Drugs:      drugNames,
+			Condition:  condition,
+			Effect:     effect,
+			Management: management,
+			Status:     InteractionOpen,
+		})
+	}
+
+	for _, rule := range kb.DrugDrug {
+		for i := range drugs {
+			for j := range drugs {
+				if i == j || !kb.matches(drugs[i], rule.A) || !kb.matches(drugs[j], rule.B) {
+					continue
+				}
+				// Symmetric rules (e.g. class against itself) report each pair once
+				pair := []string{drugs[i].text, drugs[j].text}
+				if rule.A == rule.B && pair[0] > pair[1] {
+					continue
+				}
+				add(rule.ID, InteractionDrugDrug, rule.Severity, pair, "", rule.Effect, rule.Management)
+			}
+		}
+	}
+
+	for _, rule := range kb.DrugCondition {
+		for _, drug := range drugs {
+			if !kb.matches(drug, rule.Drug) {
+				continue
+			}
+			for _, diagnosis := range conditions {
+				if conditionMatches(analysis, diagnosis, rule.Conditions, rule.ICD10) {
+					add(rule.ID, InteractionDrugCondition, rule.Severity, []string{drug.text}, diagnosis, rule.Effect, rule.Management)
+				}
+			}
+		}
+	}
+
+	if patient != nil && patient.Age > 0 {
+		gender := normalizeGender(patient.Gender)
+		for _, rule := range kb.DrugAge {
+			if (rule.MinAge > 0 && patient.Age < rule.MinAge) || (rule.MaxAge > 0 && patient.Age > rule.MaxAge) {
+				continue
+			}
+			if rule.Gender != "" && rule.Gender != gender {
+				continue
+			}
+			condition := fmt.Sprintf("age %d", patient.Age)
+			if rule.Gender != "" {
+				condition = fmt.Sprintf("%s, age %d", gender, patient.Age)
+			}
+			for _, drug := range drugs {
+				if kb.matches(drug, rule.Drug) {
+					add(rule.ID, InteractionDrugAge, rule.Severity, []string{drug.text}, condition, rule.Effect, rule.Management)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+	analysis.Interactions = findings
+	analysis.InteractionsVersion = InteractionsVersion()
+	for _, f := range findings {
+		analysis.Warnings = append(analysis.Warnings, InteractionWarning(f))
+	}
+}
+
+// InteractionWarning renders a finding as an analysis warning, e.g.
+// "Drug interaction (major): warfarin + ibuprofen - Increased risk of serious bleeding. Avoid ..."
+func InteractionWarning(f models.InteractionFinding) string {
+	label := "Drug interaction"
+	subject := strings.Join(f.Drugs, " + ")
+	switch f.Type {
+	case InteractionDrugCondition:
+		label = "Drug-condition contraindication"
+		subject += " with " + f.Condition
+	case InteractionDrugAge:
+		label = "Drug-age caution"
+		subject += " at " + f.Condition
+	}
+	return fmt.Sprintf("%s (%s): %s - %s. %s", label, f.Severity, subject, f.Effect, f.Management)
+}
+
+// normalizeGender maps the free-text patient gender to "female", "male" or ""
+func normalizeGender(gender string) string {
+	switch strings.ToLower(strings.TrimSpace(gender)) {
+	case "f", "female", "woman":
+		return "female"
+	case "m", "male", "man":
+		return "male"
+	}
+	return ""
+}
+
+// ApplyInteractionChecks loads the patient and runs CheckInteractions. A
+// missing patient only disables the demographic rules.
+func ApplyInteractionChecks(analysis *models.AIAnalysis, patientID primitive.ObjectID) {
+	CheckInteractions(analysis, loadAnalysisPatient(patientID))
+}
+
+// loadAnalysisPatient returns the patient a report belongs to, or nil when
+// unknown
+func loadAnalysisPatient(patientID primitive.ObjectID) *models.Patient {
+	if patientID.IsZero() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var patient models.Patient
+	if err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": patientID}).Decode(&patient); err != nil {
+		return nil
+	}
+	return &patient
+}
+
+// OpenInteractions counts the findings of an analysis not yet reviewed
+func OpenInteractions(analysis *models.AIAnalysis) int {
+	open := 0
+	for _, f := range analysis.Interactions {
+		if f.Status == InteractionOpen {
+			open++
+		}
+	}
+	return open
+}
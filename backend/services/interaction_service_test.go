@@ -0,0 +1,115 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestCheckInteractions(t *testing.T) {
+	tests := []struct {
+		name        string
+		medications []string
+		diagnoses   []string
+		text        string // Report text for assertions, if any
+		patient     *models.Patient
+		want        []string // Rule IDs
+	}{
+		{"ingredient and class", []string{"warfarin", "ibuprofen"}, nil, "", nil, []string{"DDI-001"}},
+		{"drug in two classes", []string{"warfarin", "aspirin"}, nil, "", nil, []string{"DDI-001", "DDI-002"}},
+		{"symmetric class rule reported once", []string{"warfarin", "apixaban"}, nil, "", nil, []string{"DDI-003"}},
+		{"no interaction", []string{"paracetamol", "amoxicillin"}, nil, "", nil, nil},
+		{"negated medication", []string{"warfarin", "ibuprofen"}, nil, "On warfarin. No ibuprofen.", nil, nil},
+		{"drug-condition by name", []string{"metformin"}, []string{"chronic kidney disease"}, "", nil, []string{"DCI-001"}},
+		{"family history is not the patient's", []string{"metformin"}, []string{"chronic kidney disease"}, "On metformin. Mother has chronic kidney disease.", nil, nil},
+		{"negated condition", []string{"metformin"}, []string{"chronic kidney disease"}, "On metformin. No chronic kidney disease.", nil, nil},
+		{"historical condition counts", []string{"propranolol"}, []string{"asthma"}, "On propranolol. History of asthma.", nil, []string{"DCI-005"}},
+		{"older adult", []string{"diazepam"}, nil, "", &models.Patient{Age: 70}, []string{"DAI-001"}},
+		{"younger adult", []string{"diazepam"}, nil, "", &models.Patient{Age: 40}, nil},
+		{"age bound inclusive", []string{"codeine"}, nil, "", &models.Patient{Age: 11}, []string{"DAI-008"}},
+		{"gender rule applies", []string{"isotretinoin"}, nil, "", &models.Patient{Age: 25, Gender: "F"}, []string{"DAI-009"}},
+		{"gender rule skipped", []string{"isotretinoin"}, nil, "", &models.Patient{Age: 25, Gender: "male"}, nil},
+		{"unknown age skips demographics", []string{"diazepam"}, nil, "", &models.Patient{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var analysis models.AIAnalysis
+			analysis.Entities.Medications = tt.medications
+			analysis.Entities.Diagnoses = tt.diagnoses
+			if tt.text != "" {
+				ApplyAssertions(&analysis, tt.text)
+			}
+			CheckInteractions(&analysis, tt.patient)
+
+			var got []string
+			for _, f := range analysis.Interactions {
+				got = append(got, f.RuleID)
+				if f.Status != InteractionOpen {
+					t.Errorf("finding %s status = %s, want open", f.RuleID, f.Status)
+				}
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("rules = %v, want %v", got, tt.want)
+			}
+			if len(analysis.Warnings) != len(analysis.Interactions) {
+				t.Errorf("%d warnings for %d findings", len(analysis.Warnings), len(analysis.Interactions))
+			}
+			if analysis.InteractionsVersion == "" {
+				t.Error("interactions version not stamped")
+			}
+		})
+	}
+}
+
+func TestCheckInteractionsOrderAndIDs(t *testing.T) {
+	run := func() models.AIAnalysis {
+		var analysis models.AIAnalysis
+		analysis.Entities.Medications = []string{"digoxin", "furosemide", "simvastatin", "clarithromycin"}
+		CheckInteractions(&analysis, nil)
+		return analysis
+	}
+	first, second := run(), run()
+
+	for i := 1; i < len(first.Interactions); i++ {
+		if severityRank[first.Interactions[i-1].Severity] < severityRank[first.Interactions[i].Severity] {
+			t.Errorf("finding %d (%s) sorted before a more severe one", i-1, first.Interactions[i-1].Severity)
+		}
+	}
+	if len(first.Interactions) == 0 || first.Interactions[0].Severity != SeverityContraindicated {
+		t.Fatalf("findings = %+v, want the contraindication first", first.Interactions)
+	}
+	for i := range first.Interactions {
+		if first.Interactions[i].ID != second.Interactions[i].ID {
+			t.Errorf("finding %d ID changed between runs: %s vs %s", i, first.Interactions[i].ID, second.Interactions[i].ID)
+		}
+	}
+}
+
+func TestInteractionWarning(t *testing.T) {
+	tests := []struct {
+		finding models.InteractionFinding
+		want    string
+	}{
+		{
+			models.InteractionFinding{Type: InteractionDrugDrug, Severity: SeverityMajor, Drugs: []string{"warfarin", "ibuprofen"}, Effect: "Bleeding", Management: "Avoid."},
+			"Drug interaction (major): warfarin + ibuprofen - Bleeding. Avoid.",
+		},
+		{
+			models.InteractionFinding{Type: InteractionDrugCondition, Severity: SeverityMajor, Drugs: []string{"metformin"}, Condition: "CKD", Effect: "Lactic acidosis", Management: "Review dose."},
+			"Drug-condition contraindication (major): metformin with CKD - Lactic acidosis. Review dose.",
+		},
+		{
+			models.InteractionFinding{Type: InteractionDrugAge, Severity: SeverityModerate, Drugs: []string{"diazepam"}, Condition: "age 70", Effect: "Falls", Management: "Taper."},
+			"Drug-age caution (moderate): diazepam at age 70 - Falls. Taper.",
+		},
+	}
+	for _, tt := range tests {
+		if got := InteractionWarning(tt.finding); got != tt.want {
+			t.Errorf("InteractionWarning() = %q, want %q", got, tt.want)
+		}
+	}
+}
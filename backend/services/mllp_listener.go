@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// MLLP framing bytes: <VT> message <FS><CR>
+const (
+	mllpStartBlock = 0x0b
+	mllpEndBlock   = 0x1c
+	mllpCarriage   = 0x0d
+)
+
+// maxMLLPMessageSize guards against senders that never close a frame
+const maxMLLPMessageSize = 16 << 20
+
+// MLLPListener accepts HL7 v2 messages over the Minimal Lower Layer Protocol
+// and answers each with an ACK or NAK
+type MLLPListener struct {
+	Addr        string
+	Service     *HL7Service
+	IdleTimeout time.Duration // closes connections idle for longer; defaults to 5 minutes
+}
+
+// ListenAndServe listens on l.Addr and handles connections until the listener fails
+func (l *MLLPListener) ListenAndServe() error {
+	ln, err := net.Listen("tcp", l.Addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	log.Println("🧪 HL7 MLLP listener on", l.Addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+		go l.serveConn(conn)
+	}
+}
+
+// serveConn processes frames on one connection; senders may keep it open for
+// many messages
+func (l *MLLPListener) serveConn(conn net.Conn) {
+	defer conn.Close()
+	idle := l.IdleTimeout
+	if idle == 0 {
+		idle = 5 * time.Minute
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(idle))
+		message, err := readMLLPFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("HL7 MLLP %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		ack := l.Service.ProcessMessage(message, conn.RemoteAddr().String())
+		conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+		if err := writeMLLPFrame(conn, ack); err != nil {
+			log.Printf("HL7 MLLP %s: failed to send ACK: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// readMLLPFrame reads one framed message, skipping any bytes before the start block
+func readMLLPFrame(r *bufio.Reader) (string, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == mllpStartBlock {
+			break
+		}
+	}
+
+	var buf []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("connection closed inside a frame")
+			}
+			return "", err
+		}
+		if b == mllpEndBlock {
+			// The trailing CR is skipped while looking for the next start block
+			return string(buf), nil
+		}
+		buf = append(buf, b)
+		if len(buf) > maxMLLPMessageSize {
+			return "", fmt.Errorf("message exceeds %d MB", maxMLLPMessageSize>>20)
+		}
+	}
+}
+
+// writeMLLPFrame writes a message wrapped in MLLP framing
+func writeMLLPFrame(w io.Writer, message string) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, mllpStartBlock)
+	frame = append(frame, message...)
+	frame = append(frame, mllpEndBlock, mllpCarriage)
+	_, err := w.Write(frame)
+	return err
+}
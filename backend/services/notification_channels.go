@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+// NotificationSender delivers a notification through an external channel.
+// Senders are registered with RegisterNotificationSender; the last sender
+// registered for a channel wins.
+type NotificationSender interface {
+	Channel() string
+	Send(recipient *NotificationRecipient, n *models.Notification) error
+}
+
+// SMSProvider sends a text message. Plug a provider in with
+// RegisterSMSProvider, or set SMS_GATEWAY_URL to use HTTPSMSProvider.
+type SMSProvider interface {
+	SendSMS(to, message string) error
+}
+
+var (
+	notificationSendersOnce sync.Once
+	notificationSendersMu   sync.RWMutex
+	notificationSenders     map[string]NotificationSender
+)
+
+// RegisterNotificationSender sets the sender for its channel
+func RegisterNotificationSender(sender NotificationSender) {
+	loadNotificationSenders()
+	notificationSendersMu.Lock()
+	defer notificationSendersMu.Unlock()
+	notificationSenders[sender.Channel()] = sender
+}
+
+// RegisterSMSProvider sends SMS notifications through provider
+func RegisterSMSProvider(provider SMSProvider) {
+	RegisterNotificationSender(&SMSNotificationSender{Provider: provider})
+}
+
+// NotificationSenderFor returns the sender for a channel, or nil when the
+// channel is not configured
+func NotificationSenderFor(channel string) NotificationSender {
+	loadNotificationSenders()
+	notificationSendersMu.RLock()
+	defer notificationSendersMu.RUnlock()
+	return notificationSenders[channel]
+}
+
+func loadNotificationSenders() {
+	notificationSendersOnce.Do(func() {
+		notificationSenders = map[string]NotificationSender{}
+		if os.Getenv("SMTP_HOST") != "" {
+			notificationSenders[ChannelEmail] = &EmailNotificationSender{SMTP: NewSMTPAlertChannel()}
+		}
+		if url := os.Getenv("SMS_GATEWAY_URL"); url != "" {
+			notificationSenders[ChannelSMS] = &SMSNotificationSender{Provider: &HTTPSMSProvider{
+				URL:    url,
+				Token:  os.Getenv("SMS_GATEWAY_TOKEN"),
+				Client: &http.Client{Timeout: 10 * time.Second},
+			}}
+		}
+	})
+}
+
+// EmailNotificationSender emails notifications using the SMTP settings shared
+// with critical alerts
+type EmailNotificationSender struct {
+	SMTP *SMTPAlertChannel
+}
+
+func (s *EmailNotificationSender) Channel() string { return ChannelEmail }
+
+func (s *EmailNotificationSender) Send(recipient *NotificationRecipient, n *models.Notification) error {
+	if recipient.Email == "" {
+		return fmt.Errorf("recipient has no email address")
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n\r\nSign in to the portal for details.\r\n",
+		s.SMTP.From, recipient.Email, n.Title, n.Body)
+	return smtp.SendMail(s.SMTP.Addr, s.SMTP.Auth, s.SMTP.From, []string{recipient.Email}, []byte(msg))
+}
+
+// SMSNotificationSender texts the notification title to the recipient
+type SMSNotificationSender struct {
+	Provider SMSProvider
+}
+
+func (s *SMSNotificationSender) Channel() string { return ChannelSMS }
+
+func (s *SMSNotificationSender) Send(recipient *NotificationRecipient, n *models.Notification) error {
+	if recipient.Phone == "" {
+		return fmt.Errorf("recipient has no phone number")
+	}
+	// Texts carry only the title; the body may say more than belongs on a lock screen
+	return s.Provider.SendSMS(recipient.Phone, n.Title+" - sign in to the portal for details.")
+}
+
+// HTTPSMSProvider posts {"to", "message"} as JSON to an SMS gateway,
+// authenticating with a bearer token when one is set
+type HTTPSMSProvider struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func (p *HTTPSMSProvider) SendSMS(to, message string) error {
+	data, err := json.Marshal(map[string]string{"to": to, "message": message})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Notification events
+const (
+	EventAnalysisComplete       = "analysis_complete"
+	EventReportReviewed         = "report_reviewed"
+	EventReportEdited           = "report_edited"
+	EventSecondOpinionRequested = "second_opinion_requested"
+	EventChatEscalation         = "chat_escalation"
+	EventCriticalAlert          = "critical_alert"
+	EventCareTask               = "care_task"
+	EventAppointment            = "appointment"
+	EventDelegation             = "delegation"
+	EventBreakGlass             = "break_glass"
+	EventReferral               = "referral"
+)
+
+// NotificationEvents lists the events users can set preferences for
+var NotificationEvents = []string{
+	EventAnalysisComplete,
+	EventReportReviewed,
+	EventReportEdited,
+	EventSecondOpinionRequested,
+	EventChatEscalation,
+	EventCareTask,
+	EventAppointment,
+	EventDelegation,
+	EventBreakGlass,
+}
+
+// Notification channels. The inbox always keeps a copy; "in-app" pushes it to
+// open sessions as well.
+const (
+	ChannelInApp = "in-app"
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Notification recipients
+const (
+	RecipientPatient = "patient"
+	RecipientDoctor  = "doctor"
+	RecipientAdmin   = "admin"
+)
+
+// DefaultNotificationChannels apply until a user saves preferences
+var DefaultNotificationChannels = []string{ChannelInApp, ChannelEmail}
+
+// ValidNotificationChannel reports whether a channel can be chosen in preferences
+func ValidNotificationChannel(channel string) bool {
+	return channel == ChannelInApp || channel == ChannelEmail || channel == ChannelSMS
+}
+
+// ValidRecipientType reports whether notifications can be sent to the user type
+func ValidRecipientType(userType string) bool {
+	return userType == RecipientPatient || userType == RecipientDoctor
+}
+
+// ValidInboxOwner reports whether a user type has a notification inbox.
+// Admins only receive system notices, so they aren't valid recipients for
+// user-chosen targets such as care task assignees.
+func ValidInboxOwner(userType string) bool {
+	return ValidRecipientType(userType) || userType == RecipientAdmin
+}
+
+// NotificationRecipient is the contact information a sender needs
+type NotificationRecipient struct {
+	ID    primitive.ObjectID
+	Type  string
+	Name  string
+	Email string
+	Phone string
+}
+
+// NotificationPreferencesFor returns a user's saved preferences, or the
+// defaults when none are saved
+func NotificationPreferencesFor(ctx context.Context, userID primitive.ObjectID, userType string) (*models.NotificationPreferences, error) {
+	var prefs models.NotificationPreferences
+	err := config.GetCollection("notification_preferences").FindOne(ctx, bson.M{"user_id": userID, "user_type": userType}).Decode(&prefs)
+	if err == mongo.ErrNoDocuments {
+		return &models.NotificationPreferences{
+			UserID:   userID,
+			UserType: userType,
+			Channels: append([]string(nil), DefaultNotificationChannels...),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// notificationChannels returns the channels chosen for an event
+func notificationChannels(prefs *models.NotificationPreferences, event string) []string {
+	if channels, ok := prefs.Events[event]; ok {
+		return channels
+	}
+	return prefs.Channels
+}
+
+// loadRecipient looks up the contact details of a patient or doctor
+func loadRecipient(ctx context.Context, userID primitive.ObjectID, userType string) (*NotificationRecipient, error) {
+	recipient := &NotificationRecipient{ID: userID, Type: userType}
+	switch userType {
+	case RecipientPatient:
+		var patient models.Patient
+		if err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": userID}).Decode(&patient); err != nil {
+			return nil, err
+		}
+		recipient.Name, recipient.Email, recipient.Phone = patient.Name, patient.Email, patient.Phone
+	case RecipientDoctor:
+		var doctor models.Doctor
+		if err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": userID}).Decode(&doctor); err != nil {
+			return nil, err
+		}
+		recipient.Name, recipient.Email = doctor.Name, doctor.Email
+	case RecipientAdmin:
+		var admin models.Admin
+		if err := config.GetCollection("admins").FindOne(ctx, bson.M{"_id": userID}).Decode(&admin); err != nil {
+			return nil, err
+		}
+		recipient.Name, recipient.Email = admin.Name, admin.Email
+	default:
+		return nil, fmt.Errorf("unknown recipient type %q", userType)
+	}
+	return recipient, nil
+}
+
+// Notify stores a notification in the recipient's inbox and sends it through
+// the channels they chose
+func Notify(n *models.Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	prefs, err := NotificationPreferencesFor(ctx, n.UserID, n.UserType)
+	if err != nil {
+		log.Printf("notifications: failed to load preferences of %s %s: %v", n.UserType, n.UserID.Hex(), err)
+		prefs = &models.NotificationPreferences{Channels: DefaultNotificationChannels}
+	}
+	channels := notificationChannels(prefs, n.Event)
+
+	push := false
+	var external []string
+	for _, channel := range channels {
+		if channel == ChannelInApp {
+			push = true
+		} else {
+			external = append(external, channel)
+		}
+	}
+	if err := storeNotification(ctx, n, push); err != nil {
+		return
+	}
+	if len(external) > 0 {
+		go deliverNotification(n, prefs, external)
+	}
+}
+
+// NotifyInApp stores a notification and pushes it to open sessions without
+// using external channels, for events that have their own delivery
+func NotifyInApp(n *models.Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	storeNotification(ctx, n, true)
+}
+
+func storeNotification(ctx context.Context, n *models.Notification, push bool) error {
+	n.ID = primitive.NewObjectID()
+	n.Read = false
+	n.CreatedAt = time.Now()
+	if _, err := config.GetCollection("notifications").InsertOne(ctx, n); err != nil {
+		log.Printf("notifications: failed to store %s notification for %s %s: %v", n.Event, n.UserType, n.UserID.Hex(), err)
+		return err
+	}
+	if push {
+		notificationHub.publish(*n)
+	}
+	return nil
+}
+
+// deliverNotification sends a stored notification through external channels
+// and records each attempt on it
+func deliverNotification(n *models.Notification, prefs *models.NotificationPreferences, channels []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	recipient, err := loadRecipient(ctx, n.UserID, n.UserType)
+	if err != nil {
+		log.Printf("notifications: failed to load %s %s: %v", n.UserType, n.UserID.Hex(), err)
+		return
+	}
+	if prefs.Phone != "" {
+		recipient.Phone = prefs.Phone
+	}
+
+	var deliveries []models.NotificationDelivery
+	for _, channel := range channels {
+		delivery := models.NotificationDelivery{Channel: channel, SentAt: time.Now()}
+		sender := NotificationSenderFor(channel)
+		if sender == nil {
+			delivery.Error = "channel is not configured"
+		} else if err := sender.Send(recipient, n); err != nil {
+			delivery.Error = err.Error()
+			log.Printf("notifications: %s delivery of %s failed: %v", channel, n.ID.Hex(), err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	_, err = config.GetCollection("notifications").UpdateOne(ctx,
+		bson.M{"_id": n.ID},
+		bson.M{"$push": bson.M{"deliveries": bson.M{"$each": deliveries}}},
+	)
+	if err != nil {
+		log.Printf("notifications: failed to record delivery of %s: %v", n.ID.Hex(), err)
+	}
+}
+
+// NotifyAdmins sends a copy of a notification to every admin
+func NotifyAdmins(template models.Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("admins").Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		log.Printf("notifications: failed to load admins: %v", err)
+		return
+	}
+	var admins []models.Admin
+	if err := cursor.All(ctx, &admins); err != nil {
+		log.Printf("notifications: failed to decode admins: %v", err)
+		return
+	}
+	for _, admin := range admins {
+		n := template
+		n.UserID, n.UserType = admin.ID, RecipientAdmin
+		Notify(&n)
+	}
+}
+
+// reportLabel names a report in notification text without revealing findings
+func reportLabel(report *models.Report) string {
+	if report.PDFFileName != "" {
+		return fmt.Sprintf("Your report %q", report.PDFFileName)
+	}
+	return "Your lab report"
+}
+
+// NotifyAnalysisComplete tells the patient their report has been analysed.
+// Reports not yet matched to a patient are skipped.
+func NotifyAnalysisComplete(report *models.Report) {
+	if report.PatientID.IsZero() {
+		return
+	}
+	reportID := report.ID
+	Notify(&models.Notification{
+		UserID:   report.PatientID,
+		UserType: RecipientPatient,
+		Event:    EventAnalysisComplete,
+		Title:    "Report analysis complete",
+		Body:     reportLabel(report) + " has been analysed and is waiting for doctor review.",
+		ReportID: &reportID,
+	})
+}
+
+// NotifyReportReviewed tells the patient a doctor reviewed or edited their
+// report; event is EventReportReviewed or EventReportEdited
+func NotifyReportReviewed(report *models.Report, event string) {
+	if report.PatientID.IsZero() {
+		return
+	}
+	title, verb := "Report reviewed", "reviewed"
+	if event == EventReportEdited {
+		title, verb = "Report updated by your doctor", "reviewed and updated"
+	}
+	reportID := report.ID
+	Notify(&models.Notification{
+		UserID:   report.PatientID,
+		UserType: RecipientPatient,
+		Event:    event,
+		Title:    title,
+		Body:     fmt.Sprintf("%s has been %s by a doctor.", reportLabel(report), verb),
+		ReportID: &reportID,
+	})
+}
+
+// notificationHub fans stored notifications out to SSE subscribers
+var notificationHub = &notificationBroker{subscribers: map[string]map[chan models.Notification]struct{}{}}
+
+type notificationBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.Notification]struct{}
+}
+
+func subscriberKey(userID primitive.ObjectID, userType string) string {
+	return userType + ":" + userID.Hex()
+}
+
+// SubscribeNotifications streams a user's new notifications until the
+// returned cancel function is called
+func SubscribeNotifications(userID primitive.ObjectID, userType string) (<-chan models.Notification, func()) {
+	key := subscriberKey(userID, userType)
+	ch := make(chan models.Notification, 16)
+
+	notificationHub.mu.Lock()
+	if notificationHub.subscribers[key] == nil {
+		notificationHub.subscribers[key] = map[chan models.Notification]struct{}{}
+	}
+	notificationHub.subscribers[key][ch] = struct{}{}
+	notificationHub.mu.Unlock()
+
+	return ch, func() {
+		notificationHub.mu.Lock()
+		defer notificationHub.mu.Unlock()
+		delete(notificationHub.subscribers[key], ch)
+		if len(notificationHub.subscribers[key]) == 0 {
+			delete(notificationHub.subscribers, key)
+		}
+	}
+}
+
+// publish never blocks; a slow subscriber misses live events but still finds
+// them in the inbox
+func (b *notificationBroker) publish(n models.Notification) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[subscriberKey(n.UserID, n.UserType)] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNotificationValidation(t *testing.T) {
+	tests := []struct {
+		value                      string
+		channel, recipient, holder bool
+	}{
+		{ChannelInApp, true, false, false},
+		{ChannelEmail, true, false, false},
+		{ChannelSMS, true, false, false},
+		{RecipientPatient, false, true, true},
+		{RecipientDoctor, false, true, true},
+		{RecipientAdmin, false, false, true},
+		{"push", false, false, false},
+	}
+	for _, tt := range tests {
+		if got := ValidNotificationChannel(tt.value); got != tt.channel {
+			t.Errorf("ValidNotificationChannel(%q) = %v", tt.value, got)
+		}
+		if got := ValidRecipientType(tt.value); got != tt.recipient {
+			t.Errorf("ValidRecipientType(%q) = %v", tt.value, got)
+		}
+		if got := ValidInboxOwner(tt.value); got != tt.holder {
+			t.Errorf("ValidInboxOwner(%q) = %v", tt.value, got)
+		}
+	}
+}
+
+func TestNotificationChannels(t *testing.T) {
+	prefs := &models.NotificationPreferences{
+		Channels: []string{ChannelInApp, ChannelEmail},
+		Events: map[string][]string{
+			EventCareTask:    {ChannelSMS},
+			EventAppointment: {},
+		},
+	}
+	tests := []struct {
+		event string
+		want  []string
+	}{
+		{EventAnalysisComplete, []string{ChannelInApp, ChannelEmail}},
+		{EventCareTask, []string{ChannelSMS}},
+		{EventAppointment, nil}, // Muted by an empty override
+	}
+	for _, tt := range tests {
+		if got := notificationChannels(prefs, tt.event); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("notificationChannels(%s) = %v, want %v", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestReportLabel(t *testing.T) {
+	if got := reportLabel(&models.Report{PDFFileName: "blood.pdf"}); got != `Your report "blood.pdf"` {
+		t.Errorf("reportLabel() = %q", got)
+	}
+	if got := reportLabel(&models.Report{}); got != "Your lab report" {
+		t.Errorf("reportLabel() without a file = %q", got)
+	}
+}
+
+func TestSubscribeNotifications(t *testing.T) {
+	userID := primitive.NewObjectID()
+	patient, cancelPatient := SubscribeNotifications(userID, RecipientPatient)
+	doctor, cancelDoctor := SubscribeNotifications(userID, RecipientDoctor)
+	defer cancelDoctor()
+
+	notificationHub.publish(models.Notification{UserID: userID, UserType: RecipientPatient, Title: "hello"})
+	select {
+	case n := <-patient:
+		if n.Title != "hello" {
+			t.Errorf("received %q", n.Title)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not receive the notification")
+	}
+	select {
+	case n := <-doctor:
+		t.Errorf("notification for the patient reached the doctor subscription: %+v", n)
+	default:
+	}
+
+	// A full subscriber does not block publishing
+	for i := 0; i < cap(patient)+5; i++ {
+		notificationHub.publish(models.Notification{UserID: userID, UserType: RecipientPatient})
+	}
+	cancelPatient()
+	notificationHub.mu.Lock()
+	_, stillSubscribed := notificationHub.subscribers[subscriberKey(userID, RecipientPatient)]
+	notificationHub.mu.Unlock()
+	if stillSubscribed {
+		t.Error("cancelled subscription is still registered")
+	}
+}
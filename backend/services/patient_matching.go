@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrPatientNotFound is returned when no patient matches the given identifiers
+	ErrPatientNotFound = errors.New("patient not found")
+	// ErrPatientReconciliation is returned when a record cannot be linked to a
+	// patient safely and an admin has to pick the patient
+	ErrPatientReconciliation = errors.New("patient needs manual reconciliation")
+)
+
+// PatientDemographics are the details used to create a patient from an external record
+type PatientDemographics struct {
+	Name   string
+	Gender string
+	Phone  string
+	Email  string
+	Age    int
+}
+
+// FindPatientByIdentifiers returns the first patient matching any identifier.
+// Identifiers in this backend's own system match the patient ID directly;
+// others must match both system and value. Identifiers without a system are
+// ignored, since the same value may be issued by several systems.
+func FindPatientByIdentifiers(identifiers []models.PatientIdentifier) (*models.Patient, error) {
+	collection := config.GetCollection("patients")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, identifier := range identifiers {
+		value := strings.TrimSpace(identifier.Value)
+		if value == "" {
+			continue
+		}
+
+		var filter bson.M
+		switch identifier.System {
+		case FHIRPatientIdentifierSystem:
+			objID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				continue
+			}
+			filter = bson.M{"_id": objID}
+		case "":
+			continue
+		default:
+			filter = bson.M{"identifiers": bson.M{"$elemMatch": bson.M{"system": identifier.System, "value": value}}}
+		}
+
+		var patient models.Patient
+		err := collection.FindOne(ctx, filter).Decode(&patient)
+		if err == nil {
+			return &patient, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("failed to look up patient: %w", err)
+		}
+	}
+	return nil, ErrPatientNotFound
+}
+
+// MatchOrCreatePatient finds a patient by identifier, creating one from the
+// demographics when none matches and a name is available. It reports whether
+// a new patient was created. Records whose identifiers have no system, or
+// whose demographics disagree with the matched patient, return
+// ErrPatientReconciliation.
+func MatchOrCreatePatient(identifiers []models.PatientIdentifier, demographics *PatientDemographics) (*models.Patient, bool, error) {
+	hasSystem := false
+	for _, identifier := range identifiers {
+		if identifier.System != "" && strings.TrimSpace(identifier.Value) != "" {
+			hasSystem = true
+		}
+	}
+	if !hasSystem {
+		return nil, false, fmt.Errorf("%w: no identifier has an assigning system", ErrPatientReconciliation)
+	}
+
+	patient, err := FindPatientByIdentifiers(identifiers)
+	if err == nil {
+		if reason := demographicsConflict(patient, demographics); reason != "" {
+			return nil, false, fmt.Errorf("%w: %s of patient %s does not match", ErrPatientReconciliation, reason, patient.ID.Hex())
+		}
+		return patient, false, nil
+	}
+	if !errors.Is(err, ErrPatientNotFound) || demographics == nil || strings.TrimSpace(demographics.Name) == "" {
+		return nil, false, err
+	}
+
+	// Only keep external identifiers; our own system is the document ID
+	var external []models.PatientIdentifier
+	for _, identifier := range identifiers {
+		if identifier.System != "" && identifier.System != FHIRPatientIdentifierSystem && strings.TrimSpace(identifier.Value) != "" {
+			external = append(external, identifier)
+		}
+	}
+	if len(external) == 0 {
+		return nil, false, ErrPatientNotFound
+	}
+
+	// Patients created from external records have no password and cannot log
+	// in until they complete signup
+	created := models.Patient{
+		ID:          primitive.NewObjectID(),
+		Name:        demographics.Name,
+		Email:       demographics.Email,
+		Age:         demographics.Age,
+		Gender:      demographics.Gender,
+		Phone:       demographics.Phone,
+		Identifiers: external,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("patients").InsertOne(ctx, created); err != nil {
+		return nil, false, fmt.Errorf("failed to create patient: %w", err)
+	}
+	return &created, true, nil
+}
+
+// demographicsConflict returns which detail of an incoming record contradicts
+// the matched patient, or "" when they agree. Missing details never conflict.
+func demographicsConflict(patient *models.Patient, demographics *PatientDemographics) string {
+	if demographics == nil {
+		return ""
+	}
+	if demographics.Name != "" && patient.Name != "" && !namesAgree(patient.Name, demographics.Name) {
+		return "name"
+	}
+	if demographics.Gender != "" && patient.Gender != "" && !strings.EqualFold(demographics.Gender, patient.Gender) {
+		return "gender"
+	}
+	if demographics.Age > 0 && patient.Age > 0 && (demographics.Age-patient.Age > 1 || patient.Age-demographics.Age > 1) {
+		return "age"
+	}
+	return ""
+}
+
+// namesAgree reports whether every part of the shorter name appears in the
+// longer one, so a missing middle name or reordered parts still agree
+func namesAgree(a, b string) bool {
+	partsA, partsB := strings.Fields(strings.ToLower(a)), strings.Fields(strings.ToLower(b))
+	if len(partsA) > len(partsB) {
+		partsA, partsB = partsB, partsA
+	}
+	longer := map[string]bool{}
+	for _, part := range partsB {
+		longer[strings.Trim(part, ".,")] = true
+	}
+	for _, part := range partsA {
+		if !longer[strings.Trim(part, ".,")] {
+			return false
+		}
+	}
+	return len(partsA) > 0
+}
+
+// LinkPatientIdentifiers adds the external identifiers of a reconciled record
+// to the patient an admin chose, so later records match automatically
+func LinkPatientIdentifiers(ctx context.Context, patientID primitive.ObjectID, identifiers []models.PatientIdentifier) (*models.Patient, error) {
+	var external []models.PatientIdentifier
+	for _, identifier := range identifiers {
+		if identifier.System != "" && identifier.System != FHIRPatientIdentifierSystem && strings.TrimSpace(identifier.Value) != "" {
+			external = append(external, identifier)
+		}
+	}
+	update := bson.M{"$set": bson.M{"updated_at": time.Now()}}
+	if len(external) > 0 {
+		update["$addToSet"] = bson.M{"identifiers": bson.M{"$each": external}}
+	}
+	var patient models.Patient
+	err := config.GetCollection("patients").FindOneAndUpdate(ctx, bson.M{"_id": patientID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&patient)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPatientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
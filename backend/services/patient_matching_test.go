@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+func TestDemographicsConflict(t *testing.T) {
+	patient := &models.Patient{Name: "Asha Devi Rao", Gender: "female", Age: 54}
+	tests := []struct {
+		name         string
+		demographics *PatientDemographics
+		want         string
+	}{
+		{"no demographics", nil, ""},
+		{"same", &PatientDemographics{Name: "Asha Devi Rao", Gender: "female", Age: 54}, ""},
+		{"missing middle name", &PatientDemographics{Name: "Asha Rao"}, ""},
+		{"reordered", &PatientDemographics{Name: "RAO ASHA"}, ""},
+		{"birthday since", &PatientDemographics{Name: "Asha Rao", Age: 55}, ""},
+		{"other name", &PatientDemographics{Name: "Ravi Rao"}, "name"},
+		{"other gender", &PatientDemographics{Name: "Asha Rao", Gender: "male"}, "gender"},
+		{"other age", &PatientDemographics{Name: "Asha Rao", Age: 12}, "age"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := demographicsConflict(patient, tt.demographics); got != tt.want {
+				t.Errorf("demographicsConflict() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchOrCreatePatientRequiresSystem(t *testing.T) {
+	_, _, err := MatchOrCreatePatient([]models.PatientIdentifier{{Value: "12345"}}, &PatientDemographics{Name: "Asha Rao"})
+	if !errors.Is(err, ErrPatientReconciliation) {
+		t.Errorf("MatchOrCreatePatient() error = %v, want ErrPatientReconciliation", err)
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimal PDF 1.4 writer for server-rendered documents. It supports the
+// standard Helvetica fonts (no embedding), text, lines and filled rectangles
+// on A4 pages, which is all the summary PDFs need.
+
+const (
+	pdfPageWidth  = 595.28 // A4 in points
+	pdfPageHeight = 841.89
+)
+
+type pdfFont string
+
+const (
+	pdfFontRegular pdfFont = "F1" // Helvetica
+	pdfFontBold    pdfFont = "F2" // Helvetica-Bold
+)
+
+type pdfColor struct{ R, G, B float64 }
+
+var (
+	pdfBlack     = pdfColor{0, 0, 0}
+	pdfDarkGray  = pdfColor{0.25, 0.25, 0.25}
+	pdfGray      = pdfColor{0.45, 0.45, 0.45}
+	pdfLightGray = pdfColor{0.85, 0.85, 0.85}
+	pdfRed       = pdfColor{0.75, 0.1, 0.1}
+	pdfOrange    = pdfColor{0.85, 0.45, 0}
+	pdfGreen     = pdfColor{0.1, 0.55, 0.2}
+)
+
+// helveticaWidths are the Helvetica glyph widths (1/1000 em) for ASCII 32-126
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// winAnsiExtras maps common non-Latin-1 characters to WinAnsiEncoding bytes
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// parseHexColor parses "#rrggbb", returning fallback when invalid
+func parseHexColor(hex string, fallback pdfColor) pdfColor {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) != 6 {
+		return fallback
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fallback
+	}
+	return pdfColor{
+		R: float64(value>>16&0xff) / 255,
+		G: float64(value>>8&0xff) / 255,
+		B: float64(value&0xff) / 255,
+	}
+}
+
+// pdfDocument accumulates page content streams
+type pdfDocument struct {
+	title string
+	pages []*bytes.Buffer
+}
+
+func (d *pdfDocument) addPage() int {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	return len(d.pages) - 1
+}
+
+// text draws a single line of text with its baseline at (x, y)
+func (d *pdfDocument) text(page int, x, y float64, font pdfFont, size float64, color pdfColor, s string) {
+	fmt.Fprintf(d.pages[page], "BT /%s %.1f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+		font, size, color.R, color.G, color.B, x, y, pdfEscape(s))
+}
+
+// line draws a straight line
+func (d *pdfDocument) line(page int, x1, y1, x2, y2, width float64, color pdfColor) {
+	fmt.Fprintf(d.pages[page], "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		color.R, color.G, color.B, width, x1, y1, x2, y2)
+}
+
+// rect draws a filled rectangle with its lower-left corner at (x, y)
+func (d *pdfDocument) rect(page int, x, y, w, h float64, color pdfColor) {
+	fmt.Fprintf(d.pages[page], "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		color.R, color.G, color.B, x, y, w, h)
+}
+
+// bytes serialises the document
+func (d *pdfDocument) bytes() ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Fixed objects: 1 catalog, 2 page tree, 3-4 fonts, 5 info; pages follow
+	const firstPageObject = 6
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPageObject+2*i))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (Medical Report Analyzer) /CreationDate (D:%s) >>",
+		pdfEscape(d.title), time.Now().UTC().Format("20060102150405Z")))
+
+	for i, content := range d.pages {
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		if _, err := w.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPageObject+2*i+1))
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+			compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
+
+// pdfEncode converts a string to WinAnsiEncoding, replacing unsupported characters
+func pdfEncode(s string) []byte {
+	encoded := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			encoded = append(encoded, ' ')
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			encoded = append(encoded, byte(r))
+		default:
+			if b, ok := winAnsiExtras[r]; ok {
+				encoded = append(encoded, b)
+			} else if r >= 32 {
+				encoded = append(encoded, '?')
+			}
+		}
+	}
+	return encoded
+}
+
+// pdfEscape encodes and escapes a string for use in a PDF literal string
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, c := range pdfEncode(s) {
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the rendered width of s in points
+func pdfTextWidth(s string, font pdfFont, size float64) float64 {
+	total := 0
+	for _, c := range pdfEncode(s) {
+		if c >= 32 && c <= 126 {
+			total += helveticaWidths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	width := float64(total) * size / 1000
+	if font == pdfFontBold {
+		// Helvetica-Bold is slightly wider on average
+		width *= 1.07
+	}
+	return width
+}
+
+// pdfWrap breaks text into lines no wider than maxWidth
+func pdfWrap(s string, font pdfFont, size, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		current := ""
+		for _, word := range words {
+			// Break words that cannot fit on a line by themselves
+			for pdfTextWidth(word, font, size) > maxWidth {
+				cut := len([]rune(word)) - 1
+				for cut > 1 && pdfTextWidth(string([]rune(word)[:cut]), font, size) > maxWidth {
+					cut--
+				}
+				if current != "" {
+					lines = append(lines, current)
+					current = ""
+				}
+				lines = append(lines, string([]rune(word)[:cut]))
+				word = string([]rune(word)[cut:])
+			}
+			candidate := word
+			if current != "" {
+				candidate = current + " " + word
+			}
+			if pdfTextWidth(candidate, font, size) > maxWidth && current != "" {
+				lines = append(lines, current)
+				current = word
+			} else {
+				current = candidate
+			}
+		}
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// pdfLayout flows content top to bottom across pages. Headers and footers are
+// drawn once the page count is known.
+type pdfLayout struct {
+	doc          *pdfDocument
+	page         int
+	y            float64
+	marginLeft   float64
+	marginRight  float64
+	marginTop    float64
+	marginBottom float64
+}
+
+func newPDFLayout(title string) *pdfLayout {
+	l := &pdfLayout{
+		doc:          &pdfDocument{title: title},
+		marginLeft:   50,
+		marginRight:  50,
+		marginTop:    70, // room for the page header
+		marginBottom: 60, // room for the page footer
+	}
+	l.newPage()
+	return l
+}
+
+func (l *pdfLayout) width() float64 {
+	return pdfPageWidth - l.marginLeft - l.marginRight
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = l.doc.addPage()
+	l.y = pdfPageHeight - l.marginTop
+}
+
+// ensure starts a new page unless height points remain on the current one
+func (l *pdfLayout) ensure(height float64) {
+	if l.y-height < l.marginBottom {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) space(height float64) {
+	l.y -= height
+}
+
+// paragraph writes wrapped text starting indent points from the left margin
+func (l *pdfLayout) paragraph(s string, font pdfFont, size float64, color pdfColor, indent float64) {
+	leading := size * 1.35
+	for _, line := range pdfWrap(s, font, size, l.width()-indent) {
+		l.ensure(leading)
+		l.y -= leading
+		l.doc.text(l.page, l.marginLeft+indent, l.y+size*0.3, font, size, color, line)
+	}
+}
+
+// heading writes a section title with an accent rule underneath
+func (l *pdfLayout) heading(s string, accent pdfColor) {
+	l.ensure(40) // keep headings with at least one line of content
+	l.space(10)
+	l.paragraph(s, pdfFontBold, 13, accent, 0)
+	l.space(3)
+	l.doc.line(l.page, l.marginLeft, l.y, pdfPageWidth-l.marginRight, l.y, 0.8, accent)
+	l.space(4)
+}
+
+// field writes "label: value" with the label in bold
+func (l *pdfLayout) field(label, value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	const size = 10
+	labelWidth := pdfTextWidth(label+": ", pdfFontBold, size)
+	lines := pdfWrap(value, pdfFontRegular, size, l.width()-labelWidth)
+	leading := size * 1.35
+	for i, line := range lines {
+		l.ensure(leading)
+		l.y -= leading
+		if i == 0 {
+			l.doc.text(l.page, l.marginLeft, l.y+size*0.3, pdfFontBold, size, pdfDarkGray, label+":")
+		}
+		l.doc.text(l.page, l.marginLeft+labelWidth, l.y+size*0.3, pdfFontRegular, size, pdfBlack, line)
+	}
+}
+
+// bullet writes a bulleted, wrapped item
+func (l *pdfLayout) bullet(s string, color pdfColor) {
+	const size = 10
+	leading := size * 1.35
+	for i, line := range pdfWrap(s, pdfFontRegular, size, l.width()-14) {
+		l.ensure(leading)
+		l.y -= leading
+		if i == 0 {
+			l.doc.text(l.page, l.marginLeft+2, l.y+size*0.3, pdfFontRegular, size, color, "•")
+		}
+		l.doc.text(l.page, l.marginLeft+14, l.y+size*0.3, pdfFontRegular, size, color, line)
+	}
+}
+
+// decorate draws a header and footer on every page. The callback returns the
+// left and right header and footer texts for a page (1-based) of total.
+func (l *pdfLayout) decorate(accent pdfColor, texts func(page, total int) (headerLeft, headerRight, footerLeft, footerRight string)) {
+	total := len(l.doc.pages)
+	right := pdfPageWidth - l.marginRight
+	for i := range l.doc.pages {
+		headerLeft, headerRight, footerLeft, footerRight := texts(i+1, total)
+
+		headerY := pdfPageHeight - 40
+		l.doc.text(i, l.marginLeft, headerY, pdfFontBold, 9, accent, headerLeft)
+		l.doc.text(i, right-pdfTextWidth(headerRight, pdfFontRegular, 9), headerY, pdfFontRegular, 9, pdfGray, headerRight)
+		l.doc.line(i, l.marginLeft, headerY-6, right, headerY-6, 0.5, pdfLightGray)
+
+		footerY := 35.0
+		l.doc.line(i, l.marginLeft, footerY+12, right, footerY+12, 0.5, pdfLightGray)
+		l.doc.text(i, l.marginLeft, footerY, pdfFontRegular, 8, pdfGray, footerLeft)
+		l.doc.text(i, right-pdfTextWidth(footerRight, pdfFontRegular, 8), footerY, pdfFontRegular, 8, pdfGray, footerRight)
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"f(x) = a\\b", `f\(x\) = a\\b`},
+		{"tab\tstop", "tab stop"},
+		{"line\nbreak", "linebreak"},
+		{"café", "caf\xe9"},
+		{"€5 – “quoted” …", "\x805 \x96 \x93quoted\x94 \x85"},
+		{"血液", "??"},
+	}
+	for _, tt := range tests {
+		if got := pdfEscape(tt.in); got != tt.want {
+			t.Errorf("pdfEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	fallback := pdfColor{0.1, 0.2, 0.3}
+	tests := []struct {
+		in   string
+		want pdfColor
+	}{
+		{"#ff0000", pdfColor{1, 0, 0}},
+		{" 00ff00 ", pdfColor{0, 1, 0}},
+		{"#0000FF", pdfColor{0, 0, 1}},
+		{"#fff", fallback},
+		{"#gg0000", fallback},
+		{"", fallback},
+	}
+	for _, tt := range tests {
+		if got := parseHexColor(tt.in, fallback); got != tt.want {
+			t.Errorf("parseHexColor(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFTextWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		font pdfFont
+		want float64
+	}{
+		{"", pdfFontRegular, 0},
+		{"i", pdfFontRegular, 2.22},
+		{"W", pdfFontRegular, 9.44},
+		{"ii", pdfFontRegular, 4.44},
+		{"é", pdfFontRegular, 5.56}, // Outside ASCII uses the average width
+	}
+	for _, tt := range tests {
+		if got := pdfTextWidth(tt.s, tt.font, 10); got < tt.want-0.001 || got > tt.want+0.001 {
+			t.Errorf("pdfTextWidth(%q) = %.3f, want %.3f", tt.s, got, tt.want)
+		}
+	}
+	if pdfTextWidth("Report", pdfFontBold, 10) <= pdfTextWidth("Report", pdfFontRegular, 10) {
+		t.Error("bold text is not wider than regular text")
+	}
+}
+
+func TestPDFWrap(t *testing.T) {
+	const size, maxWidth = 10.0, 100.0
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"fits", "Short line", []string{"Short line"}},
+		{"wraps at words", "Haemoglobin is below the reference range", []string{"Haemoglobin is below", "the reference range"}},
+		{"keeps paragraphs", "First\n\nThird", []string{"First", "", "Third"}},
+		{"collapses spaces", "a   b", []string{"a b"}},
+		{"empty", "", []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pdfWrap(tt.in, pdfFontRegular, size, maxWidth)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("pdfWrap(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("breaks long words", func(t *testing.T) {
+		word := strings.Repeat("x", 60)
+		lines := pdfWrap("see "+word, pdfFontRegular, size, maxWidth)
+		if len(lines) < 3 || lines[0] != "see" {
+			t.Fatalf("pdfWrap() = %q", lines)
+		}
+		if joined := strings.Join(lines[1:], ""); joined != word {
+			t.Errorf("word pieces join to %q", joined)
+		}
+		for _, line := range lines {
+			if pdfTextWidth(line, pdfFontRegular, size) > maxWidth {
+				t.Errorf("line %q is wider than %.0f", line, maxWidth)
+			}
+		}
+	})
+}
+
+func TestPDFDocumentBytes(t *testing.T) {
+	l := newPDFLayout("Summary (draft)")
+	for i := 0; i < 80; i++ {
+		l.paragraph(fmt.Sprintf("Paragraph %d", i), pdfFontRegular, 10, pdfBlack, 0)
+	}
+	l.decorate(pdfBlack, func(page, total int) (string, string, string, string) {
+		return "Header", "", "Footer", fmt.Sprintf("Page %d of %d", page, total)
+	})
+	if len(l.doc.pages) < 2 {
+		t.Fatalf("80 paragraphs fit on %d page(s), want them to flow onto a second page", len(l.doc.pages))
+	}
+
+	out, err := l.doc.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or EOF marker")
+	}
+	if !bytes.Contains(out, []byte("/Title (Summary \\(draft\\))")) {
+		t.Error("title not escaped in the info dictionary")
+	}
+	if !bytes.Contains(out, []byte(fmt.Sprintf("/Count %d", len(l.doc.pages)))) {
+		t.Error("page tree count does not match the pages")
+	}
+
+	// Every xref entry points at its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if startxref == nil {
+		t.Fatal("startxref missing")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if want := 5 + 2*len(l.doc.pages); len(entries) != want {
+		t.Fatalf("xref has %d entries, want %d", len(entries), want)
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+
+	// Content streams inflate to the drawn text
+	streams := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(out, -1)
+	if len(streams) != len(l.doc.pages) {
+		t.Fatalf("%d content streams for %d pages", len(streams), len(l.doc.pages))
+	}
+	r, err := zlib.NewReader(bytes.NewReader(streams[len(streams)-1][1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := fmt.Sprintf("(Page %d of %d) Tj", len(l.doc.pages), len(l.doc.pages))
+	if !bytes.Contains(content, []byte("(Paragraph 79) Tj")) || !bytes.Contains(content, []byte(last)) {
+		t.Errorf("last page content is missing the final paragraph or footer:\n%s", content)
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PHI categories found in free text
+const (
+	PHIName    = "NAME"
+	PHIPhone   = "PHONE"
+	PHIEmail   = "EMAIL"
+	PHIAddress = "ADDRESS"
+	PHIMRN     = "MRN"
+	PHIDate    = "DATE"
+	PHIURL     = "URL"
+	PHIIP      = "IP"
+	PHISSN     = "SSN"
+)
+
+// PHIMatch is a span of text identified as protected health information
+type PHIMatch struct {
+	Category string `json:"category"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Text     string `json:"-"`
+}
+
+const monthPattern = `(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Sept|Oct|Nov|Dec)[a-z]*\.?`
+
+// phiPatterns are the rule-based detectors. When a pattern has a capture group
+// only the group is reported, so labels such as "MRN:" are kept.
+var phiPatterns = []struct {
+	category string
+	re       *regexp.Regexp
+}{
+	{PHIEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{PHIURL, regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)},
+	{PHIIP, regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+	{PHISSN, regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{PHIMRN, regexp.MustCompile(`(?i)\b(?:MRN|MR\s*No\.?|Medical\s+Record(?:\s+(?:Number|No\.?))?|Patient\s+ID|UHID|Reg(?:istration)?\.?\s*No\.?|Hospital\s+No\.?)\s*[:#]?\s*([A-Z0-9][A-Z0-9/-]{3,})\b`)},
+	{PHIPhone, regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b|\+\d{1,3}[\s-]?\d{5}[\s-]?\d{5}\b|\b[6-9]\d{9}\b|\b[6-9]\d{4}[\s-]\d{5}\b`)},
+	// Dotted dates need a four-digit year so values such as "2.5.10" are not dates
+	{PHIDate, regexp.MustCompile(`(?i)\b\d{1,2}/\d{1,2}/(?:\d{4}|\d{2})\b|\b\d{1,2}-\d{1,2}-(?:\d{4}|\d{2})\b|\b\d{1,2}\.\d{1,2}\.\d{4}\b|\b\d{4}-\d{2}-\d{2}\b|\b\d{1,2}(?:st|nd|rd|th)?\s+` + monthPattern + `,?\s+\d{2,4}\b|\b` + monthPattern + `\s+\d{1,2}(?:st|nd|rd|th)?,?\s+\d{4}\b`)},
+	{PHIAddress, regexp.MustCompile(`\b\d{1,5}(?:\s+[A-Z][A-Za-z]+){1,4}\s+(?:Street|St|Road|Rd|Avenue|Ave|Lane|Ln|Drive|Dr|Boulevard|Blvd|Nagar|Marg|Colony|Sector)\b\.?`)},
+	{PHIAddress, regexp.MustCompile(`(?i)\b(?:zip|pin|pincode|postal\s+code)\s*[:#]?\s*(\d{5,6}(?:-\d{4})?)\b`)},
+	{PHIName, regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss|Dr|Prof)\.?\s+[A-Z][A-Za-z'-]+(?:\s+[A-Z][A-Za-z'-]+){0,2}`)},
+	{PHIName, regexp.MustCompile(`(?i:patient(?:'s)?\s+name|name\s+of\s+patient)\s*:\s*([A-Z][A-Za-z'-]+(?:[ \t]+[A-Z][A-Za-z'-]+){0,3})`)},
+	{PHIName, regexp.MustCompile(`(?im:^[ \t]*name)[ \t]*:[ \t]*([A-Z][A-Za-z'-]+(?:[ \t]+[A-Z][A-Za-z'-]+){0,3})`)},
+}
+
+// DetectPHI finds PHI in text using the rule-based patterns and the given known
+// names (e.g. the patient's and doctors' names). Matches do not overlap and are
+// ordered by position.
+func DetectPHI(text string, knownNames []string) []PHIMatch {
+	var matches []PHIMatch
+	for _, p := range phiPatterns {
+		for _, loc := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			matches = append(matches, PHIMatch{Category: p.category, Start: start, End: end})
+		}
+	}
+	for _, name := range knownNamePatterns(knownNames) {
+		for _, loc := range name.FindAllStringIndex(text, -1) {
+			matches = append(matches, PHIMatch{Category: PHIName, Start: loc[0], End: loc[1]})
+		}
+	}
+
+	// Prefer earlier, then longer matches, dropping overlaps
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+	var result []PHIMatch
+	lastEnd := -1
+	for _, m := range matches {
+		if m.Start < lastEnd || m.End <= m.Start {
+			continue
+		}
+		m.Text = text[m.Start:m.End]
+		result = append(result, m)
+		lastEnd = m.End
+	}
+	return result
+}
+
+// knownNamePatterns builds word-boundary patterns for full names and their
+// individual parts of at least three letters. Full names match in any case;
+// a part only matches capitalised, so "Grace Stone" does not turn "kidney
+// stone" into a name.
+func knownNamePatterns(names []string) []*regexp.Regexp {
+	type term struct {
+		text string
+		part bool
+	}
+	seen := map[string]bool{}
+	var terms []term
+	add := func(text string, part bool) {
+		text = strings.Trim(text, ".,")
+		key := strings.ToLower(text)
+		if len([]rune(text)) < 3 || seen[key] {
+			return
+		}
+		seen[key] = true
+		terms = append(terms, term{text: text, part: part})
+	}
+	for _, name := range names {
+		fields := strings.Fields(name)
+		if len(fields) > 1 {
+			add(strings.Join(fields, " "), false)
+		}
+		for _, field := range fields {
+			add(field, true)
+		}
+	}
+	// Longest first so full names win over their parts
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i].text) > len(terms[j].text) })
+
+	var patterns []*regexp.Regexp
+	for _, t := range terms {
+		if !t.part {
+			words := strings.Fields(t.text)
+			for i := range words {
+				words[i] = regexp.QuoteMeta(words[i])
+			}
+			patterns = append(patterns, regexp.MustCompile(`(?i)\b`+strings.Join(words, `\s+`)+`\b`))
+			continue
+		}
+		first, size := utf8.DecodeRuneInString(t.text)
+		patterns = append(patterns, regexp.MustCompile(`\b`+regexp.QuoteMeta(string(unicode.ToUpper(first)))+`(?i:`+regexp.QuoteMeta(t.text[size:])+`)\b`))
+	}
+	return patterns
+}
+
+// ScrubPHI replaces detected PHI with a bracketed category, e.g. "[PHONE]"
+func ScrubPHI(text string, knownNames []string) string {
+	matches := DetectPHI(text, knownNames)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString("[" + m.Category + "]")
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
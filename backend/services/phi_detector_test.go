@@ -0,0 +1,74 @@
+package services
+
+import "testing"
+
+func TestScrubPHI(t *testing.T) {
+	known := []string{"Grace Stone", "Hari Arm"}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"full name", "Seen with Grace Stone today.", "Seen with [NAME] today."},
+		{"full name lowercase", "seen with grace stone today", "seen with [NAME] today"},
+		{"capitalised part", "Ms Stone reports pain.", "[NAME] reports pain."},
+		{"surname alone", "Stone was reviewed.", "[NAME] was reviewed."},
+		{"clinical term", "Ultrasound shows a kidney stone.", "Ultrasound shows a kidney stone."},
+		{"body part", "Pain in the left arm.", "Pain in the left arm."},
+		{"titled name", "Referred by Dr. Mehta.", "Referred by [NAME]."},
+		{"labelled name", "Patient Name: Asha Rao\nAge: 54", "Patient Name: [NAME]\nAge: 54"},
+		{"phone", "Call 555-123-4567 for results.", "Call [PHONE] for results."},
+		{"indian mobile", "Mobile 9876543210", "Mobile [PHONE]"},
+		{"email", "Mail asha@example.com", "Mail [EMAIL]"},
+		{"mrn keeps label", "MRN: AB12345", "MRN: [MRN]"},
+		{"slash date", "Collected 12/03/2024.", "Collected [DATE]."},
+		{"dotted date", "Collected 12.03.2024.", "Collected [DATE]."},
+		{"iso date", "Collected 2024-03-12.", "Collected [DATE]."},
+		{"written date", "Seen on 5th March 2024.", "Seen on [DATE]."},
+		{"lab values", "Platelets 2.5.10 lakh, Hb 11.2 g/dL", "Platelets 2.5.10 lakh, Hb 11.2 g/dL"},
+		{"ratio", "BP 120/80 mmHg", "BP 120/80 mmHg"},
+		{"ssn", "SSN 123-45-6789", "SSN [SSN]"},
+		{"ip", "from 192.168.1.20", "from [IP]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScrubPHI(tt.text, known); got != tt.want {
+				t.Errorf("ScrubPHI(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPHIRedactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		redacted string
+		restored string
+	}{
+		{"same value shares a placeholder", "Grace Stone called. Grace Stone will return.", "[NAME_1] called. [NAME_1] will return.", "Grace Stone called. Grace Stone will return."},
+		{"phone formats share a placeholder", "Call 555-123-4567 or (555) 123-4567", "Call [PHONE_1] or [PHONE_1]", "Call 555-123-4567 or 555-123-4567"},
+		{"clinical terms kept", "Grace Stone: kidney stone, arm pain", "[NAME_1]: kidney stone, arm pain", "Grace Stone: kidney stone, arm pain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewPHIRedactor([]string{"Grace Stone", "Hari Arm"})
+			redacted := r.Redact(tt.text)
+			if redacted != tt.redacted {
+				t.Fatalf("Redact(%q) = %q, want %q", tt.text, redacted, tt.redacted)
+			}
+			if restored := r.Reidentify(redacted); restored != tt.restored {
+				t.Errorf("Reidentify(%q) = %q, want %q", redacted, restored, tt.restored)
+			}
+		})
+	}
+
+	t.Run("unknown placeholders kept", func(t *testing.T) {
+		r := NewPHIRedactor(nil)
+		if got := r.Reidentify("Ask [NAME_7] about it"); got != "Ask [NAME_7] about it" {
+			t.Errorf("Reidentify() = %q", got)
+		}
+	})
+}
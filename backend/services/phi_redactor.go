@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// placeholderPattern matches placeholders produced by PHIRedactor
+var placeholderPattern = regexp.MustCompile(`\[(NAME|PHONE|EMAIL|ADDRESS|MRN|DATE|URL|IP|SSN)_(\d+)\]`)
+
+// PHIRedactionEnabled reports whether text is redacted before outbound calls.
+// Redaction is on unless PHI_REDACTION=off.
+func PHIRedactionEnabled() bool {
+	return !strings.EqualFold(os.Getenv("PHI_REDACTION"), "off")
+}
+
+// PHIRedactor replaces PHI with stable placeholders such as "[NAME_1]" before
+// text leaves the backend and restores them in the response. Use one redactor
+// per outbound request so a value maps to the same placeholder everywhere in it.
+type PHIRedactor struct {
+	id           primitive.ObjectID // Audit record ID, shared by every Report call
+	knownNames   []string
+	byValue      map[string]string // normalized original -> placeholder
+	originals    map[string]string // placeholder -> original
+	categories   map[string]string // placeholder -> category
+	occurrences  map[string]int
+	order        []string
+	counters     map[string]int
+	charsSent    int
+	reidentified int
+}
+
+func NewPHIRedactor(knownNames []string) *PHIRedactor {
+	return &PHIRedactor{
+		id:          primitive.NewObjectID(),
+		knownNames:  knownNames,
+		byValue:     map[string]string{},
+		originals:   map[string]string{},
+		categories:  map[string]string{},
+		occurrences: map[string]int{},
+		counters:    map[string]int{},
+	}
+}
+
+// normalizePHIValue makes formatting variants of a value share a placeholder
+func normalizePHIValue(category, value string) string {
+	switch category {
+	case PHIPhone, PHIMRN, PHISSN:
+		var b strings.Builder
+		for _, r := range strings.ToLower(value) {
+			if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') {
+				b.WriteRune(r)
+			}
+		}
+		return category + ":" + b.String()
+	default:
+		return category + ":" + strings.ToLower(strings.Join(strings.Fields(value), " "))
+	}
+}
+
+// Redact replaces PHI in text with placeholders
+func (r *PHIRedactor) Redact(text string) string {
+	matches := DetectPHI(text, r.knownNames)
+	if len(matches) == 0 {
+		r.charsSent += len(text)
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		key := normalizePHIValue(m.Category, m.Text)
+		placeholder, ok := r.byValue[key]
+		if !ok {
+			r.counters[m.Category]++
+			placeholder = fmt.Sprintf("[%s_%d]", m.Category, r.counters[m.Category])
+			r.byValue[key] = placeholder
+			r.originals[placeholder] = m.Text
+			r.categories[placeholder] = m.Category
+			r.order = append(r.order, placeholder)
+		}
+		r.occurrences[placeholder]++
+		b.WriteString(text[last:m.Start])
+		b.WriteString(placeholder)
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	r.charsSent += b.Len()
+	return b.String()
+}
+
+// RedactAll redacts each string of a slice in place
+func (r *PHIRedactor) RedactAll(values []string) {
+	for i := range values {
+		values[i] = r.Redact(values[i])
+	}
+}
+
+// Reidentify restores the original values of placeholders in a response.
+// Placeholders this redactor did not issue are left as they are.
+func (r *PHIRedactor) Reidentify(text string) string {
+	if len(r.originals) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if original, ok := r.originals[placeholder]; ok {
+			r.reidentified++
+			return original
+		}
+		return placeholder
+	})
+}
+
+// ReidentifyAll re-identifies each string of a slice in place
+func (r *PHIRedactor) ReidentifyAll(values []string) {
+	for i := range values {
+		values[i] = r.Reidentify(values[i])
+	}
+}
+
+// ReidentifyAnalysis restores placeholders in every text field of an analysis
+func (r *PHIRedactor) ReidentifyAnalysis(analysis *models.AIAnalysis) {
+	entities := &analysis.Entities
+	for _, values := range [][]string{
+		entities.Symptoms, entities.Diagnoses, entities.Medications, entities.Tests,
+		entities.Vitals, entities.Severity, entities.Urgency, entities.FunctionalImpact,
+		analysis.Warnings,
+	} {
+		r.ReidentifyAll(values)
+	}
+	for i := range analysis.Recommendations {
+		rec := &analysis.Recommendations[i]
+		rec.Test = r.Reidentify(rec.Test)
+		rec.Reason = r.Reidentify(rec.Reason)
+		rec.Explanation = r.Reidentify(rec.Explanation)
+		r.ReidentifyAll(rec.Contraindications)
+	}
+}
+
+// Report summarizes the redaction for audit without the original values. It is
+// called before the request to build its headers and again after the response,
+// when re-identification counts are known.
+func (r *PHIRedactor) Report(destination, purpose string) *models.RedactionReport {
+	report := &models.RedactionReport{
+		ID:           r.id,
+		Destination:  destination,
+		Purpose:      purpose,
+		Counts:       map[string]int{},
+		Placeholders: []models.RedactedPlaceholder{},
+		CharsSent:    r.charsSent,
+		Reidentified: r.reidentified,
+		CreatedAt:    time.Now(),
+	}
+	for _, placeholder := range r.order {
+		category := r.categories[placeholder]
+		report.Counts[category] += r.occurrences[placeholder]
+		report.Total += r.occurrences[placeholder]
+		report.Placeholders = append(report.Placeholders, models.RedactedPlaceholder{
+			Placeholder: placeholder,
+			Category:    category,
+			Occurrences: r.occurrences[placeholder],
+		})
+	}
+	return report
+}
+
+// SetRedactionHeaders attaches the redaction report to an outbound request so
+// the receiving service can log it with its own audit trail
+func SetRedactionHeaders(req *http.Request, report *models.RedactionReport) {
+	categories := make([]string, 0, len(report.Counts))
+	for category := range report.Counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	counts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		counts = append(counts, fmt.Sprintf("%s=%d", category, report.Counts[category]))
+	}
+	req.Header.Set("X-Redaction-Id", report.ID.Hex())
+	req.Header.Set("X-Redaction-Report", strings.Join(counts, ";"))
+}
+
+// SaveRedactionReport stores a redaction report in the audit log
+func SaveRedactionReport(report *models.RedactionReport) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("redaction_audit").InsertOne(ctx, report); err != nil {
+		log.Printf("redaction %s: failed to save audit record: %v", report.ID.Hex(), err)
+	}
+}
+
+// PHIKnownNames returns the patient's name for name detection. The zero ID or
+// an unknown patient yields no names.
+func PHIKnownNames(patientID primitive.ObjectID) []string {
+	if patientID.IsZero() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var patient models.Patient
+	if err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": patientID}).Decode(&patient); err != nil {
+		return nil
+	}
+	return []string{patient.Name}
+}
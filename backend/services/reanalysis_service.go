@@ -0,0 +1,503 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultReanalysisThrottle is the pause between reports when none is given
+const DefaultReanalysisThrottle = 500 * time.Millisecond
+
+// MaxReanalysisThrottle caps the pause between reports, well below the lease
+const MaxReanalysisThrottle = time.Minute
+
+// reviewedStatuses are report states that contain doctor-reviewed content
+var reviewedStatuses = map[string]bool{
+	"reviewed": true,
+	"edited":   true,
+	"signed":   true,
+	"amended":  true,
+}
+
+// ReanalysisService runs batch re-analysis jobs against the Python analyzer
+type ReanalysisService struct{}
+
+// ReanalysisLease is how long a job may go without a heartbeat before it is
+// treated as abandoned, e.g. after a server restart
+const ReanalysisLease = 10 * time.Minute
+
+// activeReanalysisStatuses are job states that hold the single job slot
+var activeReanalysisStatuses = []string{"queued", "running", "cancelling"}
+
+// Only one re-analysis job runs at a time to protect the Python service.
+// The slot is held by a job whose heartbeat is within the lease.
+var reanalysisMu sync.Mutex
+
+// StartJob persists a new job and runs it in the background
+func (s *ReanalysisService) StartJob(job *models.ReanalysisJob) error {
+	reanalysisMu.Lock()
+	defer reanalysisMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := config.GetCollection("reanalysis_jobs")
+	if err := expireStaleReanalysisJobs(ctx); err != nil {
+		return fmt.Errorf("failed to check running jobs: %w", err)
+	}
+	active, err := collection.CountDocuments(ctx, bson.M{"status": bson.M{"$in": activeReanalysisStatuses}})
+	if err != nil {
+		return fmt.Errorf("failed to check running jobs: %w", err)
+	}
+	if active > 0 {
+		return fmt.Errorf("a re-analysis job is already running")
+	}
+
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.Status = "queued"
+	job.CreatedAt = now
+	job.HeartbeatAt = &now
+	if job.ThrottleMs <= 0 {
+		job.ThrottleMs = int(DefaultReanalysisThrottle / time.Millisecond)
+	}
+	if job.ThrottleMs > int(MaxReanalysisThrottle/time.Millisecond) {
+		job.ThrottleMs = int(MaxReanalysisThrottle / time.Millisecond)
+	}
+
+	if _, err := collection.InsertOne(ctx, job); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	go s.run(*job)
+	return nil
+}
+
+// RecoverReanalysisJobs fails jobs left active by a previous server run
+func RecoverReanalysisJobs() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return expireStaleReanalysisJobs(ctx)
+}
+
+// expireStaleReanalysisJobs fails active jobs whose heartbeat is older than
+// the lease, freeing the job slot
+func expireStaleReanalysisJobs(ctx context.Context) error {
+	now := time.Now()
+	_, err := config.GetCollection("reanalysis_jobs").UpdateMany(ctx,
+		bson.M{
+			"status": bson.M{"$in": activeReanalysisStatuses},
+			"$or": []bson.M{
+				{"heartbeat_at": bson.M{"$lt": now.Add(-ReanalysisLease)}},
+				{"heartbeat_at": bson.M{"$exists": false}},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"status":       "failed",
+			"error":        "job stopped without finishing (server restarted?)",
+			"completed_at": now,
+		}},
+	)
+	return err
+}
+
+// CancelJob asks a running job to stop after the current report
+func (s *ReanalysisService) CancelJob(jobID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("reanalysis_jobs").UpdateOne(ctx,
+		bson.M{"_id": jobID, "status": bson.M{"$in": []string{"queued", "running"}}},
+		bson.M{"$set": bson.M{"status": "cancelling"}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// run processes every selected report, recording progress on the job document.
+// It stops as soon as the job is no longer active, e.g. cancelled or expired.
+func (s *ReanalysisService) run(job models.ReanalysisJob) {
+	reportIDs, err := s.selectReports(&job)
+	if err != nil {
+		s.finish(job.ID, "failed", err.Error())
+		return
+	}
+
+	// A cancel may have landed while the job was queued
+	startedAt := time.Now()
+	if !s.transition(job.ID, "queued", bson.M{
+		"status":       "running",
+		"total":        len(reportIDs),
+		"started_at":   startedAt,
+		"heartbeat_at": startedAt,
+	}) {
+		s.stop(job.ID)
+		return
+	}
+
+	throttle := time.Duration(job.ThrottleMs) * time.Millisecond
+	for i, reportID := range reportIDs {
+		if s.jobStatus(job.ID) != "running" {
+			s.stop(job.ID)
+			return
+		}
+
+		result := s.processReport(&job, reportID)
+		s.saveResult(result)
+
+		inc := bson.M{"processed": 1}
+		switch {
+		case result.Error != "":
+			inc["failed"] = 1
+		case result.SkipReason != "":
+			inc["skipped"] = 1
+		}
+		if result.Diff != nil && (result.Diff.EntitiesChanged || result.Diff.RecommendationsChanged) {
+			inc["changed"] = 1
+		}
+		if result.Applied {
+			inc["applied"] = 1
+		}
+		s.updateJob(job.ID, bson.M{"$inc": inc, "$set": bson.M{"heartbeat_at": time.Now()}})
+
+		if i < len(reportIDs)-1 {
+			s.pause(job.ID, throttle)
+		}
+	}
+
+	s.transition(job.ID, "running", bson.M{"status": "completed", "completed_at": time.Now()})
+}
+
+// selectReports returns the IDs of reports matching the job filters
+func (s *ReanalysisService) selectReports(job *models.ReanalysisJob) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if len(job.ReportIDs) > 0 {
+		filter["_id"] = bson.M{"$in": job.ReportIDs}
+	}
+	if len(job.Statuses) > 0 {
+		filter["status"] = bson.M{"$in": job.Statuses}
+	}
+	if job.ExcludeVersion != "" {
+		filter["ai_analysis.analyzer.version"] = bson.M{"$ne": job.ExcludeVersion}
+	}
+
+	cursor, err := config.GetCollection("reports").Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err == nil {
+			ids = append(ids, doc.ID)
+		}
+	}
+	return ids, cursor.Err()
+}
+
+// processReport re-analyses one report and applies the result when allowed
+func (s *ReanalysisService) processReport(job *models.ReanalysisJob, reportID primitive.ObjectID) models.ReanalysisResult {
+	result := models.ReanalysisResult{
+		ID:          primitive.NewObjectID(),
+		JobID:       job.ID,
+		ReportID:    reportID,
+		ProcessedAt: time.Now(),
+	}
+
+	report, err := GetReportByID(reportID.Hex())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if report.AIAnalysis.Analyzer != nil {
+		result.PreviousVersion = report.AIAnalysis.Analyzer.Version
+	}
+
+	// HL7 and most FHIR reports were built from structured data
+	if report.PDFPath == "" {
+		result.SkipReason = "report has no PDF to re-analyse"
+		return result
+	}
+	if _, err := os.Stat(report.PDFPath); err != nil {
+		result.Error = "original PDF is no longer available"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	consented, err := HasConsent(ctx, report.PatientID, ConsentAIAnalysis)
+	cancel()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !consented {
+		result.SkipReason = "patient has not consented to AI analysis"
+		return result
+	}
+
+	analysis, err := AnalyzePDFReport(report.PDFPath, report.PatientID, report.UploadedAt)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if analysis.Analyzer != nil {
+		result.NewVersion = analysis.Analyzer.Version
+	}
+
+	carryInteractionReviews(&report.AIAnalysis, analysis)
+	diff := DiffAnalyses(&report.AIAnalysis, analysis)
+	result.Diff = &diff
+
+	// Decide whether the new analysis may be written
+	switch {
+	case job.DryRun:
+		result.SkipReason = "dry run"
+	case report.Signature != nil:
+		result.SkipReason = "report is signed"
+	case isDoctorReviewed(report) && !job.IncludeReviewed:
+		result.SkipReason = "report has doctor-reviewed content"
+	}
+	if result.SkipReason != "" {
+		return result
+	}
+
+	if err := s.applyAnalysis(job.ID, report, analysis); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Applied = true
+
+	updated := *report
+	updated.AIAnalysis = *analysis
+	RaiseCriticalAlert(&updated)
+	SyncCareTasks(&updated)
+	PublishWebhookEvent(WebhookAnalysisCompleted, ReportWebhookData(&updated))
+	return result
+}
+
+// applyAnalysis stores the new analysis and archives the previous one once
+// the update has gone through. The doctor review, if any, is kept as-is.
+func (s *ReanalysisService) applyAnalysis(jobID primitive.ObjectID, report *models.Report, analysis *models.AIAnalysis) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Guard against a signature or edit landing while we were analysing
+	filter := bson.M{
+		"_id":        report.ID,
+		"updated_at": report.UpdatedAt,
+		"signature":  bson.M{"$exists": false},
+	}
+	replacedAt := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"ai_analysis": analysis,
+			"updated_at":  replacedAt,
+		},
+		"$unset": bson.M{"analysis_skipped": ""},
+	}
+
+	result, err := config.GetCollection("reports").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update report: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("report changed during re-analysis")
+	}
+
+	history := models.AnalysisHistoryEntry{
+		ID:         primitive.NewObjectID(),
+		ReportID:   report.ID,
+		JobID:      jobID,
+		Analysis:   report.AIAnalysis,
+		ReplacedAt: replacedAt,
+	}
+	if _, err := config.GetCollection("analysis_history").InsertOne(ctx, history); err != nil {
+		log.Printf("failed to archive previous analysis of report %s: %v", report.ID.Hex(), err)
+	}
+	return nil
+}
+
+func (s *ReanalysisService) saveResult(result models.ReanalysisResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("reanalysis_results").InsertOne(ctx, result); err != nil {
+		log.Println("failed to save re-analysis result:", err)
+	}
+}
+
+func (s *ReanalysisService) updateJob(jobID primitive.ObjectID, update bson.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("reanalysis_jobs").UpdateOne(ctx, bson.M{"_id": jobID}, update); err != nil {
+		log.Println("failed to update re-analysis job:", err)
+	}
+}
+
+// finish ends a job that has not already stopped
+func (s *ReanalysisService) finish(jobID primitive.ObjectID, status, errMsg string) {
+	set := bson.M{
+		"status":       status,
+		"completed_at": time.Now(),
+	}
+	if errMsg != "" {
+		set["error"] = errMsg
+	}
+	s.updateJobIf(jobID, bson.M{"status": bson.M{"$in": activeReanalysisStatuses}}, bson.M{"$set": set})
+}
+
+// transition moves a job on from the given status, reporting whether it was
+// still in that status
+func (s *ReanalysisService) transition(jobID primitive.ObjectID, from string, set bson.M) bool {
+	return s.updateJobIf(jobID, bson.M{"status": from}, bson.M{"$set": set})
+}
+
+// stop ends a job that is no longer running: a requested cancel completes,
+// any other state (e.g. expired by the lease) is left alone
+func (s *ReanalysisService) stop(jobID primitive.ObjectID) {
+	s.transition(jobID, "cancelling", bson.M{"status": "cancelled", "completed_at": time.Now()})
+}
+
+// pause waits between reports, keeping the heartbeat fresh
+func (s *ReanalysisService) pause(jobID primitive.ObjectID, d time.Duration) {
+	for d > 0 {
+		step := d
+		if step > ReanalysisLease/10 {
+			step = ReanalysisLease / 10
+		}
+		time.Sleep(step)
+		d -= step
+		s.updateJob(jobID, bson.M{"$set": bson.M{"heartbeat_at": time.Now()}})
+	}
+}
+
+func (s *ReanalysisService) updateJobIf(jobID primitive.ObjectID, filter, update bson.M) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter["_id"] = jobID
+	result, err := config.GetCollection("reanalysis_jobs").UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.Println("failed to update re-analysis job:", err)
+		return false
+	}
+	return result.MatchedCount > 0
+}
+
+// jobStatus returns the job's current status, or "" when it cannot be read
+func (s *ReanalysisService) jobStatus(jobID primitive.ObjectID) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.ReanalysisJob
+	if err := config.GetCollection("reanalysis_jobs").FindOne(ctx, bson.M{"_id": jobID}).Decode(&job); err != nil {
+		return ""
+	}
+	return job.Status
+}
+
+// carryInteractionReviews copies doctors' decisions on interaction findings
+// to the same findings in a new analysis
+func carryInteractionReviews(previous, current *models.AIAnalysis) {
+	reviewed := map[string]models.InteractionFinding{}
+	for _, f := range previous.Interactions {
+		if f.Status != "" && f.Status != InteractionOpen {
+			reviewed[f.ID] = f
+		}
+	}
+	for i := range current.Interactions {
+		if old, ok := reviewed[current.Interactions[i].ID]; ok {
+			current.Interactions[i].Status = old.Status
+			current.Interactions[i].ReviewedBy = old.ReviewedBy
+			current.Interactions[i].ReviewedAt = old.ReviewedAt
+			current.Interactions[i].Comment = old.Comment
+		}
+	}
+}
+
+// isDoctorReviewed reports whether a report contains doctor-reviewed content
+func isDoctorReviewed(report *models.Report) bool {
+	return report.DoctorReview != nil || reviewedStatuses[report.Status]
+}
+
+// DiffAnalyses compares entities and recommended tests between two analyses
+func DiffAnalyses(previous, current *models.AIAnalysis) models.AnalysisDiff {
+	diff := models.AnalysisDiff{
+		AddedEntities:   map[string][]string{},
+		RemovedEntities: map[string][]string{},
+	}
+
+	for _, category := range analyticsEntityTypes {
+		if category == "recommendations" {
+			continue
+		}
+		added, removed := diffStrings(entityCategory(previous, category), entityCategory(current, category))
+		if len(added) > 0 {
+			diff.AddedEntities[category] = added
+			diff.EntitiesChanged = true
+		}
+		if len(removed) > 0 {
+			diff.RemovedEntities[category] = removed
+			diff.EntitiesChanged = true
+		}
+	}
+
+	var prevTests, currTests []string
+	for _, rec := range previous.Recommendations {
+		prevTests = append(prevTests, rec.Test)
+	}
+	for _, rec := range current.Recommendations {
+		currTests = append(currTests, rec.Test)
+	}
+	diff.AddedRecommendations, diff.RemovedRecommendations = diffStrings(prevTests, currTests)
+	diff.RecommendationsChanged = len(diff.AddedRecommendations) > 0 || len(diff.RemovedRecommendations) > 0
+
+	return diff
+}
+
+// diffStrings returns normalized values added in and removed from current
+func diffStrings(previous, current []string) (added, removed []string) {
+	prevSet := map[string]bool{}
+	for _, v := range previous {
+		if n := normalizeEntity(v); n != "" {
+			prevSet[n] = true
+		}
+	}
+	currSet := map[string]bool{}
+	for _, v := range current {
+		if n := normalizeEntity(v); n != "" {
+			currSet[n] = true
+		}
+	}
+
+	for v := range currSet {
+		if !prevSet[v] {
+			added = append(added, v)
+		}
+	}
+	for v := range prevSet {
+		if !currSet[v] {
+			removed = append(removed, v)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCarryInteractionReviews(t *testing.T) {
+	doctor := primitive.NewObjectID()
+	reviewedAt := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+
+	previous := models.AIAnalysis{Interactions: []models.InteractionFinding{
+		{ID: "a", Status: "acknowledged", ReviewedBy: &doctor, ReviewedAt: &reviewedAt, Comment: "Monitoring INR"},
+		{ID: "b", Status: InteractionOpen},
+		{ID: "gone", Status: "dismissed", ReviewedBy: &doctor, ReviewedAt: &reviewedAt},
+	}}
+	current := models.AIAnalysis{Interactions: []models.InteractionFinding{
+		{ID: "a", Status: InteractionOpen},
+		{ID: "b", Status: InteractionOpen},
+		{ID: "new", Status: InteractionOpen},
+	}}
+
+	carryInteractionReviews(&previous, &current)
+
+	a := current.Interactions[0]
+	if a.Status != "acknowledged" || a.ReviewedBy == nil || *a.ReviewedBy != doctor || a.ReviewedAt == nil || a.Comment != "Monitoring INR" {
+		t.Errorf("reviewed finding not carried over: %+v", a)
+	}
+	for _, f := range current.Interactions[1:] {
+		if f.Status != InteractionOpen || f.ReviewedBy != nil {
+			t.Errorf("finding %s = %+v, want it left open", f.ID, f)
+		}
+	}
+	if len(current.Interactions) != 3 {
+		t.Errorf("findings = %+v, want the new analysis' findings only", current.Interactions)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -17,14 +18,64 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// AnalyzePDFReport sends a PDF to the Python FastAPI and returns structured analysis
-func AnalyzePDFReport(pdfPath string) (*models.AIAnalysis, error) {
-	// Python API endpoint
-	pythonAPIURL := os.Getenv("PYTHON_API_URL")
-	if pythonAPIURL == "" {
-		pythonAPIURL = "http://localhost:8000" // Default
+// analyzerResponse is the analysis returned by the Python service
+type analyzerResponse struct {
+	Entities        map[string][]string `json:"entities"`
+	Recommendations []struct {
+		Test              string   `json:"test"`
+		Reason            string   `json:"reason"`
+		Contraindications []string `json:"contraindications"`
+		Confidence        float64  `json:"confidence"`
+		Urgency           string   `json:"urgency"`
+		Explanation       string   `json:"explanation"`
+	} `json:"recommendations"`
+	Warnings []string `json:"warnings"`
+	Analyzer struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"analyzer"`
+}
+
+// NewUploadedReport builds the pending report for an uploaded PDF. A nil
+// analysis stores the report unanalysed for lack of AI analysis consent.
+func NewUploadedReport(patientID primitive.ObjectID, path, filename string, uploadedAt time.Time, uploadedBy *models.Actor, analysis *models.AIAnalysis) models.Report {
+	report := models.Report{
+		ID:          primitive.NewObjectID(),
+		PatientID:   patientID,
+		PDFPath:     path,
+		PDFFileName: filename,
+		UploadedAt:  uploadedAt,
+		Status:      "pending",
+		UploadedBy:  uploadedBy,
+		UpdatedAt:   time.Now(),
+	}
+	if analysis != nil {
+		report.AIAnalysis = *analysis
+	} else {
+		report.AnalysisSkipped = AnalysisSkippedNoConsent
 	}
+	return report
+}
+
+func pythonAPIURL() string {
+	if url := os.Getenv("PYTHON_API_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8000" // Default
+}
 
+// pdfExtractURL is the text extraction service. It receives the raw PDF, so it
+// must run inside the trust boundary; it defaults to the Python API.
+func pdfExtractURL() string {
+	if url := os.Getenv("PDF_EXTRACT_URL"); url != "" {
+		return url
+	}
+	return pythonAPIURL()
+}
+
+// postPDF uploads a PDF as multipart form data. The form file name is generic
+// so the stored path (which may contain the original file name) is not sent.
+func postPDF(url, pdfPath string, timeout time.Duration) (*http.Response, error) {
 	// Open the PDF file
 	file, err := os.Open(pdfPath)
 	if err != nil {
@@ -33,10 +84,9 @@ func AnalyzePDFReport(pdfPath string) (*models.AIAnalysis, error) {
 	defer file.Close()
 
 	// Create multipart form
-
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", pdfPath)
+	part, err := writer.CreateFormFile("file", "report.pdf")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create form file: %w", err)
 	}
@@ -47,46 +97,148 @@ func AnalyzePDFReport(pdfPath string) (*models.AIAnalysis, error) {
 	}
 	writer.Close()
 
-	// Make POST request to Python API
-	req, err := http.NewRequest("POST", pythonAPIURL+"/analyze_report", body)
+	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
+	client := &http.Client{Timeout: timeout}
+	return client.Do(req)
+}
+
+// extractPDFText asks the extraction service for the text of a PDF
+func extractPDFText(pdfPath string) (string, error) {
+	resp, err := postPDF(pdfExtractURL()+"/extract_text", pdfPath, 60*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("failed to call extraction service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("extraction error (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var extracted struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&extracted); err != nil {
+		return "", fmt.Errorf("failed to decode extraction response: %w", err)
+	}
+	return extracted.Text, nil
+}
+
+// AnalyzePDFReport sends a report to the Python FastAPI and returns structured
+// analysis. Unless PHI redaction is disabled, only the extracted text is sent
+// for analysis, with PHI replaced by placeholders that are restored in the
+// returned analysis; the redaction report is stored in the audit log.
+// uploadedAt is the report's date, which decides which reports are prior.
+func AnalyzePDFReport(pdfPath string, patientID primitive.ObjectID, uploadedAt time.Time) (*models.AIAnalysis, error) {
+	if !PHIRedactionEnabled() {
+		resp, err := postPDF(pythonAPIURL()+"/analyze_report", pdfPath, 60*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call Python API: %w", err)
+		}
+		defer resp.Body.Close()
+		analysis, err := decodeAnalyzerResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		// Assertions need the report text; without it every entity stays affirmed
+		if text, err := extractPDFText(pdfPath); err != nil {
+			log.Printf("entity assertions skipped: %v", err)
+		} else {
+			ApplyAssertions(analysis, text)
+		}
+		finishPDFAnalysis(analysis, patientID, uploadedAt)
+		return analysis, nil
+	}
+
+	text, err := extractPDFText(pdfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor := NewPHIRedactor(PHIKnownNames(patientID))
+	redacted := redactor.Redact(text)
+	auditReport := func() *models.RedactionReport {
+		report := redactor.Report("python-analyzer", "report-analysis")
+		if !patientID.IsZero() {
+			report.PatientID = &patientID
+		}
+		return report
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"text": redacted})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", pythonAPIURL()+"/analyze_text", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	SetRedactionHeaders(req, auditReport())
+
 	client := &http.Client{Timeout: 60 * time.Second}
 	resp, err := client.Do(req)
+	// The redacted text has left the backend once the request is attempted
+	defer func() { SaveRedactionReport(auditReport()) }()
 	if err != nil {
 		return nil, fmt.Errorf("failed to call Python API: %w", err)
 	}
 	defer resp.Body.Close()
 
+	analysis, err := decodeAnalyzerResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	redactor.ReidentifyAnalysis(analysis)
+	// Assert and code entities after re-identification so they match the
+	// original text
+	ApplyAssertions(analysis, text)
+	finishPDFAnalysis(analysis, patientID, uploadedAt)
+	return analysis, nil
+}
+
+// finishPDFAnalysis codes the analysis, applies the clinical rules and scores
+// it, in the same order as structured ingest. Interaction checks run after
+// scoring: interaction warnings are not extraction problems.
+func finishPDFAnalysis(analysis *models.AIAnalysis, patientID primitive.ObjectID, uploadedAt time.Time) {
+	ApplyTerminology(analysis)
+	ApplyClinicalRules(analysis, nil, patientID, uploadedAt)
+	ApplyConfidenceScore(analysis)
+	ApplyInteractionChecks(analysis, patientID)
+}
+
+// decodeAnalyzerResponse maps a Python API response to an AIAnalysis
+func decodeAnalyzerResponse(resp *http.Response) (*models.AIAnalysis, error) {
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("Python API error (status %d): %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	// Parse response
-	var apiResponse struct {
-		Entities        map[string][]string `json:"entities"`
-		Recommendations []struct {
-			Test              string   `json:"test"`
-			Reason            string   `json:"reason"`
-			Contraindications []string `json:"contraindications"`
-			Confidence        float64  `json:"confidence"`
-			Urgency           string   `json:"urgency"`
-			Explanation       string   `json:"explanation"`
-		} `json:"recommendations"`
-		Warnings []string `json:"warnings"`
-	}
-
+	var apiResponse analyzerResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Map to AIAnalysis model
+	// Map to AIAnalysis model, stamped with the analyzer that produced it
 	analysis := &models.AIAnalysis{
 		Warnings: apiResponse.Warnings,
+		Analyzer: &models.AnalyzerInfo{
+			Name:       apiResponse.Analyzer.Name,
+			Version:    apiResponse.Analyzer.Version,
+			AnalyzedAt: time.Now(),
+		},
+	}
+	if analysis.Analyzer.Name == "" {
+		analysis.Analyzer.Name = "python-analyzer"
+	}
+	if analysis.Analyzer.Version == "" {
+		analysis.Analyzer.Version = "unknown"
 	}
 
 	// Map entities
@@ -115,36 +267,18 @@ func AnalyzePDFReport(pdfPath string) (*models.AIAnalysis, error) {
 		analysis.Entities.FunctionalImpact = val
 	}
 
-	// Map recommendations
+	// Map recommendations, normalizing confidences to 0-100
 	for _, rec := range apiResponse.Recommendations {
-		analysis.Recommendations = append(analysis.Recommendations, struct {
-			Test              string   `bson:"test" json:"test"`
-			Reason            string   `bson:"reason" json:"reason"`
-			Contraindications []string `bson:"contraindications" json:"contraindications"`
-			Confidence        float64  `bson:"confidence" json:"confidence"`
-			Urgency           string   `bson:"urgency" json:"urgency"`
-			Explanation       string   `bson:"explanation,omitempty" json:"explanation,omitempty"`
-		}{
+		analysis.Recommendations = append(analysis.Recommendations, models.Recommendation{
 			Test:              rec.Test,
 			Reason:            rec.Reason,
 			Contraindications: rec.Contraindications,
-			Confidence:        100 * rec.Confidence,
+			Confidence:        NormalizeConfidence(rec.Confidence),
 			Urgency:           rec.Urgency,
 			Explanation:       rec.Explanation,
 		})
 	}
 
-	// Calculate overall confidence score (average of recommendations)
-	if len(analysis.Recommendations) > 0 {
-		var sum float64
-		for _, rec := range analysis.Recommendations {
-			sum += rec.Confidence
-		}
-		analysis.ConfidenceScore = (sum / float64(len(analysis.Recommendations))) * 100
-	} else {
-		analysis.ConfidenceScore = 0.0
-	}
-
 	return analysis, nil
 }
 
@@ -0,0 +1,556 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ResearchSchemaVersion = "1.0"
+	ResearchMethod        = "HIPAA Safe Harbor (45 CFR 164.514(b)(2)) with k-anonymity over quasi-identifiers, limited to patients consenting to research use"
+
+	// DefaultResearchK is the minimum number of distinct patients sharing each
+	// combination of quasi-identifiers
+	DefaultResearchK = 5
+	// DefaultResearchMaxSuppression is the largest share of records that may be
+	// dropped to reach k-anonymity before the dataset is rejected
+	DefaultResearchMaxSuppression = 0.1
+
+	researchDataFile     = "data.jsonl"
+	researchManifestFile = "manifest.json"
+)
+
+// researchAgeBands are the age generalizations tried, narrowest first
+var researchAgeBands = []int{5, 10, 20}
+
+var researchNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,63}$`)
+
+// researchQuasiIdentifiers are the fields checked for k-anonymity
+var researchQuasiIdentifiers = []string{"age_group", "gender", "diagnoses"}
+
+// researchFields documents every field of a research record for the manifest
+var researchFields = []models.ResearchField{
+	{Name: "patient_code", Description: "Pseudonymous patient code", Transformation: "Random code assigned per dataset version; not derived from any identifier"},
+	{Name: "record_code", Description: "Pseudonymous report code", Transformation: "Random code assigned per dataset version; not derived from any identifier"},
+	{Name: "age_group", Description: "Patient age band", Transformation: "Generalized to the band width in age_band_years; ages over 89 aggregated as 90+"},
+	{Name: "gender", Description: "Patient gender", Transformation: "Normalized to male, female, other or unknown"},
+	{Name: "year", Description: "Year the report was received", Transformation: "Date reduced to year"},
+	{Name: "status", Description: "Review status", Transformation: "None"},
+	{Name: "source", Description: "How the report was received", Transformation: "None"},
+	{Name: "diagnoses", Description: "Extracted diagnoses", Transformation: "Free-text PHI scrubbed"},
+	{Name: "symptoms", Description: "Extracted symptoms", Transformation: "Free-text PHI scrubbed"},
+	{Name: "medications", Description: "Extracted medications", Transformation: "Free-text PHI scrubbed"},
+	{Name: "tests", Description: "Extracted tests", Transformation: "Free-text PHI scrubbed"},
+	{Name: "vitals", Description: "Extracted vitals", Transformation: "Free-text PHI scrubbed"},
+	{Name: "severity", Description: "Extracted severity indicators", Transformation: "Free-text PHI scrubbed"},
+	{Name: "warnings", Description: "Extraction warnings", Transformation: "Free-text PHI scrubbed"},
+	{Name: "observations", Description: "Structured lab results", Transformation: "Dates, reference ranges and status removed; text values PHI scrubbed"},
+	{Name: "recommendations", Description: "AI test recommendations", Transformation: "Reasons PHI scrubbed; contraindications and explanations removed"},
+	{Name: "confidence_score", Description: "Overall analysis confidence (0-100)", Transformation: "None"},
+	{Name: "reviewed", Description: "Whether a doctor reviewed the report", Transformation: "Reviewer identity removed"},
+	{Name: "review_notes", Description: "Doctor review notes", Transformation: "Free-text PHI scrubbed"},
+}
+
+// researchCandidate is a de-identified record before k-anonymity is applied
+type researchCandidate struct {
+	record    models.ResearchRecord
+	patientID primitive.ObjectID
+	age       int
+}
+
+// ResearchService builds versioned, de-identified research datasets
+type ResearchService struct{}
+
+// ValidateDataset checks a dataset request and fills in defaults
+func (s *ResearchService) ValidateDataset(ds *models.ResearchDataset) error {
+	ds.Name = strings.ToLower(strings.TrimSpace(ds.Name))
+	if !researchNamePattern.MatchString(ds.Name) {
+		return fmt.Errorf("name must be 2-64 lowercase letters, digits, '-' or '_'")
+	}
+	if ds.K == 0 {
+		ds.K = DefaultResearchK
+	}
+	if ds.K < 2 {
+		return fmt.Errorf("k must be at least 2")
+	}
+	if ds.MaxSuppression == 0 {
+		ds.MaxSuppression = DefaultResearchMaxSuppression
+	}
+	if ds.MaxSuppression < 0 || ds.MaxSuppression > 1 {
+		return fmt.Errorf("max_suppression must be between 0 and 1")
+	}
+	return nil
+}
+
+// nextResearchVersion atomically assigns the version number for a new release
+// of a dataset from a per-name counter, so concurrent releases never share one
+func nextResearchVersion(ctx context.Context, name string) (int, error) {
+	var latest models.ResearchDataset
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	err := config.GetCollection("research_datasets").FindOne(ctx, bson.M{"name": name}, opts).Decode(&latest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	// Versions released before the counter existed are never reused
+	counters := config.GetCollection("counters")
+	key := bson.M{"_id": "research_dataset:" + name}
+	seed := func() error {
+		_, err := counters.UpdateOne(ctx, key, bson.M{"$max": bson.M{"seq": latest.Version}}, options.Update().SetUpsert(true))
+		return err
+	}
+	// Two first releases may race to create the counter; the loser retries
+	if err = seed(); mongo.IsDuplicateKeyError(err) {
+		err = seed()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var counter struct {
+		Seq int `bson:"seq"`
+	}
+	err = counters.FindOneAndUpdate(ctx, key, bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// StartDataset stores a new dataset version and builds it in the background
+func (s *ResearchService) StartDataset(ds *models.ResearchDataset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	version, err := nextResearchVersion(ctx, ds.Name)
+	if err != nil {
+		return fmt.Errorf("failed to determine dataset version: %w", err)
+	}
+	ds.ID = primitive.NewObjectID()
+	ds.Version = version
+	ds.Status = "queued"
+	ds.CreatedAt = time.Now()
+
+	if _, err := config.GetCollection("research_datasets").InsertOne(ctx, ds); err != nil {
+		return fmt.Errorf("failed to create research dataset: %w", err)
+	}
+
+	go s.run(*ds)
+	return nil
+}
+
+// run builds, de-identifies and writes a dataset version
+func (s *ResearchService) run(ds models.ResearchDataset) {
+	exportSlots <- struct{}{}
+	defer func() { <-exportSlots }()
+
+	collection := config.GetCollection("research_datasets")
+	update := func(fields bson.M) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": ds.ID}, bson.M{"$set": fields}); err != nil {
+			log.Printf("research dataset %s: failed to update status: %v", ds.ID.Hex(), err)
+		}
+	}
+	finish := func(status string, err error) {
+		update(bson.M{"status": status, "error": err.Error(), "completed_at": time.Now()})
+	}
+
+	update(bson.M{"status": "running"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	candidates, excluded, err := s.collect(ctx, ds.Filters)
+	if err != nil {
+		finish("failed", err)
+		return
+	}
+	if len(candidates) == 0 {
+		if excluded > 0 {
+			finish("failed", fmt.Errorf("no reports of consenting patients match the filters (%d excluded without research consent)", excluded))
+			return
+		}
+		finish("failed", fmt.Errorf("no reports match the filters"))
+		return
+	}
+
+	records, manifest, err := anonymizeResearchRecords(candidates, ds.K, ds.MaxSuppression)
+	if err != nil {
+		finish("rejected", err)
+		return
+	}
+	manifest.Dataset = ds.Name
+	manifest.Version = ds.Version
+	manifest.Filters = ds.Filters
+	manifest.ConsentExcluded = excluded
+
+	dir := filepath.Join(os.TempDir(), "research", ds.Name, fmt.Sprintf("v%d", ds.Version))
+	if err := writeResearchFiles(dir, records, manifest); err != nil {
+		os.RemoveAll(dir)
+		finish("failed", err)
+		return
+	}
+
+	update(bson.M{
+		"status":       "completed",
+		"manifest":     manifest,
+		"file_path":    dir,
+		"completed_at": time.Now(),
+	})
+}
+
+// collect loads matching reports of patients who consent to research and
+// strips direct identifiers from them. It also returns how many reports were
+// left out for lack of consent.
+func (s *ResearchService) collect(ctx context.Context, filters models.ExportFilters) ([]researchCandidate, int, error) {
+	doctorNames, err := researchDoctorNames(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	consented, err := ConsentedPatients(ctx, ConsentResearch)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load research consents: %w", err)
+	}
+
+	opts := options.Find().SetBatchSize(500)
+	cursor, err := config.GetCollection("reports").Find(ctx, exportFilter("reports", filters), opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	lookup := newExportLookup()
+	var candidates []researchCandidate
+	excluded := 0
+	for cursor.Next(ctx) {
+		var report models.Report
+		if err := cursor.Decode(&report); err != nil {
+			return nil, 0, err
+		}
+		if !consented[report.PatientID] {
+			excluded++
+			continue
+		}
+		patient := lookup.patient(ctx, report.PatientID)
+		if patient == nil {
+			continue
+		}
+		names := append([]string{patient.Name}, doctorNames...)
+		candidates = append(candidates, researchCandidate{
+			record:    deidentifyReport(&report, patient, names),
+			patientID: patient.ID,
+			age:       patient.Age,
+		})
+	}
+	return candidates, excluded, cursor.Err()
+}
+
+// researchDoctorNames returns all doctor names so they can be scrubbed from free text
+func researchDoctorNames(ctx context.Context) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"name": 1})
+	cursor, err := config.GetCollection("doctors").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load doctors: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var names []string
+	for cursor.Next(ctx) {
+		var doctor models.Doctor
+		if err := cursor.Decode(&doctor); err == nil && doctor.Name != "" {
+			names = append(names, doctor.Name)
+		}
+	}
+	return names, cursor.Err()
+}
+
+// deidentifyReport removes direct identifiers and scrubs free text. Age is
+// generalized later, once the band width is known.
+func deidentifyReport(report *models.Report, patient *models.Patient, names []string) models.ResearchRecord {
+	scrub := func(values []string) []string {
+		result := make([]string, 0, len(values))
+		for _, v := range values {
+			result = append(result, ScrubPHI(v, names))
+		}
+		return result
+	}
+
+	entities := report.AIAnalysis.Entities
+	record := models.ResearchRecord{
+		Gender:          normalizeResearchGender(patient.Gender),
+		Year:            report.UploadedAt.UTC().Year(),
+		Status:          report.Status,
+		Source:          report.Source,
+		Diagnoses:       scrub(entities.Diagnoses),
+		Symptoms:        scrub(entities.Symptoms),
+		Medications:     scrub(entities.Medications),
+		Tests:           scrub(entities.Tests),
+		Vitals:          scrub(entities.Vitals),
+		Severity:        scrub(entities.Severity),
+		Warnings:        scrub(report.AIAnalysis.Warnings),
+		Recommendations: []models.ResearchRecommendation{},
+		ConfidenceScore: report.AIAnalysis.ConfidenceScore,
+		Reviewed:        report.DoctorReview != nil,
+	}
+	if record.Source == "" {
+		record.Source = "upload"
+	}
+	for _, obs := range report.Observations {
+		record.Observations = append(record.Observations, models.ResearchObservation{
+			Code:           obs.Code,
+			CodeSystem:     obs.CodeSystem,
+			Display:        ScrubPHI(obs.Display, names),
+			Value:          obs.Value,
+			ValueString:    ScrubPHI(obs.ValueString, names),
+			Unit:           obs.Unit,
+			Interpretation: obs.Interpretation,
+		})
+	}
+	for _, rec := range report.AIAnalysis.Recommendations {
+		record.Recommendations = append(record.Recommendations, models.ResearchRecommendation{
+			Test:       ScrubPHI(rec.Test, names),
+			Urgency:    rec.Urgency,
+			Confidence: rec.Confidence,
+			Reason:     ScrubPHI(rec.Reason, names),
+		})
+	}
+	if report.DoctorReview != nil {
+		record.ReviewNotes = ScrubPHI(report.DoctorReview.Notes, names)
+	}
+	return record
+}
+
+func normalizeResearchGender(gender string) string {
+	switch strings.ToLower(strings.TrimSpace(gender)) {
+	case "m", "male":
+		return "male"
+	case "f", "female":
+		return "female"
+	case "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
+
+// researchAgeGroup generalizes an age to a band; Safe Harbor requires ages over
+// 89 to be aggregated
+func researchAgeGroup(age, width int) string {
+	if age <= 0 {
+		return "unknown"
+	}
+	if age >= 90 {
+		return "90+"
+	}
+	low := age / width * width
+	high := low + width - 1
+	if high > 89 {
+		high = 89
+	}
+	return fmt.Sprintf("%d-%d", low, high)
+}
+
+// researchClassKey identifies the equivalence class of a record
+func researchClassKey(r *models.ResearchRecord) string {
+	diagnoses := make([]string, 0, len(r.Diagnoses))
+	for _, d := range r.Diagnoses {
+		diagnoses = append(diagnoses, strings.ToLower(strings.TrimSpace(d)))
+	}
+	sort.Strings(diagnoses)
+	return r.AgeGroup + "|" + r.Gender + "|" + strings.Join(diagnoses, ";")
+}
+
+// researchClasses counts distinct patients in each equivalence class for an
+// age band width
+func researchClasses(candidates []researchCandidate, width int) map[string]map[primitive.ObjectID]bool {
+	classes := map[string]map[primitive.ObjectID]bool{}
+	for i := range candidates {
+		c := &candidates[i]
+		c.record.AgeGroup = researchAgeGroup(c.age, width)
+		key := researchClassKey(&c.record)
+		if classes[key] == nil {
+			classes[key] = map[primitive.ObjectID]bool{}
+		}
+		classes[key][c.patientID] = true
+	}
+	return classes
+}
+
+// anonymizeResearchRecords generalizes ages until every equivalence class holds
+// at least k patients after suppressing no more than maxSuppression of the
+// records, then assigns random pseudonyms
+func anonymizeResearchRecords(candidates []researchCandidate, k int, maxSuppression float64) ([]models.ResearchRecord, *models.ResearchManifest, error) {
+	width := 0
+	var classes map[string]map[primitive.ObjectID]bool
+	var suppressed int
+	for _, w := range researchAgeBands {
+		classes = researchClasses(candidates, w)
+		suppressed = 0
+		for i := range candidates {
+			if len(classes[researchClassKey(&candidates[i].record)]) < k {
+				suppressed++
+			}
+		}
+		if float64(suppressed) <= maxSuppression*float64(len(candidates)) {
+			width = w
+			break
+		}
+	}
+	if width == 0 {
+		return nil, nil, fmt.Errorf("k-anonymity (k=%d) not achievable: %d of %d records fall in smaller classes even with %d-year age bands; broaden the filters or lower k",
+			k, suppressed, len(candidates), researchAgeBands[len(researchAgeBands)-1])
+	}
+
+	var kept []researchCandidate
+	for _, c := range candidates {
+		if len(classes[researchClassKey(&c.record)]) >= k {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, nil, fmt.Errorf("k-anonymity (k=%d) leaves no records", k)
+	}
+
+	// Shuffle so neither codes nor record order reveal upload order
+	rand.Shuffle(len(kept), func(i, j int) { kept[i], kept[j] = kept[j], kept[i] })
+	patientCodes := map[primitive.ObjectID]string{}
+	records := make([]models.ResearchRecord, 0, len(kept))
+	minClass := 0
+	keptClasses := map[string]bool{}
+	for i, c := range kept {
+		code, ok := patientCodes[c.patientID]
+		if !ok {
+			code = fmt.Sprintf("P%05d", len(patientCodes)+1)
+			patientCodes[c.patientID] = code
+		}
+		c.record.PatientCode = code
+		c.record.RecordCode = fmt.Sprintf("R%06d", i+1)
+		records = append(records, c.record)
+
+		key := researchClassKey(&c.record)
+		keptClasses[key] = true
+		if size := len(classes[key]); minClass == 0 || size < minClass {
+			minClass = size
+		}
+	}
+
+	manifest := &models.ResearchManifest{
+		SchemaVersion:    ResearchSchemaVersion,
+		GeneratedAt:      time.Now(),
+		Method:           ResearchMethod,
+		RecordCount:      len(records),
+		PatientCount:     len(patientCodes),
+		SourceRecords:    len(candidates),
+		Suppressed:       len(candidates) - len(records),
+		QuasiIdentifiers: researchQuasiIdentifiers,
+		AgeBandYears:     width,
+		K:                k,
+		MinClassSize:     minClass,
+		ClassCount:       len(keptClasses),
+		Fields:           researchFields,
+		DataFile:         researchDataFile,
+	}
+	return records, manifest, nil
+}
+
+// writeResearchFiles writes the JSON Lines data file and the manifest, which
+// carries the data file's checksum
+func writeResearchFiles(dir string, records []models.ResearchRecord, manifest *models.ResearchManifest) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, researchDataFile), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	buffered := bufio.NewWriter(io.MultiWriter(file, hash))
+	encoder := json.NewEncoder(buffered)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	manifest.DataSHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, researchManifestFile), data, 0600)
+}
+
+// ResearchArchiveName is the download name of a dataset version
+func ResearchArchiveName(ds *models.ResearchDataset) string {
+	return fmt.Sprintf("%s_v%d.zip", ds.Name, ds.Version)
+}
+
+// WriteArchive streams a completed dataset version as a zip of its data file
+// and manifest
+func (s *ResearchService) WriteArchive(ds *models.ResearchDataset, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	prefix := fmt.Sprintf("%s_v%d/", ds.Name, ds.Version)
+	for _, name := range []string{researchManifestFile, researchDataFile} {
+		src, err := os.Open(filepath.Join(ds.FilePath, name))
+		if err != nil {
+			return err
+		}
+		dst, err := archive.Create(prefix + name)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// GetDataset loads a research dataset
+func (s *ResearchService) GetDataset(id primitive.ObjectID) (*models.ResearchDataset, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ds models.ResearchDataset
+	err := config.GetCollection("research_datasets").FindOne(ctx, bson.M{"_id": id}).Decode(&ds)
+	if err == mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load research dataset: %w", err)
+	}
+	return &ds, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResearchAgeGroup(t *testing.T) {
+	tests := []struct {
+		age, width int
+		want       string
+	}{
+		{0, 5, "unknown"},
+		{-3, 5, "unknown"},
+		{1, 5, "0-4"},
+		{34, 5, "30-34"},
+		{35, 10, "30-39"},
+		{59, 20, "40-59"},
+		{87, 5, "85-89"},
+		{88, 20, "80-89"},
+		{89, 10, "80-89"},
+		{90, 5, "90+"},
+		{104, 20, "90+"},
+	}
+	for _, tt := range tests {
+		if got := researchAgeGroup(tt.age, tt.width); got != tt.want {
+			t.Errorf("researchAgeGroup(%d, %d) = %s, want %s", tt.age, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestResearchClassKey(t *testing.T) {
+	a := models.ResearchRecord{AgeGroup: "30-34", Gender: "female", Diagnoses: []string{"Asthma", " anaemia"}}
+	b := models.ResearchRecord{AgeGroup: "30-34", Gender: "female", Diagnoses: []string{"anaemia", "asthma"}}
+	if researchClassKey(&a) != researchClassKey(&b) {
+		t.Errorf("diagnosis order and case changed the class: %q vs %q", researchClassKey(&a), researchClassKey(&b))
+	}
+	b.Gender = "male"
+	if researchClassKey(&a) == researchClassKey(&b) {
+		t.Error("gender did not change the class")
+	}
+}
+
+func TestAnonymizeResearchRecords(t *testing.T) {
+	candidate := func(patient primitive.ObjectID, age int, gender string, diagnoses ...string) researchCandidate {
+		return researchCandidate{
+			record:    models.ResearchRecord{Gender: gender, Diagnoses: diagnoses},
+			patientID: patient,
+			age:       age,
+		}
+	}
+	p := func() primitive.ObjectID { return primitive.NewObjectID() }
+
+	tests := []struct {
+		name           string
+		candidates     []researchCandidate
+		k              int
+		maxSuppression float64
+		wantErr        bool
+		wantBand       int
+		wantKept       int
+	}{
+		{
+			name:       "narrowest band suffices",
+			candidates: []researchCandidate{candidate(p(), 31, "female", "asthma"), candidate(p(), 33, "female", "asthma")},
+			k:          2, wantBand: 5, wantKept: 2,
+		},
+		{
+			name:       "band widened to merge classes",
+			candidates: []researchCandidate{candidate(p(), 31, "female", "asthma"), candidate(p(), 36, "female", "asthma")},
+			k:          2, wantBand: 10, wantKept: 2,
+		},
+		{
+			name: "outlier suppressed within budget",
+			candidates: []researchCandidate{
+				candidate(p(), 31, "male", "diabetes"), candidate(p(), 32, "male", "diabetes"),
+				candidate(p(), 33, "male", "diabetes"), candidate(p(), 70, "female", "gout"),
+			},
+			k: 3, maxSuppression: 0.25, wantBand: 5, wantKept: 3,
+		},
+		{
+			name: "suppression over budget",
+			candidates: []researchCandidate{
+				candidate(p(), 31, "male", "diabetes"), candidate(p(), 32, "male", "diabetes"),
+				candidate(p(), 33, "male", "diabetes"), candidate(p(), 70, "female", "gout"),
+			},
+			k: 3, maxSuppression: 0.1, wantErr: true,
+		},
+		{
+			name: "repeat reports of one patient count once",
+			candidates: func() []researchCandidate {
+				same := p()
+				return []researchCandidate{candidate(same, 40, "male", "gout"), candidate(same, 40, "male", "gout")}
+			}(),
+			k: 2, wantErr: true,
+		},
+		{
+			name:       "diagnoses are quasi-identifiers",
+			candidates: []researchCandidate{candidate(p(), 31, "female", "asthma"), candidate(p(), 31, "female", "lupus")},
+			k:          2, wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, manifest, err := anonymizeResearchRecords(tt.candidates, tt.k, tt.maxSuppression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("anonymizeResearchRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if manifest.AgeBandYears != tt.wantBand {
+				t.Errorf("age band = %d, want %d", manifest.AgeBandYears, tt.wantBand)
+			}
+			if len(records) != tt.wantKept || manifest.Suppressed != len(tt.candidates)-tt.wantKept {
+				t.Errorf("kept %d, suppressed %d; want %d kept", len(records), manifest.Suppressed, tt.wantKept)
+			}
+			if manifest.MinClassSize < tt.k {
+				t.Errorf("smallest class has %d patients, want at least %d", manifest.MinClassSize, tt.k)
+			}
+
+			classes := map[string]map[string]bool{}
+			for _, r := range records {
+				key := researchClassKey(&r)
+				if classes[key] == nil {
+					classes[key] = map[string]bool{}
+				}
+				classes[key][r.PatientCode] = true
+			}
+			for key, patients := range classes {
+				if len(patients) < tt.k {
+					t.Errorf("class %q has %d patients, want at least %d", key, len(patients), tt.k)
+				}
+			}
+		})
+	}
+}
+
+func TestAnonymizeResearchRecordsPseudonyms(t *testing.T) {
+	patientA, patientB := primitive.NewObjectID(), primitive.NewObjectID()
+	candidates := []researchCandidate{
+		{record: models.ResearchRecord{Gender: "male"}, patientID: patientA, age: 40},
+		{record: models.ResearchRecord{Gender: "male"}, patientID: patientA, age: 41},
+		{record: models.ResearchRecord{Gender: "male"}, patientID: patientB, age: 42},
+	}
+	records, manifest, err := anonymizeResearchRecords(candidates, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.PatientCount != 2 {
+		t.Errorf("patient count = %d, want 2", manifest.PatientCount)
+	}
+
+	codes := map[string]int{}
+	recordCodes := map[string]bool{}
+	for _, r := range records {
+		codes[r.PatientCode]++
+		if recordCodes[r.RecordCode] {
+			t.Errorf("record code %s reused", r.RecordCode)
+		}
+		recordCodes[r.RecordCode] = true
+		if r.PatientCode == patientA.Hex() || r.PatientCode == patientB.Hex() {
+			t.Errorf("patient code %s is the patient ID", r.PatientCode)
+		}
+	}
+	if len(codes) != 2 {
+		t.Errorf("patient codes = %v, want one per patient", codes)
+	}
+}
+
+func TestDeidentifyReport(t *testing.T) {
+	report := &models.Report{Status: "reviewed"}
+	report.AIAnalysis.Entities.Diagnoses = []string{"Asthma per Dr. Mehta"}
+	report.AIAnalysis.Recommendations = []models.Recommendation{{Test: "Spirometry", Reason: "Call 555-123-4567", Contraindications: []string{"none"}}}
+	report.DoctorReview = &models.DoctorReview{Notes: "Discussed with Grace Stone"}
+	patient := &models.Patient{Name: "Grace Stone", Gender: "F", Age: 34}
+
+	record := deidentifyReport(report, patient, []string{"Grace Stone"})
+	if record.Gender != "female" || record.Source != "upload" || record.AgeGroup != "" {
+		t.Errorf("gender %q source %q age group %q", record.Gender, record.Source, record.AgeGroup)
+	}
+	if record.Diagnoses[0] != "Asthma per [NAME]" {
+		t.Errorf("diagnosis = %q", record.Diagnoses[0])
+	}
+	if record.Recommendations[0].Reason != "Call [PHONE]" {
+		t.Errorf("recommendation reason = %q", record.Recommendations[0].Reason)
+	}
+	if record.ReviewNotes != "Discussed with [NAME]" {
+		t.Errorf("review notes = %q", record.ReviewNotes)
+	}
+}
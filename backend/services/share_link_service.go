@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Share link scopes. Download includes viewing.
+const (
+	ShareScopeView     = "view"
+	ShareScopeDownload = "download"
+)
+
+// Share link limits
+const (
+	DefaultShareLinkTTL        = 7 * 24 * time.Hour
+	MaxShareLinkTTL            = 30 * 24 * time.Hour
+	maxSharePasscodeFailures   = 10 // The link is revoked after this many wrong passcodes
+	minSharePasscodeLength     = 4
+	shareAccessGranted         = "granted"
+	shareAccessPasscodeMissing = "passcode_required"
+)
+
+// Share link errors; each refused access is logged with the error's outcome
+var (
+	ErrShareLinkNotFound      = errors.New("share link not found")
+	ErrShareLinkRevoked       = errors.New("share link has been revoked")
+	ErrShareLinkExpired       = errors.New("share link has expired")
+	ErrShareLinkExhausted     = errors.New("share link has been used the maximum number of times")
+	ErrShareScope             = errors.New("share link does not allow downloads")
+	ErrSharePasscodeRequired  = errors.New("passcode required")
+	ErrSharePasscodeIncorrect = errors.New("incorrect passcode")
+)
+
+// shareAccessOutcomes names refused accesses in the access log
+var shareAccessOutcomes = map[error]string{
+	ErrShareLinkRevoked:       "revoked",
+	ErrShareLinkExpired:       "expired",
+	ErrShareLinkExhausted:     "exhausted",
+	ErrShareScope:             "scope",
+	ErrSharePasscodeRequired:  shareAccessPasscodeMissing,
+	ErrSharePasscodeIncorrect: "bad_passcode",
+}
+
+// ValidShareScope reports whether a scope can be given to a link
+func ValidShareScope(scope string) bool {
+	return scope == ShareScopeView || scope == ShareScopeDownload
+}
+
+// ValidSharePasscode reports whether a passcode is long enough; empty means none
+func ValidSharePasscode(passcode string) bool {
+	return passcode == "" || len(passcode) >= minSharePasscodeLength
+}
+
+// hashShareToken returns the stored form of a link token. Tokens are random
+// and long, so a fast hash is enough to keep them out of the database.
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateShareLink stores a link to a patient's report and returns it with
+// the token, which is not stored and can't be shown again
+func CreateShareLink(link *models.ShareLink, passcode string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	if passcode != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(passcode), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		link.PasscodeHash, link.Protected = string(hash), true
+	}
+	link.ID = primitive.NewObjectID()
+	link.TokenHash = hashShareToken(token)
+	link.AccessCount, link.FailedPasscodes = 0, 0
+	link.CreatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("share_links").InsertOne(ctx, link); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokePatientShareLinks revokes every active link of a patient
+func RevokePatientShareLinks(patientID primitive.ObjectID, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := config.GetCollection("share_links").UpdateMany(ctx,
+		bson.M{"patient_id": patientID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_reason": reason}},
+	)
+	if err != nil {
+		log.Printf("share links: failed to revoke links of patient %s: %v", patientID.Hex(), err)
+	}
+}
+
+// ShareAccess describes one attempt to use a link
+type ShareAccess struct {
+	Token     string
+	Passcode  string
+	Action    string // ShareScopeView or ShareScopeDownload
+	IP        string
+	UserAgent string
+}
+
+// OpenShareLink checks a link for an access and counts it, returning the
+// shared report. Every attempt on an existing link is logged for its owner.
+func OpenShareLink(access ShareAccess) (*models.ShareLink, *models.Report, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := config.GetCollection("share_links")
+
+	var link models.ShareLink
+	if err := collection.FindOne(ctx, bson.M{"token_hash": hashShareToken(access.Token)}).Decode(&link); err != nil {
+		return nil, nil, ErrShareLinkNotFound
+	}
+
+	err := checkShareLink(ctx, &link, access)
+	if err == nil {
+		err = countShareAccess(ctx, &link)
+	}
+	outcome := shareAccessGranted
+	if err != nil {
+		outcome = shareAccessOutcomes[err]
+	}
+	logShareAccess(ctx, &link, access, outcome)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var report models.Report
+	if err := config.GetCollection("reports").FindOne(ctx, bson.M{"_id": link.ReportID}).Decode(&report); err != nil {
+		return nil, nil, ErrShareLinkNotFound
+	}
+	return &link, &report, nil
+}
+
+// checkShareLink applies the link's restrictions to an access
+func checkShareLink(ctx context.Context, link *models.ShareLink, access ShareAccess) error {
+	now := time.Now()
+	switch {
+	case link.RevokedAt != nil:
+		return ErrShareLinkRevoked
+	case !now.Before(link.ExpiresAt):
+		return ErrShareLinkExpired
+	case link.MaxAccesses > 0 && link.AccessCount >= link.MaxAccesses:
+		return ErrShareLinkExhausted
+	case access.Action == ShareScopeDownload && link.Scope != ShareScopeDownload:
+		return ErrShareScope
+	}
+	if !link.Protected {
+		return nil
+	}
+	if access.Passcode == "" {
+		return ErrSharePasscodeRequired
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasscodeHash), []byte(access.Passcode)) == nil {
+		return nil
+	}
+
+	// Guessing is cut off by revoking the link after repeated failures. The
+	// limit is part of the filter so concurrent guesses cannot overshoot it.
+	collection := config.GetCollection("share_links")
+	var counted models.ShareLink
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": link.ID, "failed_passcodes": bson.M{"$lt": maxSharePasscodeFailures}},
+		bson.M{"$inc": bson.M{"failed_passcodes": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&counted)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrShareLinkRevoked
+	case err != nil:
+		log.Printf("share links: failed to record passcode failure on %s: %v", link.ID.Hex(), err)
+	case counted.FailedPasscodes >= maxSharePasscodeFailures:
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": link.ID, "revoked_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revoked_at": now, "revoked_reason": "too many incorrect passcodes"}},
+		)
+		if err != nil {
+			log.Printf("share links: failed to revoke %s after passcode failures: %v", link.ID.Hex(), err)
+		}
+	}
+	return ErrSharePasscodeIncorrect
+}
+
+// countShareAccess increments the access count unless the link was revoked,
+// locked by passcode failures or used up since it was read
+func countShareAccess(ctx context.Context, link *models.ShareLink) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id":              link.ID,
+		"revoked_at":       bson.M{"$exists": false},
+		"expires_at":       bson.M{"$gt": now},
+		"failed_passcodes": bson.M{"$lt": maxSharePasscodeFailures},
+	}
+	if link.MaxAccesses > 0 {
+		filter["access_count"] = bson.M{"$lt": link.MaxAccesses}
+	}
+	result, err := config.GetCollection("share_links").UpdateOne(ctx, filter, bson.M{
+		"$inc": bson.M{"access_count": 1},
+		"$set": bson.M{"last_accessed_at": now},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrShareLinkExhausted
+	}
+	link.AccessCount++
+	link.LastAccessedAt = &now
+	return nil
+}
+
+func logShareAccess(ctx context.Context, link *models.ShareLink, access ShareAccess, outcome string) {
+	if outcome == "" {
+		outcome = "error"
+	}
+	entry := models.ShareLinkAccess{
+		ID:        primitive.NewObjectID(),
+		LinkID:    link.ID,
+		ReportID:  link.ReportID,
+		PatientID: link.PatientID,
+		Action:    access.Action,
+		Outcome:   outcome,
+		IP:        access.IP,
+		UserAgent: access.UserAgent,
+		At:        time.Now(),
+	}
+	if _, err := config.GetCollection("share_link_accesses").InsertOne(ctx, entry); err != nil {
+		log.Printf("share links: failed to log access to %s: %v", link.ID.Hex(), err)
+	}
+}
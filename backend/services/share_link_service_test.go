@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestShareLinkValidation(t *testing.T) {
+	for scope, want := range map[string]bool{ShareScopeView: true, ShareScopeDownload: true, "edit": false, "": false} {
+		if got := ValidShareScope(scope); got != want {
+			t.Errorf("ValidShareScope(%q) = %v, want %v", scope, got, want)
+		}
+	}
+	for passcode, want := range map[string]bool{"": true, "123": false, "1234": true, "correct horse": true} {
+		if got := ValidSharePasscode(passcode); got != want {
+			t.Errorf("ValidSharePasscode(%q) = %v, want %v", passcode, got, want)
+		}
+	}
+}
+
+func TestHashShareToken(t *testing.T) {
+	hash := hashShareToken("token")
+	if hash == "token" || len(hash) != 64 {
+		t.Errorf("hashShareToken() = %q, want a hex SHA-256", hash)
+	}
+	if hashShareToken("token") != hash || hashShareToken("other") == hash {
+		t.Error("hash is not a stable function of the token")
+	}
+}
+
+// Passcode failures are counted in the database, so only checks that don't
+// reach it are covered here
+func TestCheckShareLink(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("2468"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	open := models.ShareLink{Scope: ShareScopeView, ExpiresAt: future}
+	protected := models.ShareLink{Scope: ShareScopeDownload, ExpiresAt: future, Protected: true, PasscodeHash: string(hash)}
+
+	with := func(link models.ShareLink, change func(*models.ShareLink)) models.ShareLink {
+		change(&link)
+		return link
+	}
+	view := ShareAccess{Action: ShareScopeView}
+
+	tests := []struct {
+		name   string
+		link   models.ShareLink
+		access ShareAccess
+		want   error
+	}{
+		{"open link", open, view, nil},
+		{"revoked", with(open, func(l *models.ShareLink) { l.RevokedAt = &past }), view, ErrShareLinkRevoked},
+		{"expired", with(open, func(l *models.ShareLink) { l.ExpiresAt = past }), view, ErrShareLinkExpired},
+		{"used up", with(open, func(l *models.ShareLink) { l.MaxAccesses, l.AccessCount = 2, 2 }), view, ErrShareLinkExhausted},
+		{"uses left", with(open, func(l *models.ShareLink) { l.MaxAccesses, l.AccessCount = 2, 1 }), view, nil},
+		{"download on a view link", open, ShareAccess{Action: ShareScopeDownload}, ErrShareScope},
+		{"passcode missing", protected, view, ErrSharePasscodeRequired},
+		{"passcode correct", protected, ShareAccess{Action: ShareScopeDownload, Passcode: "2468"}, nil},
+		{"revoked before passcode", with(protected, func(l *models.ShareLink) { l.RevokedAt = &past }), ShareAccess{Passcode: "0000"}, ErrShareLinkRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkShareLink(context.Background(), &tt.link, tt.access); err != tt.want {
+				t.Errorf("checkShareLink() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareAccessOutcomes(t *testing.T) {
+	for _, err := range []error{ErrShareLinkRevoked, ErrShareLinkExpired, ErrShareLinkExhausted, ErrShareScope, ErrSharePasscodeRequired, ErrSharePasscodeIncorrect} {
+		if shareAccessOutcomes[err] == "" {
+			t.Errorf("refused access %q has no log outcome", err)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SignatureAlgorithm is the algorithm used for doctor sign-off
+const SignatureAlgorithm = "Ed25519"
+
+// SigningService manages doctor key pairs and report signatures
+type SigningService struct{}
+
+// SignaturePayloadVersion is the canonical payload layout used for new
+// signatures. Version 1 signed the whole stored analysis, so recomputed
+// metadata such as confidence scores broke verification.
+const SignaturePayloadVersion = 2
+
+// signedPayload is the canonical content covered by a doctor's signature.
+// Field order is fixed and maps are serialized with sorted keys by encoding/json.
+type signedPayload struct {
+	Version      int            `json:"version"`
+	ReportID     string         `json:"report_id"`
+	PatientID    string         `json:"patient_id"`
+	PDFFileName  string         `json:"pdf_filename"`
+	UploadedAt   string         `json:"uploaded_at"`
+	Findings     signedFindings `json:"findings"`
+	DoctorReview *signedReview  `json:"doctor_review"`
+	SignerID     string         `json:"signer_id"`
+	SignedAt     string         `json:"signed_at"`
+}
+
+// signedFindings is the clinical content of the analysis a doctor signs off
+// on. Scores, analyzer details, rule traces and version stamps are left out.
+type signedFindings struct {
+	Symptoms         []string               `json:"symptoms"`
+	Diagnoses        []string               `json:"diagnoses"`
+	Medications      []string               `json:"medications"`
+	Tests            []string               `json:"tests"`
+	Vitals           []string               `json:"vitals"`
+	Severity         []string               `json:"severity"`
+	Urgency          []string               `json:"urgency"`
+	FunctionalImpact []string               `json:"functional_impact"`
+	Assertions       []signedAssertion      `json:"assertions"`
+	Codes            []signedCode           `json:"codes"`
+	Recommendations  []signedRecommendation `json:"recommendations"`
+	Interactions     []signedInteraction    `json:"interactions"`
+	Warnings         []string               `json:"warnings"`
+}
+
+type signedAssertion struct {
+	Category string `json:"category"`
+	Text     string `json:"text"`
+	Status   string `json:"status"`
+}
+
+type signedCode struct {
+	Category string `json:"category"`
+	Text     string `json:"text"`
+	System   string `json:"system"`
+	Code     string `json:"code"`
+}
+
+type signedRecommendation struct {
+	Test              string   `json:"test"`
+	Reason            string   `json:"reason"`
+	Urgency           string   `json:"urgency"`
+	Contraindications []string `json:"contraindications"`
+}
+
+type signedInteraction struct {
+	RuleID   string   `json:"rule_id"`
+	Severity string   `json:"severity"`
+	Drugs    []string `json:"drugs"`
+	Status   string   `json:"status"`
+}
+
+type signedReview struct {
+	ReviewedBy    string                 `json:"reviewed_by"`
+	ReviewedAt    string                 `json:"reviewed_at"`
+	EditedFields  map[string]interface{} `json:"edited_fields"`
+	Notes         string                 `json:"notes"`
+	Override      bool                   `json:"override"`
+	Justification string                 `json:"justification"`
+}
+
+// legacySignedPayload is the version 1 layout, kept to verify old signatures
+type legacySignedPayload struct {
+	ReportID     string               `json:"report_id"`
+	PatientID    string               `json:"patient_id"`
+	PDFFileName  string               `json:"pdf_filename"`
+	UploadedAt   string               `json:"uploaded_at"`
+	AIAnalysis   models.AIAnalysis    `json:"ai_analysis"`
+	DoctorReview *models.DoctorReview `json:"doctor_review"`
+	SignerID     string               `json:"signer_id"`
+	SignedAt     string               `json:"signed_at"`
+}
+
+// CanonicalReportPayload serializes the report and review for signing in the
+// given payload version. Times are normalized to UTC millisecond precision to
+// match MongoDB storage.
+func CanonicalReportPayload(report *models.Report, signerID primitive.ObjectID, signedAt time.Time, version int) ([]byte, error) {
+	switch version {
+	case 1:
+		return legacyReportPayload(report, signerID, signedAt)
+	case SignaturePayloadVersion:
+	default:
+		return nil, fmt.Errorf("unknown signature payload version %d", version)
+	}
+
+	payload := signedPayload{
+		Version:     version,
+		ReportID:    report.ID.Hex(),
+		PatientID:   report.PatientID.Hex(),
+		PDFFileName: report.PDFFileName,
+		UploadedAt:  normalizeTime(report.UploadedAt).Format(time.RFC3339Nano),
+		Findings:    signedFindingsOf(&report.AIAnalysis),
+		SignerID:    signerID.Hex(),
+		SignedAt:    normalizeTime(signedAt).Format(time.RFC3339Nano),
+	}
+	if r := report.DoctorReview; r != nil {
+		payload.DoctorReview = &signedReview{
+			ReviewedBy:    r.ReviewedBy.Hex(),
+			ReviewedAt:    normalizeTime(r.ReviewedAt).Format(time.RFC3339Nano),
+			EditedFields:  r.EditedFields,
+			Notes:         r.Notes,
+			Override:      r.Override,
+			Justification: r.Justification,
+		}
+	}
+	return json.Marshal(payload)
+}
+
+func signedFindingsOf(analysis *models.AIAnalysis) signedFindings {
+	e := analysis.Entities
+	findings := signedFindings{
+		Symptoms:         e.Symptoms,
+		Diagnoses:        e.Diagnoses,
+		Medications:      e.Medications,
+		Tests:            e.Tests,
+		Vitals:           e.Vitals,
+		Severity:         e.Severity,
+		Urgency:          e.Urgency,
+		FunctionalImpact: e.FunctionalImpact,
+		Warnings:         analysis.Warnings,
+	}
+	for _, a := range analysis.Assertions {
+		findings.Assertions = append(findings.Assertions, signedAssertion{Category: a.Category, Text: a.Text, Status: a.Status})
+	}
+	for _, c := range analysis.CodedEntities {
+		findings.Codes = append(findings.Codes, signedCode{Category: c.Category, Text: c.Text, System: c.System, Code: c.Code})
+	}
+	for _, r := range analysis.Recommendations {
+		findings.Recommendations = append(findings.Recommendations, signedRecommendation{
+			Test: r.Test, Reason: r.Reason, Urgency: r.Urgency, Contraindications: r.Contraindications,
+		})
+	}
+	for _, i := range analysis.Interactions {
+		findings.Interactions = append(findings.Interactions, signedInteraction{
+			RuleID: i.RuleID, Severity: i.Severity, Drugs: i.Drugs, Status: i.Status,
+		})
+	}
+	return findings
+}
+
+func legacyReportPayload(report *models.Report, signerID primitive.ObjectID, signedAt time.Time) ([]byte, error) {
+	var review *models.DoctorReview
+	if report.DoctorReview != nil {
+		r := *report.DoctorReview
+		r.ReviewedAt = normalizeTime(r.ReviewedAt)
+		review = &r
+	}
+
+	payload := legacySignedPayload{
+		ReportID:     report.ID.Hex(),
+		PatientID:    report.PatientID.Hex(),
+		PDFFileName:  report.PDFFileName,
+		UploadedAt:   normalizeTime(report.UploadedAt).Format(time.RFC3339Nano),
+		AIAnalysis:   report.AIAnalysis,
+		DoctorReview: review,
+		SignerID:     signerID.Hex(),
+		SignedAt:     normalizeTime(signedAt).Format(time.RFC3339Nano),
+	}
+	return json.Marshal(payload)
+}
+
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Millisecond)
+}
+
+// GetOrCreateKey returns the doctor's active key, generating one if needed
+func (s *SigningService) GetOrCreateKey(doctorID primitive.ObjectID) (*models.DoctorKey, error) {
+	collection := config.GetCollection("doctor_keys")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var key models.DoctorKey
+	err := collection.FindOne(ctx, bson.M{"doctor_id": doctorID, "active": true}).Decode(&key)
+	if err == nil {
+		return &key, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to fetch doctor key: %w", err)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %w", err)
+	}
+
+	encrypted, err := encryptPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key = models.DoctorKey{
+		ID:                  primitive.NewObjectID(),
+		DoctorID:            doctorID,
+		Algorithm:           SignatureAlgorithm,
+		PublicKey:           base64.StdEncoding.EncodeToString(publicKey),
+		EncryptedPrivateKey: encrypted,
+		Active:              true,
+		CreatedAt:           time.Now(),
+	}
+
+	if _, err = collection.InsertOne(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to store doctor key: %w", err)
+	}
+	return &key, nil
+}
+
+// GetKeyByID loads a key by ID (including rotated, inactive keys)
+func (s *SigningService) GetKeyByID(keyID primitive.ObjectID) (*models.DoctorKey, error) {
+	collection := config.GetCollection("doctor_keys")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var key models.DoctorKey
+	if err := collection.FindOne(ctx, bson.M{"_id": keyID}).Decode(&key); err != nil {
+		return nil, fmt.Errorf("signing key not found: %w", err)
+	}
+	return &key, nil
+}
+
+// SignReport signs the canonical payload of a report on behalf of a doctor
+func (s *SigningService) SignReport(report *models.Report, doctorID primitive.ObjectID) (*models.ReportSignature, error) {
+	key, err := s.GetOrCreateKey(doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := decryptPrivateKey(key.EncryptedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signedAt := normalizeTime(time.Now())
+	payload, err := CanonicalReportPayload(report, doctorID, signedAt, SignaturePayloadVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize report: %w", err)
+	}
+
+	hash := sha256.Sum256(payload)
+	signature := ed25519.Sign(privateKey, payload)
+
+	return &models.ReportSignature{
+		DoctorID:       doctorID,
+		KeyID:          key.ID,
+		Algorithm:      SignatureAlgorithm,
+		PayloadVersion: SignaturePayloadVersion,
+		PayloadHash:    hex.EncodeToString(hash[:]),
+		Signature:      base64.StdEncoding.EncodeToString(signature),
+		SignedAt:       signedAt,
+	}, nil
+}
+
+// VerifyReport checks the report's signature against its current content.
+// It returns whether the signature is valid and, if not, why.
+func (s *SigningService) VerifyReport(report *models.Report) (bool, string, error) {
+	if report.Signature == nil {
+		return false, "report is not signed", nil
+	}
+	sig := report.Signature
+
+	key, err := s.GetKeyByID(sig.KeyID)
+	if err != nil {
+		return false, "", err
+	}
+	if key.DoctorID != sig.DoctorID {
+		return false, "signing key does not belong to the signing doctor", nil
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(key.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false, "stored public key is malformed", nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return false, "signature is malformed", nil
+	}
+
+	version := sig.PayloadVersion
+	if version == 0 {
+		version = 1
+	}
+	payload, err := CanonicalReportPayload(report, sig.DoctorID, sig.SignedAt, version)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to serialize report: %w", err)
+	}
+
+	hash := sha256.Sum256(payload)
+	if hex.EncodeToString(hash[:]) != sig.PayloadHash {
+		return false, "report content has changed since signing", nil
+	}
+	if !ed25519.Verify(ed25519.PublicKey(publicKey), payload, signature) {
+		return false, "signature does not match", nil
+	}
+	return true, "signature is valid", nil
+}
+
+// masterKey derives the AES key used to protect stored private keys
+func masterKey() ([]byte, error) {
+	secret := os.Getenv("SIGNING_MASTER_KEY")
+	if secret == "" {
+		return nil, fmt.Errorf("SIGNING_MASTER_KEY not set in environment")
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:], nil
+}
+
+func encryptPrivateKey(privateKey ed25519.PrivateKey) (string, error) {
+	key, err := masterKey()
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, privateKey, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptPrivateKey(encrypted string) (ed25519.PrivateKey, error) {
+	key, err := masterKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted private key is too short")
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+	return ed25519.PrivateKey(plain), nil
+}
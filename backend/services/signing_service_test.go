@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCanonicalReportPayload(t *testing.T) {
+	signer := primitive.NewObjectID()
+	signedAt := time.Date(2026, 3, 12, 9, 30, 0, 0, time.UTC)
+	base := func() *models.Report {
+		report := &models.Report{ID: primitive.NewObjectID(), PatientID: primitive.NewObjectID(), PDFFileName: "cbc.pdf"}
+		report.AIAnalysis.Entities.Diagnoses = []string{"anaemia"}
+		report.AIAnalysis.Recommendations = []models.Recommendation{{Test: "Ferritin", Reason: "Low Hb", Confidence: 80}}
+		report.AIAnalysis.ConfidenceScore = 72
+		report.DoctorReview = &models.DoctorReview{ReviewedBy: signer, ReviewedAt: signedAt, Notes: "Agree"}
+		return report
+	}
+	original := base()
+	want, err := CanonicalReportPayload(original, signer, signedAt, SignaturePayloadVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(r *models.Report)
+		changed bool
+	}{
+		{"confidence rescored", func(r *models.Report) { r.AIAnalysis.ConfidenceScore = 40 }, false},
+		{"recommendation confidence", func(r *models.Report) { r.AIAnalysis.Recommendations[0].Confidence = 50 }, false},
+		{"rule set version", func(r *models.Report) { r.AIAnalysis.RuleSetVersion = "2026.10" }, false},
+		{"diagnosis", func(r *models.Report) { r.AIAnalysis.Entities.Diagnoses = []string{"thalassaemia"} }, true},
+		{"recommendation", func(r *models.Report) { r.AIAnalysis.Recommendations[0].Test = "B12" }, true},
+		{"assertion", func(r *models.Report) {
+			r.AIAnalysis.Assertions = []models.EntityAssertion{{Category: TermCategoryDiagnosis, Text: "anaemia", Status: AssertionNegated}}
+		}, true},
+		{"review notes", func(r *models.Report) { r.DoctorReview.Notes = "Disagree" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := base()
+			report.ID, report.PatientID = original.ID, original.PatientID
+			tt.change(report)
+			got, err := CanonicalReportPayload(report, signer, signedAt, SignaturePayloadVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := !bytes.Equal(got, want); changed != tt.changed {
+				t.Errorf("payload changed = %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+
+	t.Run("legacy version covers whole analysis", func(t *testing.T) {
+		report := base()
+		report.ID, report.PatientID = original.ID, original.PatientID
+		before, _ := CanonicalReportPayload(report, signer, signedAt, 1)
+		report.AIAnalysis.ConfidenceScore = 40
+		after, _ := CanonicalReportPayload(report, signer, signedAt, 1)
+		if bytes.Equal(before, after) {
+			t.Error("version 1 payload ignored the confidence score")
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		if _, err := CanonicalReportPayload(original, signer, signedAt, 99); err == nil {
+			t.Error("expected an error for an unknown payload version")
+		}
+	})
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Summary PDF sections, in default order
+const (
+	SummarySectionPatient         = "patient"
+	SummarySectionReview          = "review"
+	SummarySectionObservations    = "observations"
+	SummarySectionEntities        = "entities"
+	SummarySectionRecommendations = "recommendations"
+	SummarySectionWarnings        = "warnings"
+	SummarySectionSignature       = "signature"
+)
+
+// DefaultSummarySections lists every section in the order used when a template sets none
+var DefaultSummarySections = []string{
+	SummarySectionPatient,
+	SummarySectionReview,
+	SummarySectionObservations,
+	SummarySectionEntities,
+	SummarySectionRecommendations,
+	SummarySectionWarnings,
+	SummarySectionSignature,
+}
+
+// DefaultSummaryTemplate is used when no template has been configured
+var DefaultSummaryTemplate = models.SummaryTemplate{
+	Name:           "default",
+	Title:          "Medical Report Analysis Summary",
+	HeaderLeft:     "{{hospital}}",
+	HeaderRight:    "Report {{report_id}}",
+	FooterLeft:     "Generated {{generated_at}}",
+	FooterRight:    "Page {{page}} of {{pages}}",
+	AccentColor:    "#1f4e8c",
+	ShowConfidence: true,
+	Disclaimer: "This summary was produced with AI assistance. Findings not marked as " +
+		"reviewed have not been confirmed by a doctor and must not be used as a diagnosis.",
+}
+
+// IsValidSummarySection reports whether name is a known section
+func IsValidSummarySection(name string) bool {
+	for _, section := range DefaultSummarySections {
+		if section == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SummaryPDFService renders analysis summaries as PDF documents
+type SummaryPDFService struct{}
+
+// ResolveTemplate picks the template for a hospital: an exact hospital match,
+// then the admin-defined default, then DefaultSummaryTemplate
+func (s *SummaryPDFService) ResolveTemplate(hospital string) (*models.SummaryTemplate, error) {
+	collection := config.GetCollection("summary_templates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := []bson.M{{"is_default": true}}
+	if hospital != "" {
+		filters = append([]bson.M{{"hospital": hospital}}, filters...)
+	}
+	for _, filter := range filters {
+		var template models.SummaryTemplate
+		err := collection.FindOne(ctx, filter).Decode(&template)
+		if err == nil {
+			return &template, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("failed to load summary template: %w", err)
+		}
+	}
+
+	template := DefaultSummaryTemplate
+	return &template, nil
+}
+
+// summaryData is everything a summary PDF shows
+type summaryData struct {
+	report   *models.Report
+	patient  *models.Patient
+	reviewer *models.Doctor
+	signer   *models.Doctor
+	hospital string
+}
+
+// loadSummaryData fetches the patient and doctors referenced by a report
+func (s *SummaryPDFService) loadSummaryData(report *models.Report) (*summaryData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data := &summaryData{report: report}
+
+	var patient models.Patient
+	err := config.GetCollection("patients").FindOne(ctx, bson.M{"_id": report.PatientID}).Decode(&patient)
+	if err == nil {
+		data.patient = &patient
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to load patient: %w", err)
+	}
+
+	loadDoctor := func(id primitive.ObjectID) (*models.Doctor, error) {
+		var doctor models.Doctor
+		err := config.GetCollection("doctors").FindOne(ctx, bson.M{"_id": id}).Decode(&doctor)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load doctor: %w", err)
+		}
+		return &doctor, nil
+	}
+	if report.DoctorReview != nil && !report.DoctorReview.ReviewedBy.IsZero() {
+		if data.reviewer, err = loadDoctor(report.DoctorReview.ReviewedBy); err != nil {
+			return nil, err
+		}
+	}
+	if report.Signature != nil {
+		if data.signer, err = loadDoctor(report.Signature.DoctorID); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case data.signer != nil && data.signer.Hospital != "":
+		data.hospital = data.signer.Hospital
+	case data.reviewer != nil:
+		data.hospital = data.reviewer.Hospital
+	}
+	return data, nil
+}
+
+// RenderReport renders the summary PDF of a report. When template is nil the
+// template is resolved from the hospital of the signing or reviewing doctor.
+func (s *SummaryPDFService) RenderReport(report *models.Report, template *models.SummaryTemplate) ([]byte, error) {
+	data, err := s.loadSummaryData(report)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		if template, err = s.ResolveTemplate(data.hospital); err != nil {
+			return nil, err
+		}
+	}
+	return s.render(data, template)
+}
+
+func (s *SummaryPDFService) render(data *summaryData, template *models.SummaryTemplate) ([]byte, error) {
+	report := data.report
+	accent := parseHexColor(template.AccentColor, parseHexColor(DefaultSummaryTemplate.AccentColor, pdfBlack))
+
+	title := template.Title
+	if title == "" {
+		title = DefaultSummaryTemplate.Title
+	}
+	layout := newPDFLayout(title)
+
+	// Title block
+	layout.paragraph(title, pdfFontBold, 18, accent, 0)
+	layout.space(4)
+	meta := fmt.Sprintf("Report uploaded %s  •  Status: %s", report.UploadedAt.Format("02 Jan 2006 15:04"), reportStatusLabel(report.Status))
+	if report.PDFFileName != "" {
+		meta = report.PDFFileName + "  •  " + meta
+	}
+	layout.paragraph(meta, pdfFontRegular, 9, pdfGray, 0)
+	if template.ShowConfidence {
+		layout.paragraph(fmt.Sprintf("Overall AI confidence: %.0f%%", report.AIAnalysis.ConfidenceScore), pdfFontRegular, 9, pdfGray, 0)
+	}
+
+	sections := template.Sections
+	if len(sections) == 0 {
+		sections = DefaultSummarySections
+	}
+	for _, section := range sections {
+		switch section {
+		case SummarySectionPatient:
+			s.patientSection(layout, data, accent)
+		case SummarySectionReview:
+			s.reviewSection(layout, data, accent)
+		case SummarySectionObservations:
+			s.observationsSection(layout, report, accent)
+		case SummarySectionEntities:
+			s.entitiesSection(layout, report, accent)
+		case SummarySectionRecommendations:
+			s.recommendationsSection(layout, report, template.ShowConfidence, accent)
+		case SummarySectionWarnings:
+			s.warningsSection(layout, report, accent)
+		case SummarySectionSignature:
+			s.signatureSection(layout, data, accent)
+		}
+	}
+
+	disclaimer := template.Disclaimer
+	if disclaimer == "" {
+		disclaimer = DefaultSummaryTemplate.Disclaimer
+	}
+	layout.space(18)
+	layout.paragraph(disclaimer, pdfFontRegular, 8, pdfGray, 0)
+
+	// Headers and footers
+	patientName := ""
+	if data.patient != nil {
+		patientName = data.patient.Name
+	}
+	generatedAt := time.Now().Format("02 Jan 2006 15:04")
+	layout.decorate(accent, func(page, total int) (string, string, string, string) {
+		replacer := strings.NewReplacer(
+			"{{hospital}}", data.hospital,
+			"{{patient_name}}", patientName,
+			"{{report_id}}", report.ID.Hex(),
+			"{{generated_at}}", generatedAt,
+			"{{page}}", fmt.Sprint(page),
+			"{{pages}}", fmt.Sprint(total),
+		)
+		headerLeft := replacer.Replace(template.HeaderLeft)
+		if strings.TrimSpace(headerLeft) == "" {
+			headerLeft = "Medical Report Analyzer"
+		}
+		return headerLeft, replacer.Replace(template.HeaderRight), replacer.Replace(template.FooterLeft), replacer.Replace(template.FooterRight)
+	})
+
+	return layout.doc.bytes()
+}
+
+func (s *SummaryPDFService) patientSection(layout *pdfLayout, data *summaryData, accent pdfColor) {
+	layout.heading("Patient", accent)
+	if data.patient == nil {
+		layout.paragraph("Patient record not found.", pdfFontRegular, 10, pdfGray, 0)
+		return
+	}
+	p := data.patient
+	layout.field("Name", p.Name)
+	if p.Age > 0 {
+		layout.field("Age", fmt.Sprint(p.Age))
+	}
+	layout.field("Gender", p.Gender)
+	layout.field("Patient ID", p.ID.Hex())
+	for _, identifier := range p.Identifiers {
+		label := "Identifier"
+		if identifier.System != "" {
+			label = "Identifier (" + identifier.System + ")"
+		}
+		layout.field(label, identifier.Value)
+	}
+	layout.field("Phone", p.Phone)
+	layout.field("Email", p.Email)
+}
+
+func (s *SummaryPDFService) reviewSection(layout *pdfLayout, data *summaryData, accent pdfColor) {
+	layout.heading("Doctor Review", accent)
+	review := data.report.DoctorReview
+	if review == nil {
+		layout.paragraph("This report has not yet been reviewed by a doctor.", pdfFontRegular, 10, pdfOrange, 0)
+		return
+	}
+	if data.reviewer != nil {
+		layout.field("Reviewed by", doctorLabel(data.reviewer))
+		layout.field("Hospital", data.reviewer.Hospital)
+	}
+	layout.field("Reviewed at", review.ReviewedAt.Format("02 Jan 2006 15:04"))
+	layout.field("Notes", review.Notes)
+	if review.Override {
+		layout.field("Policy override", review.Justification)
+	}
+}
+
+func (s *SummaryPDFService) observationsSection(layout *pdfLayout, report *models.Report, accent pdfColor) {
+	if len(report.Observations) == 0 {
+		return
+	}
+	layout.heading("Results", accent)
+	for _, obs := range report.Observations {
+		line := ObservationEntityText(obs)
+		if obs.ReferenceRange != "" {
+			line += "  (ref " + obs.ReferenceRange + ")"
+		}
+		color := pdfBlack
+		if flag := strings.ToUpper(obs.Interpretation); flag != "" && flag != "N" {
+			line += "  [" + flag + "]"
+			color = pdfRed
+		}
+		layout.bullet(line, color)
+	}
+}
+
+// entitiesSection lists the extracted findings. Negated findings are left
+// out; historical, hypothetical and family findings are labelled.
+func (s *SummaryPDFService) entitiesSection(layout *pdfLayout, report *models.Report, accent pdfColor) {
+	analysis := &report.AIAnalysis
+	entities := analysis.Entities
+	asserted := func(category string, values []string) []string {
+		var kept []string
+		for _, value := range values {
+			switch EntityStatus(analysis, category, value) {
+			case AssertionNegated:
+				continue
+			case AssertionHistorical:
+				value += " (history)"
+			case AssertionHypothetical:
+				value += " (possible)"
+			case AssertionFamily:
+				value += " (family history)"
+			}
+			kept = append(kept, value)
+		}
+		return kept
+	}
+	groups := []struct {
+		label  string
+		values []string
+	}{
+		{"Diagnoses", asserted(TermCategoryDiagnosis, entities.Diagnoses)},
+		{"Symptoms", asserted(TermCategorySymptom, entities.Symptoms)},
+		{"Medications", asserted(TermCategoryMedication, entities.Medications)},
+		{"Tests", asserted(TermCategoryTest, entities.Tests)},
+		{"Vitals", entities.Vitals},
+		{"Severity", entities.Severity},
+		{"Urgency", entities.Urgency},
+		{"Functional impact", entities.FunctionalImpact},
+	}
+
+	layout.heading("Findings", accent)
+	empty := true
+	for _, group := range groups {
+		if len(group.values) == 0 {
+			continue
+		}
+		empty = false
+		layout.field(group.label, strings.Join(group.values, ", "))
+	}
+	if empty {
+		layout.paragraph("No findings were extracted from this report.", pdfFontRegular, 10, pdfGray, 0)
+	}
+}
+
+func (s *SummaryPDFService) recommendationsSection(layout *pdfLayout, report *models.Report, showConfidence bool, accent pdfColor) {
+	recs := report.AIAnalysis.Recommendations
+	if len(recs) == 0 {
+		return
+	}
+	layout.heading("Recommendations", accent)
+	for _, rec := range recs {
+		layout.space(4)
+		heading := rec.Test
+		if rec.Urgency != "" {
+			heading += "  —  " + strings.ToUpper(rec.Urgency)
+		}
+		layout.paragraph(heading, pdfFontBold, 11, urgencyColor(rec.Urgency), 0)
+		if rec.Reason != "" {
+			layout.paragraph(rec.Reason, pdfFontRegular, 10, pdfBlack, 10)
+		}
+		if showConfidence {
+			layout.paragraph(fmt.Sprintf("Confidence: %.0f%%", rec.Confidence), pdfFontRegular, 9, pdfGray, 10)
+		}
+		if len(rec.Contraindications) > 0 {
+			layout.paragraph("Contraindications: "+strings.Join(rec.Contraindications, ", "), pdfFontRegular, 9, pdfRed, 10)
+		}
+	}
+}
+
+func (s *SummaryPDFService) warningsSection(layout *pdfLayout, report *models.Report, accent pdfColor) {
+	if len(report.AIAnalysis.Warnings) == 0 {
+		return
+	}
+	layout.heading("Warnings", accent)
+	for _, warning := range report.AIAnalysis.Warnings {
+		layout.bullet(warning, pdfRed)
+	}
+}
+
+func (s *SummaryPDFService) signatureSection(layout *pdfLayout, data *summaryData, accent pdfColor) {
+	report := data.report
+	if report.Signature == nil && len(report.Amendments) == 0 {
+		return
+	}
+	layout.heading("Signature", accent)
+
+	if sig := report.Signature; sig != nil {
+		if data.signer != nil {
+			layout.field("Signed by", doctorLabel(data.signer))
+			layout.field("License", data.signer.LicenseNumber)
+		}
+		layout.field("Signed at", sig.SignedAt.Format("02 Jan 2006 15:04:05 MST"))
+		layout.field("Algorithm", sig.Algorithm)
+		layout.field("Key ID", sig.KeyID.Hex())
+		layout.field("Payload SHA-256", sig.PayloadHash)
+
+		valid, reason, err := (&SigningService{}).VerifyReport(report)
+		switch {
+		case err != nil:
+			layout.paragraph("Signature could not be verified: "+err.Error(), pdfFontBold, 10, pdfOrange, 0)
+		case valid:
+			layout.paragraph("Signature verified", pdfFontBold, 10, pdfGreen, 0)
+		default:
+			layout.paragraph("Signature INVALID: "+reason, pdfFontBold, 10, pdfRed, 0)
+		}
+	}
+
+	for _, amendment := range report.Amendments {
+		layout.space(4)
+		layout.field("Amended", amendment.AmendedAt.Format("02 Jan 2006 15:04")+" — "+amendment.Reason)
+	}
+}
+
+func doctorLabel(d *models.Doctor) string {
+	label := "Dr. " + d.Name
+	if d.Specialization != "" {
+		label += " (" + d.Specialization + ")"
+	}
+	return label
+}
+
+func reportStatusLabel(status string) string {
+	if status == "" {
+		return "pending"
+	}
+	return status
+}
+
+func urgencyColor(urgency string) pdfColor {
+	switch strings.ToLower(urgency) {
+	case "urgent", "high", "immediate", "stat":
+		return pdfRed
+	case "soon", "medium", "moderate":
+		return pdfOrange
+	case "routine", "low":
+		return pdfGreen
+	}
+	return pdfBlack
+}
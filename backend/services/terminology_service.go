@@ -0,0 +1,335 @@
+package services
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+)
+
+// Entity categories that can be coded
+const (
+	TermCategoryDiagnosis  = "diagnosis"
+	TermCategorySymptom    = "symptom"
+	TermCategoryTest       = "test"
+	TermCategoryMedication = "medication"
+)
+
+// Match types of a coded entity
+const (
+	TermMatchExact        = "exact"
+	TermMatchAbbreviation = "abbreviation"
+	TermMatchPartial      = "partial"
+	TermMatchNone         = "none"
+)
+
+// TerminologyCodingThreshold is the minimum match confidence for a code to be
+// used in interoperability output such as FHIR codings
+const TerminologyCodingThreshold = 70
+
+// terminologyPartialMin is the minimum token overlap (Dice coefficient) for a
+// partial match
+const terminologyPartialMin = 0.5
+
+//go:embed terminology/*.json
+var terminologyFiles embed.FS
+
+// termTableFile is the on-disk format of a bundled code table
+type termTableFile struct {
+	Name    string `json:"name"`
+	System  string `json:"system"`
+	Version string `json:"version"`
+	Entries []struct {
+		Code    string   `json:"code"`
+		System  string   `json:"system"` // Overrides the table system
+		Display string   `json:"display"`
+		Class   bool     `json:"class"` // Drug class rather than an ingredient
+		Terms   []string `json:"terms"`
+	} `json:"entries"`
+}
+
+// abbreviationFile is the on-disk format of the abbreviation table
+type abbreviationFile struct {
+	Version string `json:"version"`
+	Entries []struct {
+		Abbreviation string `json:"abbreviation"`
+		Expansion    string `json:"expansion"`
+		Ambiguous    bool   `json:"ambiguous"` // Has other common meanings
+	} `json:"entries"`
+}
+
+type termEntry struct {
+	system  string
+	code    string
+	display string
+	class   bool
+}
+
+type termCandidate struct {
+	tokens []string
+	entry  *termEntry
+}
+
+type termTable struct {
+	byTerm     map[string]*termEntry
+	candidates []termCandidate
+}
+
+type abbreviation struct {
+	expansion string
+	ambiguous bool
+}
+
+type terminology struct {
+	tables        map[string]*termTable // By file name
+	abbreviations map[string]abbreviation
+	version       string
+}
+
+var (
+	terminologyOnce sync.Once
+	terminologyData *terminology
+)
+
+// termCategoryTables maps entity categories to their code table
+var termCategoryTables = map[string]string{
+	TermCategoryDiagnosis:  "icd10cm",
+	TermCategorySymptom:    "icd10cm",
+	TermCategoryTest:       "tests",
+	TermCategoryMedication: "rxnorm",
+}
+
+// termStopwords are ignored when comparing terms token by token
+var termStopwords = map[string]bool{
+	"of": true, "the": true, "and": true, "with": true, "without": true, "in": true,
+	"on": true, "a": true, "an": true, "for": true, "to": true, "level": true, "levels": true,
+}
+
+// loadTerminology parses the bundled tables. They are compiled into the binary,
+// so a malformed table is a build defect and panics.
+func loadTerminology() *terminology {
+	terminologyOnce.Do(func() {
+		t := &terminology{tables: map[string]*termTable{}, abbreviations: map[string]abbreviation{}}
+		var versions []string
+		for _, name := range []string{"icd10cm", "tests", "rxnorm"} {
+			data, err := terminologyFiles.ReadFile("terminology/" + name + ".json")
+			if err != nil {
+				panic(fmt.Sprintf("terminology: %v", err))
+			}
+			var file termTableFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				panic(fmt.Sprintf("terminology: invalid %s table: %v", name, err))
+			}
+			table := &termTable{byTerm: map[string]*termEntry{}}
+			for _, e := range file.Entries {
+				entry := &termEntry{system: file.System, code: e.Code, display: e.Display, class: e.Class}
+				if e.System != "" {
+					entry.system = e.System
+				}
+				for _, term := range e.Terms {
+					normalized := normalizeTerm(term)
+					if _, exists := table.byTerm[normalized]; !exists {
+						table.byTerm[normalized] = entry
+					}
+					table.candidates = append(table.candidates, termCandidate{tokens: termTokens(normalized), entry: entry})
+				}
+			}
+			t.tables[name] = table
+			versions = append(versions, name+"@"+file.Version)
+		}
+
+		data, err := terminologyFiles.ReadFile("terminology/abbreviations.json")
+		if err != nil {
+			panic(fmt.Sprintf("terminology: %v", err))
+		}
+		var abbrevs abbreviationFile
+		if err := json.Unmarshal(data, &abbrevs); err != nil {
+			panic(fmt.Sprintf("terminology: invalid abbreviations table: %v", err))
+		}
+		for _, e := range abbrevs.Entries {
+			t.abbreviations[normalizeTerm(e.Abbreviation)] = abbreviation{expansion: normalizeTerm(e.Expansion), ambiguous: e.Ambiguous}
+		}
+		versions = append(versions, "abbreviations@"+abbrevs.Version)
+
+		sort.Strings(versions)
+		t.version = strings.Join(versions, ",")
+		terminologyData = t
+	})
+	return terminologyData
+}
+
+// TerminologyVersion identifies the bundled table versions used for coding
+func TerminologyVersion() string {
+	return loadTerminology().version
+}
+
+// normalizeTerm lowercases text and reduces punctuation other than hyphens and
+// apostrophes to single spaces
+func normalizeTerm(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '\'':
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func termTokens(normalized string) []string {
+	var tokens []string
+	for _, token := range strings.Fields(normalized) {
+		if !termStopwords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// expandAbbreviations expands every token that is a known abbreviation
+func (t *terminology) expandAbbreviations(normalized string) (string, bool, bool) {
+	tokens := strings.Fields(normalized)
+	expanded, ambiguous := false, false
+	for i, token := range tokens {
+		if abbrev, ok := t.abbreviations[token]; ok {
+			tokens[i] = abbrev.expansion
+			expanded = true
+			ambiguous = ambiguous || abbrev.ambiguous
+		}
+	}
+	return strings.Join(tokens, " "), expanded, ambiguous
+}
+
+// diceScore is the token overlap of two token lists (0-1)
+func diceScore(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := map[string]bool{}
+	for _, token := range a {
+		set[token] = true
+	}
+	common := 0
+	for _, token := range b {
+		if set[token] {
+			common++
+			delete(set, token)
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// bestPartial finds the table term with the highest token overlap
+func (table *termTable) bestPartial(normalized string) (*termEntry, float64) {
+	tokens := termTokens(normalized)
+	var best *termEntry
+	bestScore := 0.0
+	for _, candidate := range table.candidates {
+		if score := diceScore(tokens, candidate.tokens); score > bestScore {
+			best, bestScore = candidate.entry, score
+		}
+	}
+	return best, bestScore
+}
+
+// CodeEntity maps an extracted entity to the code table of its category. Exact
+// term matches score 100; matches that needed abbreviation expansion score
+// lower, and lower still when the abbreviation is ambiguous; partial matches
+// score by token overlap and stay below TerminologyCodingThreshold unless
+// every token matches. Drug classes are capped below ingredient matches.
+func CodeEntity(category, text string) models.CodedEntity {
+	coded := models.CodedEntity{Category: category, Text: text, MatchType: TermMatchNone}
+	t := loadTerminology()
+	table := t.tables[termCategoryTables[category]]
+	normalized := normalizeTerm(text)
+	if table == nil || normalized == "" {
+		return coded
+	}
+
+	expandedText, expanded, ambiguous := t.expandAbbreviations(normalized)
+	if expanded {
+		coded.Expanded = expandedText
+	}
+
+	var entry *termEntry
+	switch {
+	case table.byTerm[normalized] != nil:
+		entry = table.byTerm[normalized]
+		coded.MatchType = TermMatchExact
+		coded.Confidence = 100
+	case expanded && table.byTerm[expandedText] != nil:
+		entry = table.byTerm[expandedText]
+		coded.MatchType = TermMatchAbbreviation
+		coded.Confidence = 95
+		if ambiguous {
+			coded.Confidence = 70
+		}
+	default:
+		var score float64
+		entry, score = table.bestPartial(expandedText)
+		if entry == nil || score < terminologyPartialMin {
+			return coded
+		}
+		coded.MatchType = TermMatchPartial
+		coded.Confidence = math.Min(85, math.Round(40+50*score))
+		// Only a reordering of every token is safe to code; any other overlap,
+		// e.g. "diabetes insipidus" against "diabetes", stays a suggestion
+		if score < 1 {
+			coded.Confidence = math.Min(coded.Confidence, TerminologyCodingThreshold-1)
+		}
+		if ambiguous {
+			coded.Confidence = math.Min(coded.Confidence, 60)
+		}
+	}
+
+	if entry.class {
+		coded.Confidence = math.Min(coded.Confidence, 80)
+	}
+	coded.System = entry.system
+	coded.Code = entry.code
+	coded.Display = entry.display
+	return coded
+}
+
+// ApplyTerminology codes the diagnoses, symptoms, tests and medications of an
+// analysis and records the table versions used
+func ApplyTerminology(analysis *models.AIAnalysis) {
+	entities := analysis.Entities
+	analysis.CodedEntities = nil
+	for _, group := range []struct {
+		category string
+		values   []string
+	}{
+		{TermCategoryDiagnosis, entities.Diagnoses},
+		{TermCategorySymptom, entities.Symptoms},
+		{TermCategoryTest, entities.Tests},
+		{TermCategoryMedication, entities.Medications},
+	} {
+		for _, value := range group.values {
+			analysis.CodedEntities = append(analysis.CodedEntities, CodeEntity(group.category, value))
+		}
+	}
+	analysis.TerminologyVersion = TerminologyVersion()
+}
+
+// EntityCoding returns the stored coding of an entity, or nil when the entity
+// has no code at or above TerminologyCodingThreshold
+func EntityCoding(analysis *models.AIAnalysis, category, text string) *models.CodedEntity {
+	for i := range analysis.CodedEntities {
+		coded := &analysis.CodedEntities[i]
+		if coded.Category == category && coded.Text == text {
+			if coded.Code == "" || coded.Confidence < TerminologyCodingThreshold {
+				return nil
+			}
+			return coded
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestCodeEntity(t *testing.T) {
+	tests := []struct {
+		name      string
+		category  string
+		text      string
+		wantCode  string // Expected code at or above the coding threshold; "" for uncoded
+		wantMatch string
+	}{
+		{"exact", TermCategoryDiagnosis, "type 2 diabetes mellitus", "E11.9", TermMatchExact},
+		{"exact synonym", TermCategoryDiagnosis, "Diabetes", "E11.9", TermMatchExact},
+		{"reordered", TermCategoryDiagnosis, "mellitus diabetes", "E11.9", TermMatchPartial},
+		{"diabetes insipidus", TermCategoryDiagnosis, "diabetes insipidus", "", ""},
+		{"gestational diabetes", TermCategoryDiagnosis, "gestational diabetes", "", ""},
+		{"lung cancer", TermCategoryDiagnosis, "lung cancer", "", ""},
+		{"chronic pain", TermCategorySymptom, "chronic pain", "", ""},
+		{"unknown", TermCategoryDiagnosis, "xyzzy", "", TermMatchNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coded := CodeEntity(tt.category, tt.text)
+			code := ""
+			if coded.Confidence >= TerminologyCodingThreshold {
+				code = coded.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("CodeEntity(%q) = %s at %.0f%%, want %q", tt.text, coded.Code, coded.Confidence, tt.wantCode)
+			}
+			if tt.wantMatch != "" && coded.MatchType != tt.wantMatch {
+				t.Errorf("CodeEntity(%q) match = %s, want %s", tt.text, coded.MatchType, tt.wantMatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/config"
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook event types
+const (
+	WebhookReportUploaded    = "report.uploaded"
+	WebhookAnalysisCompleted = "analysis.completed"
+	WebhookReportReviewed    = "report.reviewed"
+	WebhookReportEdited      = "report.edited"
+	WebhookPatientDeleted    = "patient.deleted"
+)
+
+// WebhookEvents lists the events a subscription can choose
+var WebhookEvents = []string{
+	WebhookReportUploaded,
+	WebhookAnalysisCompleted,
+	WebhookReportReviewed,
+	WebhookReportEdited,
+	WebhookPatientDeleted,
+}
+
+// Webhook delivery states
+const (
+	WebhookPending   = "pending"
+	WebhookRetrying  = "retrying"
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+)
+
+// Signature headers sent with every delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Retry defaults, overridden by WEBHOOK_MAX_ATTEMPTS and WEBHOOK_RETRY_BASE
+// (a Go duration). Attempt n waits base * 2^(n-1), capped at an hour.
+const (
+	DefaultWebhookMaxAttempts = 6
+	DefaultWebhookRetryBase   = 30 * time.Second
+	maxWebhookRetryDelay      = time.Hour
+)
+
+// ValidWebhookEvent reports whether a subscription may choose the event
+func ValidWebhookEvent(event string) bool {
+	if event == "*" {
+		return true
+	}
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWebhookSecret returns a random signing secret
+func NewWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// SignWebhookPayload computes the signature header value for a body
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRetryDelay returns how long to wait after the given failed attempt
+func WebhookRetryDelay(attempt int) time.Duration {
+	base := DefaultWebhookRetryBase
+	if d, err := time.ParseDuration(os.Getenv("WEBHOOK_RETRY_BASE")); err == nil && d > 0 {
+		base = d
+	}
+	delay := base
+	for i := 1; i < attempt && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookRetryDelay {
+		delay = maxWebhookRetryDelay
+	}
+	return delay
+}
+
+func webhookMaxAttempts() int {
+	if n, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultWebhookMaxAttempts
+}
+
+// WebhookSender makes the HTTP request of a delivery attempt. It does not
+// touch the database, so it can be exercised against a local receiver.
+type WebhookSender struct {
+	Client *http.Client
+}
+
+var webhookSender = &WebhookSender{Client: &http.Client{Timeout: 10 * time.Second}}
+
+// Send posts the delivery's payload, signed with the subscription's secret.
+// Any 2xx response is a success.
+func (s *WebhookSender) Send(sub *models.WebhookSubscription, delivery *models.WebhookDelivery) models.WebhookAttempt {
+	attempt := models.WebhookAttempt{At: time.Now()}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := attempt.At.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Medical-Report-Analyzer-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(sub.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	attempt.DurationMs = time.Since(attempt.At).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("receiver returned status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// webhookEnvelope is the JSON body of every delivery
+type webhookEnvelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// ReportWebhookData describes a report in event payloads. Findings are left
+// out; receivers fetch the report through the API.
+func ReportWebhookData(report *models.Report) map[string]interface{} {
+	data := map[string]interface{}{
+		"report_id":        report.ID.Hex(),
+		"patient_id":       report.PatientID.Hex(),
+		"status":           report.Status,
+		"source":           report.Source,
+		"uploaded_at":      report.UploadedAt,
+		"confidence_score": report.AIAnalysis.ConfidenceScore,
+	}
+	if report.Source == "" {
+		data["source"] = "upload"
+	}
+	if report.DoctorReview != nil {
+		data["reviewed_by"] = report.DoctorReview.ReviewedBy.Hex()
+		data["reviewed_at"] = report.DoctorReview.ReviewedAt
+	}
+	return data
+}
+
+// PublishWebhookEvent queues the event for every active subscription to it
+// and makes the first attempts in the background
+func PublishWebhookEvent(event string, data interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("webhook_subscriptions").Find(ctx, bson.M{
+		"active": true,
+		"events": bson.M{"$in": []string{event, "*"}},
+	})
+	if err != nil {
+		log.Printf("webhooks: failed to load subscriptions for %s: %v", event, err)
+		return
+	}
+	var subs []models.WebhookSubscription
+	if err := cursor.All(ctx, &subs); err != nil {
+		log.Printf("webhooks: failed to load subscriptions for %s: %v", event, err)
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	eventID := primitive.NewObjectID().Hex()
+	payload, err := json.Marshal(webhookEnvelope{ID: eventID, Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		log.Printf("webhooks: failed to encode %s: %v", event, err)
+		return
+	}
+
+	for i := range subs {
+		delivery := &models.WebhookDelivery{
+			ID:             primitive.NewObjectID(),
+			SubscriptionID: subs[i].ID,
+			EventID:        eventID,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         WebhookPending,
+			Attempts:       []models.WebhookAttempt{},
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if _, err := config.GetCollection("webhook_deliveries").InsertOne(ctx, delivery); err != nil {
+			log.Printf("webhooks: failed to queue %s for subscription %s: %v", event, subs[i].ID.Hex(), err)
+			continue
+		}
+		go AttemptWebhookDelivery(&subs[i], delivery)
+	}
+}
+
+// RedeliverWebhook sends a past delivery's payload again as a new delivery
+// with its own attempts, and returns it after the first attempt
+func RedeliverWebhook(deliveryID, adminID primitive.ObjectID) (*models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var original models.WebhookDelivery
+	if err := config.GetCollection("webhook_deliveries").FindOne(ctx, bson.M{"_id": deliveryID}).Decode(&original); err != nil {
+		return nil, err
+	}
+	var sub models.WebhookSubscription
+	if err := config.GetCollection("webhook_subscriptions").FindOne(ctx, bson.M{"_id": original.SubscriptionID}).Decode(&sub); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:             primitive.NewObjectID(),
+		SubscriptionID: sub.ID,
+		EventID:        original.EventID,
+		Event:          original.Event,
+		Payload:        original.Payload,
+		Status:         WebhookPending,
+		Attempts:       []models.WebhookAttempt{},
+		RedeliveryOf:   &original.ID,
+		RequestedBy:    &adminID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if _, err := config.GetCollection("webhook_deliveries").InsertOne(ctx, delivery); err != nil {
+		return nil, err
+	}
+	AttemptWebhookDelivery(&sub, delivery)
+	return delivery, nil
+}
+
+// AttemptWebhookDelivery makes one attempt and records it, scheduling a retry
+// on failure until the attempts run out
+func AttemptWebhookDelivery(sub *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	attempt := webhookSender.Send(sub, delivery)
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.UpdatedAt = time.Now()
+
+	set := bson.M{"updated_at": delivery.UpdatedAt}
+	unset := bson.M{}
+	switch {
+	case attempt.Error == "":
+		delivery.Status, delivery.NextAttemptAt = WebhookSucceeded, nil
+		unset["next_attempt_at"] = ""
+	case len(delivery.Attempts) >= webhookMaxAttempts():
+		delivery.Status, delivery.NextAttemptAt = WebhookFailed, nil
+		unset["next_attempt_at"] = ""
+		log.Printf("webhooks: delivery %s of %s to %s failed after %d attempts: %s",
+			delivery.ID.Hex(), delivery.Event, sub.URL, len(delivery.Attempts), attempt.Error)
+	default:
+		next := time.Now().Add(WebhookRetryDelay(len(delivery.Attempts)))
+		delivery.Status, delivery.NextAttemptAt = WebhookRetrying, &next
+		set["next_attempt_at"] = next
+	}
+	set["status"] = delivery.Status
+
+	update := bson.M{"$set": set, "$push": bson.M{"attempts": attempt}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := config.GetCollection("webhook_deliveries").UpdateOne(ctx, bson.M{"_id": delivery.ID}, update); err != nil {
+		log.Printf("webhooks: failed to record attempt of delivery %s: %v", delivery.ID.Hex(), err)
+	}
+}
+
+// RunWebhookRetrier retries deliveries whose backoff has elapsed. Deliveries
+// of deactivated or deleted subscriptions are marked failed.
+func RunWebhookRetrier(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		cursor, err := config.GetCollection("webhook_deliveries").Find(ctx, bson.M{
+			"status":          WebhookRetrying,
+			"next_attempt_at": bson.M{"$lte": time.Now()},
+		})
+		var due []models.WebhookDelivery
+		if err == nil {
+			err = cursor.All(ctx, &due)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("webhooks: failed to load due deliveries: %v", err)
+			continue
+		}
+
+		for i := range due {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			var sub models.WebhookSubscription
+			err := config.GetCollection("webhook_subscriptions").FindOne(ctx, bson.M{"_id": due[i].SubscriptionID, "active": true}).Decode(&sub)
+			if err != nil {
+				config.GetCollection("webhook_deliveries").UpdateOne(ctx, bson.M{"_id": due[i].ID}, bson.M{
+					"$set":   bson.M{"status": WebhookFailed, "updated_at": time.Now()},
+					"$unset": bson.M{"next_attempt_at": ""},
+				})
+				cancel()
+				continue
+			}
+			cancel()
+			AttemptWebhookDelivery(&sub, &due[i])
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Aashishvatwani/Medical-Report-Analyzer/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWebhookSenderSignsPayload(t *testing.T) {
+	const secret = "whsec_test"
+	payload := `{"id":"1","event":"report.reviewed","data":{"report_id":"abc"}}`
+
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	sub := &models.WebhookSubscription{URL: receiver.URL, Secret: secret}
+	delivery := &models.WebhookDelivery{ID: primitive.NewObjectID(), Event: WebhookReportReviewed, Payload: payload}
+	attempt := (&WebhookSender{Client: receiver.Client()}).Send(sub, delivery)
+
+	if attempt.Error != "" || attempt.StatusCode != http.StatusNoContent {
+		t.Fatalf("attempt = %+v, want success with 204", attempt)
+	}
+	if string(body) != payload {
+		t.Errorf("body = %s, want %s", body, payload)
+	}
+	if got.Header.Get(WebhookEventHeader) != WebhookReportReviewed {
+		t.Errorf("event header = %q", got.Header.Get(WebhookEventHeader))
+	}
+	if got.Header.Get(WebhookDeliveryHeader) != delivery.ID.Hex() {
+		t.Errorf("delivery header = %q", got.Header.Get(WebhookDeliveryHeader))
+	}
+
+	// A receiver verifies the signature from the timestamp header and raw body
+	timestamp, err := strconv.ParseInt(got.Header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header: %v", err)
+	}
+	if want := SignWebhookPayload(secret, timestamp, body); got.Header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", got.Header.Get(WebhookSignatureHeader), want)
+	}
+	if SignWebhookPayload("other", timestamp, body) == got.Header.Get(WebhookSignatureHeader) {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestWebhookSenderReportsFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	sub := &models.WebhookSubscription{URL: receiver.URL, Secret: "s"}
+	delivery := &models.WebhookDelivery{ID: primitive.NewObjectID(), Event: WebhookReportUploaded, Payload: "{}"}
+	sender := &WebhookSender{Client: receiver.Client()}
+
+	attempt := sender.Send(sub, delivery)
+	if attempt.StatusCode != http.StatusServiceUnavailable || attempt.Error == "" {
+		t.Errorf("attempt = %+v, want failure with 503", attempt)
+	}
+
+	receiver.Close()
+	attempt = sender.Send(sub, delivery)
+	if attempt.StatusCode != 0 || attempt.Error == "" {
+		t.Errorf("attempt against a closed receiver = %+v, want a connection error", attempt)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRY_BASE", "")
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := WebhookRetryDelay(tt.attempt); got != tt.want {
+			t.Errorf("WebhookRetryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
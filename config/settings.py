@@ -8,6 +8,8 @@ load_dotenv()
 def get_app_settings():
     return {
         "allowed_file_types": [".pdf"],
+        "analyzer_name": os.getenv("ANALYZER_NAME", "medical-report-analyzer"),
+        "analyzer_version": os.getenv("ANALYZER_VERSION", "1.0.0"),
         "nlp_model_name": os.getenv("NLP_MODEL_NAME", "emilyalsentzer/Bio_ClinicalBERT"),
         "fallback_nlp_model": "en_core_web_sm",
         "diagnostic_tests": [
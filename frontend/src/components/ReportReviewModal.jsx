@@ -346,6 +346,20 @@ const ReportReviewModal = ({ isOpen, onClose, report, onSubmitReview, onSubmitEd
                 </div>
               )}
 
+              {/* Recommendations dropped because their findings are negated */}
+              {(report.ai_analysis?.dropped_recommendations || []).length > 0 && (
+                <div className="space-y-2">
+                  <h3 className="text-sm font-semibold text-gray-400">Dropped Recommendations</h3>
+                  <ul className="space-y-1 text-sm text-gray-400">
+                    {report.ai_analysis.dropped_recommendations.map((rec, index) => (
+                      <li key={index}>
+                        {rec.test}: supporting findings are negated ({(rec.negated_findings || []).join(', ')})
+                      </li>
+                    ))}
+                  </ul>
+                </div>
+              )}
+
               {/* Review/Edit Notes */}
               {!isEditing ? (
                 <div className="space-y-2">
@@ -73,7 +73,7 @@ const ReportSummary = ({ isOpen, onClose, analysisData, reportName }) => {
               <div className="text-right">
                 <p className="text-xs text-white/70">Confidence Score</p>
                 <p className={`text-2xl font-bold ${getConfidenceColor(confidence_score)}`}>
-                  {confidence_score/100?.toFixed(1) || 0}%
+                  {confidence_score?.toFixed(1) || 0}%
                 </p>
               </div>
               <button
@@ -48,8 +48,9 @@ const DoctorDashboard = () => {
     setError(null)
 
     try {
-      // Fetch all reports without status filter
-      const data = await api.get('/api/doctor/reports')
+      // Fetch reports of this doctor's patients without status filter
+      const doctor = JSON.parse(localStorage.getItem('user') || '{}')
+      const data = await api.get(`/api/doctor/reports?doctor_id=${doctor.id || ''}`)
       setAllReports(data.reports || [])
     } catch (err) {
       console.error('Failed to fetch reports', err)
@@ -279,7 +280,7 @@ const DoctorDashboard = () => {
                         <TrendingUp className="w-4 h-4 text-accent" />
                         <span>Confidence: <span className={`font-bold ${
                           report.ai_analysis?.confidence_score < 80 ? 'text-red-400' : report.ai_analysis?.confidence_score < 90 ? 'text-yellow-400' : 'text-green-400'
-                        }`}>{report.ai_analysis?.confidence_score/100?.toFixed(0) || 0}%</span></span>
+                        }`}>{report.ai_analysis?.confidence_score?.toFixed(0) || 0}%</span></span>
                       </div>
                       <div>
                         <span className={`px-3 py-1 rounded-full text-xs font-semibold ${
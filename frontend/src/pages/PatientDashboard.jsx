@@ -3,7 +3,8 @@ import { useState, useEffect } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { 
   Activity, Upload, FileText, MessageSquare, LogOut, User,
-  TrendingUp, Clock, CheckCircle2, AlertCircle, Download, ChevronDown, ChevronUp
+  TrendingUp, Clock, CheckCircle2, AlertCircle, Download, ChevronDown, ChevronUp,
+  ShieldCheck
 } from 'lucide-react'
 import GlowButton from '../components/GlowButton'
 import AIChatBot from '../components/AIChatBot'
@@ -26,6 +27,8 @@ const PatientDashboard = () => {
   const [currentAnalysis, setCurrentAnalysis] = useState(null)
   const [expandedReviews, setExpandedReviews] = useState({}) // Track which reviews are expanded
   const [isProfileOpen, setIsProfileOpen] = useState(false)
+  const [consents, setConsents] = useState([])
+  const [expandedConsent, setExpandedConsent] = useState(null)
 
   useEffect(() => {
     // Get user from localStorage
@@ -33,8 +36,9 @@ const PatientDashboard = () => {
     if (storedUser) {
       const parsedUser = JSON.parse(storedUser)
       setUser(parsedUser)
-      // Fetch reports for this user
+      // Fetch reports and consents for this user
       fetchAllReports(parsedUser.id)
+      fetchConsents(parsedUser.id)
     } else {
       // If no user, redirect to login
       navigate('/login')
@@ -76,6 +80,45 @@ const PatientDashboard = () => {
     }
   }
 
+  const fetchConsents = async (patientId) => {
+    if (!patientId) return
+    try {
+      const data = await api.get('/api/patient/consents', { params: { patient_id: patientId } })
+      if (data.success) {
+        setConsents(data.consents || [])
+      }
+    } catch (err) {
+      console.error('Failed to fetch consents:', err)
+    }
+  }
+
+  const handleGrantConsent = async (consent) => {
+    if (!user?.id || !consent.current_document) return
+    try {
+      await api.post('/api/patient/consents', {
+        patient_id: user.id,
+        purpose: consent.purpose,
+        document_version: consent.current_document.version
+      })
+      await fetchConsents(user.id)
+    } catch (err) {
+      console.error('Failed to grant consent:', err)
+      setError(err.response?.data?.error || 'Failed to record consent')
+    }
+  }
+
+  const handleWithdrawConsent = async (consent) => {
+    if (!user?.id) return
+    if (!window.confirm(`Withdraw consent for "${consent.current_document?.title || consent.purpose}"?`)) return
+    try {
+      await api.post(`/api/patient/consents/${consent.purpose}/withdraw`, { patient_id: user.id })
+      await fetchConsents(user.id)
+    } catch (err) {
+      console.error('Failed to withdraw consent:', err)
+      setError(err.response?.data?.error || 'Failed to withdraw consent')
+    }
+  }
+
   const handleLogout = async () => {
     try{
   
@@ -117,7 +160,14 @@ const PatientDashboard = () => {
       })
 
       const data = response.data
-      if (data.success) {
+      if (data.success && data.analysis_skipped) {
+        // Stored, but not analysed until the patient agrees to automated analysis
+        setUploadSuccess('Report uploaded. It will not be analysed until you agree to automated analysis in the Consent section.')
+        setExpandedConsent(data.consent)
+        if (user?.id) {
+          await fetchAllReports(user.id)
+        }
+      } else if (data.success) {
         setUploadSuccess(`Report uploaded successfully! Report ID: ${data.report_id}`)
         
         // Re-fetch all reports from backend to ensure persistence
@@ -237,7 +287,7 @@ const PatientDashboard = () => {
           { 
             label: 'Avg Confidence', 
             value: reports.length > 0 
-              ? `${((reports.reduce((acc, r) => acc + (r.confidence || 0), 0) / reports.length) / 100).toFixed(0)}%`
+              ? `${(reports.reduce((acc, r) => acc + (r.confidence || 0), 0) / reports.length).toFixed(0)}%`
               : '0%', 
             icon: <TrendingUp />, 
             color: 'from-orange-500 to-red-500' 
@@ -340,7 +390,7 @@ const PatientDashboard = () => {
                         {report.status === 'completed' && report.confidence && (
                           <div className="text-sm text-right">
                             <div className="text-gray-400">Confidence</div>
-                            <div className="font-bold text-green-400">{(report.confidence/100).toFixed(1)}%</div>
+                            <div className="font-bold text-green-400">{report.confidence.toFixed(1)}%</div>
                           </div>
                         )}
                         {report.analysis && (
@@ -487,6 +537,52 @@ const PatientDashboard = () => {
             </div>
           </motion.div>
 
+          {/* Consent */}
+          <motion.div
+            initial={{ opacity: 0, x: 20 }}
+            animate={{ opacity: 1, x: 0 }}
+            transition={{ delay: 0.1 }}
+            className="glow-card"
+          >
+            <h3 className="text-xl font-bold mb-4 flex items-center gap-2">
+              <ShieldCheck className="w-5 h-5 text-accent" />
+              Consent
+            </h3>
+            <div className="space-y-3">
+              {consents.filter(consent => consent.current_document).map(consent => (
+                <div key={consent.purpose} className="glass-effect p-3 rounded-xl">
+                  <button
+                    onClick={() => setExpandedConsent(expandedConsent === consent.purpose ? null : consent.purpose)}
+                    className="w-full flex items-center justify-between text-left"
+                  >
+                    <span className="text-sm font-semibold">{consent.current_document.title}</span>
+                    <span className={`text-xs ${consent.consented ? 'text-green-400' : 'text-yellow-400'}`}>
+                      {consent.consented ? 'Given' : consent.needs_reconsent ? 'Updated - review' : 'Not given'}
+                    </span>
+                  </button>
+                  {expandedConsent === consent.purpose && (
+                    <div className="mt-3 text-sm text-gray-300">
+                      <p className="whitespace-pre-wrap mb-3">{consent.current_document.body}</p>
+                      <p className="text-xs text-gray-500 mb-3">Version {consent.current_document.version}</p>
+                      {consent.consented ? (
+                        <button
+                          onClick={() => handleWithdrawConsent(consent)}
+                          className="px-3 py-1 rounded-lg bg-red-500/10 border border-red-500/20 text-red-400 hover:bg-red-500/20 transition"
+                        >
+                          Withdraw
+                        </button>
+                      ) : (
+                        <GlowButton size="sm" type="button" onClick={() => handleGrantConsent(consent)}>
+                          I agree
+                        </GlowButton>
+                      )}
+                    </div>
+                  )}
+                </div>
+              ))}
+            </div>
+          </motion.div>
+
           {/* Health Tips */}
           <motion.div
             initial={{ opacity: 0, x: 20 }}
@@ -85,7 +85,7 @@ const createHeader = (doc, pageWidth, colors, report) => {
 
 // SUMMARY CARDS SECTION
 const createSummaryCards = (doc, margin, y, colors, report) => {
-  const score = report.confidence / 100 || 0
+  const score = report.confidence || 0
   const barWidth = 170
   const barHeight = 8
 
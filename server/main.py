@@ -1,10 +1,11 @@
 """
 Main FastAPI server for Medical Diagnostic Test Recommendation System
 """
-from fastapi import FastAPI, UploadFile, File, HTTPException
+from fastapi import FastAPI, UploadFile, File, HTTPException, Request
 from fastapi.responses import HTMLResponse, JSONResponse
 from fastapi.middleware.cors import CORSMiddleware
 from fastapi.staticfiles import StaticFiles
+from pydantic import BaseModel
 import uvicorn
 from pdf_extraction.extractor import extract_text_from_pdf
 from nlp_processing.pipeline import process_text
@@ -32,16 +33,47 @@ def index():
     html = html.replace('src="main.js"', 'src="/static/main.js"')
     return html
 
+def _analyze(text, source, redaction=None):
+    entities = process_text(text)
+    recommendations = recommend_tests(entities)
+    log_audit(source, entities, recommendations, redaction)
+    analyzer = {"name": settings["analyzer_name"], "version": settings["analyzer_version"]}
+    return {"entities": entities, "recommendations": recommendations, "analyzer": analyzer}
+
 @app.post("/analyze_report")
 def analyze_report(file: UploadFile = File(...)):
     try:
         if not file.filename.lower().endswith(tuple(settings["allowed_file_types"])):
             raise HTTPException(status_code=400, detail="Invalid file type.")
         text = extract_text_from_pdf(file.file)
-        entities = process_text(text)
-        recommendations = recommend_tests(entities)
-        log_audit(file.filename, entities, recommendations)
-        return JSONResponse(content={"entities": entities, "recommendations": recommendations})
+        return JSONResponse(content=_analyze(text, file.filename))
+    except Exception as e:
+        traceback.print_exc()
+        return JSONResponse(content={"error": str(e)}, status_code=500)
+
+@app.post("/extract_text")
+def extract_text(file: UploadFile = File(...)):
+    """Extract text only; the backend redacts PHI before calling /analyze_text."""
+    try:
+        if not file.filename.lower().endswith(tuple(settings["allowed_file_types"])):
+            raise HTTPException(status_code=400, detail="Invalid file type.")
+        return JSONResponse(content={"text": extract_text_from_pdf(file.file)})
+    except Exception as e:
+        traceback.print_exc()
+        return JSONResponse(content={"error": str(e)}, status_code=500)
+
+class AnalyzeTextRequest(BaseModel):
+    text: str
+
+@app.post("/analyze_text")
+def analyze_text(body: AnalyzeTextRequest, request: Request):
+    """Analyze PHI-redacted text. The X-Redaction-* headers are kept in the audit log."""
+    try:
+        redaction = {
+            "id": request.headers.get("X-Redaction-Id", ""),
+            "report": request.headers.get("X-Redaction-Report", ""),
+        }
+        return JSONResponse(content=_analyze(body.text, "redacted-text", redaction))
     except Exception as e:
         traceback.print_exc()
         return JSONResponse(content={"error": str(e)}, status_code=500)